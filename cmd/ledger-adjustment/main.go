@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db"
+	"github.com/getAlby/lndhub.go/lib"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/joho/godotenv"
+	"github.com/kelseyhightower/envconfig"
+)
+
+// script to post a manual ledger adjustment for a user, e.g. after a tapd incident.
+// the adjustment is booked against the system adjustments account of the asset
+// and recorded in the ledger_adjustments audit table.
+//
+// USER_ID=1 ASSET_ID=btc AMOUNT=-500 REASON="double credit" TICKET_REF=OPS-12 OPERATOR=alice go run ./cmd/ledger-adjustment
+func main() {
+
+	c := &service.Config{}
+	// Load configruation from environment variables
+	err := godotenv.Load(".env")
+	if err != nil {
+		fmt.Println("Failed to load .env file")
+	}
+	err = envconfig.Process("", c)
+	if err != nil {
+		log.Fatalf("Error loading environment variables: %v", err)
+	}
+
+	// Setup logging to STDOUT or a configrued log file
+	logger := lib.Logger(c.LogFilePath)
+
+	req, err := loadAdjustmentFromEnv()
+	if err != nil {
+		logger.Fatalf("Could not load adjustment from env %v", err)
+	}
+	if err = req.Validate(); err != nil {
+		logger.Fatalf("Invalid adjustment: %v", err)
+	}
+	// Open a DB connection based on the configured DATABASE_URI
+	dbConn, err := db.Open(c)
+	if err != nil {
+		logger.Fatalf("Error initializing db connection: %v", err)
+	}
+	svc := &service.LndhubService{
+		Config: c,
+		DB:     dbConn,
+		Logger: logger,
+	}
+	ctx := context.Background()
+	balance, err := svc.CurrentUserBalance(ctx, req.TaAssetID, req.UserID)
+	if err != nil {
+		logger.Fatalf("Could not fetch current balance user_id:%d asset:%s %v", req.UserID, req.TaAssetID, err)
+	}
+	logger.Infof("Adjusting user_id:%d asset:%s balance:%d by %d (ticket %s)", req.UserID, req.TaAssetID, balance, req.Amount, req.TicketRef)
+	if os.Getenv("DRY_RUN") == "true" {
+		logger.Infof("Dry run, resulting balance would be %d", balance+req.Amount)
+		return
+	}
+	adjustment, err := svc.PostLedgerAdjustment(ctx, req)
+	if err != nil {
+		logger.Fatalf("Failed to post adjustment: %v", err)
+	}
+	logger.Infof("Posted adjustment id:%d transaction_entry_id:%d", adjustment.ID, adjustment.TransactionEntryID)
+}
+
+func loadAdjustmentFromEnv() (req service.LedgerAdjustmentRequest, err error) {
+	req.UserID, err = strconv.ParseInt(os.Getenv("USER_ID"), 10, 64)
+	if err != nil {
+		return
+	}
+	req.Amount, err = strconv.ParseInt(os.Getenv("AMOUNT"), 10, 64)
+	if err != nil {
+		return
+	}
+	req.TaAssetID = os.Getenv("ASSET_ID")
+	if req.TaAssetID == "" {
+		req.TaAssetID = common.BTC_TA_ASSET_ID
+	}
+	req.Reason = os.Getenv("REASON")
+	req.TicketRef = os.Getenv("TICKET_REF")
+	req.Operator = os.Getenv("OPERATOR")
+	return
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/getAlby/lndhub.go/db"
+	"github.com/getAlby/lndhub.go/lib"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/joho/godotenv"
+	"github.com/kelseyhightower/envconfig"
+)
+
+// script to take a proof-of-liabilities snapshot and publish its root on nostr.
+// ASSET_ID limits the snapshot to a single asset, otherwise all assets are snapshotted.
+//
+// ASSET_ID=btc go run ./cmd/liability-snapshot
+func main() {
+
+	c := &service.Config{}
+
+	// Load configruation from environment variables
+	err := godotenv.Load(".env")
+	if err != nil {
+		fmt.Println("Failed to load .env file")
+	}
+	err = envconfig.Process("", c)
+	if err != nil {
+		log.Fatalf("Error loading environment variables: %v", err)
+	}
+
+	// Setup logging to STDOUT or a configrued log file
+	logger := lib.Logger(c.LogFilePath)
+
+	// Open a DB connection based on the configured DATABASE_URI
+	dbConn, err := db.Open(c)
+	if err != nil {
+		logger.Fatalf("Error initializing db connection: %v", err)
+	}
+	svc := &service.LndhubService{
+		Config: c,
+		DB:     dbConn,
+		Logger: logger,
+	}
+	ctx := context.Background()
+	assetId := os.Getenv("ASSET_ID")
+	if assetId == "" {
+		err = svc.SnapshotAllLiabilities(ctx)
+		if err != nil {
+			logger.Fatalf("Failed to snapshot liabilities: %v", err)
+		}
+		return
+	}
+	snapshot, err := svc.SnapshotLiabilities(ctx, assetId)
+	if err != nil {
+		logger.Fatalf("Failed to snapshot liabilities for asset %s: %v", assetId, err)
+	}
+	logger.Infof("Snapshot id:%d root:%s sum:%d nostr event:%s", snapshot.ID, snapshot.RootHash, snapshot.RootSum, snapshot.NostrEventID)
+}
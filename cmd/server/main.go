@@ -55,6 +55,10 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error loading environment variables: %v", err)
 	}
+	// receives deeper than one confirmation are only credited by the periodic receive tracking
+	if c.ReceiveConfirmationDepth > 1 && c.ReceiveTrackingInterval <= 0 {
+		log.Fatalf("RECEIVE_CONFIRMATION_DEPTH %d requires a RECEIVE_TRACKING_INTERVAL above 0", c.ReceiveConfirmationDepth)
+	}
 
 	// Setup logging to STDOUT or a configrued log file
 	logger := lib.Logger(c.LogFilePath)
@@ -141,11 +145,21 @@ func main() {
 		DB:             dbConn,
 		LndClient:      lndClient,
 		TapdClient:     tapdClient,
+		AssetChannelClient: tapd.InitAssetChannelClient(tapdConfig),
 		Logger:         logger,
 		InvoicePubSub:  service.NewPubsub(),
 		//TaprootAssetPubSub: service.NewTapdPubsub(),
 		RabbitMQClient: rabbitmqClient,
 	}
+	priceOracle, err := service.NewPriceOracle(c)
+	if err != nil {
+		logger.Fatalf("Error initializing the price oracle: %v", err)
+	}
+	svc.PriceOracle = priceOracle
+	// pick up decimal displays of assets registered before they were tracked or changed on tapd
+	if err := svc.RefreshAssetDecimalDisplays(startupCtx); err != nil {
+		logger.Errorf("Error reading asset decimal displays: %v", err)
+	}
 
 	//init echo server
 	e := transport.InitEcho(c, logger)
@@ -170,6 +184,11 @@ func main() {
 	docs.SwaggerInfo.Host = c.Host
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
+	// receives credited before the address was recorded get it from tapd, before they are tracked again
+	if err := svc.BackfillReceiveAddresses(startupCtx); err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("Error backfilling receive addresses: %v", err)
+	}
 	var backgroundWg sync.WaitGroup
 	backGroundCtx, _ := signal.NotifyContext(context.Background(), os.Interrupt)
 	// Subscribe to TAPD process `receive`` updates in the background
@@ -245,6 +264,105 @@ func main() {
 		svc.Logger.Info("Tapd Send routine done")
 		backgroundWg.Done()
 	}()
+	// Periodically compare user liabilities against tapd and lnd holdings
+	if svc.Config.SolvencyCheckInterval > 0 {
+		backgroundWg.Add(1)
+		go func() {
+			svc.StartSolvencyRoutine(backGroundCtx)
+			svc.Logger.Info("Solvency routine done")
+			backgroundWg.Done()
+		}()
+	}
+	// Periodically publish proof-of-liabilities roots
+	if svc.Config.LiabilitySnapshotInterval > 0 {
+		backgroundWg.Add(1)
+		go func() {
+			svc.StartLiabilitySnapshotRoutine(backGroundCtx)
+			svc.Logger.Info("Liability snapshot routine done")
+			backgroundWg.Done()
+		}()
+	}
+	// Periodically track mint batches and register minted assets
+	if svc.Config.MintBatchSyncInterval > 0 {
+		backgroundWg.Add(1)
+		go func() {
+			svc.StartMintBatchSyncRoutine(backGroundCtx)
+			svc.Logger.Info("Mint batch sync routine done")
+			backgroundWg.Done()
+		}()
+	}
+	// Book the batches interrupted by the last shutdown, then periodically send the queued
+	// external asset sends in batches
+	if err := svc.ReconcileSendingBatches(startupCtx); err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("Error reconciling sending batches: %v", err)
+	}
+	backgroundWg.Add(1)
+	go func() {
+		svc.StartSendBatchRoutine(backGroundCtx)
+		svc.Logger.Info("Send batch routine done")
+		backgroundWg.Done()
+	}()
+	// Book the burns interrupted by the last shutdown, then keep reconciling the pending burns
+	if err := svc.ReconcilePendingBurns(startupCtx); err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("Error reconciling pending burns: %v", err)
+	}
+	backgroundWg.Add(1)
+	go func() {
+		svc.StartBurnReconcileRoutine(backGroundCtx)
+		svc.Logger.Info("Burn reconcile routine done")
+		backgroundWg.Done()
+	}()
+	// Keep the price history current for fiat valuations
+	if svc.PriceOracle != nil {
+		if err := svc.RefreshPrices(startupCtx); err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("Error reading prices from the %s price oracle: %v", svc.PriceOracle.Source(), err)
+		}
+		if svc.Config.PriceOracleInterval > 0 {
+			backgroundWg.Add(1)
+			go func() {
+				svc.StartPriceOracleRoutine(backGroundCtx)
+				svc.Logger.Info("Price oracle routine done")
+				backgroundWg.Done()
+			}()
+		}
+	}
+	// Periodically move receives through their confirmation states
+	if svc.Config.ReceiveTrackingInterval > 0 {
+		backgroundWg.Add(1)
+		go func() {
+			svc.StartReceiveTrackingRoutine(backGroundCtx)
+			svc.Logger.Info("Receive tracking routine done")
+			backgroundWg.Done()
+		}()
+	}
+	// Return transfers to unregistered pubkeys that were not claimed in time
+	if svc.Config.ClaimableTransferExpiry > 0 && svc.Config.ClaimableTransferExpiryInterval > 0 {
+		backgroundWg.Add(1)
+		go func() {
+			svc.StartClaimableTransferExpiryRoutine(backGroundCtx)
+			svc.Logger.Info("Claimable transfer expiry routine done")
+			backgroundWg.Done()
+		}()
+	}
+	// Mark btc checkouts paid as their invoices settle
+	backgroundWg.Add(1)
+	go func() {
+		svc.StartCheckoutInvoiceSubscription(backGroundCtx)
+		svc.Logger.Info("Checkout invoice routine done")
+		backgroundWg.Done()
+	}()
+	// Periodically expire checkouts, catch up on missed payments and retry their webhooks
+	if svc.Config.CheckoutInterval > 0 {
+		backgroundWg.Add(1)
+		go func() {
+			svc.StartCheckoutRoutine(backGroundCtx)
+			svc.Logger.Info("Checkout routine done")
+			backgroundWg.Done()
+		}()
+	}
 	//Start webhook subscription
 	if svc.Config.WebhookUrl != "" {
 		backgroundWg.Add(1)
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/getAlby/lndhub.go/db"
+	"github.com/getAlby/lndhub.go/lib"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/getAlby/lndhub.go/lnd"
+	"github.com/getAlby/lndhub.go/tapd"
+	"github.com/joho/godotenv"
+	"github.com/kelseyhightower/envconfig"
+)
+
+// script to compare the sum of all users' current balances against the
+// tapd and lnd holdings of the hub. exits with status 1 if the liabilities
+// for any asset exceed the holdings so it can be run as a cron job.
+func main() {
+
+	c := &service.Config{}
+
+	// Load configruation from environment variables
+	err := godotenv.Load(".env")
+	if err != nil {
+		fmt.Println("Failed to load .env file")
+	}
+	err = envconfig.Process("", c)
+	if err != nil {
+		log.Fatalf("Error loading environment variables: %v", err)
+	}
+
+	// Setup logging to STDOUT or a configrued log file
+	logger := lib.Logger(c.LogFilePath)
+
+	// Open a DB connection based on the configured DATABASE_URI
+	dbConn, err := db.Open(c)
+	if err != nil {
+		logger.Fatalf("Error initializing db connection: %v", err)
+	}
+	ctx := context.Background()
+
+	// Init new LND client
+	lnCfg, err := lnd.LoadConfig()
+	if err != nil {
+		logger.Fatalf("Failed to load lnd config %v", err)
+	}
+	lndClient, err := lnd.InitLNClient(lnCfg, logger, ctx)
+	if err != nil {
+		logger.Fatalf("Error initializing the %s connection: %v", lnCfg.LNClientType, err)
+	}
+	// Init new TAPD client
+	tapdConfig, err := tapd.LoadConfig()
+	if err != nil {
+		logger.Fatalf("Failed to load tapd config %v", err)
+	}
+	tapdClient, err := tapd.InitTAPDClient(tapdConfig, logger, ctx)
+	if err != nil {
+		logger.Fatalf("Error initializing the %s connection: %v", tapdConfig.TAPDClientType, err)
+	}
+
+	svc := &service.LndhubService{
+		Config:     c,
+		DB:         dbConn,
+		LndClient:  lndClient,
+		TapdClient: tapdClient,
+		Logger:     logger,
+	}
+	report, err := svc.GetSolvencyReport(ctx)
+	if err != nil {
+		logger.Fatalf("Failed to compute solvency report: %v", err)
+	}
+	for _, asset := range report.Assets {
+		logger.Infof("asset:%s (%s) liabilities:%d holdings:%d surplus:%d solvent:%t", asset.TaAssetID, asset.AssetName, asset.Liabilities, asset.Holdings, asset.Surplus, asset.Solvent)
+	}
+	if !report.Solvent {
+		logger.Error("Liabilities exceed holdings")
+		os.Exit(1)
+	}
+	logger.Info("Holdings cover all liabilities")
+}
@@ -29,6 +29,29 @@ const (
 	AccountTypeCurrent  = "current"
 	AccountTypeOutgoing = "outgoing"
 	AccountTypeFees     = "fees"
+	// funds locked by resting orders
+	AccountTypeReserved = "reserved"
+	// system account types, these accounts have no user_id
+	AccountTypeAdjustments = "adjustments"
+	AccountTypeTreasury    = "treasury"
+	AccountTypeFeeRevenue  = "fee_revenue"
+	AccountTypeChainFees   = "chain_fees"
+	AccountTypeSuspense    = "suspense"
+	AccountTypeBurned      = "burned"
+	AccountTypeLiquidity   = "liquidity"
+	AccountTypeEscrow      = "escrow"
 
 	DestinationPubkeyHexSize = 66
 )
+
+// hub owned accounts created for every asset
+var SystemAccountTypes = []string{
+	AccountTypeTreasury,
+	AccountTypeFeeRevenue,
+	AccountTypeChainFees,
+	AccountTypeSuspense,
+	AccountTypeAdjustments,
+	AccountTypeBurned,
+	AccountTypeLiquidity,
+	AccountTypeEscrow,
+}
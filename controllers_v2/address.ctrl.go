@@ -2,7 +2,6 @@ package v2controllers
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/getAlby/lndhub.go/lib/responses"
 	"github.com/getAlby/lndhub.go/lib/service"
@@ -36,7 +35,7 @@ type AddressResponseBody struct {
 // @Produce      json
 // @Tags         Address
 // @Param        asset_id  body  string  true  "Asset ID"
-// @Param        amt       body  string  true  "Amount"
+// @Param        amt       body  string  true  "Amount as a decimal string in the asset's decimal display"
 // @Success      200      {object}  AddressResponseBody
 // @Failure      400      {object}  responses.ErrorResponse
 // @Failure      500      {object}  responses.ErrorResponse
@@ -57,11 +56,15 @@ func (controller *AddressController) CreateAddress(c echo.Context) error {
 		// TODO this is not a nostr error responder
 		return controller.responder.NostrErrorJson(c, responses.BadArgumentsError.Message)
 	}
-	// convert string amount to uint
-	amt, err := strconv.ParseUint(body.Amt, 10, 64)
+	// convert the decimal string amount to asset units
+	amt, err := controller.svc.ParseAssetAmountFor(c.Request().Context(), body.AssetId, body.Amt)
 	if err != nil {
-		c.Logger().Errorf("Invalid amount. Pass value as a string: %v", err)
-		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+		c.Logger().Errorf("Invalid amount. Pass value as a decimal string: %v", err)
+		return c.JSON(http.StatusBadRequest, &responses.ErrorResponse{
+			Error:   true,
+			Code:    responses.BadArgumentsError.Code,
+			Message: err.Error(),
+		})
 	}
 	result, err := controller.svc.FetchOrCreateAssetAddr(c.Request().Context(), uint64(userId), body.AssetId, amt)
 	if err != nil {
@@ -0,0 +1,101 @@
+package v2controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// AdjustmentController : Admin ledger adjustment controller struct
+type AdjustmentController struct {
+	svc *service.LndhubService
+}
+
+func NewAdjustmentController(svc *service.LndhubService) *AdjustmentController {
+	return &AdjustmentController{svc: svc}
+}
+
+type AdjustmentRequestBody struct {
+	UserID    int64  `json:"user_id" validate:"required"`
+	AssetID   string `json:"asset_id" validate:"required"`
+	Amount    int64  `json:"amount" validate:"required"`
+	Reason    string `json:"reason" validate:"required"`
+	TicketRef string `json:"ticket_ref" validate:"required"`
+	Operator  string `json:"operator" validate:"required"`
+}
+
+type AdjustmentsResponseBody struct {
+	Adjustments []models.LedgerAdjustment `json:"adjustments"`
+}
+
+// PostAdjustment godoc
+// @Summary      Post a manual ledger adjustment
+// @Description  Credit (positive amount) or debit (negative amount) a user's current account against the system adjustments account. Requires Authorization header with admin token.
+// @Accept       json
+// @Produce      json
+// @Tags         Admin
+// @Param        adjustment  body      AdjustmentRequestBody  true  "Ledger adjustment"
+// @Success      200         {object}  models.LedgerAdjustment
+// @Failure      400         {object}  responses.ErrorResponse
+// @Failure      500         {object}  responses.ErrorResponse
+// @Router       /v2/admin/adjustments [post]
+func (controller *AdjustmentController) PostAdjustment(c echo.Context) error {
+	var body AdjustmentRequestBody
+
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load adjustment request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid adjustment request body error: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	adjustment, err := controller.svc.PostLedgerAdjustment(c.Request().Context(), service.LedgerAdjustmentRequest{
+		UserID:    body.UserID,
+		TaAssetID: body.AssetID,
+		Amount:    body.Amount,
+		Reason:    body.Reason,
+		TicketRef: body.TicketRef,
+		Operator:  body.Operator,
+	})
+	if err != nil {
+		c.Logger().Errorf("Failed to post adjustment: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	return c.JSON(http.StatusOK, adjustment)
+}
+
+// ListAdjustments godoc
+// @Summary      List ledger adjustments
+// @Description  Audit trail of the latest manual ledger adjustments, optionally filtered by user. Requires Authorization header with admin token.
+// @Accept       json
+// @Produce      json
+// @Tags         Admin
+// @Param        user_id  query     int  false  "User ID"
+// @Success      200      {object}  AdjustmentsResponseBody
+// @Failure      400      {object}  responses.ErrorResponse
+// @Failure      500      {object}  responses.ErrorResponse
+// @Router       /v2/admin/adjustments [get]
+func (controller *AdjustmentController) ListAdjustments(c echo.Context) error {
+	var userId int64
+	if param := c.QueryParam("user_id"); param != "" {
+		parsed, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			c.Logger().Errorf("Invalid user_id query param: %v", err)
+			return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+		}
+		userId = parsed
+	}
+	adjustments, err := controller.svc.LedgerAdjustmentsFor(c.Request().Context(), userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to list adjustments: %v", err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &AdjustmentsResponseBody{
+		Adjustments: adjustments,
+	})
+}
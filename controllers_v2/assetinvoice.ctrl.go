@@ -0,0 +1,140 @@
+package v2controllers
+
+import (
+	"net/http"
+
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// AssetInvoiceController : taproot asset invoices and payments over lightning
+type AssetInvoiceController struct {
+	svc *service.LndhubService
+}
+
+func NewAssetInvoiceController(svc *service.LndhubService) *AssetInvoiceController {
+	return &AssetInvoiceController{svc: svc}
+}
+
+type AddAssetInvoiceRequestBody struct {
+	AssetID string `json:"asset_id" validate:"required"`
+	Amount  string `json:"amount" validate:"required"`
+	Memo    string `json:"memo"`
+}
+
+type AddAssetInvoiceResponseBody struct {
+	AssetID        string `json:"asset_id"`
+	Amount         int64  `json:"amount"`
+	PaymentHash    string `json:"payment_hash"`
+	PaymentRequest string `json:"payment_request"`
+	ExpiresAt      int64  `json:"expires_at"`
+}
+
+type PayAssetInvoiceRequestBody struct {
+	AssetID string `json:"asset_id" validate:"required"`
+	Invoice string `json:"invoice" validate:"required"`
+}
+
+type PayAssetInvoiceResponseBody struct {
+	AssetID         string `json:"asset_id"`
+	Amount          int64  `json:"amount"`
+	PaymentHash     string `json:"payment_hash"`
+	PaymentPreimage string `json:"payment_preimage"`
+}
+
+// AddAssetInvoice godoc
+// @Summary      Create an asset invoice
+// @Description  Creates a lightning invoice that is paid in a taproot asset through an asset channel
+// @Accept       json
+// @Produce      json
+// @Tags         Invoice
+// @Param        invoice  body      AddAssetInvoiceRequestBody  True  "Asset invoice"
+// @Success      200      {object}  AddAssetInvoiceResponseBody
+// @Failure      400      {object}  responses.ErrorResponse
+// @Failure      500      {object}  responses.ErrorResponse
+// @Router       /v2/invoices/asset [post]
+// @Security     OAuth2Password
+func (controller *AssetInvoiceController) AddAssetInvoice(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	var body AddAssetInvoiceRequestBody
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load asset invoice request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid asset invoice request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	amount, err := controller.svc.ParseAssetAmountFor(c.Request().Context(), body.AssetID, body.Amount)
+	if err != nil {
+		c.Logger().Errorf("Invalid asset invoice amount %s: %v", body.Amount, err)
+		return c.JSON(http.StatusBadRequest, &responses.ErrorResponse{
+			Error:   true,
+			Code:    responses.BadArgumentsError.Code,
+			Message: err.Error(),
+		})
+	}
+	invoice, err := controller.svc.AddAssetInvoice(c.Request().Context(), userId, body.AssetID, int64(amount), body.Memo)
+	if err != nil {
+		c.Logger().Errorf("Failed to create asset invoice user_id:%d: %v", userId, err)
+		if service.IsAssetInvoiceUserError(err) {
+			return c.JSON(http.StatusBadRequest, &responses.ErrorResponse{
+				Error:   true,
+				Code:    responses.BadArgumentsError.Code,
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &AddAssetInvoiceResponseBody{
+		AssetID:        invoice.TaAssetID,
+		Amount:         invoice.Amount,
+		PaymentHash:    invoice.RHash,
+		PaymentRequest: invoice.PaymentRequest,
+		ExpiresAt:      invoice.ExpiresAt.Unix(),
+	})
+}
+
+// PayAssetInvoice godoc
+// @Summary      Pay an invoice with a taproot asset
+// @Description  Pays a lightning invoice with the user's asset balance through an asset channel
+// @Accept       json
+// @Produce      json
+// @Tags         Payment
+// @Param        PayAssetInvoiceRequest  body      PayAssetInvoiceRequestBody  True  "Invoice to pay"
+// @Success      200                     {object}  PayAssetInvoiceResponseBody
+// @Failure      400                     {object}  responses.ErrorResponse
+// @Failure      500                     {object}  responses.ErrorResponse
+// @Router       /v2/payments/asset [post]
+// @Security     OAuth2Password
+func (controller *AssetInvoiceController) PayAssetInvoice(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	var body PayAssetInvoiceRequestBody
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load pay asset invoice request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid pay asset invoice request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	result, err := controller.svc.PayAssetInvoice(c.Request().Context(), userId, body.AssetID, body.Invoice)
+	if err != nil {
+		c.Logger().Errorf("Failed to pay asset invoice user_id:%d: %v", userId, err)
+		if service.IsAssetInvoiceUserError(err) {
+			return c.JSON(http.StatusBadRequest, &responses.ErrorResponse{
+				Error:   true,
+				Code:    responses.BadArgumentsError.Code,
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &PayAssetInvoiceResponseBody{
+		AssetID:         result.Invoice.TaAssetID,
+		Amount:          result.Invoice.Amount,
+		PaymentHash:     result.PaymentHashStr,
+		PaymentPreimage: result.PaymentPreimageStr,
+	})
+}
@@ -21,6 +21,9 @@ func NewBalanceController(svc *service.LndhubService) *BalanceController {
 type BalanceResponse struct {
 	Balance  int64  `json:"balance"`
 	AssetId  string `json:"asset_id"`
+	// balance as a decimal string in the asset's decimal display
+	Amount         string `json:"amount"`
+	DecimalDisplay uint32 `json:"decimal_display"`
 	// TODO add these back with taproot-assets multi-asset double-entry ledger
 	// Currency string `json:"currency"`
 	// Unit     string `json:"unit"`
@@ -29,6 +32,10 @@ type BalanceResponse struct {
 /// get all balances
 type BalancesResponse struct {
 	Balances map[string]int64 `json:"balances"`
+	// balances as decimal strings in the asset's decimal display
+	Amounts map[string]string `json:"amounts"`
+	// fiat value of the balances, only with a price oracle configured
+	Valuation *service.BalanceValuation `json:"valuation,omitempty"`
 }
 
 // Balance godoc
@@ -60,9 +67,16 @@ func (controller *BalanceController) Balance(c echo.Context) error {
 		)
 		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
 	}
+	decimalDisplay, err := controller.svc.AssetDecimalDisplay(c.Request().Context(), assetParam)
+	if err != nil {
+		c.Logger().Errorf("failed to retrieve decimal display of asset %s: %v", assetParam, err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
 	return c.JSON(http.StatusOK, &BalanceResponse{
-		Balance:  balance,
-		AssetId: assetParam,
+		Balance:        balance,
+		AssetId:        assetParam,
+		Amount:         service.FormatAssetAmount(balance, decimalDisplay),
+		DecimalDisplay: decimalDisplay,
 	})
 }
 
@@ -79,7 +93,7 @@ func (controller *BalanceController) Balance(c echo.Context) error {
 func (controller *BalanceController) Balances(c echo.Context) error {
 	userId := c.Get("UserID").(int64)
 
-	balances, err := controller.svc.GetAllCurrentBalancesJson(c.Request().Context(), userId)
+	balances, amounts, err := controller.svc.GetAllCurrentBalancesJson(c.Request().Context(), userId)
 	if err != nil {
 		c.Logger().Errorj(
 			log.JSON{
@@ -90,7 +104,14 @@ func (controller *BalanceController) Balances(c echo.Context) error {
 		)
 		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
 	}
+	// balances are returned without a valuation if the prices are unavailable
+	valuation, err := controller.svc.ValueBalances(c.Request().Context(), userId)
+	if err != nil {
+		c.Logger().Errorf("failed to value balances of user %d: %v", userId, err)
+	}
 	return c.JSON(http.StatusOK, &BalancesResponse{
-		Balances: balances,
+		Balances:  balances,
+		Amounts:   amounts,
+		Valuation: valuation,
 	})
 }
@@ -0,0 +1,39 @@
+package v2controllers
+
+import (
+	"net/http"
+
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// BalanceSheetController : Admin balance sheet controller struct
+type BalanceSheetController struct {
+	svc *service.LndhubService
+}
+
+func NewBalanceSheetController(svc *service.LndhubService) *BalanceSheetController {
+	return &BalanceSheetController{svc: svc}
+}
+
+type BalanceSheetResponseBody struct {
+	Assets []service.AssetBalanceSheet `json:"assets"`
+}
+
+// BalanceSheet godoc
+// @Summary      Retrieve the hub balance sheet
+// @Description  Per asset sums of user liabilities and the system treasury, fee revenue, chain fees, suspense and adjustments accounts. Requires Authorization header with admin token.
+// @Produce      json
+// @Tags         Admin
+// @Success      200  {object}  BalanceSheetResponseBody
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/admin/balance-sheet [get]
+func (controller *BalanceSheetController) BalanceSheet(c echo.Context) error {
+	sheets, err := controller.svc.GetBalanceSheet(c.Request().Context())
+	if err != nil {
+		c.Logger().Errorf("Failed to compute balance sheet: %v", err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &BalanceSheetResponseBody{Assets: sheets})
+}
@@ -0,0 +1,128 @@
+package v2controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// BurnController : asset burning and supply controller struct
+type BurnController struct {
+	svc *service.LndhubService
+}
+
+func NewBurnController(svc *service.LndhubService) *BurnController {
+	return &BurnController{svc: svc}
+}
+
+type BurnRequestBody struct {
+	AssetID string `json:"asset_id" validate:"required"`
+	Amount  int64  `json:"amount" validate:"required,gt=0"`
+	Note    string `json:"note"`
+}
+
+type AdminBurnRequestBody struct {
+	// empty burns hub owned units from the treasury
+	UserID  int64  `json:"user_id"`
+	AssetID string `json:"asset_id" validate:"required"`
+	Amount  int64  `json:"amount" validate:"required,gt=0"`
+	Note    string `json:"note"`
+}
+
+// Burn godoc
+// @Summary      Burn assets
+// @Description  Destroys units of a taproot asset from the user's current balance
+// @Accept       json
+// @Produce      json
+// @Tags         Assets
+// @Param        burn  body      BurnRequestBody  true  "Burn"
+// @Success      200   {object}  models.AssetBurn
+// @Failure      400   {object}  responses.ErrorResponse
+// @Failure      500   {object}  responses.ErrorResponse
+// @Router       /v2/burns [post]
+// @Security     OAuth2Password
+func (controller *BurnController) Burn(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	var body BurnRequestBody
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load burn request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid burn request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	return controller.burn(c, service.BurnAssetRequest{
+		UserID:    userId,
+		TaAssetID: body.AssetID,
+		Amount:    body.Amount,
+		Note:      body.Note,
+	})
+}
+
+// AdminBurn godoc
+// @Summary      Burn assets of a user or the treasury
+// @Description  Destroys units of a taproot asset from a user's current balance, or from the hub owned treasury if user_id is empty. Requires Authorization header with admin token.
+// @Accept       json
+// @Produce      json
+// @Tags         Admin
+// @Param        burn  body      AdminBurnRequestBody  true  "Burn"
+// @Success      200   {object}  models.AssetBurn
+// @Failure      400   {object}  responses.ErrorResponse
+// @Failure      500   {object}  responses.ErrorResponse
+// @Router       /v2/admin/burns [post]
+func (controller *BurnController) AdminBurn(c echo.Context) error {
+	var body AdminBurnRequestBody
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load admin burn request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid admin burn request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	return controller.burn(c, service.BurnAssetRequest{
+		UserID:    body.UserID,
+		TaAssetID: body.AssetID,
+		Amount:    body.Amount,
+		Note:      body.Note,
+	})
+}
+
+func (controller *BurnController) burn(c echo.Context, req service.BurnAssetRequest) error {
+	burn, err := controller.svc.BurnAsset(c.Request().Context(), req)
+	if err != nil {
+		c.Logger().Errorf("Failed to burn asset user_id:%d asset:%s: %v", req.UserID, req.TaAssetID, err)
+		if errors.Is(err, service.BurnBtcError) || errors.Is(err, service.InvalidBurnAmountError) || errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+		}
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, burn)
+}
+
+// Supply godoc
+// @Summary      Asset supply
+// @Description  Total issued, burned and circulating units of a taproot asset
+// @Produce      json
+// @Tags         Assets
+// @Param        asset_id  path      string  true  "Asset ID"
+// @Success      200       {object}  service.AssetSupply
+// @Failure      400       {object}  responses.ErrorResponse
+// @Failure      500       {object}  responses.ErrorResponse
+// @Router       /v2/assets/{asset_id}/supply [get]
+func (controller *BurnController) Supply(c echo.Context) error {
+	supply, err := controller.svc.AssetSupplyFor(c.Request().Context(), c.Param("asset_id"))
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch supply of asset %s: %v", c.Param("asset_id"), err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+		}
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, supply)
+}
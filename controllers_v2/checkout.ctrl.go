@@ -0,0 +1,142 @@
+package v2controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// CheckoutController : merchants getting paid for orders
+type CheckoutController struct {
+	svc *service.LndhubService
+}
+
+func NewCheckoutController(svc *service.LndhubService) *CheckoutController {
+	return &CheckoutController{svc: svc}
+}
+
+type CheckoutRequestBody struct {
+	AssetID string `json:"asset_id" validate:"required"`
+	// decimal amount of the asset, leave empty for a fiat priced checkout
+	Amount string `json:"amount"`
+	// decimal amount in the hub's price currency, converted at the latest price of the asset
+	FiatAmount string `json:"fiat_amount"`
+	Memo       string `json:"memo"`
+	// the merchant's own reference of the order
+	OrderRef string `json:"order_ref"`
+	// receives the signed checkout.paid event, defaults to the hub's CHECKOUT_WEBHOOK_URL
+	WebhookUrl string `json:"webhook_url"`
+}
+
+// CheckoutResponseBody : a new checkout with the secret its webhooks are signed with, which is
+// only returned here
+type CheckoutResponseBody struct {
+	models.Checkout
+	WebhookSecret string `json:"webhook_secret,omitempty"`
+}
+
+type CheckoutsResponseBody struct {
+	Checkouts []models.Checkout `json:"checkouts"`
+}
+
+// Create godoc
+// @Summary      Create a checkout
+// @Description  Opens a checkout for an amount of an asset or a fiat amount. Asset checkouts get their own tap address, btc checkouts an LN invoice.
+// @Accept       json
+// @Produce      json
+// @Tags         Checkouts
+// @Param        checkout  body      CheckoutRequestBody  True  "Checkout"
+// @Success      200       {object}  CheckoutResponseBody
+// @Failure      400       {object}  responses.ErrorResponse
+// @Failure      500       {object}  responses.ErrorResponse
+// @Router       /v2/checkouts [post]
+// @Security     OAuth2Password
+func (controller *CheckoutController) Create(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	var body CheckoutRequestBody
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load checkout request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid checkout request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	checkout, err := controller.svc.CreateCheckout(c.Request().Context(), service.CheckoutRequest{
+		UserID:     userId,
+		AssetID:    body.AssetID,
+		Amount:     body.Amount,
+		FiatAmount: body.FiatAmount,
+		Memo:       body.Memo,
+		OrderRef:   body.OrderRef,
+		WebhookUrl: body.WebhookUrl,
+	})
+	if err != nil {
+		c.Logger().Errorf("Failed to create checkout user_id:%d: %v", userId, err)
+		return checkoutError(c, err)
+	}
+	return c.JSON(http.StatusOK, &CheckoutResponseBody{Checkout: *checkout, WebhookSecret: checkout.WebhookSecret})
+}
+
+// Get godoc
+// @Summary      Checkout status
+// @Description  A checkout of the merchant
+// @Produce      json
+// @Tags         Checkouts
+// @Param        id   path      int  true  "Checkout ID"
+// @Success      200  {object}  models.Checkout
+// @Failure      400  {object}  responses.ErrorResponse
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/checkouts/{id} [get]
+// @Security     OAuth2Password
+func (controller *CheckoutController) Get(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	checkoutId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Logger().Errorf("Invalid checkout id: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	checkout, err := controller.svc.CheckoutFor(c.Request().Context(), userId, checkoutId)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch checkout:%d user_id:%d: %v", checkoutId, userId, err)
+		return checkoutError(c, err)
+	}
+	return c.JSON(http.StatusOK, checkout)
+}
+
+// List godoc
+// @Summary      List checkouts
+// @Description  Latest checkouts of the merchant, optionally filtered by state
+// @Produce      json
+// @Tags         Checkouts
+// @Param        state  query     string  false  "open, paid or expired"
+// @Success      200    {object}  CheckoutsResponseBody
+// @Failure      400    {object}  responses.ErrorResponse
+// @Failure      500    {object}  responses.ErrorResponse
+// @Router       /v2/checkouts [get]
+// @Security     OAuth2Password
+func (controller *CheckoutController) List(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	checkouts, err := controller.svc.CheckoutsFor(c.Request().Context(), userId, c.QueryParam("state"))
+	if err != nil {
+		c.Logger().Errorf("Failed to list checkouts user_id:%d: %v", userId, err)
+		return checkoutError(c, err)
+	}
+	return c.JSON(http.StatusOK, &CheckoutsResponseBody{Checkouts: checkouts})
+}
+
+// checkoutError reports the errors a merchant can act on with their message, anything else as a server error
+func checkoutError(c echo.Context, err error) error {
+	if service.IsCheckoutUserError(err) {
+		return c.JSON(http.StatusBadRequest, &responses.ErrorResponse{
+			Error:   true,
+			Code:    responses.BadArgumentsError.Code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+}
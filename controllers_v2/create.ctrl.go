@@ -55,6 +55,10 @@ func (controller *CreateUserController) CreateUser(c echo.Context) error {
 		c.Logger().Errorf("Failed to create user: %v", err)
 		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
 	}
+	// credit what was sent to the pubkey before it registered
+	if _, err := controller.svc.ClaimTransfersFor(c.Request().Context(), user); err != nil {
+		c.Logger().Errorf("Failed to claim transfers for user_id:%d: %v", user.ID, err)
+	}
 
 	var ResponseBody CreateUserResponseBody
 	ResponseBody.Pubkey = user.Pubkey
@@ -0,0 +1,101 @@
+package v2controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// LnurlController : LNURL-pay for the lightning addresses of hub users
+type LnurlController struct {
+	svc *service.LndhubService
+}
+
+func NewLnurlController(svc *service.LndhubService) *LnurlController {
+	return &LnurlController{svc: svc}
+}
+
+// LnurlErrorResponseBody : LNURL wallets expect errors in this form instead of the regular response
+type LnurlErrorResponseBody struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+type LnurlCallbackResponseBody struct {
+	PaymentRequest string        `json:"pr"`
+	Routes         []interface{} `json:"routes"`
+}
+
+// PayParams godoc
+// @Summary      Lightning address
+// @Description  LNURL-pay parameters of the lightning address of a username
+// @Produce      json
+// @Tags         LNURL
+// @Param        name  path      string  true  "Username"
+// @Success      200   {object}  service.LnurlPayParams
+// @Failure      404   {object}  LnurlErrorResponseBody
+// @Router       /.well-known/lnurlp/{name} [get]
+func (controller *LnurlController) PayParams(c echo.Context) error {
+	params, err := controller.svc.LnurlPayParamsFor(c.Request().Context(), c.Param("name"))
+	if err != nil {
+		c.Logger().Errorf("Failed to serve lnurlp for %s: %v", c.Param("name"), err)
+		return lnurlError(c, err)
+	}
+	return c.JSON(http.StatusOK, params)
+}
+
+// Callback godoc
+// @Summary      LNURL-pay callback
+// @Description  Creates the invoice of a payment to a lightning address
+// @Produce      json
+// @Tags         LNURL
+// @Param        name     path      string  true   "Username"
+// @Param        amount   query     int     true   "Amount in millisatoshis"
+// @Param        comment  query     string  false  "Comment for the recipient"
+// @Success      200      {object}  LnurlCallbackResponseBody
+// @Failure      400      {object}  LnurlErrorResponseBody
+// @Router       /lnurlp/{name}/callback [get]
+func (controller *LnurlController) Callback(c echo.Context) error {
+	ctx := c.Request().Context()
+	amount, err := strconv.ParseInt(c.QueryParam("amount"), 10, 64)
+	if err != nil {
+		return lnurlError(c, service.LnurlAmountError)
+	}
+	user, err := controller.svc.FindUserByUsername(ctx, c.Param("name"))
+	if err != nil {
+		c.Logger().Errorf("Failed to find lnurlp user %s: %v", c.Param("name"), err)
+		return lnurlError(c, err)
+	}
+	resp, err := controller.svc.CheckIncomingPaymentAllowed(c, amount/1000, common.BTC_TA_ASSET_ID, user.ID)
+	if err != nil {
+		return lnurlError(c, err)
+	}
+	if resp != nil {
+		c.Logger().Errorf("Error: %v user_id:%v amount:%v", resp.Message, user.ID, amount)
+		return c.JSON(http.StatusBadRequest, &LnurlErrorResponseBody{Status: "ERROR", Reason: resp.Message})
+	}
+	invoice, err := controller.svc.LnurlPayInvoice(ctx, user, amount, c.QueryParam("comment"))
+	if err != nil {
+		c.Logger().Errorf("Failed to create lnurlp invoice user_id:%d: %v", user.ID, err)
+		return lnurlError(c, err)
+	}
+	return c.JSON(http.StatusOK, &LnurlCallbackResponseBody{
+		PaymentRequest: invoice.PaymentRequest,
+		Routes:         []interface{}{},
+	})
+}
+
+// lnurlError reports the errors a payer can act on with their message, anything else as a server error
+func lnurlError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.UsernameNotFoundError), errors.Is(err, service.LightningAddressesDisabledError):
+		return c.JSON(http.StatusNotFound, &LnurlErrorResponseBody{Status: "ERROR", Reason: err.Error()})
+	case service.IsLnurlUserError(err):
+		return c.JSON(http.StatusBadRequest, &LnurlErrorResponseBody{Status: "ERROR", Reason: err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, &LnurlErrorResponseBody{Status: "ERROR", Reason: "internal server error"})
+}
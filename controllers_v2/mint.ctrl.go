@@ -0,0 +1,148 @@
+package v2controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// MintController : Admin asset minting controller struct
+type MintController struct {
+	svc *service.LndhubService
+}
+
+func NewMintController(svc *service.LndhubService) *MintController {
+	return &MintController{svc: svc}
+}
+
+type MintAssetRequestBody struct {
+	Name            string `json:"name" validate:"required"`
+	AssetType       string `json:"asset_type" validate:"required,oneof=normal collectible"`
+	Amount          int64  `json:"amount" validate:"required,gt=0"`
+	Metadata        string `json:"metadata"`
+	DecimalDisplay  uint32 `json:"decimal_display"`
+	GroupKey        string `json:"group_key"`
+	NewGroupedAsset bool   `json:"new_grouped_asset"`
+	IssuerUserID    int64  `json:"issuer_user_id"`
+}
+
+type FinalizeMintBatchRequestBody struct {
+	// sat/kw, 0 lets tapd estimate the fee rate
+	FeeRate uint32 `json:"fee_rate"`
+}
+
+type MintBatchesResponseBody struct {
+	Batches []models.MintBatch `json:"batches"`
+}
+
+// MintAsset godoc
+// @Summary      Add an asset to the pending mint batch
+// @Description  Adds a normal or collectible asset to tapd's pending minting batch. The minted supply is credited to issuer_user_id once the batch is finalized. Requires Authorization header with admin token.
+// @Accept       json
+// @Produce      json
+// @Tags         Admin
+// @Param        asset  body      MintAssetRequestBody  true  "Asset to mint"
+// @Success      200    {object}  models.MintBatchAsset
+// @Failure      400    {object}  responses.ErrorResponse
+// @Failure      500    {object}  responses.ErrorResponse
+// @Router       /v2/admin/mint/assets [post]
+func (controller *MintController) MintAsset(c echo.Context) error {
+	var body MintAssetRequestBody
+
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load mint asset request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid mint asset request body error: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	seedling, err := controller.svc.MintAsset(c.Request().Context(), service.MintAssetRequest{
+		Name:            body.Name,
+		AssetType:       body.AssetType,
+		Amount:          body.Amount,
+		Metadata:        body.Metadata,
+		DecimalDisplay:  body.DecimalDisplay,
+		GroupKey:        body.GroupKey,
+		NewGroupedAsset: body.NewGroupedAsset,
+		IssuerUserID:    body.IssuerUserID,
+	})
+	if err != nil {
+		c.Logger().Errorf("Failed to mint asset: %v", err)
+		if errors.Is(err, service.InvalidMintRequestError) {
+			return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+		}
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, seedling)
+}
+
+// FinalizeBatch godoc
+// @Summary      Finalize the pending mint batch
+// @Description  Broadcasts the minting transaction of the pending batch. Requires Authorization header with admin token.
+// @Accept       json
+// @Produce      json
+// @Tags         Admin
+// @Param        batch  body      FinalizeMintBatchRequestBody  false  "Fee rate"
+// @Success      200    {object}  models.MintBatch
+// @Failure      400    {object}  responses.ErrorResponse
+// @Failure      500    {object}  responses.ErrorResponse
+// @Router       /v2/admin/mint/finalize [post]
+func (controller *MintController) FinalizeBatch(c echo.Context) error {
+	var body FinalizeMintBatchRequestBody
+
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load finalize batch request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	batch, err := controller.svc.FinalizeMintBatch(c.Request().Context(), body.FeeRate)
+	if err != nil {
+		c.Logger().Errorf("Failed to finalize mint batch: %v", err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, batch)
+}
+
+// CancelBatch godoc
+// @Summary      Cancel the pending mint batch
+// @Description  Requires Authorization header with admin token.
+// @Produce      json
+// @Tags         Admin
+// @Success      200  {object}  models.MintBatch
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/admin/mint/cancel [post]
+func (controller *MintController) CancelBatch(c echo.Context) error {
+	batch, err := controller.svc.CancelMintBatch(c.Request().Context())
+	if err != nil {
+		c.Logger().Errorf("Failed to cancel mint batch: %v", err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, batch)
+}
+
+// ListBatches godoc
+// @Summary      List mint batches
+// @Description  The latest mint batches with their assets, states are refreshed from tapd first. Requires Authorization header with admin token.
+// @Produce      json
+// @Tags         Admin
+// @Success      200  {object}  MintBatchesResponseBody
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/admin/mint/batches [get]
+func (controller *MintController) ListBatches(c echo.Context) error {
+	if err := controller.svc.SyncMintBatches(c.Request().Context()); err != nil {
+		// the stored batches are still returned
+		c.Logger().Errorf("Failed to sync mint batches: %v", err)
+	}
+	batches, err := controller.svc.MintBatches(c.Request().Context())
+	if err != nil {
+		c.Logger().Errorf("Failed to list mint batches: %v", err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &MintBatchesResponseBody{
+		Batches: batches,
+	})
+}
@@ -0,0 +1,36 @@
+package v2controllers
+
+import (
+	"net/http"
+
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// Nip05Controller : NIP-05 identifiers of hub users, name@domain resolves to their pubkey
+type Nip05Controller struct {
+	svc *service.LndhubService
+}
+
+func NewNip05Controller(svc *service.LndhubService) *Nip05Controller {
+	return &Nip05Controller{svc: svc}
+}
+
+// NostrJson godoc
+// @Summary      NIP-05 identifiers
+// @Description  Resolves a username to the pubkey of its user and the relays of the hub, _ resolves to the hub itself
+// @Produce      json
+// @Tags         NIP-05
+// @Param        name  query     string  true  "Username"
+// @Success      200   {object}  service.Nip05Response
+// @Failure      500   {object}  responses.ErrorResponse
+// @Router       /.well-known/nostr.json [get]
+func (controller *Nip05Controller) NostrJson(c echo.Context) error {
+	res, err := controller.svc.Nip05For(c.Request().Context(), c.QueryParam("name"))
+	if err != nil {
+		c.Logger().Errorf("Failed to serve nostr.json for %s: %v", c.QueryParam("name"), err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, res)
+}
@@ -102,6 +102,10 @@ func (controller *NostrController) HandleNostrEvent(c echo.Context) error {
 			c.Logger().Errorf("Failed to create user via Nostr event: %v", err)
 			return controller.responder.NostrErrorJson(c, "failed to insert user into database.")
 		}
+		// credit what was sent to the pubkey before it registered
+		if _, err := controller.svc.ClaimTransfersFor(c.Request().Context(), user); err != nil {
+			c.Logger().Errorf("Failed to claim transfers for user_id:%d: %v", user.ID, err)
+		}
 		// create user success response
 		return controller.responder.CreateUserJson(c, user.ID)
 	} else if data[0] == "TAHUB_AUTH" {
@@ -148,10 +152,10 @@ func (controller *NostrController) HandleNostrEvent(c echo.Context) error {
 		// given an asset_id and amt, return the address
 		// these values are prevalidated by CheckEvent
 		assetId := data[1]
-		amt, err := strconv.ParseUint(data[2], 10, 64)
+		amt, err := controller.svc.ParseAssetAmountFor(c.Request().Context(), assetId, data[2])
 		if err != nil {
 			c.Logger().Errorf("Failed to parse amt field in content: %v", err)
-			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+			return controller.responder.NostrErrorJson(c, err.Error())
 		}
 		msgContent, err := controller.svc.FetchOrCreateAssetAddr(c.Request().Context(), uint64(existingUser.ID), assetId, amt)
 		if err != nil {
@@ -173,13 +177,21 @@ func (controller *NostrController) HandleNostrEvent(c echo.Context) error {
 		}
 		// pull all accounts
 		// group by assets, total current accounts - outgoing accounts
-		data, err := controller.svc.GetAllCurrentBalancesJson(c.Request().Context(), existingUser.ID)
+		data, amounts, err := controller.svc.GetAllCurrentBalancesJson(c.Request().Context(), existingUser.ID)
 		if err != nil {
 			controller.svc.Logger.Errorf("Failed to get all current balances: %v", err)
 			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
 		}
+		// balances are returned without a valuation if the prices are unavailable
+		var valuation interface{}
+		balanceValuation, err := controller.svc.ValueBalances(c.Request().Context(), existingUser.ID)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to value balances: %v", err)
+		} else if balanceValuation != nil {
+			valuation = balanceValuation
+		}
 		// respond
-		return controller.responder.GetBalancesJson(c, data)
+		return controller.responder.GetBalancesJson(c, data, amounts, valuation)
 	} else if data[0] == "TAHUB_SEND_ASSET" {
 		// authentication required
 		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
@@ -188,7 +200,7 @@ func (controller *NostrController) HandleNostrEvent(c echo.Context) error {
 			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
 		}
 		// check balance and send
-		msg, status := controller.svc.TransferAssets(c.Request().Context(), uint64(existingUser.ID), data[1])
+		msg, status := controller.svc.TransferAssets(c.Request().Context(), uint64(existingUser.ID), data[1], len(data) == 3)
 		if !status {
 			controller.svc.Logger.Errorf("Failed to transfer assets: %v", msg)
 			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
@@ -196,6 +208,322 @@ func (controller *NostrController) HandleNostrEvent(c echo.Context) error {
 			// success
 			return controller.responder.TransferAssetsJson(c, msg)
 		}
+	} else if data[0] == "TAHUB_GET_HISTORY" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for get history.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		// params were validated in CheckEvent
+		params, _ := service.ParseHistoryCommandParams(data[1:])
+		filter, _ := service.ParseTransactionHistoryFilter(params)
+		page, err := controller.svc.TransactionHistoryFor(c.Request().Context(), existingUser.ID, filter)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to get transaction history: %v", err)
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.TransactionHistoryJson(c, page)
+	} else if data[0] == "TAHUB_GET_LIABILITY_PROOF" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for get liability proof.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		proof, err := controller.svc.GetLiabilityProof(c.Request().Context(), existingUser, data[1])
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to get liability proof: %v", err)
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.LiabilityProofJson(c, proof)
+	} else if data[0] == "TAHUB_GET_RECEIVES" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for get receives.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		receives, err := controller.svc.ReceivesFor(c.Request().Context(), existingUser.ID)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to get receives: %v", err)
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.ReceivesJson(c, receives)
+	} else if data[0] == "TAHUB_CREATE_ASSET_INVOICE" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for create asset invoice.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		amt, err := controller.svc.ParseAssetAmountFor(c.Request().Context(), data[1], data[2])
+		if err != nil {
+			c.Logger().Errorf("Failed to parse amt field in content: %v", err)
+			return controller.responder.NostrErrorJson(c, err.Error())
+		}
+		// the memo may contain colons
+		memo := strings.Join(data[3:], ":")
+		invoice, err := controller.svc.AddAssetInvoice(c.Request().Context(), existingUser.ID, data[1], int64(amt), memo)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to create asset invoice: %v", err)
+			if service.IsAssetInvoiceUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.AssetInvoiceJson(c, invoice.PaymentRequest, invoice.RHash)
+	} else if data[0] == "TAHUB_PAY_ASSET_INVOICE" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for pay asset invoice.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		result, err := controller.svc.PayAssetInvoice(c.Request().Context(), existingUser.ID, data[1], data[2])
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to pay asset invoice: %v", err)
+			if service.IsAssetInvoiceUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.AssetPaymentJson(c, result.PaymentPreimageStr, result.Invoice.Amount)
+	} else if data[0] == "TAHUB_SWAP_QUOTE" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for swap quote.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		quote, err := controller.svc.SwapQuoteFor(c.Request().Context(), existingUser.ID, data[1], data[2], data[3])
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to quote swap: %v", err)
+			if service.IsSwapUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.SwapQuoteJson(c, quote)
+	} else if data[0] == "TAHUB_SWAP_EXECUTE" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for swap execute.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		// quote_id was validated in CheckEvent
+		quoteId, _ := strconv.ParseInt(data[1], 10, 64)
+		quote, err := controller.svc.ExecuteSwap(c.Request().Context(), existingUser.ID, quoteId)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to execute swap: %v", err)
+			if service.IsSwapUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.SwapQuoteJson(c, quote)
+	} else if data[0] == "TAHUB_PLACE_ORDER" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for place order.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		placement, err := controller.svc.PlaceOrder(c.Request().Context(), service.OrderRequest{
+			UserID:       existingUser.ID,
+			Side:         data[1],
+			BaseAssetID:  data[2],
+			QuoteAssetID: data[3],
+			Amount:       data[4],
+			Price:        data[5],
+		})
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to place order: %v", err)
+			if service.IsOrderUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.OrderPlacementJson(c, placement)
+	} else if data[0] == "TAHUB_CANCEL_ORDER" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for cancel order.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		// order_id was validated in CheckEvent
+		orderId, _ := strconv.ParseInt(data[1], 10, 64)
+		order, err := controller.svc.CancelOrder(c.Request().Context(), existingUser.ID, orderId)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to cancel order: %v", err)
+			if service.IsOrderUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.OrderJson(c, order)
+	} else if data[0] == "TAHUB_GET_ORDERS" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for get orders.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		state := ""
+		if len(data) == 2 {
+			state = data[1]
+		}
+		orders, err := controller.svc.OrdersFor(c.Request().Context(), existingUser.ID, state)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to get orders: %v", err)
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.OrdersJson(c, orders)
+	} else if data[0] == "TAHUB_GET_ORDER_BOOK" {
+		// public, no authentication
+		book, err := controller.svc.OrderBookFor(c.Request().Context(), data[1], data[2])
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to get order book: %v", err)
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.OrderBookJson(c, book)
+	} else if data[0] == "TAHUB_GET_TRADES" {
+		// public, no authentication
+		trades, err := controller.svc.TradesFor(c.Request().Context(), data[1], data[2], 0, service.DefaultTradesLimit)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to get trades: %v", err)
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.TradesJson(c, trades)
+	} else if data[0] == "TAHUB_SEND_TO_PUBKEY" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for send to pubkey.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		// the memo may contain colons itself
+		memo := strings.Join(data[4:], ":")
+		transfer, err := controller.svc.TransferToPubkey(c.Request().Context(), existingUser.ID, data[1], data[2], data[3], memo)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to send to pubkey: %v", err)
+			if service.IsTransferUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.InternalTransferJson(c, transfer)
+	} else if data[0] == "TAHUB_REQUEST_PAYMENT" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for request payment.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		// the memo may contain colons itself
+		memo := strings.Join(data[4:], ":")
+		request, err := controller.svc.RequestPayment(c.Request().Context(), existingUser, data[1], data[2], data[3], memo)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to request payment: %v", err)
+			if service.IsPaymentRequestUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.PaymentRequestJson(c, request)
+	} else if data[0] == "TAHUB_PAY_REQUEST" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for pay request.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		// request_id was validated in CheckEvent
+		requestId, _ := strconv.ParseInt(data[1], 10, 64)
+		request, err := controller.svc.PayPaymentRequest(c.Request().Context(), existingUser, requestId)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to pay request: %v", err)
+			if service.IsPaymentRequestUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.PaymentRequestJson(c, request)
+	} else if data[0] == "TAHUB_CANCEL_PAYMENT_REQUEST" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for cancel payment request.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		// request_id was validated in CheckEvent
+		requestId, _ := strconv.ParseInt(data[1], 10, 64)
+		request, err := controller.svc.CancelPaymentRequest(c.Request().Context(), existingUser, requestId)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to cancel payment request: %v", err)
+			if service.IsPaymentRequestUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.PaymentRequestJson(c, request)
+	} else if data[0] == "TAHUB_GET_PAYMENT_REQUEST" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for get payment request.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		// request_id was validated in CheckEvent
+		requestId, _ := strconv.ParseInt(data[1], 10, 64)
+		request, err := controller.svc.PaymentRequestFor(c.Request().Context(), existingUser, requestId)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to get payment request: %v", err)
+			if service.IsPaymentRequestUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.PaymentRequestJson(c, request)
+	} else if data[0] == "TAHUB_GET_PAYMENT_REQUESTS" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for get payment requests.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		state := ""
+		if len(data) == 2 {
+			state = data[1]
+		}
+		requests, err := controller.svc.PaymentRequestsFor(c.Request().Context(), existingUser, state)
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to get payment requests: %v", err)
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		return controller.responder.PaymentRequestsJson(c, requests)
+	} else if data[0] == "TAHUB_SET_USERNAME" {
+		// authentication required
+		existingUser, isAuthenticated := controller.svc.GetUserIfExists(c.Request().Context(), decodedPayload)
+		if existingUser == nil || !isAuthenticated {
+			controller.svc.Logger.Errorf("Failed to authenticate user for set username.")
+			return controller.responder.NostrErrorJson(c, responses.BadAuthError.Message)
+		}
+		user, err := controller.svc.SetUsername(c.Request().Context(), existingUser, data[1])
+		if err != nil {
+			controller.svc.Logger.Errorf("Failed to set username: %v", err)
+			if service.IsUsernameUserError(err) {
+				return controller.responder.NostrErrorJson(c, err.Error())
+			}
+			return controller.responder.NostrErrorJson(c, responses.GeneralServerError.Message)
+		}
+		lightningAddress := ""
+		if user.Username != "" {
+			lightningAddress, _ = controller.svc.LightningAddress(user.Username)
+		}
+		return controller.responder.UsernameJson(c, user.Username, lightningAddress)
 	} else {
 		// catch all - unimplemented
 		controller.svc.Logger.Errorf("Unimplemented Nostr Event content: %v", decodedPayload.Content)
@@ -0,0 +1,197 @@
+package v2controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// OrderController : peer to peer order book between hub users
+type OrderController struct {
+	svc *service.LndhubService
+}
+
+func NewOrderController(svc *service.LndhubService) *OrderController {
+	return &OrderController{svc: svc}
+}
+
+type PlaceOrderRequestBody struct {
+	Side         string `json:"side" validate:"required,oneof=buy sell"`
+	BaseAssetID  string `json:"base_asset_id" validate:"required"`
+	QuoteAssetID string `json:"quote_asset_id" validate:"required"`
+	// decimal amount of the base asset
+	Amount string `json:"amount" validate:"required"`
+	// decimal price of one base asset in the quote asset
+	Price string `json:"price" validate:"required"`
+}
+
+type OrdersResponseBody struct {
+	Orders []models.Order `json:"orders"`
+}
+
+type TradesResponseBody struct {
+	Trades []models.Trade `json:"trades"`
+}
+
+// PlaceOrder godoc
+// @Summary      Place a limit order
+// @Description  Locks the funds of the order and matches it against the resting orders of other users, the rest of the order stays in the book
+// @Accept       json
+// @Produce      json
+// @Tags         Orders
+// @Param        order  body      PlaceOrderRequestBody  True  "Limit order"
+// @Success      200    {object}  service.OrderPlacement
+// @Failure      400    {object}  responses.ErrorResponse
+// @Failure      500    {object}  responses.ErrorResponse
+// @Router       /v2/orders [post]
+// @Security     OAuth2Password
+func (controller *OrderController) PlaceOrder(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	var body PlaceOrderRequestBody
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load order request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid order request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	placement, err := controller.svc.PlaceOrder(c.Request().Context(), service.OrderRequest{
+		UserID:       userId,
+		Side:         body.Side,
+		BaseAssetID:  body.BaseAssetID,
+		QuoteAssetID: body.QuoteAssetID,
+		Amount:       body.Amount,
+		Price:        body.Price,
+	})
+	if err != nil {
+		c.Logger().Errorf("Failed to place order user_id:%d: %v", userId, err)
+		return orderError(c, err)
+	}
+	return c.JSON(http.StatusOK, placement)
+}
+
+// CancelOrder godoc
+// @Summary      Cancel an order
+// @Description  Takes an open order out of the book and releases its locked funds
+// @Produce      json
+// @Tags         Orders
+// @Param        id  path      int  true  "Order ID"
+// @Success      200  {object}  models.Order
+// @Failure      400  {object}  responses.ErrorResponse
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/orders/{id} [delete]
+// @Security     OAuth2Password
+func (controller *OrderController) CancelOrder(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	orderId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Logger().Errorf("Invalid order id: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	order, err := controller.svc.CancelOrder(c.Request().Context(), userId, orderId)
+	if err != nil {
+		c.Logger().Errorf("Failed to cancel order:%d user_id:%d: %v", orderId, userId, err)
+		return orderError(c, err)
+	}
+	return c.JSON(http.StatusOK, order)
+}
+
+// Orders godoc
+// @Summary      List orders
+// @Description  Latest orders of the user, optionally filtered by state
+// @Produce      json
+// @Tags         Orders
+// @Param        state  query     string  false  "open, filled or cancelled"
+// @Success      200    {object}  OrdersResponseBody
+// @Failure      500    {object}  responses.ErrorResponse
+// @Router       /v2/orders [get]
+// @Security     OAuth2Password
+func (controller *OrderController) Orders(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	orders, err := controller.svc.OrdersFor(c.Request().Context(), userId, c.QueryParam("state"))
+	if err != nil {
+		c.Logger().Errorf("Failed to list orders user_id:%d: %v", userId, err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &OrdersResponseBody{Orders: orders})
+}
+
+// OrderBook godoc
+// @Summary      Order book
+// @Description  Open orders of an asset pair aggregated by price, best price first
+// @Produce      json
+// @Tags         Orders
+// @Param        base_asset_id   query     string  true  "Base asset ID"
+// @Param        quote_asset_id  query     string  true  "Quote asset ID"
+// @Success      200             {object}  service.OrderBook
+// @Failure      400             {object}  responses.ErrorResponse
+// @Failure      500             {object}  responses.ErrorResponse
+// @Router       /v2/orderbook [get]
+func (controller *OrderController) OrderBook(c echo.Context) error {
+	base, quote := c.QueryParam("base_asset_id"), c.QueryParam("quote_asset_id")
+	if base == "" || quote == "" {
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	book, err := controller.svc.OrderBookFor(c.Request().Context(), base, quote)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch order book %s/%s: %v", base, quote, err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, book)
+}
+
+// Trades godoc
+// @Summary      Trades feed
+// @Description  Trades of an asset pair, newest first
+// @Produce      json
+// @Tags         Orders
+// @Param        base_asset_id   query     string  true   "Base asset ID"
+// @Param        quote_asset_id  query     string  true   "Quote asset ID"
+// @Param        cursor          query     int     false  "ID of the last trade of the previous page"
+// @Param        limit           query     int     false  "Page size, at most 100"
+// @Success      200             {object}  TradesResponseBody
+// @Failure      400             {object}  responses.ErrorResponse
+// @Failure      500             {object}  responses.ErrorResponse
+// @Router       /v2/trades [get]
+func (controller *OrderController) Trades(c echo.Context) error {
+	base, quote := c.QueryParam("base_asset_id"), c.QueryParam("quote_asset_id")
+	if base == "" || quote == "" {
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	var cursor int64
+	var limit int
+	var err error
+	if param := c.QueryParam("cursor"); param != "" {
+		if cursor, err = strconv.ParseInt(param, 10, 64); err != nil {
+			return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+		}
+	}
+	if param := c.QueryParam("limit"); param != "" {
+		if limit, err = strconv.Atoi(param); err != nil {
+			return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+		}
+	}
+	trades, err := controller.svc.TradesFor(c.Request().Context(), base, quote, cursor, limit)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch trades %s/%s: %v", base, quote, err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &TradesResponseBody{Trades: trades})
+}
+
+// orderError reports the errors a user can act on with their message, anything else as a server error
+func orderError(c echo.Context, err error) error {
+	if service.IsOrderUserError(err) {
+		return c.JSON(http.StatusBadRequest, &responses.ErrorResponse{
+			Error:   true,
+			Code:    responses.BadArgumentsError.Code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+}
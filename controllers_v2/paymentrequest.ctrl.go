@@ -0,0 +1,198 @@
+package v2controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// PaymentRequestController : users asking each other for asset payments
+type PaymentRequestController struct {
+	svc *service.LndhubService
+}
+
+func NewPaymentRequestController(svc *service.LndhubService) *PaymentRequestController {
+	return &PaymentRequestController{svc: svc}
+}
+
+type PaymentRequestRequestBody struct {
+	// npub or hex pubkey of the payer
+	Payer   string `json:"payer" validate:"required"`
+	AssetID string `json:"asset_id" validate:"required"`
+	// decimal amount of the asset
+	Amount string `json:"amount" validate:"required"`
+	Memo   string `json:"memo"`
+}
+
+type PaymentRequestsResponseBody struct {
+	PaymentRequests []models.PaymentRequest `json:"payment_requests"`
+}
+
+// Create godoc
+// @Summary      Request a payment
+// @Description  Asks the owner of a pubkey for an amount of an asset, the request is sent to them by DM
+// @Accept       json
+// @Produce      json
+// @Tags         Payment requests
+// @Param        request  body      PaymentRequestRequestBody  True  "Payment request"
+// @Success      200      {object}  models.PaymentRequest
+// @Failure      400      {object}  responses.ErrorResponse
+// @Failure      500      {object}  responses.ErrorResponse
+// @Router       /v2/payment-requests [post]
+// @Security     OAuth2Password
+func (controller *PaymentRequestController) Create(c echo.Context) error {
+	user, err := controller.user(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	var body PaymentRequestRequestBody
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load payment request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid payment request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	request, err := controller.svc.RequestPayment(c.Request().Context(), user, body.Payer, body.AssetID, body.Amount, body.Memo)
+	if err != nil {
+		c.Logger().Errorf("Failed to request payment user_id:%d: %v", user.ID, err)
+		return paymentRequestError(c, err)
+	}
+	return c.JSON(http.StatusOK, request)
+}
+
+// Pay godoc
+// @Summary      Pay a payment request
+// @Description  Pays an open request addressed to the user with an internal transfer to the requester
+// @Produce      json
+// @Tags         Payment requests
+// @Param        id   path      int  true  "Payment request ID"
+// @Success      200  {object}  models.PaymentRequest
+// @Failure      400  {object}  responses.ErrorResponse
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/payment-requests/{id}/pay [post]
+// @Security     OAuth2Password
+func (controller *PaymentRequestController) Pay(c echo.Context) error {
+	requestId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Logger().Errorf("Invalid payment request id: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	user, err := controller.user(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	request, err := controller.svc.PayPaymentRequest(c.Request().Context(), user, requestId)
+	if err != nil {
+		c.Logger().Errorf("Failed to pay payment request:%d user_id:%d: %v", requestId, user.ID, err)
+		return paymentRequestError(c, err)
+	}
+	return c.JSON(http.StatusOK, request)
+}
+
+// Cancel godoc
+// @Summary      Cancel a payment request
+// @Description  Withdraws a request the user made or declines one addressed to them
+// @Produce      json
+// @Tags         Payment requests
+// @Param        id   path      int  true  "Payment request ID"
+// @Success      200  {object}  models.PaymentRequest
+// @Failure      400  {object}  responses.ErrorResponse
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/payment-requests/{id} [delete]
+// @Security     OAuth2Password
+func (controller *PaymentRequestController) Cancel(c echo.Context) error {
+	requestId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Logger().Errorf("Invalid payment request id: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	user, err := controller.user(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	request, err := controller.svc.CancelPaymentRequest(c.Request().Context(), user, requestId)
+	if err != nil {
+		c.Logger().Errorf("Failed to cancel payment request:%d user_id:%d: %v", requestId, user.ID, err)
+		return paymentRequestError(c, err)
+	}
+	return c.JSON(http.StatusOK, request)
+}
+
+// Get godoc
+// @Summary      Payment request status
+// @Description  A request the user made or that is addressed to them
+// @Produce      json
+// @Tags         Payment requests
+// @Param        id   path      int  true  "Payment request ID"
+// @Success      200  {object}  models.PaymentRequest
+// @Failure      400  {object}  responses.ErrorResponse
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/payment-requests/{id} [get]
+// @Security     OAuth2Password
+func (controller *PaymentRequestController) Get(c echo.Context) error {
+	requestId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Logger().Errorf("Invalid payment request id: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	user, err := controller.user(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	request, err := controller.svc.PaymentRequestFor(c.Request().Context(), user, requestId)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch payment request:%d user_id:%d: %v", requestId, user.ID, err)
+		return paymentRequestError(c, err)
+	}
+	return c.JSON(http.StatusOK, request)
+}
+
+// List godoc
+// @Summary      List payment requests
+// @Description  Latest requests the user made or that are addressed to them, optionally filtered by state
+// @Produce      json
+// @Tags         Payment requests
+// @Param        state  query     string  false  "open, paid, cancelled or expired"
+// @Success      200    {object}  PaymentRequestsResponseBody
+// @Failure      500    {object}  responses.ErrorResponse
+// @Router       /v2/payment-requests [get]
+// @Security     OAuth2Password
+func (controller *PaymentRequestController) List(c echo.Context) error {
+	user, err := controller.user(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	requests, err := controller.svc.PaymentRequestsFor(c.Request().Context(), user, c.QueryParam("state"))
+	if err != nil {
+		c.Logger().Errorf("Failed to list payment requests user_id:%d: %v", user.ID, err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &PaymentRequestsResponseBody{PaymentRequests: requests})
+}
+
+func (controller *PaymentRequestController) user(c echo.Context) (*models.User, error) {
+	userId := c.Get("UserID").(int64)
+	user, err := controller.svc.FindUser(c.Request().Context(), userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to find user_id:%d: %v", userId, err)
+	}
+	return user, err
+}
+
+// paymentRequestError reports the errors a user can act on with their message, anything else as a server error
+func paymentRequestError(c echo.Context, err error) error {
+	if service.IsPaymentRequestUserError(err) {
+		return c.JSON(http.StatusBadRequest, &responses.ErrorResponse{
+			Error:   true,
+			Code:    responses.BadArgumentsError.Code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+}
@@ -0,0 +1,71 @@
+package v2controllers
+
+import (
+	"net/http"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+const priceHistoryLimit = 100
+
+// PricesController : PricesController struct
+type PricesController struct {
+	svc *service.LndhubService
+}
+
+func NewPricesController(svc *service.LndhubService) *PricesController {
+	return &PricesController{svc: svc}
+}
+
+type PricesResponseBody struct {
+	Currency string `json:"currency"`
+	// latest price keyed by asset id
+	Prices map[string]models.AssetPrice `json:"prices"`
+}
+
+type PriceHistoryResponseBody struct {
+	Prices []models.AssetPrice `json:"prices"`
+}
+
+// Prices godoc
+// @Summary      Latest prices
+// @Description  Latest fiat price of one unit of every asset in its decimal display
+// @Produce      json
+// @Tags         Prices
+// @Success      200  {object}  PricesResponseBody
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/prices [get]
+// @Security     OAuth2Password
+func (controller *PricesController) Prices(c echo.Context) error {
+	prices, err := controller.svc.LatestPrices(c.Request().Context())
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch latest prices: %v", err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &PricesResponseBody{
+		Currency: controller.svc.Config.PriceCurrency,
+		Prices:   prices,
+	})
+}
+
+// History godoc
+// @Summary      Price history
+// @Description  Latest prices of an asset, newest first
+// @Produce      json
+// @Tags         Prices
+// @Param        asset_id  path  string  true  "Asset ID"
+// @Success      200  {object}  PriceHistoryResponseBody
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/prices/{asset_id} [get]
+// @Security     OAuth2Password
+func (controller *PricesController) History(c echo.Context) error {
+	prices, err := controller.svc.PriceHistory(c.Request().Context(), c.Param("asset_id"), priceHistoryLimit)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch price history: %v", err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &PriceHistoryResponseBody{Prices: prices})
+}
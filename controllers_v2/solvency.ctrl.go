@@ -0,0 +1,35 @@
+package v2controllers
+
+import (
+	"net/http"
+
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// SolvencyController : Admin solvency report controller struct
+type SolvencyController struct {
+	svc *service.LndhubService
+}
+
+func NewSolvencyController(svc *service.LndhubService) *SolvencyController {
+	return &SolvencyController{svc: svc}
+}
+
+// Solvency godoc
+// @Summary      Retrieve the solvency report
+// @Description  Compares per asset the sum of users' current balances against tapd and lnd holdings. Requires Authorization header with admin token.
+// @Produce      json
+// @Tags         Admin
+// @Success      200  {object}  service.SolvencyReport
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/admin/solvency [get]
+func (controller *SolvencyController) Solvency(c echo.Context) error {
+	report, err := controller.svc.CheckSolvency(c.Request().Context())
+	if err != nil {
+		c.Logger().Errorf("Failed to compute solvency report: %v", err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, report)
+}
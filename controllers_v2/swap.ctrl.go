@@ -0,0 +1,160 @@
+package v2controllers
+
+import (
+	"net/http"
+
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// SwapController : swaps between the assets of a user against the hub liquidity
+type SwapController struct {
+	svc *service.LndhubService
+}
+
+func NewSwapController(svc *service.LndhubService) *SwapController {
+	return &SwapController{svc: svc}
+}
+
+type SwapQuoteRequestBody struct {
+	FromAssetID string `json:"from_asset_id" validate:"required"`
+	ToAssetID   string `json:"to_asset_id" validate:"required"`
+	// decimal amount of the from asset
+	Amount string `json:"amount" validate:"required"`
+}
+
+type SwapExecuteRequestBody struct {
+	QuoteID int64 `json:"quote_id" validate:"required,gt=0"`
+}
+
+type SwapLiquidityRequestBody struct {
+	AssetID string `json:"asset_id" validate:"required"`
+	// positive amounts move units from the treasury to the liquidity account, negative amounts back
+	Amount int64 `json:"amount" validate:"required"`
+}
+
+type SwapLiquidityResponseBody struct {
+	// liquidity account balance keyed by asset id
+	Liquidity map[string]int64 `json:"liquidity"`
+}
+
+// Quote godoc
+// @Summary      Quote a swap
+// @Description  Quotes a swap of an amount of one asset into another at the latest prices, the quote can be executed until it expires
+// @Accept       json
+// @Produce      json
+// @Tags         Swap
+// @Param        quote  body      SwapQuoteRequestBody  True  "Swap quote"
+// @Success      200    {object}  models.SwapQuote
+// @Failure      400    {object}  responses.ErrorResponse
+// @Failure      500    {object}  responses.ErrorResponse
+// @Router       /v2/swaps/quote [post]
+// @Security     OAuth2Password
+func (controller *SwapController) Quote(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	var body SwapQuoteRequestBody
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load swap quote request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid swap quote request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	quote, err := controller.svc.SwapQuoteFor(c.Request().Context(), userId, body.FromAssetID, body.ToAssetID, body.Amount)
+	if err != nil {
+		c.Logger().Errorf("Failed to quote swap user_id:%d: %v", userId, err)
+		return swapError(c, err)
+	}
+	return c.JSON(http.StatusOK, quote)
+}
+
+// Execute godoc
+// @Summary      Execute a swap quote
+// @Description  Swaps the quoted amounts between the user's accounts and the hub liquidity in one transaction
+// @Accept       json
+// @Produce      json
+// @Tags         Swap
+// @Param        quote  body      SwapExecuteRequestBody  True  "Swap quote to execute"
+// @Success      200    {object}  models.SwapQuote
+// @Failure      400    {object}  responses.ErrorResponse
+// @Failure      500    {object}  responses.ErrorResponse
+// @Router       /v2/swaps/execute [post]
+// @Security     OAuth2Password
+func (controller *SwapController) Execute(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	var body SwapExecuteRequestBody
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load swap execute request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid swap execute request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	quote, err := controller.svc.ExecuteSwap(c.Request().Context(), userId, body.QuoteID)
+	if err != nil {
+		c.Logger().Errorf("Failed to execute swap quote:%d user_id:%d: %v", body.QuoteID, userId, err)
+		return swapError(c, err)
+	}
+	return c.JSON(http.StatusOK, quote)
+}
+
+// FundLiquidity godoc
+// @Summary      Fund swap liquidity
+// @Description  Moves hub owned units between the treasury and the swap liquidity account of an asset. Requires Authorization header with admin token.
+// @Accept       json
+// @Produce      json
+// @Tags         Admin
+// @Param        liquidity  body      SwapLiquidityRequestBody  true  "Liquidity change"
+// @Success      200        {object}  models.TransactionEntry
+// @Failure      400        {object}  responses.ErrorResponse
+// @Failure      500        {object}  responses.ErrorResponse
+// @Router       /v2/admin/swaps/liquidity [post]
+func (controller *SwapController) FundLiquidity(c echo.Context) error {
+	var body SwapLiquidityRequestBody
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load swap liquidity request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid swap liquidity request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	entry, err := controller.svc.FundSwapLiquidity(c.Request().Context(), body.AssetID, body.Amount)
+	if err != nil {
+		c.Logger().Errorf("Failed to fund swap liquidity: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	return c.JSON(http.StatusOK, entry)
+}
+
+// Liquidity godoc
+// @Summary      Swap liquidity
+// @Description  Balance of the swap liquidity account of every asset. Requires Authorization header with admin token.
+// @Produce      json
+// @Tags         Admin
+// @Success      200  {object}  SwapLiquidityResponseBody
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/admin/swaps/liquidity [get]
+func (controller *SwapController) Liquidity(c echo.Context) error {
+	liquidity, err := controller.svc.SwapLiquidity(c.Request().Context())
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch swap liquidity: %v", err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &SwapLiquidityResponseBody{Liquidity: liquidity})
+}
+
+// swapError reports the errors a user can act on with their message, anything else as a server error
+func swapError(c echo.Context, err error) error {
+	if service.IsSwapUserError(err) {
+		return c.JSON(http.StatusBadRequest, &responses.ErrorResponse{
+			Error:   true,
+			Code:    responses.BadArgumentsError.Code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+}
@@ -0,0 +1,54 @@
+package v2controllers
+
+import (
+	"net/http"
+
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// TransactionsController : TransactionsController struct
+type TransactionsController struct {
+	svc *service.LndhubService
+}
+
+func NewTransactionsController(svc *service.LndhubService) *TransactionsController {
+	return &TransactionsController{svc: svc}
+}
+
+// Transactions godoc
+// @Summary      Retrieve transaction history
+// @Description  Current user's transaction entries across all assets, newest first. Pass next_cursor as cursor to fetch the following page.
+// @Produce      json
+// @Tags         Account
+// @Param        asset_id         query     string  false  "Asset ID"
+// @Param        entry_type       query     string  false  "Entry type, e.g. incoming or outgoing"
+// @Param        broadcast_state  query     string  false  "Broadcast state"
+// @Param        from             query     int     false  "Unix timestamp, inclusive"
+// @Param        to               query     int     false  "Unix timestamp, exclusive"
+// @Param        cursor           query     int     false  "next_cursor of the previous page"
+// @Param        limit            query     int     false  "Page size, max 100"
+// @Success      200  {object}  service.TransactionHistoryPage
+// @Failure      400  {object}  responses.ErrorResponse
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/transactions [get]
+// @Security     OAuth2Password
+func (controller *TransactionsController) Transactions(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	params := make(map[string]string)
+	for key := range c.QueryParams() {
+		params[key] = c.QueryParam(key)
+	}
+	filter, err := service.ParseTransactionHistoryFilter(params)
+	if err != nil {
+		c.Logger().Errorf("Invalid transaction history filter: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	page, err := controller.svc.TransactionHistoryFor(c.Request().Context(), userId, filter)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch transaction history user_id:%d: %v", userId, err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, page)
+}
@@ -2,6 +2,7 @@ package v2controllers
 
 import (
 	"net/http"
+	"github.com/getAlby/lndhub.go/db/models"
 	"github.com/getAlby/lndhub.go/lib/responses"
 	"github.com/getAlby/lndhub.go/lib/service"
 	"github.com/labstack/echo/v4"
@@ -18,6 +19,8 @@ func NewTransferController(svc *service.LndhubService) *TransferController {
 // transfer request
 type TransferRequestBody struct {
 	Address string `json:"address"`
+	// export the proof file once an external send completes
+	Withdraw bool `json:"withdraw"`
 }
 // transfer response
 type TransferResponseBody struct {
@@ -53,7 +56,7 @@ func (controller *TransferController) Transfer(c echo.Context) error {
 		return controller.responder.NostrErrorJson(c, responses.BadArgumentsError.Message)
 	}
 	// transfer assets
-	msg, status := controller.svc.TransferAssets(c.Request().Context(), uint64(userId), body.Address)
+	msg, status := controller.svc.TransferAssets(c.Request().Context(), uint64(userId), body.Address, body.Withdraw)
 	if !status {
 		c.Logger().Errorf("Failed to send assets: %v", msg)
 		// TODO improve error response
@@ -65,3 +68,100 @@ func (controller *TransferController) Transfer(c echo.Context) error {
 	})
 }
 
+
+type ReceivesResponseBody struct {
+	Receives []models.AssetReceive `json:"receives"`
+}
+
+type SendsResponseBody struct {
+	Sends []models.SendBatchItem `json:"sends"`
+}
+
+// Sends godoc
+// @Summary      List external sends
+// @Description  The current user's latest external sends with their batch state and chain fee share
+// @Produce      json
+// @Tags         Transfer
+// @Success      200  {object}  SendsResponseBody
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/sends [get]
+// @Security     OAuth2Password
+func (controller *TransferController) Sends(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	sends, err := controller.svc.SendBatchItemsFor(c.Request().Context(), userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to list sends user_id:%d: %v", userId, err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &SendsResponseBody{
+		Sends: sends,
+	})
+}
+
+// Receives godoc
+// @Summary      List incoming transfers
+// @Description  The current user's latest receives with their lifecycle state and confirmations
+// @Produce      json
+// @Tags         Transfer
+// @Success      200  {object}  ReceivesResponseBody
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/receives [get]
+// @Security     OAuth2Password
+func (controller *TransferController) Receives(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	receives, err := controller.svc.ReceivesFor(c.Request().Context(), userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to list receives user_id:%d: %v", userId, err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &ReceivesResponseBody{
+		Receives: receives,
+	})
+}
+
+type InternalTransferRequestBody struct {
+	// npub or hex pubkey of a hub user
+	Recipient string `json:"recipient" validate:"required"`
+	AssetID   string `json:"asset_id" validate:"required"`
+	// decimal amount of the asset
+	Amount string `json:"amount" validate:"required"`
+	Memo   string `json:"memo"`
+}
+
+// InternalTransfer godoc
+// @Summary      Transfer to a hub user
+// @Description  Moves assets to the hub user with the given npub without a tap address or chain fee, both users are notified by DM
+// @Accept       json
+// @Produce      json
+// @Tags         Transfer
+// @Param        transfer  body      InternalTransferRequestBody  True  "Internal transfer"
+// @Success      200       {object}  models.InternalTransfer
+// @Failure      400       {object}  responses.ErrorResponse
+// @Failure      500       {object}  responses.ErrorResponse
+// @Router       /v2/transfer/internal [post]
+// @Security     OAuth2Password
+func (controller *TransferController) InternalTransfer(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	var body InternalTransferRequestBody
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load internal transfer request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid internal transfer request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	transfer, err := controller.svc.TransferToPubkey(c.Request().Context(), userId, body.Recipient, body.AssetID, body.Amount, body.Memo)
+	if err != nil {
+		c.Logger().Errorf("Failed to transfer internally user_id:%d: %v", userId, err)
+		if service.IsTransferUserError(err) {
+			return c.JSON(http.StatusBadRequest, &responses.ErrorResponse{
+				Error:   true,
+				Code:    responses.BadArgumentsError.Code,
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, transfer)
+}
@@ -0,0 +1,109 @@
+package v2controllers
+
+import (
+	"net/http"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// UsernameController : Admin moderation of usernames
+type UsernameController struct {
+	svc *service.LndhubService
+}
+
+func NewUsernameController(svc *service.LndhubService) *UsernameController {
+	return &UsernameController{svc: svc}
+}
+
+type RevokeUsernameRequestBody struct {
+	Name     string `json:"name" validate:"required"`
+	Reason   string `json:"reason" validate:"required"`
+	Operator string `json:"operator" validate:"required"`
+}
+
+type UsernameHoldsResponseBody struct {
+	Holds []models.UsernameHold `json:"holds"`
+}
+
+// RevokeUsername godoc
+// @Summary      Revoke a username
+// @Description  Takes a username away from its user and blocks it until it is unblocked, names nobody holds can be blocked too. Requires Authorization header with admin token.
+// @Accept       json
+// @Produce      json
+// @Tags         Admin
+// @Param        revocation  body      RevokeUsernameRequestBody  true  "Username revocation"
+// @Success      200         {object}  models.UsernameHold
+// @Failure      400         {object}  responses.ErrorResponse
+// @Failure      500         {object}  responses.ErrorResponse
+// @Router       /v2/admin/usernames/revoke [post]
+func (controller *UsernameController) RevokeUsername(c echo.Context) error {
+	var body RevokeUsernameRequestBody
+
+	if err := c.Bind(&body); err != nil {
+		c.Logger().Errorf("Failed to load revoke username request body: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	if err := c.Validate(&body); err != nil {
+		c.Logger().Errorf("Invalid revoke username request body error: %v", err)
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	hold, err := controller.svc.RevokeUsername(c.Request().Context(), body.Name, body.Reason, body.Operator)
+	if err != nil {
+		c.Logger().Errorf("Failed to revoke username %s: %v", body.Name, err)
+		return usernameError(c, err)
+	}
+	return c.JSON(http.StatusOK, hold)
+}
+
+// ListHolds godoc
+// @Summary      List username holds
+// @Description  Revoked usernames and released usernames still held for their former owner. Requires Authorization header with admin token.
+// @Produce      json
+// @Tags         Admin
+// @Success      200  {object}  UsernameHoldsResponseBody
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/admin/username-holds [get]
+func (controller *UsernameController) ListHolds(c echo.Context) error {
+	holds, err := controller.svc.UsernameHolds(c.Request().Context())
+	if err != nil {
+		c.Logger().Errorf("Failed to list username holds: %v", err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &UsernameHoldsResponseBody{
+		Holds: holds,
+	})
+}
+
+// UnblockUsername godoc
+// @Summary      Unblock a username
+// @Description  Lifts the hold of a username so anyone can claim it again. Requires Authorization header with admin token.
+// @Produce      json
+// @Tags         Admin
+// @Param        name  path      string  true  "Username"
+// @Success      200   {object}  models.UsernameHold
+// @Failure      400   {object}  responses.ErrorResponse
+// @Failure      500   {object}  responses.ErrorResponse
+// @Router       /v2/admin/username-holds/{name} [delete]
+func (controller *UsernameController) UnblockUsername(c echo.Context) error {
+	hold, err := controller.svc.UnblockUsername(c.Request().Context(), c.Param("name"))
+	if err != nil {
+		c.Logger().Errorf("Failed to unblock username %s: %v", c.Param("name"), err)
+		return usernameError(c, err)
+	}
+	return c.JSON(http.StatusOK, hold)
+}
+
+// usernameError reports the errors an operator can act on with their message, anything else as a server error
+func usernameError(c echo.Context, err error) error {
+	if service.IsUsernameUserError(err) {
+		return c.JSON(http.StatusBadRequest, &responses.ErrorResponse{
+			Error:   true,
+			Code:    responses.BadArgumentsError.Code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+}
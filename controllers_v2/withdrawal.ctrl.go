@@ -0,0 +1,77 @@
+package v2controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/labstack/echo/v4"
+)
+
+// WithdrawalController : self-custody withdrawal proofs controller struct
+type WithdrawalController struct {
+	svc *service.LndhubService
+}
+
+func NewWithdrawalController(svc *service.LndhubService) *WithdrawalController {
+	return &WithdrawalController{svc: svc}
+}
+
+type WithdrawalsResponseBody struct {
+	Withdrawals []models.AssetWithdrawal `json:"withdrawals"`
+}
+
+// Withdrawals godoc
+// @Summary      List withdrawals
+// @Description  The current user's latest sends made in withdrawal mode
+// @Produce      json
+// @Tags         Transfer
+// @Success      200  {object}  WithdrawalsResponseBody
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/withdrawals [get]
+// @Security     OAuth2Password
+func (controller *WithdrawalController) Withdrawals(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	withdrawals, err := controller.svc.WithdrawalsFor(c.Request().Context(), userId)
+	if err != nil {
+		c.Logger().Errorf("Failed to list withdrawals user_id:%d: %v", userId, err)
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	return c.JSON(http.StatusOK, &WithdrawalsResponseBody{
+		Withdrawals: withdrawals,
+	})
+}
+
+// Proof godoc
+// @Summary      Download a withdrawal proof file
+// @Description  Raw taproot assets proof file of the withdrawn output, to be imported into the user's own tapd. Available once the send is complete.
+// @Produce      octet-stream
+// @Tags         Transfer
+// @Param        id   path      int  true  "Withdrawal ID"
+// @Success      200  {file}    binary
+// @Failure      400  {object}  responses.ErrorResponse
+// @Failure      500  {object}  responses.ErrorResponse
+// @Router       /v2/withdrawals/{id}/proof [get]
+// @Security     OAuth2Password
+func (controller *WithdrawalController) Proof(c echo.Context) error {
+	userId := c.Get("UserID").(int64)
+	withdrawalId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+	}
+	withdrawal, proofFile, err := controller.svc.WithdrawalProofFor(c.Request().Context(), userId, withdrawalId)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch proof of withdrawal id:%d user_id:%d: %v", withdrawalId, userId, err)
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, service.WithdrawalProofNotReadyError) {
+			return c.JSON(http.StatusBadRequest, responses.BadArgumentsError)
+		}
+		return c.JSON(http.StatusInternalServerError, responses.GeneralServerError)
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"%s-%d.proof\"", withdrawal.TaAssetID, withdrawal.ID))
+	return c.Blob(http.StatusOK, echo.MIMEOctetStream, proofFile)
+}
@@ -0,0 +1,35 @@
+-- system accounts (e.g. adjustments) are owned by the hub and not by a user
+ALTER TABLE accounts ALTER COLUMN user_id DROP NOT NULL;
+--bun:split
+CREATE UNIQUE INDEX IF NOT EXISTS index_accounts_system_on_ta_asset_id_type
+    ON accounts (ta_asset_id, type)
+    WHERE user_id IS NULL;
+--bun:split
+CREATE TABLE ledger_adjustments (
+    id SERIAL PRIMARY KEY,
+    user_id bigint NOT NULL,
+    ta_asset_id character varying NOT NULL,
+    amount bigint NOT NULL,
+    transaction_entry_id bigint NOT NULL,
+    reason character varying NOT NULL,
+    ticket_ref character varying NOT NULL,
+    operator character varying NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_asset
+        FOREIGN KEY(ta_asset_id)
+        REFERENCES assets(ta_asset_id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_transaction_entry
+        FOREIGN KEY(transaction_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT check_reason_not_empty CHECK (char_length(reason) > 0),
+    CONSTRAINT check_ticket_ref_not_empty CHECK (char_length(ticket_ref) > 0)
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_ledger_adjustments_on_user_id
+    ON ledger_adjustments (user_id);
@@ -0,0 +1,9 @@
+-- entries moving funds between two system accounts (e.g. chain fees paid from the treasury) have no user
+ALTER TABLE transaction_entries ALTER COLUMN user_id DROP NOT NULL;
+--bun:split
+-- create the system accounts for every known asset, new assets get them on creation
+INSERT INTO accounts (user_id, ta_asset_id, type)
+SELECT NULL, assets.ta_asset_id, system_types.type
+FROM assets
+CROSS JOIN (VALUES ('treasury'), ('fee_revenue'), ('chain_fees'), ('suspense'), ('adjustments')) AS system_types(type)
+ON CONFLICT (ta_asset_id, type) WHERE user_id IS NULL DO NOTHING;
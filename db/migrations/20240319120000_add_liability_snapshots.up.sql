@@ -0,0 +1,40 @@
+CREATE TABLE liability_snapshots (
+    id SERIAL PRIMARY KEY,
+    ta_asset_id character varying NOT NULL,
+    root_hash character varying NOT NULL,
+    root_sum bigint NOT NULL,
+    leaf_count bigint NOT NULL,
+    nostr_event_id character varying,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_asset
+        FOREIGN KEY(ta_asset_id)
+        REFERENCES assets(ta_asset_id)
+        ON DELETE NO ACTION
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_liability_snapshots_on_ta_asset_id
+    ON liability_snapshots (ta_asset_id, id);
+--bun:split
+CREATE TABLE liability_leaves (
+    id SERIAL PRIMARY KEY,
+    snapshot_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    leaf_index bigint NOT NULL,
+    salt character varying NOT NULL,
+    balance bigint NOT NULL,
+    CONSTRAINT fk_snapshot
+        FOREIGN KEY(snapshot_id)
+        REFERENCES liability_snapshots(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT check_balance_not_negative CHECK (balance >= 0)
+);
+--bun:split
+CREATE UNIQUE INDEX IF NOT EXISTS index_liability_leaves_on_snapshot_id_user_id
+    ON liability_leaves (snapshot_id, user_id);
+--bun:split
+CREATE UNIQUE INDEX IF NOT EXISTS index_liability_leaves_on_snapshot_id_leaf_index
+    ON liability_leaves (snapshot_id, leaf_index);
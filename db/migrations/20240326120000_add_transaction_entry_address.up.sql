@@ -0,0 +1,4 @@
+ALTER TABLE transaction_entries ADD COLUMN IF NOT EXISTS address character varying;
+--bun:split
+CREATE INDEX IF NOT EXISTS index_transaction_entries_on_user_id_id
+    ON transaction_entries (user_id, id DESC);
@@ -0,0 +1,41 @@
+CREATE TABLE mint_batches (
+    id SERIAL PRIMARY KEY,
+    batch_key character varying NOT NULL UNIQUE,
+    batch_txid character varying,
+    state character varying NOT NULL,
+    fee_rate bigint,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at timestamp with time zone
+);
+--bun:split
+CREATE TABLE mint_batch_assets (
+    id SERIAL PRIMARY KEY,
+    batch_id bigint NOT NULL,
+    asset_name character varying NOT NULL,
+    asset_type bigint NOT NULL,
+    amount bigint NOT NULL,
+    metadata character varying,
+    decimal_display bigint NOT NULL DEFAULT 0,
+    group_key character varying,
+    new_grouped_asset boolean NOT NULL DEFAULT false,
+    issuer_user_id bigint,
+    ta_asset_id character varying,
+    transaction_entry_id bigint,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_batch
+        FOREIGN KEY(batch_id)
+        REFERENCES mint_batches(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_issuer
+        FOREIGN KEY(issuer_user_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_transaction_entry
+        FOREIGN KEY(transaction_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT check_amount_positive CHECK (amount > 0)
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_mint_batch_assets_on_batch_id
+    ON mint_batch_assets (batch_id);
@@ -0,0 +1,34 @@
+-- burned units are booked to a new system account per asset
+INSERT INTO accounts (user_id, ta_asset_id, type)
+SELECT NULL, assets.ta_asset_id, 'burned'
+FROM assets
+ON CONFLICT (ta_asset_id, type) WHERE user_id IS NULL DO NOTHING;
+--bun:split
+CREATE TABLE asset_burns (
+    id SERIAL PRIMARY KEY,
+    ta_asset_id character varying NOT NULL,
+    user_id bigint,
+    amount bigint NOT NULL,
+    transaction_entry_id bigint NOT NULL,
+    anchor_txid character varying,
+    outpoint character varying,
+    burn_proof text,
+    note character varying,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_asset
+        FOREIGN KEY(ta_asset_id)
+        REFERENCES assets(ta_asset_id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_transaction_entry
+        FOREIGN KEY(transaction_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT check_amount_positive CHECK (amount > 0)
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_asset_burns_on_ta_asset_id
+    ON asset_burns (ta_asset_id);
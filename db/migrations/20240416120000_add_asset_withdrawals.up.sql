@@ -0,0 +1,28 @@
+CREATE TABLE asset_withdrawals (
+    id SERIAL PRIMARY KEY,
+    user_id bigint NOT NULL,
+    ta_asset_id character varying NOT NULL,
+    transaction_entry_id bigint NOT NULL UNIQUE,
+    address character varying NOT NULL,
+    amount bigint NOT NULL,
+    script_key character varying NOT NULL,
+    outpoint character varying NOT NULL,
+    proof_file text,
+    proof_exported_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_asset
+        FOREIGN KEY(ta_asset_id)
+        REFERENCES assets(ta_asset_id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_transaction_entry
+        FOREIGN KEY(transaction_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_asset_withdrawals_on_user_id
+    ON asset_withdrawals (user_id);
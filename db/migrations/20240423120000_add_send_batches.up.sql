@@ -0,0 +1,52 @@
+CREATE TABLE send_batches (
+    id SERIAL PRIMARY KEY,
+    ta_asset_id character varying NOT NULL,
+    state character varying NOT NULL,
+    anchor_txid character varying,
+    chain_fees bigint NOT NULL DEFAULT 0,
+    error character varying,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_asset
+        FOREIGN KEY(ta_asset_id)
+        REFERENCES assets(ta_asset_id)
+        ON DELETE NO ACTION
+);
+--bun:split
+CREATE TABLE send_batch_items (
+    id SERIAL PRIMARY KEY,
+    batch_id bigint,
+    user_id bigint NOT NULL,
+    ta_asset_id character varying NOT NULL,
+    transaction_entry_id bigint NOT NULL UNIQUE,
+    address character varying NOT NULL,
+    script_key character varying,
+    amount bigint NOT NULL,
+    withdraw boolean NOT NULL DEFAULT false,
+    state character varying NOT NULL,
+    chain_fee bigint NOT NULL DEFAULT 0,
+    fee_entry_id bigint,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at timestamp with time zone,
+    CONSTRAINT fk_batch
+        FOREIGN KEY(batch_id)
+        REFERENCES send_batches(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_transaction_entry
+        FOREIGN KEY(transaction_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_fee_entry
+        FOREIGN KEY(fee_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_send_batch_items_on_state_ta_asset_id
+    ON send_batch_items (state, ta_asset_id);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_send_batch_items_on_user_id
+    ON send_batch_items (user_id);
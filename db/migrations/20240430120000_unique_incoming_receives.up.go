@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		// receives credited more than once by replayed tapd events would block the unique index.
+		// The later copies keep their amounts (balances do not change) but get a tagged outpoint,
+		// so they can be found with `outpoint LIKE '%:duplicate:%'` and resolved with ledger adjustments.
+		var duplicates []int64
+		err := db.NewRaw(`
+			SELECT id FROM (
+				SELECT id, row_number() OVER (PARTITION BY outpoint, address ORDER BY id) AS n
+				FROM transaction_entries
+				WHERE entry_type = 'incoming' AND outpoint <> 'tahub_internal_outpoint' AND address IS NOT NULL
+			) receives
+			WHERE n > 1`).Scan(ctx, &duplicates)
+		if err != nil {
+			return err
+		}
+		if len(duplicates) > 0 {
+			fmt.Printf("\033[1;31m%s\033[0m", fmt.Sprintf("Found %d duplicate incoming receive entries, review the entries %v\n", len(duplicates), duplicates))
+			_, err = db.NewRaw(`UPDATE transaction_entries SET outpoint = outpoint || ':duplicate:' || id WHERE id IN (?)`, bun.In(duplicates)).Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = db.Exec(`
+			CREATE UNIQUE INDEX IF NOT EXISTS index_transaction_entries_on_incoming_outpoint_address
+			ON transaction_entries (outpoint, address)
+			WHERE entry_type = 'incoming' AND outpoint <> 'tahub_internal_outpoint'`)
+		return err
+	}, nil)
+}
@@ -0,0 +1,27 @@
+CREATE TABLE asset_receives (
+    id SERIAL PRIMARY KEY,
+    user_id bigint,
+    ta_asset_id character varying NOT NULL,
+    address character varying NOT NULL,
+    outpoint character varying NOT NULL,
+    amount bigint NOT NULL,
+    state character varying NOT NULL,
+    confirmation_height bigint NOT NULL DEFAULT 0,
+    confirmations bigint NOT NULL DEFAULT 0,
+    backoff_tries bigint NOT NULL DEFAULT 0,
+    transaction_entry_id bigint,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at timestamp with time zone,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_transaction_entry
+        FOREIGN KEY(transaction_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT unique_asset_receive UNIQUE (outpoint, address)
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_asset_receives_on_user_id
+    ON asset_receives (user_id);
@@ -0,0 +1 @@
+ALTER TABLE assets ADD COLUMN decimal_display bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+CREATE TABLE asset_prices (
+    id SERIAL PRIMARY KEY,
+    ta_asset_id character varying NOT NULL,
+    currency character varying NOT NULL,
+    price numeric NOT NULL,
+    source character varying NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_asset_prices_on_ta_asset_id_currency_created_at
+    ON asset_prices (ta_asset_id, currency, created_at DESC);
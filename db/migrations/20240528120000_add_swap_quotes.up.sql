@@ -0,0 +1,35 @@
+-- swaps are settled against a new system liquidity account per asset
+INSERT INTO accounts (user_id, ta_asset_id, type)
+SELECT NULL, assets.ta_asset_id, 'liquidity'
+FROM assets
+ON CONFLICT (ta_asset_id, type) WHERE user_id IS NULL DO NOTHING;
+--bun:split
+CREATE TABLE swap_quotes (
+    id SERIAL PRIMARY KEY,
+    user_id bigint NOT NULL,
+    from_asset_id character varying NOT NULL,
+    to_asset_id character varying NOT NULL,
+    from_amount bigint NOT NULL,
+    to_amount bigint NOT NULL,
+    from_price numeric NOT NULL,
+    to_price numeric NOT NULL,
+    currency character varying NOT NULL,
+    spread_bps bigint NOT NULL,
+    state character varying NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    transaction_entry_id bigint,
+    executed_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_transaction_entry
+        FOREIGN KEY(transaction_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT check_swap_amounts_positive CHECK (from_amount > 0 AND to_amount > 0)
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_swap_quotes_on_user_id
+    ON swap_quotes (user_id);
@@ -0,0 +1,54 @@
+CREATE TABLE orders (
+    id SERIAL PRIMARY KEY,
+    user_id bigint NOT NULL,
+    side character varying NOT NULL,
+    base_asset_id character varying NOT NULL,
+    quote_asset_id character varying NOT NULL,
+    price numeric NOT NULL,
+    amount bigint NOT NULL,
+    filled bigint NOT NULL DEFAULT 0,
+    locked bigint NOT NULL DEFAULT 0,
+    state character varying NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at timestamp with time zone,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT check_order_amounts CHECK (price > 0 AND amount > 0 AND filled >= 0 AND filled <= amount AND locked >= 0)
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_orders_on_book
+    ON orders (base_asset_id, quote_asset_id, side, price) WHERE state = 'open';
+--bun:split
+CREATE INDEX IF NOT EXISTS index_orders_on_user_id
+    ON orders (user_id);
+--bun:split
+CREATE TABLE trades (
+    id SERIAL PRIMARY KEY,
+    base_asset_id character varying NOT NULL,
+    quote_asset_id character varying NOT NULL,
+    buy_order_id bigint NOT NULL,
+    sell_order_id bigint NOT NULL,
+    taker_side character varying NOT NULL,
+    price numeric NOT NULL,
+    amount bigint NOT NULL,
+    quote_amount bigint NOT NULL,
+    transaction_entry_id bigint NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_buy_order
+        FOREIGN KEY(buy_order_id)
+        REFERENCES orders(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_sell_order
+        FOREIGN KEY(sell_order_id)
+        REFERENCES orders(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_transaction_entry
+        FOREIGN KEY(transaction_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_trades_on_pair
+    ON trades (base_asset_id, quote_asset_id, id);
@@ -0,0 +1,27 @@
+CREATE TABLE internal_transfers (
+    id SERIAL PRIMARY KEY,
+    sender_id bigint NOT NULL,
+    recipient_id bigint NOT NULL,
+    recipient_pubkey character varying NOT NULL,
+    ta_asset_id character varying NOT NULL,
+    amount bigint NOT NULL,
+    memo character varying,
+    transaction_entry_id bigint NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_sender
+        FOREIGN KEY(sender_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_recipient
+        FOREIGN KEY(recipient_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_transaction_entry
+        FOREIGN KEY(transaction_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT check_amount_positive CHECK (amount > 0)
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_internal_transfers_on_transaction_entry_id
+    ON internal_transfers (transaction_entry_id);
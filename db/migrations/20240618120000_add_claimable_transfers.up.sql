@@ -0,0 +1,22 @@
+-- transfers to pubkeys that are not registered yet are held in a new system escrow account per asset
+INSERT INTO accounts (user_id, ta_asset_id, type)
+SELECT NULL, assets.ta_asset_id, 'escrow'
+FROM assets
+ON CONFLICT (ta_asset_id, type) WHERE user_id IS NULL DO NOTHING;
+--bun:split
+ALTER TABLE internal_transfers
+    ALTER COLUMN recipient_id DROP NOT NULL,
+    ADD COLUMN state character varying DEFAULT 'completed' NOT NULL,
+    ADD COLUMN settlement_entry_id bigint,
+    ADD COLUMN expires_at timestamp with time zone,
+    ADD COLUMN settled_at timestamp with time zone,
+    ADD CONSTRAINT fk_settlement_entry
+        FOREIGN KEY(settlement_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION;
+--bun:split
+CREATE INDEX IF NOT EXISTS index_internal_transfers_on_pending_recipient_pubkey
+    ON internal_transfers (recipient_pubkey) WHERE state = 'pending';
+--bun:split
+CREATE INDEX IF NOT EXISTS index_internal_transfers_on_pending_expires_at
+    ON internal_transfers (expires_at) WHERE state = 'pending';
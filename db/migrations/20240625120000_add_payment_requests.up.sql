@@ -0,0 +1,29 @@
+CREATE TABLE payment_requests (
+    id SERIAL PRIMARY KEY,
+    requester_id bigint NOT NULL,
+    requester_pubkey character varying NOT NULL,
+    payer_pubkey character varying NOT NULL,
+    ta_asset_id character varying NOT NULL,
+    amount bigint NOT NULL,
+    memo character varying,
+    state character varying NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    internal_transfer_id bigint,
+    updated_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_requester
+        FOREIGN KEY(requester_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_internal_transfer
+        FOREIGN KEY(internal_transfer_id)
+        REFERENCES internal_transfers(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT check_payment_request_amount_positive CHECK (amount > 0)
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_payment_requests_on_requester_id
+    ON payment_requests (requester_id);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_payment_requests_on_payer_pubkey
+    ON payment_requests (payer_pubkey);
@@ -0,0 +1,48 @@
+CREATE TABLE checkouts (
+    id SERIAL PRIMARY KEY,
+    user_id bigint NOT NULL,
+    ta_asset_id character varying NOT NULL,
+    amount bigint NOT NULL,
+    fiat_amount character varying,
+    fiat_currency character varying,
+    fiat_price character varying,
+    memo character varying,
+    order_ref character varying,
+    address character varying,
+    invoice_id bigint,
+    payment_request character varying,
+    state character varying NOT NULL,
+    webhook_url character varying,
+    webhook_attempts integer DEFAULT 0 NOT NULL,
+    webhook_delivered_at timestamp with time zone,
+    transaction_entry_id bigint,
+    expires_at timestamp with time zone NOT NULL,
+    paid_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_invoice
+        FOREIGN KEY(invoice_id)
+        REFERENCES invoices(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT fk_transaction_entry
+        FOREIGN KEY(transaction_entry_id)
+        REFERENCES transaction_entries(id)
+        ON DELETE NO ACTION,
+    CONSTRAINT check_checkout_amount_positive CHECK (amount > 0)
+);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_checkouts_on_user_id
+    ON checkouts (user_id);
+--bun:split
+CREATE UNIQUE INDEX IF NOT EXISTS index_checkouts_on_address
+    ON checkouts (address);
+--bun:split
+CREATE UNIQUE INDEX IF NOT EXISTS index_checkouts_on_invoice_id
+    ON checkouts (invoice_id);
+--bun:split
+CREATE INDEX IF NOT EXISTS index_checkouts_on_open_expires_at
+    ON checkouts (expires_at) WHERE state = 'open';
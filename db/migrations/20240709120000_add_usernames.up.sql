@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN username character varying;
+--bun:split
+CREATE UNIQUE INDEX IF NOT EXISTS index_users_on_username
+    ON users (username);
@@ -0,0 +1,19 @@
+ALTER TABLE users ADD COLUMN username_updated_at timestamp with time zone;
+--bun:split
+CREATE TABLE username_holds (
+    id SERIAL PRIMARY KEY,
+    name character varying NOT NULL,
+    user_id bigint,
+    kind character varying NOT NULL,
+    reason character varying,
+    operator character varying,
+    expires_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE NO ACTION
+);
+--bun:split
+CREATE UNIQUE INDEX IF NOT EXISTS index_username_holds_on_name
+    ON username_holds (name);
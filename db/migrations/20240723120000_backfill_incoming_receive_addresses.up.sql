@@ -0,0 +1,27 @@
+-- incoming receives credited before the address was recorded get the address of their user and
+-- asset when it is the only one with the received amount, the rest is filled from tapd on startup
+-- (see BackfillReceiveAddresses). Only the first entry of an outpoint is filled, later copies were
+-- credited twice and are left for review.
+UPDATE transaction_entries e
+SET address = (
+    SELECT a.addr FROM addresses a
+    WHERE a.user_id = e.user_id AND a.ta_asset_id = e.ta_asset_id AND a.amount = e.amount
+)
+WHERE e.entry_type = 'incoming'
+    AND e.address IS NULL
+    AND e.outpoint <> ''
+    AND e.outpoint <> 'tahub_internal_outpoint'
+    AND (
+        SELECT count(*) FROM addresses a
+        WHERE a.user_id = e.user_id AND a.ta_asset_id = e.ta_asset_id AND a.amount = e.amount
+    ) = 1
+    AND e.id = (
+        SELECT min(d.id) FROM transaction_entries d
+        WHERE d.entry_type = 'incoming' AND d.outpoint = e.outpoint AND d.address IS NULL
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM transaction_entries d
+        JOIN addresses a ON a.addr = d.address
+        WHERE d.entry_type = 'incoming' AND d.outpoint = e.outpoint
+            AND a.user_id = e.user_id AND a.ta_asset_id = e.ta_asset_id AND a.amount = e.amount
+    );
@@ -0,0 +1 @@
+ALTER TABLE checkouts ADD COLUMN IF NOT EXISTS webhook_secret character varying;
@@ -0,0 +1,7 @@
+-- burns are pending from before the tapd call until its outcome is recorded
+ALTER TABLE asset_burns ADD COLUMN IF NOT EXISTS state character varying NOT NULL DEFAULT 'burned';
+--bun:split
+ALTER TABLE asset_burns ADD COLUMN IF NOT EXISTS error character varying;
+--bun:split
+CREATE INDEX IF NOT EXISTS index_asset_burns_on_state
+    ON asset_burns (state) WHERE state = 'pending';
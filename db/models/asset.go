@@ -18,6 +18,8 @@ type Asset struct {
 	TaAssetID string    `bun:",notnull,unique"`
 	AssetName string    `bun:",notnull,unique"`
 	AssetType int64    `bun:",notnull"` // https://lightning.engineering/api-docs/api/taproot-assets/universe/query-asset-stats#taprpcassettype
+	// number of decimal places amounts are displayed with, read from the asset's tapd meta data
+	DecimalDisplay uint32 `bun:",notnull,default:0"`
 	CreatedAt time.Time `bun:",nullzero,notnull,default:current_timestamp"`
 	UpdatedAt bun.NullTime 
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	// a burn is pending from before the BurnAsset call until its outcome is recorded
+	AssetBurnStatePending = "pending"
+	AssetBurnStateBurned  = "burned"
+	AssetBurnStateFailed  = "failed"
+)
+
+// AssetBurn : units destroyed on tapd, UserID is empty for burns of hub owned (treasury) units
+type AssetBurn struct {
+	ID                 int64  `json:"id" bun:",pk,autoincrement"`
+	TaAssetID          string `json:"asset_id" bun:",notnull"`
+	UserID             int64  `json:"user_id" bun:",nullzero"`
+	Amount             int64  `json:"amount" bun:",notnull"`
+	TransactionEntryID int64  `json:"transaction_entry_id" bun:",notnull"`
+	AnchorTxid         string `json:"anchor_txid" bun:",nullzero"`
+	Outpoint           string `json:"outpoint" bun:",nullzero"`
+	State              string `json:"state" bun:",notnull"`
+	Error              string `json:"error" bun:",nullzero"`
+	// hex encoded proof of the burn output
+	BurnProof string    `json:"burn_proof" bun:",nullzero"`
+	Note      string    `json:"note" bun:",nullzero"`
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// AssetPrice : price history, the fiat price of one unit of an asset in its decimal display as
+// reported by a price oracle. Prices are kept as exact decimal strings.
+type AssetPrice struct {
+	ID        int64     `json:"-" bun:",pk,autoincrement"`
+	TaAssetID string    `json:"asset_id" bun:",notnull"`
+	Currency  string    `json:"currency" bun:",notnull"`
+	Price     string    `json:"price" bun:"type:numeric,notnull"`
+	Source    string    `json:"source" bun:",notnull"`
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const (
+	// the receive was seen in the mempool
+	AssetReceiveStateDetected = "detected"
+	// the anchor transaction confirmed but is not yet at the configured depth
+	AssetReceiveStateConfirming = "confirming"
+	// waiting for the proof courier to deliver the proof
+	AssetReceiveStateProofPending = "proof_pending"
+	// the proof courier failed and tapd backs off before retrying
+	AssetReceiveStateBackoffRetrying = "backoff_retrying"
+	AssetReceiveStateCredited        = "credited"
+)
+
+// AssetReceive : lifecycle of an incoming transfer to a hub address, one per outpoint and address.
+// UserID is empty for addresses not matched to a user, TransactionEntryID is set once credited.
+type AssetReceive struct {
+	ID                 int64        `json:"id" bun:",pk,autoincrement"`
+	UserID             int64        `json:"-" bun:",nullzero"`
+	TaAssetID          string       `json:"asset_id" bun:",notnull"`
+	Address            string       `json:"address" bun:",notnull"`
+	Outpoint           string       `json:"outpoint" bun:",notnull"`
+	Amount             int64        `json:"amount" bun:",notnull"`
+	State              string       `json:"state" bun:",notnull"`
+	ConfirmationHeight uint32       `json:"confirmation_height"`
+	Confirmations      uint32       `json:"confirmations"`
+	BackoffTries       int64        `json:"backoff_tries"`
+	TransactionEntryID int64        `json:"transaction_entry_id" bun:",nullzero"`
+	CreatedAt          time.Time    `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt          bun.NullTime `json:"updated_at"`
+}
+
+func (r *AssetReceive) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.UpdateQuery:
+		r.UpdatedAt = bun.NullTime{Time: time.Now()}
+	}
+	return nil
+}
+
+var _ bun.BeforeAppendModelHook = (*AssetReceive)(nil)
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// AssetWithdrawal : an external send in withdrawal mode, the proof file of the recipient output is
+// exported from tapd once the send completes so the user can import it into their own wallet
+type AssetWithdrawal struct {
+	ID                 int64  `json:"id" bun:",pk,autoincrement"`
+	UserID             int64  `json:"user_id" bun:",notnull"`
+	TaAssetID          string `json:"asset_id" bun:",notnull"`
+	TransactionEntryID int64  `json:"transaction_entry_id" bun:",notnull,unique"`
+	Address            string `json:"address" bun:",notnull"`
+	Amount             int64  `json:"amount" bun:",notnull"`
+	ScriptKey          string `json:"script_key" bun:",notnull"` // hex encoded
+	Outpoint           string `json:"outpoint" bun:",notnull"`
+	// hex encoded raw proof file, empty until exported
+	ProofFile       string       `json:"-" bun:",nullzero"`
+	ProofExportedAt bun.NullTime `json:"proof_exported_at"`
+	CreatedAt       time.Time    `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
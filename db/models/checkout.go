@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const (
+	CheckoutStateOpen    = "open"
+	CheckoutStatePaid    = "paid"
+	CheckoutStateExpired = "expired"
+)
+
+// Checkout : a merchant's order for an amount of an asset. Asset checkouts are paid to their own tap
+// address, btc checkouts with the LN invoice InvoiceID. TransactionEntryID is the entry that
+// credited the payment to the merchant.
+type Checkout struct {
+	ID                 int64        `json:"checkout_id" bun:",pk,autoincrement"`
+	UserID             int64        `json:"-" bun:",notnull"`
+	TaAssetID          string       `json:"asset_id" bun:",notnull"`
+	Amount             int64        `json:"amount" bun:",notnull"`
+	FiatAmount         string       `json:"fiat_amount,omitempty" bun:",nullzero"`
+	FiatCurrency       string       `json:"fiat_currency,omitempty" bun:",nullzero"`
+	FiatPrice          string       `json:"fiat_price,omitempty" bun:",nullzero"`
+	Memo               string       `json:"memo,omitempty" bun:",nullzero"`
+	OrderRef           string       `json:"order_ref,omitempty" bun:",nullzero"`
+	Address            string       `json:"address,omitempty" bun:",nullzero"`
+	InvoiceID          int64        `json:"-" bun:",nullzero"`
+	PaymentRequest     string       `json:"payment_request,omitempty" bun:",nullzero"`
+	State              string       `json:"state" bun:",notnull"`
+	WebhookUrl         string       `json:"webhook_url,omitempty" bun:",nullzero"`
+	WebhookSecret      string       `json:"-" bun:",nullzero"` // signs the webhooks to the merchant's own url, only returned on creation
+	WebhookAttempts    int          `json:"-" bun:",notnull"`
+	WebhookDeliveredAt bun.NullTime `json:"webhook_delivered_at"`
+	TransactionEntryID int64        `json:"-" bun:",nullzero"`
+	ExpiresAt          time.Time    `json:"expires_at" bun:",notnull"`
+	PaidAt             bun.NullTime `json:"paid_at"`
+	UpdatedAt          time.Time    `json:"updated_at" bun:",nullzero"`
+	CreatedAt          time.Time    `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
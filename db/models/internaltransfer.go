@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	// credited to a registered recipient right away
+	InternalTransferStateCompleted = "completed"
+	// held in the escrow account until the recipient registers or it expires
+	InternalTransferStatePending  = "pending"
+	InternalTransferStateClaimed  = "claimed"
+	InternalTransferStateReverted = "reverted"
+)
+
+// InternalTransfer : transfer between two hub users addressed by the recipient's pubkey instead of a
+// tap address. TransactionEntryID is the sender's outgoing entry, the recipient's incoming entry is
+// its child entry. Transfers to a pubkey that is not registered yet are held in escrow, they have no
+// RecipientID until they are claimed and SettlementEntryID is the claim or the reversal.
+type InternalTransfer struct {
+	ID                 int64     `json:"transfer_id" bun:",pk,autoincrement"`
+	SenderID           int64     `json:"-" bun:",notnull"`
+	RecipientID        int64     `json:"-" bun:",nullzero"`
+	RecipientPubkey    string    `json:"recipient_pubkey" bun:",notnull"`
+	TaAssetID          string    `json:"asset_id" bun:",notnull"`
+	Amount             int64     `json:"amount" bun:",notnull"`
+	Memo               string    `json:"memo,omitempty"`
+	State              string    `json:"state" bun:",notnull"`
+	TransactionEntryID int64     `json:"-" bun:",notnull"`
+	SettlementEntryID  int64     `json:"-" bun:",nullzero"`
+	ExpiresAt          time.Time `json:"expires_at,omitempty" bun:",nullzero"`
+	SettledAt          time.Time `json:"settled_at,omitempty" bun:",nullzero"`
+	CreatedAt          time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
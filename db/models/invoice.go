@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/getAlby/lndhub.go/common"
 	"github.com/uptrace/bun"
 )
 
@@ -46,6 +47,11 @@ func (i *Invoice) SetFee(txEntry TransactionEntry, routingFee int64) {
 	}
 }
 
+// IsAssetInvoice : the invoice is denominated in a taproot asset and paid through an asset channel
+func (i *Invoice) IsAssetInvoice() bool {
+	return i.TaAssetID != "" && i.TaAssetID != common.BTC_TA_ASSET_ID
+}
+
 func (i *Invoice) BeforeAppendModel(ctx context.Context, query bun.Query) error {
 	switch query.(type) {
 	case *bun.UpdateQuery:
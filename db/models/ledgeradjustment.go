@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// LedgerAdjustment : audit trail for manual ledger adjustments posted by an operator
+type LedgerAdjustment struct {
+	ID        int64  `json:"id" bun:",pk,autoincrement"`
+	UserID    int64  `json:"user_id" bun:",notnull"`
+	User      *User  `json:"-" bun:"rel:belongs-to,join:user_id=id"`
+	TaAssetID string `json:"asset_id" bun:",notnull"`
+	// signed amount, positive amounts credit the user and negative amounts debit the user
+	Amount             int64             `json:"amount" bun:",notnull"`
+	TransactionEntryID int64             `json:"transaction_entry_id" bun:",notnull"`
+	TransactionEntry   *TransactionEntry `json:"-" bun:"rel:belongs-to,join:transaction_entry_id=id"`
+	Reason             string            `json:"reason" bun:",notnull"`
+	TicketRef          string            `json:"ticket_ref" bun:",notnull"`
+	Operator           string            `json:"operator" bun:",notnull"`
+	CreatedAt          time.Time         `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
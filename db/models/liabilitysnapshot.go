@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// LiabilitySnapshot : root of a proof-of-liabilities merkle sum tree over all users' current balances of one asset
+type LiabilitySnapshot struct {
+	ID           int64     `json:"id" bun:",pk,autoincrement"`
+	TaAssetID    string    `json:"asset_id" bun:",notnull"`
+	RootHash     string    `json:"root_hash" bun:",notnull"`
+	RootSum      int64     `json:"root_sum" bun:",notnull"`
+	LeafCount    int64     `json:"leaf_count" bun:",notnull"`
+	NostrEventID string    `json:"nostr_event_id" bun:",nullzero"`
+	CreatedAt    time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// LiabilityLeaf : a user's balance in a snapshot, leaves are ordered by leaf_index
+type LiabilityLeaf struct {
+	ID         int64  `bun:",pk,autoincrement"`
+	SnapshotID int64  `bun:",notnull"`
+	UserID     int64  `bun:",notnull"`
+	User       *User  `bun:"rel:belongs-to,join:user_id=id"`
+	LeafIndex  int64  `bun:",notnull"`
+	Salt       string `bun:",notnull"` // hex encoded
+	Balance    int64  `bun:",notnull"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const (
+	MintBatchStatePending   = "pending"
+	MintBatchStateFinalized = "finalized"
+	MintBatchStateCancelled = "seedling_cancelled"
+	// cancelled after the batch was frozen, e.g. the minting transaction could not be broadcast
+	MintBatchStateSproutCancelled = "sprout_cancelled"
+)
+
+// MintBatch : a tapd minting batch, state mirrors mintrpc.BatchState without the BATCH_STATE_ prefix
+type MintBatch struct {
+	ID        int64            `json:"id" bun:",pk,autoincrement"`
+	BatchKey  string           `json:"batch_key" bun:",notnull,unique"` // hex encoded
+	BatchTxid string           `json:"batch_txid" bun:",nullzero"`
+	State     string           `json:"state" bun:",notnull"`
+	FeeRate   uint32           `json:"fee_rate" bun:",nullzero"` // sat/kw
+	Assets    []MintBatchAsset `json:"assets" bun:"rel:has-many,join:id=batch_id"`
+	CreatedAt time.Time        `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt bun.NullTime     `json:"updated_at"`
+}
+
+// MintBatchAsset : an asset seedling of a batch. TaAssetID is set once the batch is finalized and
+// the asset is registered, IssuerUserID optionally receives the minted supply.
+type MintBatchAsset struct {
+	ID              int64  `json:"id" bun:",pk,autoincrement"`
+	BatchID         int64  `json:"batch_id" bun:",notnull"`
+	AssetName       string `json:"asset_name" bun:",notnull"`
+	AssetType       int64  `json:"asset_type" bun:",notnull"`
+	Amount          int64  `json:"amount" bun:",notnull"`
+	Metadata        string `json:"metadata" bun:",nullzero"`
+	DecimalDisplay  uint32 `json:"decimal_display"`
+	GroupKey        string `json:"group_key" bun:",nullzero"` // hex encoded
+	NewGroupedAsset bool   `json:"new_grouped_asset"`
+	IssuerUserID    int64  `json:"issuer_user_id" bun:",nullzero"`
+	TaAssetID       string `json:"asset_id" bun:",nullzero"`
+	// transaction entry crediting the minted supply to the issuer
+	TransactionEntryID int64     `json:"transaction_entry_id" bun:",nullzero"`
+	CreatedAt          time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+func (b *MintBatch) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.UpdateQuery:
+		b.UpdatedAt = bun.NullTime{Time: time.Now()}
+	}
+	return nil
+}
+
+var _ bun.BeforeAppendModelHook = (*MintBatch)(nil)
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	OrderSideBuy  = "buy"
+	OrderSideSell = "sell"
+
+	OrderStateOpen      = "open"
+	OrderStateFilled    = "filled"
+	OrderStateCancelled = "cancelled"
+)
+
+// Order : limit order to buy or sell Amount units of the base asset for the quote asset. The price
+// is the decimal price of one display unit of the base asset in display units of the quote asset.
+// Locked is what the order still holds in the owner's reserved account, quote units for buys and
+// base units for sells.
+type Order struct {
+	ID           int64     `json:"order_id" bun:",pk,autoincrement"`
+	UserID       int64     `json:"-" bun:",notnull"`
+	Side         string    `json:"side" bun:",notnull"`
+	BaseAssetID  string    `json:"base_asset_id" bun:",notnull"`
+	QuoteAssetID string    `json:"quote_asset_id" bun:",notnull"`
+	Price        string    `json:"price" bun:"type:numeric,notnull"`
+	Amount       int64     `json:"amount" bun:",notnull"`
+	Filled       int64     `json:"filled" bun:",notnull"`
+	Locked       int64     `json:"locked" bun:",notnull"`
+	State        string    `json:"state" bun:",notnull"`
+	CreatedAt    time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt    time.Time `json:"updated_at" bun:",nullzero"`
+}
+
+// Trade : fill between a buy and a sell order at the price of the resting order
+type Trade struct {
+	ID           int64  `json:"trade_id" bun:",pk,autoincrement"`
+	BaseAssetID  string `json:"base_asset_id" bun:",notnull"`
+	QuoteAssetID string `json:"quote_asset_id" bun:",notnull"`
+	BuyOrderID   int64  `json:"-" bun:",notnull"`
+	SellOrderID  int64  `json:"-" bun:",notnull"`
+	// side of the incoming order that matched the resting one
+	TakerSide   string `json:"taker_side" bun:",notnull"`
+	Price       string `json:"price" bun:"type:numeric,notnull"`
+	Amount      int64  `json:"amount" bun:",notnull"`
+	QuoteAmount int64  `json:"quote_amount" bun:",notnull"`
+	// the base asset entry to the buyer, the quote asset entry to the seller is its child entry
+	TransactionEntryID int64     `json:"-" bun:",notnull"`
+	CreatedAt          time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
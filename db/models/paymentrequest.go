@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	PaymentRequestStateOpen      = "open"
+	PaymentRequestStatePaid      = "paid"
+	PaymentRequestStateCancelled = "cancelled"
+	PaymentRequestStateExpired   = "expired"
+)
+
+// PaymentRequest : a user asking the owner of PayerPubkey for an amount of an asset. Paying it books
+// an internal transfer from the payer to the requester, InternalTransferID points to it.
+type PaymentRequest struct {
+	ID                 int64     `json:"request_id" bun:",pk,autoincrement"`
+	RequesterID        int64     `json:"-" bun:",notnull"`
+	RequesterPubkey    string    `json:"requester_pubkey" bun:",notnull"`
+	PayerPubkey        string    `json:"payer_pubkey" bun:",notnull"`
+	TaAssetID          string    `json:"asset_id" bun:",notnull"`
+	Amount             int64     `json:"amount" bun:",notnull"`
+	Memo               string    `json:"memo,omitempty"`
+	State              string    `json:"state" bun:",notnull"`
+	ExpiresAt          time.Time `json:"expires_at" bun:",notnull"`
+	InternalTransferID int64     `json:"transfer_id,omitempty" bun:",nullzero"`
+	UpdatedAt          time.Time `json:"updated_at" bun:",nullzero"`
+	CreatedAt          time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const (
+	SendBatchItemStateQueued  = "queued"
+	SendBatchItemStateSending = "sending"
+	SendBatchItemStateSent    = "sent"
+	SendBatchItemStateFailed  = "failed"
+
+	// a batch is sending from before the SendAsset call until its outcome is recorded
+	SendBatchStateSending = "sending"
+	SendBatchStateSent    = "sent"
+	SendBatchStateFailed  = "failed"
+)
+
+// SendBatch : external sends of one asset made with a single SendAsset call, ChainFees is in sats
+type SendBatch struct {
+	ID         int64           `json:"id" bun:",pk,autoincrement"`
+	TaAssetID  string          `json:"asset_id" bun:",notnull"`
+	State      string          `json:"state" bun:",notnull"`
+	AnchorTxid string          `json:"anchor_txid" bun:",nullzero"`
+	ChainFees  int64           `json:"chain_fees"`
+	Error      string          `json:"error" bun:",nullzero"`
+	Items      []SendBatchItem `json:"items" bun:"rel:has-many,join:id=batch_id"`
+	CreatedAt  time.Time       `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// SendBatchItem : a user's queued external send, ChainFee is the user's pro-rata share of the
+// batch chain fees in sats charged by FeeEntryID, 0 if the hub covered it
+type SendBatchItem struct {
+	ID                 int64  `json:"id" bun:",pk,autoincrement"`
+	BatchID            int64  `json:"batch_id" bun:",nullzero"`
+	UserID             int64  `json:"user_id" bun:",notnull"`
+	TaAssetID          string `json:"asset_id" bun:",notnull"`
+	TransactionEntryID int64  `json:"transaction_entry_id" bun:",notnull,unique"`
+	Address            string `json:"address" bun:",notnull"`
+	ScriptKey          string `json:"-" bun:",nullzero"` // hex encoded script key of the address
+	Amount             int64  `json:"amount" bun:",notnull"`
+	// export the proof file once the send completes, see AssetWithdrawal
+	Withdraw   bool         `json:"withdraw"`
+	State      string       `json:"state" bun:",notnull"`
+	ChainFee   int64        `json:"chain_fee"`
+	FeeEntryID int64        `json:"fee_entry_id" bun:",nullzero"`
+	CreatedAt  time.Time    `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt  bun.NullTime `json:"updated_at"`
+}
+
+func (i *SendBatchItem) BeforeAppendModel(ctx context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.UpdateQuery:
+		i.UpdatedAt = bun.NullTime{Time: time.Now()}
+	}
+	return nil
+}
+
+var _ bun.BeforeAppendModelHook = (*SendBatchItem)(nil)
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	SwapQuoteStateQuoted   = "quoted"
+	SwapQuoteStateExecuted = "executed"
+)
+
+// SwapQuote : time-limited offer to swap FromAmount units of one asset for ToAmount units of
+// another against the hub liquidity accounts. Prices are the fiat prices of one unit in the
+// asset's decimal display the quote was made at, the spread is already taken from ToAmount.
+type SwapQuote struct {
+	ID          int64     `json:"quote_id" bun:",pk,autoincrement"`
+	UserID      int64     `json:"-" bun:",notnull"`
+	FromAssetID string    `json:"from_asset_id" bun:",notnull"`
+	ToAssetID   string    `json:"to_asset_id" bun:",notnull"`
+	FromAmount  int64     `json:"from_amount" bun:",notnull"`
+	ToAmount    int64     `json:"to_amount" bun:",notnull"`
+	FromPrice   string    `json:"from_price" bun:"type:numeric,notnull"`
+	ToPrice     string    `json:"to_price" bun:"type:numeric,notnull"`
+	Currency    string    `json:"currency" bun:",notnull"`
+	SpreadBps   int64     `json:"spread_bps" bun:",notnull"`
+	State       string    `json:"state" bun:",notnull"`
+	ExpiresAt   time.Time `json:"expires_at" bun:",notnull"`
+	// the user's debit of FromAmount, the credit of ToAmount is its child entry
+	TransactionEntryID int64     `json:"transaction_entry_id,omitempty" bun:",nullzero"`
+	ExecutedAt         time.Time `json:"executed_at,omitempty" bun:",nullzero"`
+	CreatedAt          time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
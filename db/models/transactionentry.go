@@ -13,17 +13,32 @@ const (
 	EntryTypeServiceFeeReversal = "service_fee_reversal"
 	EntryTypeFeeReserveReversal = "fee_reserve_reversal"
 	EntryTypeOutgoingReversal   = "outgoing_reversal"
+	EntryTypeAdjustment         = "adjustment"
+	EntryTypeMint               = "mint"
+	EntryTypeBurn               = "burn"
+	EntryTypeSwap               = "swap"
+	EntryTypeLiquidity          = "liquidity"
+	EntryTypeReserve            = "reserve"
+	EntryTypeRelease            = "release"
+	EntryTypeTrade              = "trade"
 
+	BroadcastStateQueued    = "queued" // waiting for the next send batch
 	BroadcastStatePending   = "pending"
 	BroadcastStateBroadcast = "broadcast"
+	BroadcastStateFailed    = "failed"
 	TahubInternalOutpoint   = "tahub_internal_outpoint"
 	TahubInternalComplete   = "tahub_internal_complete"
+	TahubInternalPending    = "tahub_internal_pending" // held in escrow for a pubkey that is not registered yet
 )
 
+// IncomingReceiveUniquePredicate : incoming tapd receives are unique per (outpoint, address),
+// internal transfers share a placeholder outpoint and are excluded
+const IncomingReceiveUniquePredicate = "entry_type = 'incoming' AND outpoint <> 'tahub_internal_outpoint'"
+
 // TransactionEntry : Transaction Entries Model
 type TransactionEntry struct {
 	ID              int64             `bun:",pk,autoincrement"`
-	UserID          int64             `bun:",notnull"`
+	UserID          int64             `bun:",nullzero"` // null for entries between system accounts
 	User            *User             `bun:"rel:belongs-to,join:user_id=id"`
 	InvoiceID       int64             `bun:",nullzero"`
 	Invoice         *Invoice          `bun:"rel:belongs-to,join:invoice_id=id"`
@@ -41,5 +56,6 @@ type TransactionEntry struct {
 	CreatedAt       time.Time         `bun:",nullzero,notnull,default:current_timestamp"`
 	EntryType       string
 	Outpoint        string 
+	Address         string            `bun:",nullzero"` // taproot assets address sent to or received on
 	BroadcastState  string
 }
@@ -8,6 +8,8 @@ import (
 type User struct {
 	ID           int64          `bun:",pk,autoincrement"`
 	Pubkey       string         `bun:",unique,notnull"`
+	Username     string         `bun:",nullzero,unique"`
+	UsernameUpdatedAt time.Time `bun:",nullzero"`
 	Accounts     []*Account `bun:"rel:has-many,join:id=user_id"`
 	Invoices     []*Invoice `bun:"rel:has-many,join:id=user_id"`
 	Addresses    []*Address `bun:"rel:has-many,join:id=user_id"`
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	UsernameHoldReleased = "released"
+	UsernameHoldRevoked  = "revoked"
+)
+
+// UsernameHold : a username nobody else can claim. Released names are held for their former owner
+// until ExpiresAt, names revoked by an operator are held without expiry until they are unblocked.
+type UsernameHold struct {
+	ID        int64     `json:"id" bun:",pk,autoincrement"`
+	Name      string    `json:"name" bun:",notnull,unique"`
+	UserID    int64     `json:"user_id,omitempty" bun:",nullzero"`
+	Kind      string    `json:"kind" bun:",notnull"`
+	Reason    string    `json:"reason,omitempty" bun:",nullzero"`
+	Operator  string    `json:"operator,omitempty" bun:",nullzero"`
+	ExpiresAt time.Time `json:"expires_at,omitempty" bun:",nullzero"`
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
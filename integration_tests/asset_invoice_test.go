@@ -0,0 +1,159 @@
+package integration_tests
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/getAlby/lndhub.go/tapd"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AssetInvoiceTestSuite struct {
+	TestSuite
+	mlnd                     *MockLND
+	externalLND              *MockLND
+	assetChannel             *MockAssetChannel
+	service                  *service.LndhubService
+	assetId                  string
+	alice                    *models.User
+	invoiceUpdateSubCancelFn context.CancelFunc
+}
+
+func (suite *AssetInvoiceTestSuite) SetupSuite() {
+	mlnd := newDefaultMockLND()
+	suite.mlnd = mlnd
+	externalLND, err := NewMockLND("1234567890abcdefabcd", 0, make(chan (*lnrpc.Invoice)))
+	if err != nil {
+		log.Fatalf("Error initializing test service: %v", err)
+	}
+	suite.externalLND = externalLND
+	svc, err := LndHubTestServiceInit(mlnd)
+	if err != nil {
+		log.Fatalf("Error initializing test service: %v", err)
+	}
+	// 10 sats per asset unit
+	suite.assetChannel = NewMockAssetChannel(mlnd, 10)
+	svc.AssetChannelClient = suite.assetChannel
+	suite.service = svc
+
+	assetIdBytes, err := randBytesFromStr(32, "0123456789abcdef")
+	if err != nil {
+		log.Fatalf("Error creating test asset id: %v", err)
+	}
+	suite.assetId = string(assetIdBytes)
+	_, err = svc.CreateAsset(context.Background(), "asset invoice test", suite.assetId, 0)
+	if err != nil {
+		log.Fatalf("Error creating test asset: %v", err)
+	}
+	suite.alice, err = svc.CreateUser(context.Background(), "")
+	if err != nil {
+		log.Fatalf("Error creating test user: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.invoiceUpdateSubCancelFn = cancel
+	go svc.InvoiceUpdateSubscription(ctx)
+}
+
+func (suite *AssetInvoiceTestSuite) TearDownSuite() {
+	suite.invoiceUpdateSubCancelFn()
+}
+
+func (suite *AssetInvoiceTestSuite) TearDownTest() {
+	clearTable(suite.service, "transaction_entries")
+	clearTable(suite.service, "invoices")
+	suite.assetChannel.FailPayment = false
+}
+
+func (suite *AssetInvoiceTestSuite) fundAlice(amount int64) {
+	invoice, err := suite.service.AddAssetInvoice(context.Background(), suite.alice.ID, suite.assetId, amount, "integration test asset invoice")
+	assert.NoError(suite.T(), err)
+	err = suite.mlnd.mockPaidInvoice(&ExpectedAddInvoiceResponseBody{
+		RHash:  invoice.RHash,
+		PayReq: invoice.PaymentRequest,
+	}, 0, false, nil)
+	assert.NoError(suite.T(), err)
+	//wait a bit for the callback event to hit
+	time.Sleep(100 * time.Millisecond)
+}
+
+func (suite *AssetInvoiceTestSuite) TestIncomingAssetInvoice() {
+	suite.fundAlice(100)
+	balance, err := suite.service.CurrentUserBalance(context.Background(), suite.assetId, suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	// credited in asset units, not in the sats paid over lightning
+	assert.Equal(suite.T(), int64(100), balance)
+
+	treasury, err := suite.service.SystemAccountFor(context.Background(), common.AccountTypeTreasury, suite.assetId)
+	assert.NoError(suite.T(), err)
+	var treasuryBalance int64
+	err = suite.service.DB.NewSelect().Table("account_ledgers").ColumnExpr("sum(account_ledgers.amount) as balance").Where("account_ledgers.account_id = ?", treasury.ID).Scan(context.Background(), &treasuryBalance)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(-100), treasuryBalance)
+}
+
+func (suite *AssetInvoiceTestSuite) TestOutgoingAssetPayment() {
+	suite.fundAlice(100)
+	externalInvoice, err := suite.externalLND.AddInvoice(context.Background(), &lnrpc.Invoice{
+		Memo:  "integration tests: external asset pay from alice",
+		Value: 250,
+	})
+	assert.NoError(suite.T(), err)
+	// quote includes 2 units of fee the edge node does not use, they are refunded
+	suite.assetChannel.fee = 2
+	defer func() { suite.assetChannel.fee = 0 }()
+	result, err := suite.service.PayAssetInvoice(context.Background(), suite.alice.ID, suite.assetId, externalInvoice.PaymentRequest)
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), result.PaymentPreimageStr)
+	assert.Equal(suite.T(), int64(25), result.Invoice.Amount)
+
+	balance, err := suite.service.CurrentUserBalance(context.Background(), suite.assetId, suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(75), balance)
+}
+
+func (suite *AssetInvoiceTestSuite) TestOutgoingAssetPaymentFailure() {
+	suite.fundAlice(100)
+	externalInvoice, err := suite.externalLND.AddInvoice(context.Background(), &lnrpc.Invoice{
+		Memo:  "integration tests: failed asset pay from alice",
+		Value: 250,
+	})
+	assert.NoError(suite.T(), err)
+	suite.assetChannel.FailPayment = true
+	_, err = suite.service.PayAssetInvoice(context.Background(), suite.alice.ID, suite.assetId, externalInvoice.PaymentRequest)
+	assert.Error(suite.T(), err)
+
+	balance, err := suite.service.CurrentUserBalance(context.Background(), suite.assetId, suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(100), balance)
+}
+
+func (suite *AssetInvoiceTestSuite) TestAssetPaymentInsufficientBalance() {
+	suite.fundAlice(10)
+	externalInvoice, err := suite.externalLND.AddInvoice(context.Background(), &lnrpc.Invoice{
+		Memo:  "integration tests: asset pay over balance",
+		Value: 250,
+	})
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.PayAssetInvoice(context.Background(), suite.alice.ID, suite.assetId, externalInvoice.PaymentRequest)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *AssetInvoiceTestSuite) TestAssetChannelsUnsupported() {
+	suite.service.AssetChannelClient = tapd.InitAssetChannelClient(&tapd.TapdConfig{})
+	defer func() { suite.service.AssetChannelClient = suite.assetChannel }()
+	_, err := suite.service.AddAssetInvoice(context.Background(), suite.alice.ID, suite.assetId, 100, "unsupported")
+	assert.ErrorIs(suite.T(), err, tapd.AssetChannelsUnsupportedError)
+	assert.True(suite.T(), service.IsAssetInvoiceUserError(err))
+}
+
+func TestAssetInvoiceSuite(t *testing.T) {
+	suite.Run(t, new(AssetInvoiceTestSuite))
+}
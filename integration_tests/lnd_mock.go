@@ -252,6 +252,14 @@ func (mlnd *MockLND) DecodeBolt11(ctx context.Context, bolt11 string, options ..
 	return result, nil
 }
 
+func (mlnd *MockLND) WalletBalance(ctx context.Context, req *lnrpc.WalletBalanceRequest, options ...grpc.CallOption) (*lnrpc.WalletBalanceResponse, error) {
+	return &lnrpc.WalletBalanceResponse{}, nil
+}
+
+func (mlnd *MockLND) ChannelBalance(ctx context.Context, req *lnrpc.ChannelBalanceRequest, options ...grpc.CallOption) (*lnrpc.ChannelBalanceResponse, error) {
+	return &lnrpc.ChannelBalanceResponse{LocalBalance: &lnrpc.Amount{}}, nil
+}
+
 func (mlnd *MockLND) IsIdentityPubkey(pubkey string) (isOurPubkey bool) {
 	return pubkey == hex.EncodeToString(mlnd.pubKey.SerializeCompressed())
 }
@@ -141,6 +141,14 @@ func (mock *lndSubscriptionStartMockClient) DecodeBolt11(ctx context.Context, bo
 	panic("not implemented") // TODO: Implement
 }
 
+func (mock *lndSubscriptionStartMockClient) WalletBalance(ctx context.Context, req *lnrpc.WalletBalanceRequest, options ...grpc.CallOption) (*lnrpc.WalletBalanceResponse, error) {
+	panic("not implemented") // TODO: Implement
+}
+
+func (mock *lndSubscriptionStartMockClient) ChannelBalance(ctx context.Context, req *lnrpc.ChannelBalanceRequest, options ...grpc.CallOption) (*lnrpc.ChannelBalanceResponse, error) {
+	panic("not implemented") // TODO: Implement
+}
+
 func (mlnd *lndSubscriptionStartMockClient) TrackPayment(ctx context.Context, hash []byte, options ...grpc.CallOption) (*lnrpc.Payment, error) {
 	return nil, nil
 }
@@ -0,0 +1,80 @@
+package integration_tests
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/getAlby/lndhub.go/tapd"
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// MockAssetChannel : asset channel backed by a MockLND, assets are exchanged for sats at a fixed rate
+type MockAssetChannel struct {
+	lnd *MockLND
+	// sats per asset unit
+	rate uint64
+	// asset units the edge node keeps from the quote as routing fee
+	fee         uint64
+	FailPayment bool
+}
+
+func NewMockAssetChannel(lnd *MockLND, rate uint64) *MockAssetChannel {
+	return &MockAssetChannel{lnd: lnd, rate: rate}
+}
+
+func (mac *MockAssetChannel) AddInvoice(ctx context.Context, req *tapd.AssetInvoiceRequest) (*tapd.AssetInvoiceResponse, error) {
+	sats := req.AssetAmount * mac.rate
+	invoice, err := mac.lnd.AddInvoice(ctx, &lnrpc.Invoice{
+		Memo:   req.Memo,
+		Value:  int64(sats),
+		Expiry: req.Expiry,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tapd.AssetInvoiceResponse{
+		AcceptedBuyQuote: &tapd.AssetQuote{
+			ID:          hex.EncodeToString(invoice.RHash),
+			PeerPubkey:  req.PeerPubkey,
+			AssetAmount: req.AssetAmount,
+			AmtMsat:     sats * 1000,
+		},
+		InvoiceResult: invoice,
+	}, nil
+}
+
+func (mac *MockAssetChannel) QuotePayment(ctx context.Context, req *tapd.AssetPaymentQuoteRequest) (*tapd.AssetQuote, error) {
+	payReq, err := mac.lnd.DecodeBolt11(ctx, req.PaymentRequest)
+	if err != nil {
+		return nil, err
+	}
+	// round up so the quote always covers the invoice
+	assetAmount := (uint64(payReq.NumSatoshis)+mac.rate-1)/mac.rate + mac.fee
+	return &tapd.AssetQuote{
+		ID:          payReq.PaymentHash,
+		PeerPubkey:  req.PeerPubkey,
+		AssetAmount: assetAmount,
+		AmtMsat:     uint64(payReq.NumMsat),
+	}, nil
+}
+
+func (mac *MockAssetChannel) SendPayment(ctx context.Context, req *tapd.AssetPaymentRequest) (*tapd.AssetPaymentResponse, error) {
+	if mac.FailPayment {
+		return nil, errors.New("no route")
+	}
+	payReq, err := mac.lnd.DecodeBolt11(ctx, req.PaymentRequest)
+	if err != nil {
+		return nil, err
+	}
+	return &tapd.AssetPaymentResponse{
+		Payment: &lnrpc.Payment{
+			PaymentHash:     payReq.PaymentHash,
+			PaymentPreimage: hex.EncodeToString([]byte("preimage")),
+			ValueSat:        payReq.NumSatoshis,
+			Status:          lnrpc.Payment_SUCCEEDED,
+		},
+		// the edge node does not charge the fee part of the quote
+		AssetAmount: (uint64(payReq.NumSatoshis) + mac.rate - 1) / mac.rate,
+	}, nil
+}
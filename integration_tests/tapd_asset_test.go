@@ -0,0 +1,1191 @@
+package integration_tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	v2controllers "github.com/getAlby/lndhub.go/controllers_v2"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/lib"
+	"github.com/getAlby/lndhub.go/lib/responses"
+	"github.com/getAlby/lndhub.go/lib/service"
+	"github.com/getAlby/lndhub.go/lib/tokens"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TapdAssetTestSuite struct {
+	TestSuite
+	mtapd            *MockTapd
+	service          *service.LndhubService
+	assetId          string
+	aliceKey         string
+	alice            *models.User
+	aliceToken       string
+	bobKey           string
+	bob              *models.User
+	bobToken         string
+	subscriptionsCtx context.Context
+	subscriptionsFn  context.CancelFunc
+}
+
+func (suite *TapdAssetTestSuite) SetupSuite() {
+	svc, err := LndHubTestServiceInit(newDefaultMockLND())
+	if err != nil {
+		log.Fatalf("Error initializing test service: %v", err)
+	}
+	suite.mtapd = NewMockTapd()
+	svc.TapdClient = suite.mtapd
+	svc.Config.TahubPrivateKey = nostr.GeneratePrivateKey()
+	svc.Config.TahubPublicKey, err = nostr.GetPublicKey(svc.Config.TahubPrivateKey)
+	if err != nil {
+		log.Fatalf("Error creating hub keys: %v", err)
+	}
+	suite.service = svc
+
+	suite.assetId = suite.mtapd.MintFakeAsset("tapd mock test", 1000000)
+	_, err = svc.CreateAsset(context.Background(), "tapd mock test", suite.assetId, 0)
+	if err != nil {
+		log.Fatalf("Error creating test asset: %v", err)
+	}
+	suite.aliceKey, suite.alice, suite.aliceToken = suite.createNostrUser()
+	suite.bobKey, suite.bob, suite.bobToken = suite.createNostrUser()
+
+	e := echo.New()
+	e.HTTPErrorHandler = responses.HTTPErrorHandler
+	e.Validator = &lib.CustomValidator{Validator: validator.New()}
+	e.POST("/v2/event", v2controllers.NewNostrController(svc).HandleNostrEvent)
+	secured := e.Group("", tokens.Middleware(svc.Config.JWTSecret))
+	secured.POST("/v2/create-address", v2controllers.NewAddressController(svc).CreateAddress)
+	transferCtrl := v2controllers.NewTransferController(svc)
+	secured.POST("/v2/transfer", transferCtrl.Transfer)
+	secured.POST("/v2/transfer/internal", transferCtrl.InternalTransfer)
+	paymentRequestCtrl := v2controllers.NewPaymentRequestController(svc)
+	secured.POST("/v2/payment-requests", paymentRequestCtrl.Create)
+	secured.POST("/v2/payment-requests/:id/pay", paymentRequestCtrl.Pay)
+	secured.DELETE("/v2/payment-requests/:id", paymentRequestCtrl.Cancel)
+	checkoutCtrl := v2controllers.NewCheckoutController(svc)
+	secured.POST("/v2/checkouts", checkoutCtrl.Create)
+	secured.GET("/v2/checkouts/:id", checkoutCtrl.Get)
+	lnurlCtrl := v2controllers.NewLnurlController(svc)
+	e.GET("/.well-known/lnurlp/:name", lnurlCtrl.PayParams)
+	e.GET("/lnurlp/:name/callback", lnurlCtrl.Callback)
+	e.GET("/.well-known/nostr.json", v2controllers.NewNip05Controller(svc).NostrJson)
+	usernameCtrl := v2controllers.NewUsernameController(svc)
+	e.POST("/v2/admin/usernames/revoke", usernameCtrl.RevokeUsername)
+	e.GET("/v2/admin/username-holds", usernameCtrl.ListHolds)
+	e.DELETE("/v2/admin/username-holds/:name", usernameCtrl.UnblockUsername)
+	secured.GET("/v2/sends", transferCtrl.Sends)
+	secured.GET("/v2/receives", transferCtrl.Receives)
+	secured.GET("/v2/balances/all", v2controllers.NewBalanceController(svc).Balances)
+	secured.GET("/v2/transactions", v2controllers.NewTransactionsController(svc).Transactions)
+	swapCtrl := v2controllers.NewSwapController(svc)
+	secured.POST("/v2/swaps/quote", swapCtrl.Quote)
+	secured.POST("/v2/swaps/execute", swapCtrl.Execute)
+	orderCtrl := v2controllers.NewOrderController(svc)
+	secured.POST("/v2/orders", orderCtrl.PlaceOrder)
+	secured.DELETE("/v2/orders/:id", orderCtrl.CancelOrder)
+	e.GET("/v2/trades", orderCtrl.Trades)
+	suite.echo = e
+
+	suite.subscriptionsCtx, suite.subscriptionsFn = context.WithCancel(context.Background())
+	go svc.TapdReceiveSubscription(suite.subscriptionsCtx)
+	go svc.TapdSendSubscription(suite.subscriptionsCtx)
+}
+
+func (suite *TapdAssetTestSuite) TearDownSuite() {
+	suite.subscriptionsFn()
+}
+
+func (suite *TapdAssetTestSuite) TearDownTest() {
+	// tables referencing transaction entries go first
+	clearTable(suite.service, "asset_withdrawals")
+	clearTable(suite.service, "send_batch_items")
+	clearTable(suite.service, "send_batches")
+	clearTable(suite.service, "asset_receives")
+	clearTable(suite.service, "swap_quotes")
+	clearTable(suite.service, "trades")
+	clearTable(suite.service, "orders")
+	clearTable(suite.service, "ledger_adjustments")
+	clearTable(suite.service, "payment_requests")
+	clearTable(suite.service, "checkouts")
+	clearTable(suite.service, "internal_transfers")
+	clearTable(suite.service, "asset_burns")
+	clearTable(suite.service, "transaction_entries")
+	clearTable(suite.service, "asset_prices")
+	clearTable(suite.service, "username_holds")
+	suite.mtapd.SendAssetError = nil
+	suite.mtapd.BurnAssetError = nil
+}
+
+func (suite *TapdAssetTestSuite) createNostrUser() (string, *models.User, string) {
+	key := nostr.GeneratePrivateKey()
+	pubkey, err := nostr.GetPublicKey(key)
+	if err != nil {
+		log.Fatalf("Error creating user keys: %v", err)
+	}
+	user, err := suite.service.CreateUser(context.Background(), pubkey)
+	if err != nil {
+		log.Fatalf("Error creating test user: %v", err)
+	}
+	token, err := tokens.GenerateAccessToken(suite.service.Config.JWTSecret, suite.service.Config.JWTAccessTokenExpiry, user)
+	if err != nil {
+		log.Fatalf("Error creating test user token: %v", err)
+	}
+	return key, user, token
+}
+
+// nostrEvent posts a NIP-04 encrypted command signed by key to the event endpoint
+func (suite *TapdAssetTestSuite) nostrEvent(key string, content string) *httptest.ResponseRecorder {
+	pubkey, err := nostr.GetPublicKey(key)
+	assert.NoError(suite.T(), err)
+	sharedSecret, err := nip04.ComputeSharedSecret(suite.service.Config.TahubPublicKey, key)
+	assert.NoError(suite.T(), err)
+	encrypted, err := nip04.Encrypt(content, sharedSecret)
+	assert.NoError(suite.T(), err)
+	event := nostr.Event{
+		PubKey:    pubkey,
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindEncryptedDirectMessage,
+		Tags:      nostr.Tags{{"p", suite.service.Config.TahubPublicKey}},
+		Content:   encrypted,
+	}
+	assert.NoError(suite.T(), event.Sign(key))
+	var buf bytes.Buffer
+	assert.NoError(suite.T(), json.NewEncoder(&buf).Encode(&event))
+	req := httptest.NewRequest(http.MethodPost, "/v2/event", &buf)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	suite.echo.ServeHTTP(rec, req)
+	return rec
+}
+
+func (suite *TapdAssetTestSuite) restRequest(method string, path string, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		assert.NoError(suite.T(), json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	rec := httptest.NewRecorder()
+	suite.echo.ServeHTTP(rec, req)
+	return rec
+}
+
+func (suite *TapdAssetTestSuite) createAddress(token string, amt uint64) string {
+	rec := suite.restRequest(http.MethodPost, "/v2/create-address", token, &v2controllers.AddressRequestBody{
+		AssetId: suite.assetId,
+		Amt:     fmt.Sprint(amt),
+	})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	resp := &v2controllers.AddressResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(resp))
+	return resp.Address
+}
+
+func (suite *TapdAssetTestSuite) balance(user *models.User) int64 {
+	balance, err := suite.service.CurrentUserBalance(context.Background(), suite.assetId, user.ID)
+	assert.NoError(suite.T(), err)
+	return balance
+}
+
+// fund credits amt to the user through a completed receive on the subscription
+func (suite *TapdAssetTestSuite) fund(token string, amt uint64) {
+	addr := suite.createAddress(token, amt)
+	_, err := suite.mtapd.SimulateReceive(addr, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED)
+	assert.NoError(suite.T(), err)
+	//wait a bit for the subscription to credit the receive
+	time.Sleep(200 * time.Millisecond)
+}
+
+func (suite *TapdAssetTestSuite) TestReceiveOverRest() {
+	addr := suite.createAddress(suite.aliceToken, 100)
+	// the same amount returns the existing address
+	assert.Equal(suite.T(), addr, suite.createAddress(suite.aliceToken, 100))
+
+	outpoint, err := suite.mtapd.SimulateReceive(addr, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED)
+	assert.NoError(suite.T(), err)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(suite.T(), int64(100), suite.balance(suite.alice))
+
+	// a replayed completion is not credited twice
+	assert.NoError(suite.T(), suite.mtapd.UpdateReceive(outpoint, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(suite.T(), int64(100), suite.balance(suite.alice))
+
+	rec := suite.restRequest(http.MethodGet, "/v2/receives", suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	receives := &v2controllers.ReceivesResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(receives))
+	assert.Equal(suite.T(), 1, len(receives.Receives))
+	assert.Equal(suite.T(), models.AssetReceiveStateCredited, receives.Receives[0].State)
+	assert.Equal(suite.T(), outpoint, receives.Receives[0].Outpoint)
+}
+
+func (suite *TapdAssetTestSuite) TestLegacyReceiveWithoutAddress() {
+	ctx := context.Background()
+	addr := suite.createAddress(suite.aliceToken, 40)
+	outpoint, err := suite.mtapd.SimulateReceive(addr, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_TRANSACTION_CONFIRMED)
+	assert.NoError(suite.T(), err)
+	// credited before the address was recorded on incoming entries
+	incoming, err := suite.service.AccountFor(ctx, common.AccountTypeIncoming, suite.assetId, suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	current, err := suite.service.AccountFor(ctx, common.AccountTypeCurrent, suite.assetId, suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	legacy := &models.TransactionEntry{
+		UserID:          suite.alice.ID,
+		DebitAccountID:  incoming.ID,
+		CreditAccountID: current.ID,
+		Amount:          40,
+		EntryType:       models.EntryTypeIncoming,
+		Outpoint:        outpoint,
+		TaAssetID:       suite.assetId,
+		BroadcastState:  models.BroadcastStateBroadcast,
+	}
+	_, err = suite.service.DB.NewInsert().Model(legacy).Exec(ctx)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(40), suite.balance(suite.alice))
+
+	// neither the subscription nor the catch-up credit it again
+	assert.NoError(suite.T(), suite.mtapd.UpdateReceive(outpoint, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED))
+	time.Sleep(200 * time.Millisecond)
+	assert.NoError(suite.T(), suite.service.CatchUpTapdReceives(ctx))
+	assert.Equal(suite.T(), int64(40), suite.balance(suite.alice))
+
+	assert.NoError(suite.T(), suite.service.BackfillReceiveAddresses(ctx))
+	entry := models.TransactionEntry{}
+	err = suite.service.DB.NewSelect().Model(&entry).Where("id = ?", legacy.ID).Scan(ctx)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), addr, entry.Address)
+	assert.NoError(suite.T(), suite.service.CatchUpTapdReceives(ctx))
+	assert.Equal(suite.T(), int64(40), suite.balance(suite.alice))
+}
+
+func (suite *TapdAssetTestSuite) TestReceiveOverNostr() {
+	rec := suite.nostrEvent(suite.bobKey, fmt.Sprintf("TAHUB_GET_RCV_ADDR:%s:%d", suite.assetId, 50))
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	addrResp := &responses.NostrAddressResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(addrResp))
+	addr := addrResp.Address[len("address: "):]
+
+	_, err := suite.mtapd.SimulateReceive(addr, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED)
+	assert.NoError(suite.T(), err)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(suite.T(), int64(50), suite.balance(suite.bob))
+
+	rec = suite.nostrEvent(suite.bobKey, "TAHUB_GET_RECEIVES")
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	receives := &struct {
+		Receives []models.AssetReceive `json:"receives"`
+	}{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(receives))
+	assert.Equal(suite.T(), 1, len(receives.Receives))
+}
+
+func (suite *TapdAssetTestSuite) TestDecimalAmounts() {
+	ctx := context.Background()
+	usdId := suite.mtapd.MintFakeAsset("tapd mock usd", 100000000)
+	suite.mtapd.SetAssetMeta(usdId, []byte(`{"ticker":"USD","decimal_display":6}`))
+	asset, err := suite.service.CreateAsset(ctx, "tapd mock usd", usdId, 0)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), uint32(6), asset.DecimalDisplay)
+
+	// amounts are given in the decimal display of the asset
+	rec := suite.nostrEvent(suite.aliceKey, fmt.Sprintf("TAHUB_GET_RCV_ADDR:%s:1.5", usdId))
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	addrResp := &responses.NostrAddressResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(addrResp))
+	addr := addrResp.Address[len("address: "):]
+	decoded, err := suite.mtapd.GetDecodedAddress(ctx, &taprpc.DecodeAddrRequest{Addr: addr})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), uint64(1500000), decoded.Amount)
+
+	_, err = suite.mtapd.SimulateReceive(addr, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED)
+	assert.NoError(suite.T(), err)
+	time.Sleep(200 * time.Millisecond)
+	balance, err := suite.service.CurrentUserBalance(ctx, usdId, suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1500000), balance)
+
+	// balances come back in units and as decimal strings
+	rec = suite.nostrEvent(suite.aliceKey, "TAHUB_GET_BALANCES")
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	balances := &responses.NostrBalanceResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(balances))
+	assert.Equal(suite.T(), int64(1500000), balances.Balances[usdId])
+	assert.Equal(suite.T(), "1.500000", balances.Amounts[usdId])
+	rec = suite.restRequest(http.MethodGet, "/v2/balances/all", suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	restBalances := &v2controllers.BalancesResponse{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(restBalances))
+	assert.Equal(suite.T(), int64(1500000), restBalances.Balances[usdId])
+	assert.Equal(suite.T(), "1.500000", restBalances.Amounts[usdId])
+
+	// amounts finer than the decimal display are rejected
+	rec = suite.nostrEvent(suite.aliceKey, fmt.Sprintf("TAHUB_GET_RCV_ADDR:%s:0.0000001", usdId))
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	rec = suite.restRequest(http.MethodPost, "/v2/create-address", suite.aliceToken, &v2controllers.AddressRequestBody{
+		AssetId: usdId,
+		Amt:     "0.0000001",
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+}
+
+func (suite *TapdAssetTestSuite) TestFiatValuation() {
+	oracle, err := service.NewStaticPriceOracle(map[string]string{suite.assetId: "0.50"})
+	assert.NoError(suite.T(), err)
+	suite.service.PriceOracle = oracle
+	suite.service.Config.PriceCurrency = "USD"
+	defer func() { suite.service.PriceOracle = nil }()
+	assert.NoError(suite.T(), suite.service.RefreshPrices(context.Background()))
+	suite.fund(suite.aliceToken, 40)
+
+	rec := suite.restRequest(http.MethodGet, "/v2/balances/all", suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	balances := &v2controllers.BalancesResponse{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(balances))
+	assert.Equal(suite.T(), "USD", balances.Valuation.Currency)
+	assert.Equal(suite.T(), "20.00", balances.Valuation.Assets[suite.assetId].Value)
+	assert.Equal(suite.T(), "20.00", balances.Valuation.Total)
+
+	// history entries are valued at the price when they were booked
+	_, err = suite.service.DB.NewInsert().Model(&models.AssetPrice{
+		TaAssetID: suite.assetId,
+		Currency:  "USD",
+		Price:     "1",
+		Source:    service.PriceOracleStatic,
+	}).Exec(context.Background())
+	assert.NoError(suite.T(), err)
+	rec = suite.restRequest(http.MethodGet, "/v2/transactions", suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	page := &service.TransactionHistoryPage{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(page))
+	assert.Equal(suite.T(), 1, len(page.Entries))
+	assert.Equal(suite.T(), "20.00", page.Entries[0].FiatValue)
+	assert.Equal(suite.T(), "USD", page.Entries[0].FiatCurrency)
+
+	rec = suite.nostrEvent(suite.aliceKey, "TAHUB_GET_BALANCES")
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	nostrBalances := &struct {
+		Valuation service.BalanceValuation `json:"valuation"`
+	}{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(nostrBalances))
+	assert.Equal(suite.T(), "40.00", nostrBalances.Valuation.Total)
+}
+
+func (suite *TapdAssetTestSuite) TestSwap() {
+	oracle, err := service.NewStaticPriceOracle(map[string]string{suite.assetId: "0.50", common.BTC_TA_ASSET_ID: "0.001"})
+	assert.NoError(suite.T(), err)
+	suite.service.PriceOracle = oracle
+	suite.service.Config.PriceCurrency = "USD"
+	suite.service.Config.SwapSpreadBps = 100
+	suite.service.Config.SwapQuoteTTL = 30
+	defer func() { suite.service.PriceOracle = nil }()
+	assert.NoError(suite.T(), suite.service.RefreshPrices(context.Background()))
+	suite.fund(suite.aliceToken, 40)
+	btcBalance, err := suite.service.CurrentUserBalance(context.Background(), common.BTC_TA_ASSET_ID, suite.alice.ID)
+	assert.NoError(suite.T(), err)
+
+	// 40 units at 0.50 are 20.00, 20000 sats at 0.001 minus the 1% spread
+	rec := suite.nostrEvent(suite.aliceKey, fmt.Sprintf("TAHUB_SWAP_QUOTE:%s:%s:40", suite.assetId, common.BTC_TA_ASSET_ID))
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	quoteResp := &struct {
+		Quote models.SwapQuote `json:"quote"`
+	}{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(quoteResp))
+	quote := quoteResp.Quote
+	assert.Equal(suite.T(), int64(40), quote.FromAmount)
+	assert.Equal(suite.T(), int64(19800), quote.ToAmount)
+
+	// the hub has no btc liquidity yet, nothing is booked
+	rec = suite.restRequest(http.MethodPost, "/v2/swaps/execute", suite.aliceToken, &v2controllers.SwapExecuteRequestBody{QuoteID: quote.ID})
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	assert.Equal(suite.T(), int64(40), suite.balance(suite.alice))
+
+	_, err = suite.service.FundSwapLiquidity(context.Background(), common.BTC_TA_ASSET_ID, 50000)
+	assert.NoError(suite.T(), err)
+	rec = suite.restRequest(http.MethodPost, "/v2/swaps/execute", suite.aliceToken, &v2controllers.SwapExecuteRequestBody{QuoteID: quote.ID})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	executed := &models.SwapQuote{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(executed))
+	assert.Equal(suite.T(), models.SwapQuoteStateExecuted, executed.State)
+	assert.Equal(suite.T(), int64(0), suite.balance(suite.alice))
+	newBtcBalance, err := suite.service.CurrentUserBalance(context.Background(), common.BTC_TA_ASSET_ID, suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), btcBalance+19800, newBtcBalance)
+	liquidity, err := suite.service.SwapLiquidity(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(40), liquidity[suite.assetId])
+	assert.Equal(suite.T(), int64(30200), liquidity[common.BTC_TA_ASSET_ID])
+
+	// a quote executes once, and only for the user it was made for
+	rec = suite.restRequest(http.MethodPost, "/v2/swaps/execute", suite.aliceToken, &v2controllers.SwapExecuteRequestBody{QuoteID: quote.ID})
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	rec = suite.nostrEvent(suite.bobKey, fmt.Sprintf("TAHUB_SWAP_EXECUTE:%d", quote.ID))
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+
+	// the user cannot swap more than their balance
+	rec = suite.restRequest(http.MethodPost, "/v2/swaps/quote", suite.bobToken, &v2controllers.SwapQuoteRequestBody{
+		FromAssetID: suite.assetId,
+		ToAssetID:   common.BTC_TA_ASSET_ID,
+		Amount:      "10",
+	})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(&quote))
+	rec = suite.restRequest(http.MethodPost, "/v2/swaps/execute", suite.bobToken, &v2controllers.SwapExecuteRequestBody{QuoteID: quote.ID})
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	errResp := &responses.ErrorResponse{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(errResp))
+	assert.Equal(suite.T(), service.InsufficientBalanceError.Error(), errResp.Message)
+}
+
+func (suite *TapdAssetTestSuite) TestOrderBook() {
+	suite.fund(suite.aliceToken, 40)
+	_, err := suite.service.PostLedgerAdjustment(context.Background(), service.LedgerAdjustmentRequest{
+		UserID:    suite.bob.ID,
+		TaAssetID: common.BTC_TA_ASSET_ID,
+		Amount:    10000,
+		Reason:    "order book test",
+		TicketRef: "test",
+		Operator:  "test",
+	})
+	assert.NoError(suite.T(), err)
+	btcBalance := func(user *models.User) int64 {
+		balance, err := suite.service.CurrentUserBalance(context.Background(), common.BTC_TA_ASSET_ID, user.ID)
+		assert.NoError(suite.T(), err)
+		return balance
+	}
+	aliceBtc, bobBtc := btcBalance(suite.alice), btcBalance(suite.bob)
+
+	// alice offers 30 units at 100 sats, they are locked in her reserved account
+	rec := suite.restRequest(http.MethodPost, "/v2/orders", suite.aliceToken, &v2controllers.PlaceOrderRequestBody{
+		Side:         models.OrderSideSell,
+		BaseAssetID:  suite.assetId,
+		QuoteAssetID: common.BTC_TA_ASSET_ID,
+		Amount:       "30",
+		Price:        "100",
+	})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	sell := &service.OrderPlacement{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(sell))
+	assert.Equal(suite.T(), 0, len(sell.Trades))
+	assert.Equal(suite.T(), int64(10), suite.balance(suite.alice))
+
+	// bob bids up to 120 sats and is filled at alice's price, the rest of his lock is released
+	rec = suite.nostrEvent(suite.bobKey, fmt.Sprintf("TAHUB_PLACE_ORDER:buy:%s:%s:20:120", suite.assetId, common.BTC_TA_ASSET_ID))
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	buy := &service.OrderPlacement{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(buy))
+	assert.Equal(suite.T(), models.OrderStateFilled, buy.Order.State)
+	assert.Equal(suite.T(), 1, len(buy.Trades))
+	assert.Equal(suite.T(), int64(2000), buy.Trades[0].QuoteAmount)
+	assert.Equal(suite.T(), int64(20), suite.balance(suite.bob))
+	assert.Equal(suite.T(), bobBtc-2000, btcBalance(suite.bob))
+	assert.Equal(suite.T(), aliceBtc+2000, btcBalance(suite.alice))
+
+	book, err := suite.service.OrderBookFor(context.Background(), suite.assetId, common.BTC_TA_ASSET_ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, len(book.Bids))
+	assert.Equal(suite.T(), 1, len(book.Asks))
+	assert.Equal(suite.T(), int64(10), book.Asks[0].Amount)
+
+	// the trades feed is public
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v2/trades?base_asset_id=%s&quote_asset_id=%s", suite.assetId, common.BTC_TA_ASSET_ID), nil)
+	rec = httptest.NewRecorder()
+	suite.echo.ServeHTTP(rec, req)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	trades := &v2controllers.TradesResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(trades))
+	assert.Equal(suite.T(), 1, len(trades.Trades))
+	assert.Equal(suite.T(), models.OrderSideBuy, trades.Trades[0].TakerSide)
+	assert.Equal(suite.T(), int64(20), trades.Trades[0].Amount)
+
+	// cancelling releases the unfilled rest of alice's order
+	path := fmt.Sprintf("/v2/orders/%d", sell.Order.ID)
+	rec = suite.restRequest(http.MethodDelete, path, suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Equal(suite.T(), int64(20), suite.balance(suite.alice))
+	rec = suite.restRequest(http.MethodDelete, path, suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+
+	// orders cannot lock more than the balance
+	rec = suite.restRequest(http.MethodPost, "/v2/orders", suite.aliceToken, &v2controllers.PlaceOrderRequestBody{
+		Side:         models.OrderSideSell,
+		BaseAssetID:  suite.assetId,
+		QuoteAssetID: common.BTC_TA_ASSET_ID,
+		Amount:       "21",
+		Price:        "100",
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+}
+
+func (suite *TapdAssetTestSuite) TestReceiveBackoff() {
+	addr := suite.createAddress(suite.aliceToken, 30)
+	outpoint, err := suite.mtapd.SimulateReceive(addr, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_TRANSACTION_CONFIRMED)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.service.TrackTapdReceives(context.Background()))
+
+	receives, err := suite.service.ReceivesFor(context.Background(), suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(receives))
+	assert.Equal(suite.T(), models.AssetReceiveStateProofPending, receives[0].State)
+
+	suite.mtapd.BackoffReceive(2)
+	time.Sleep(200 * time.Millisecond)
+	receives, err = suite.service.ReceivesFor(context.Background(), suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.AssetReceiveStateBackoffRetrying, receives[0].State)
+	assert.Equal(suite.T(), int64(2), receives[0].BackoffTries)
+	assert.Equal(suite.T(), int64(0), suite.balance(suite.alice))
+
+	assert.NoError(suite.T(), suite.mtapd.UpdateReceive(outpoint, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(suite.T(), int64(30), suite.balance(suite.alice))
+}
+
+func (suite *TapdAssetTestSuite) TestInternalTransferOverRest() {
+	suite.fund(suite.aliceToken, 100)
+	bobAddr := suite.createAddress(suite.bobToken, 40)
+	tapdBalance := suite.mtapd.Balance(suite.assetId)
+
+	rec := suite.restRequest(http.MethodPost, "/v2/transfer", suite.aliceToken, &v2controllers.TransferRequestBody{Address: bobAddr})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	resp := &v2controllers.TransferResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(resp))
+	assert.True(suite.T(), resp.Status)
+
+	assert.Equal(suite.T(), int64(60), suite.balance(suite.alice))
+	assert.Equal(suite.T(), int64(40), suite.balance(suite.bob))
+	// internal transfers never touch tapd
+	assert.Equal(suite.T(), tapdBalance, suite.mtapd.Balance(suite.assetId))
+}
+
+func (suite *TapdAssetTestSuite) TestSendToPubkey() {
+	suite.fund(suite.aliceToken, 100)
+	tapdBalance := suite.mtapd.Balance(suite.assetId)
+	bobNpub, err := nip19.EncodePublicKey(suite.bob.Pubkey)
+	assert.NoError(suite.T(), err)
+
+	rec := suite.restRequest(http.MethodPost, "/v2/transfer/internal", suite.aliceToken, &v2controllers.InternalTransferRequestBody{
+		Recipient: bobNpub,
+		AssetID:   suite.assetId,
+		Amount:    "30",
+		Memo:      "lunch",
+	})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	transfer := &models.InternalTransfer{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(transfer))
+	assert.Equal(suite.T(), int64(30), transfer.Amount)
+	assert.Equal(suite.T(), int64(70), suite.balance(suite.alice))
+	assert.Equal(suite.T(), int64(30), suite.balance(suite.bob))
+	assert.Equal(suite.T(), tapdBalance, suite.mtapd.Balance(suite.assetId))
+
+	// the memo shows up in the history of both users, the colon is part of it
+	rec = suite.nostrEvent(suite.bobKey, fmt.Sprintf("TAHUB_SEND_TO_PUBKEY:%s:%s:10:change: thanks", suite.alice.Pubkey, suite.assetId))
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Equal(suite.T(), int64(80), suite.balance(suite.alice))
+	history, err := suite.service.TransactionHistoryFor(context.Background(), suite.alice.ID, service.TransactionHistoryFilter{AssetID: suite.assetId})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "change: thanks", history.Entries[0].Memo)
+	assert.Equal(suite.T(), suite.bob.Pubkey, history.Entries[0].CounterpartyPubkey)
+	assert.Equal(suite.T(), "lunch", history.Entries[1].Memo)
+
+	// more than the balance, to yourself and to unknown pubkeys fail
+	for _, body := range []v2controllers.InternalTransferRequestBody{
+		{Recipient: bobNpub, AssetID: suite.assetId, Amount: "81"},
+		{Recipient: suite.alice.Pubkey, AssetID: suite.assetId, Amount: "1"},
+		{Recipient: strings.Repeat("ab", 32), AssetID: suite.assetId, Amount: "1"},
+	} {
+		rec = suite.restRequest(http.MethodPost, "/v2/transfer/internal", suite.aliceToken, &body)
+		assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	}
+	assert.Equal(suite.T(), int64(80), suite.balance(suite.alice))
+}
+
+func (suite *TapdAssetTestSuite) TestSendToUnregisteredPubkey() {
+	suite.service.Config.ClaimableTransferExpiry = 3600
+	defer func() { suite.service.Config.ClaimableTransferExpiry = 0 }()
+	suite.fund(suite.aliceToken, 100)
+	carolKey := nostr.GeneratePrivateKey()
+	carolPubkey, err := nostr.GetPublicKey(carolKey)
+	assert.NoError(suite.T(), err)
+
+	// the amount is held in escrow until carol registers
+	transfer, err := suite.service.TransferToPubkey(context.Background(), suite.alice.ID, carolPubkey, suite.assetId, "25", "welcome")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.InternalTransferStatePending, transfer.State)
+	assert.Equal(suite.T(), int64(75), suite.balance(suite.alice))
+	sheets, err := suite.service.GetBalanceSheet(context.Background())
+	assert.NoError(suite.T(), err)
+	for _, sheet := range sheets {
+		if sheet.TaAssetID == suite.assetId {
+			assert.Equal(suite.T(), int64(25), sheet.Escrow)
+		}
+	}
+
+	rec := suite.nostrEvent(carolKey, "TAHUB_CREATE_USER")
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	carol, err := suite.service.FindUserByPubkey(context.Background(), carolPubkey)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(25), suite.balance(carol))
+	claimed := &models.InternalTransfer{}
+	assert.NoError(suite.T(), suite.service.DB.NewSelect().Model(claimed).Where("id = ?", transfer.ID).Scan(context.Background()))
+	assert.Equal(suite.T(), models.InternalTransferStateClaimed, claimed.State)
+	assert.Equal(suite.T(), carol.ID, claimed.RecipientID)
+	history, err := suite.service.TransactionHistoryFor(context.Background(), carol.ID, service.TransactionHistoryFilter{AssetID: suite.assetId})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "welcome", history.Entries[0].Memo)
+	assert.Equal(suite.T(), suite.alice.Pubkey, history.Entries[0].CounterpartyPubkey)
+
+	// unclaimed transfers go back to the sender once they expire
+	davePubkey, err := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	assert.NoError(suite.T(), err)
+	transfer, err = suite.service.TransferToPubkey(context.Background(), suite.alice.ID, davePubkey, suite.assetId, "10", "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(65), suite.balance(suite.alice))
+	assert.NoError(suite.T(), suite.service.RevertExpiredTransfers(context.Background()))
+	assert.Equal(suite.T(), int64(65), suite.balance(suite.alice))
+	_, err = suite.service.DB.NewUpdate().Model(&models.InternalTransfer{}).
+		Set("expires_at = ?", time.Now().Add(-time.Minute)).
+		Where("id = ?", transfer.ID).
+		Exec(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.service.RevertExpiredTransfers(context.Background()))
+	assert.Equal(suite.T(), int64(75), suite.balance(suite.alice))
+	reverted := &models.InternalTransfer{}
+	assert.NoError(suite.T(), suite.service.DB.NewSelect().Model(reverted).Where("id = ?", transfer.ID).Scan(context.Background()))
+	assert.Equal(suite.T(), models.InternalTransferStateReverted, reverted.State)
+}
+
+func (suite *TapdAssetTestSuite) TestPaymentRequest() {
+	suite.service.Config.PaymentRequestExpiry = 3600
+	suite.fund(suite.bobToken, 50)
+	aliceNpub, err := nip19.EncodePublicKey(suite.alice.Pubkey)
+	assert.NoError(suite.T(), err)
+
+	// bob asks alice for 20 units over nostr
+	rec := suite.nostrEvent(suite.bobKey, fmt.Sprintf("TAHUB_REQUEST_PAYMENT:%s:%s:20:dinner", aliceNpub, suite.assetId))
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	created := &responses.NostrPaymentRequestResponseBody{PaymentRequest: &models.PaymentRequest{}}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(created))
+	request := created.PaymentRequest.(*models.PaymentRequest)
+	assert.Equal(suite.T(), models.PaymentRequestStateOpen, request.State)
+	assert.Equal(suite.T(), suite.alice.Pubkey, request.PayerPubkey)
+
+	// only alice can pay it, and alice does not have the funds yet
+	path := fmt.Sprintf("/v2/payment-requests/%d/pay", request.ID)
+	rec = suite.restRequest(http.MethodPost, path, suite.bobToken, nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	rec = suite.restRequest(http.MethodPost, path, suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+
+	suite.fund(suite.aliceToken, 30)
+	rec = suite.restRequest(http.MethodPost, path, suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	paid := &models.PaymentRequest{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(paid))
+	assert.Equal(suite.T(), models.PaymentRequestStatePaid, paid.State)
+	assert.NotZero(suite.T(), paid.InternalTransferID)
+	assert.Equal(suite.T(), int64(10), suite.balance(suite.alice))
+	assert.Equal(suite.T(), int64(70), suite.balance(suite.bob))
+
+	// a paid request is not paid twice and can no longer be cancelled
+	rec = suite.restRequest(http.MethodPost, path, suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	rec = suite.restRequest(http.MethodDelete, fmt.Sprintf("/v2/payment-requests/%d", request.ID), suite.bobToken, nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	assert.Equal(suite.T(), int64(10), suite.balance(suite.alice))
+
+	// the payer can decline an open request
+	rec = suite.restRequest(http.MethodPost, "/v2/payment-requests", suite.bobToken, &v2controllers.PaymentRequestRequestBody{
+		Payer:   suite.alice.Pubkey,
+		AssetID: suite.assetId,
+		Amount:  "5",
+	})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(request))
+	rec = suite.restRequest(http.MethodDelete, fmt.Sprintf("/v2/payment-requests/%d", request.ID), suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	rec = suite.restRequest(http.MethodPost, fmt.Sprintf("/v2/payment-requests/%d/pay", request.ID), suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+
+	// expired requests cannot be paid
+	expired, err := suite.service.RequestPayment(context.Background(), suite.bob, suite.alice.Pubkey, suite.assetId, "1", "")
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.DB.NewUpdate().Model(&models.PaymentRequest{}).
+		Set("expires_at = ?", time.Now().Add(-time.Minute)).
+		Where("id = ?", expired.ID).
+		Exec(context.Background())
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.PayPaymentRequest(context.Background(), suite.alice, expired.ID)
+	assert.ErrorIs(suite.T(), err, service.PaymentRequestExpiredError)
+	requests, err := suite.service.PaymentRequestsFor(context.Background(), suite.alice, models.PaymentRequestStateExpired)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(requests))
+	assert.Equal(suite.T(), int64(10), suite.balance(suite.alice))
+}
+
+func (suite *TapdAssetTestSuite) TestExternalSendOverNostr() {
+	suite.fund(suite.aliceToken, 100)
+	externalAddr, err := suite.mtapd.NewExternalAddr(suite.assetId, 25)
+	assert.NoError(suite.T(), err)
+	tapdBalance := suite.mtapd.Balance(suite.assetId)
+
+	rec := suite.nostrEvent(suite.aliceKey, "TAHUB_SEND_ASSET:"+externalAddr)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Equal(suite.T(), int64(75), suite.balance(suite.alice))
+	assert.Equal(suite.T(), tapdBalance-25, suite.mtapd.Balance(suite.assetId))
+
+	suite.mtapd.BackoffSend(1)
+	suite.mtapd.CompleteSends()
+	time.Sleep(200 * time.Millisecond)
+	sends, err := suite.service.SendBatchItemsFor(context.Background(), suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(sends))
+	assert.Equal(suite.T(), models.SendBatchItemStateSent, sends[0].State)
+	entry := models.TransactionEntry{}
+	err = suite.service.DB.NewSelect().Model(&entry).Where("id = ?", sends[0].TransactionEntryID).Scan(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), mockTapdSendStateComplete, entry.BroadcastState)
+}
+
+func (suite *TapdAssetTestSuite) TestChainFeeShareWithoutBtc() {
+	suite.fund(suite.aliceToken, 100)
+	externalAddr, err := suite.mtapd.NewExternalAddr(suite.assetId, 25)
+	assert.NoError(suite.T(), err)
+	suite.mtapd.ChainFees = 500
+	defer func() { suite.mtapd.ChainFees = 0 }()
+
+	rec := suite.nostrEvent(suite.aliceKey, "TAHUB_SEND_ASSET:"+externalAddr)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	// alice has no btc, the hub covers the share and the send shows no chain fee
+	sends, err := suite.service.SendBatchItemsFor(context.Background(), suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(sends))
+	assert.Equal(suite.T(), models.SendBatchItemStateSent, sends[0].State)
+	assert.Equal(suite.T(), int64(0), sends[0].ChainFee)
+	assert.Zero(suite.T(), sends[0].FeeEntryID)
+}
+
+func (suite *TapdAssetTestSuite) TestExternalSendFailureOverRest() {
+	suite.fund(suite.bobToken, 100)
+	externalAddr, err := suite.mtapd.NewExternalAddr(suite.assetId, 25)
+	assert.NoError(suite.T(), err)
+	suite.mtapd.SendAssetError = errors.New("insufficient anchor funds")
+
+	rec := suite.restRequest(http.MethodPost, "/v2/transfer", suite.bobToken, &v2controllers.TransferRequestBody{Address: externalAddr})
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	// the failed send is refunded
+	assert.Equal(suite.T(), int64(100), suite.balance(suite.bob))
+
+	rec = suite.restRequest(http.MethodGet, "/v2/sends", suite.bobToken, nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	sends := &v2controllers.SendsResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(sends))
+	assert.Equal(suite.T(), 1, len(sends.Sends))
+	assert.Equal(suite.T(), models.SendBatchItemStateFailed, sends.Sends[0].State)
+}
+
+func (suite *TapdAssetTestSuite) TestReconcileSendingBatch() {
+	ctx := context.Background()
+	suite.fund(suite.aliceToken, 100)
+	tapdBalance := suite.mtapd.Balance(suite.assetId)
+	for _, amt := range []uint64{25, 30} {
+		externalAddr, err := suite.mtapd.NewExternalAddr(suite.assetId, amt)
+		assert.NoError(suite.T(), err)
+		rec := suite.nostrEvent(suite.aliceKey, "TAHUB_SEND_ASSET:"+externalAddr)
+		assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	}
+	assert.Equal(suite.T(), int64(45), suite.balance(suite.alice))
+	batches := []models.SendBatch{}
+	err := suite.service.DB.NewSelect().Model(&batches).Relation("Items").OrderExpr("id ASC").Scan(ctx)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(batches))
+	anchorTxid := batches[0].AnchorTxid
+
+	// both sends were interrupted before their outcome was recorded, tapd only has the first one
+	for _, batch := range batches {
+		_, err = suite.service.DB.NewUpdate().Model((*models.SendBatch)(nil)).
+			Set("state = ?, anchor_txid = NULL, created_at = ?", models.SendBatchStateSending, time.Now().Add(-2*time.Hour)).
+			Where("id = ?", batch.ID).Exec(ctx)
+		assert.NoError(suite.T(), err)
+		_, err = suite.service.DB.NewUpdate().Model((*models.SendBatchItem)(nil)).
+			Set("state = ?", models.SendBatchItemStateSending).Where("batch_id = ?", batch.ID).Exec(ctx)
+		assert.NoError(suite.T(), err)
+	}
+	_, err = suite.service.DB.NewUpdate().Model((*models.SendBatchItem)(nil)).
+		Set("script_key = ?", "00").Where("batch_id = ?", batches[1].ID).Exec(ctx)
+	assert.NoError(suite.T(), err)
+
+	assert.NoError(suite.T(), suite.service.ReconcileSendingBatches(ctx))
+	reconciled := []models.SendBatch{}
+	err = suite.service.DB.NewSelect().Model(&reconciled).Relation("Items").OrderExpr("id ASC").Scan(ctx)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.SendBatchStateSent, reconciled[0].State)
+	assert.Equal(suite.T(), anchorTxid, reconciled[0].AnchorTxid)
+	assert.Equal(suite.T(), models.SendBatchItemStateSent, reconciled[0].Items[0].State)
+	assert.Equal(suite.T(), models.SendBatchStateFailed, reconciled[1].State)
+	assert.Equal(suite.T(), models.SendBatchItemStateFailed, reconciled[1].Items[0].State)
+	// nothing is sent again, the send without a tapd transfer is refunded
+	assert.Equal(suite.T(), tapdBalance-55, suite.mtapd.Balance(suite.assetId))
+	assert.Equal(suite.T(), int64(75), suite.balance(suite.alice))
+
+	// reconciling again changes nothing
+	assert.NoError(suite.T(), suite.service.ReconcileSendingBatches(ctx))
+	assert.Equal(suite.T(), int64(75), suite.balance(suite.alice))
+}
+
+func (suite *TapdAssetTestSuite) TestSendLeftoverQueuedSends() {
+	ctx := context.Background()
+	suite.fund(suite.aliceToken, 100)
+	for _, amt := range []uint64{25, 30} {
+		externalAddr, err := suite.mtapd.NewExternalAddr(suite.assetId, amt)
+		assert.NoError(suite.T(), err)
+		rec := suite.nostrEvent(suite.aliceKey, "TAHUB_SEND_ASSET:"+externalAddr)
+		assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	}
+	sends, err := suite.service.SendBatchItemsFor(ctx, suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(sends))
+	tapdBalance := suite.mtapd.Balance(suite.assetId)
+
+	// both sends were left queued by their sender, only the older one is past the grace delay
+	for i, send := range sends {
+		_, err = suite.service.DB.NewUpdate().Model((*models.SendBatchItem)(nil)).
+			Set("state = ?, batch_id = NULL, created_at = ?", models.SendBatchItemStateQueued, time.Now().Add(-time.Duration(i*2)*time.Minute)).
+			Where("id = ?", send.ID).Exec(ctx)
+		assert.NoError(suite.T(), err)
+	}
+	assert.NoError(suite.T(), suite.service.SendQueuedBatches(ctx, time.Now().Add(-time.Minute)))
+
+	sends, err = suite.service.SendBatchItemsFor(ctx, suite.alice.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.SendBatchItemStateQueued, sends[0].State)
+	assert.Equal(suite.T(), models.SendBatchItemStateSent, sends[1].State)
+	assert.Equal(suite.T(), tapdBalance-25, suite.mtapd.Balance(suite.assetId))
+}
+
+func (suite *TapdAssetTestSuite) TestBurnAsset() {
+	ctx := context.Background()
+	suite.fund(suite.aliceToken, 100)
+	tapdBalance := suite.mtapd.Balance(suite.assetId)
+
+	burn, err := suite.service.BurnAsset(ctx, service.BurnAssetRequest{UserID: suite.alice.ID, TaAssetID: suite.assetId, Amount: 10})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.AssetBurnStateBurned, burn.State)
+	assert.NotEmpty(suite.T(), burn.Outpoint)
+	assert.Equal(suite.T(), int64(90), suite.balance(suite.alice))
+	assert.Equal(suite.T(), tapdBalance-10, suite.mtapd.Balance(suite.assetId))
+
+	// a failed burn is refunded
+	suite.mtapd.BurnAssetError = errors.New("insufficient anchor funds")
+	_, err = suite.service.BurnAsset(ctx, service.BurnAssetRequest{UserID: suite.alice.ID, TaAssetID: suite.assetId, Amount: 20})
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), int64(90), suite.balance(suite.alice))
+
+	// the outcome of the first burn was lost, it is booked from tapd's transfer and not burned again
+	_, err = suite.service.DB.NewUpdate().Model((*models.AssetBurn)(nil)).
+		Set("state = ?, outpoint = NULL, anchor_txid = NULL, burn_proof = NULL, created_at = ?", models.AssetBurnStatePending, time.Now().Add(-2*time.Hour)).
+		Where("id = ?", burn.ID).Exec(ctx)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.service.ReconcilePendingBurns(ctx))
+	reconciled := models.AssetBurn{}
+	err = suite.service.DB.NewSelect().Model(&reconciled).Where("id = ?", burn.ID).Scan(ctx)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.AssetBurnStateBurned, reconciled.State)
+	assert.Equal(suite.T(), burn.Outpoint, reconciled.Outpoint)
+	assert.Equal(suite.T(), int64(90), suite.balance(suite.alice))
+	assert.Equal(suite.T(), tapdBalance-10, suite.mtapd.Balance(suite.assetId))
+
+	supply, err := suite.service.AssetSupplyFor(ctx, suite.assetId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(10), supply.Burned)
+}
+
+func (suite *TapdAssetTestSuite) TestSendInsufficientBalance() {
+	externalAddr, err := suite.mtapd.NewExternalAddr(suite.assetId, 25)
+	assert.NoError(suite.T(), err)
+	rec := suite.nostrEvent(suite.bobKey, "TAHUB_SEND_ASSET:"+externalAddr)
+	errResp := &responses.NostrErrorResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(errResp))
+	assert.Equal(suite.T(), responses.GeneralServerError.Message, errResp.Message)
+}
+
+func (suite *TapdAssetTestSuite) TestCheckout() {
+	webhooks := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		webhooks <- r
+		bodies <- body
+	}))
+	defer server.Close()
+	suite.service.Config.CheckoutExpiry = 900
+	suite.service.Config.CheckoutWebhookSecret = "checkout secret"
+	defer func() { suite.service.Config.CheckoutWebhookSecret = "" }()
+
+	// webhooks into the hub's network are rejected unless their host is allowed
+	rec := suite.restRequest(http.MethodPost, "/v2/checkouts", suite.aliceToken, &v2controllers.CheckoutRequestBody{
+		AssetID:    suite.assetId,
+		Amount:     "25",
+		WebhookUrl: server.URL,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	suite.service.Config.CheckoutWebhookAllowedHosts = []string{"127.0.0.1"}
+	defer func() { suite.service.Config.CheckoutWebhookAllowedHosts = nil }()
+
+	rec = suite.restRequest(http.MethodPost, "/v2/checkouts", suite.aliceToken, &v2controllers.CheckoutRequestBody{
+		AssetID:    suite.assetId,
+		Amount:     "25",
+		OrderRef:   "order-1",
+		WebhookUrl: server.URL,
+	})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	created := &v2controllers.CheckoutResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(created))
+	assert.Len(suite.T(), created.WebhookSecret, 64)
+	checkout := &created.Checkout
+	assert.Equal(suite.T(), models.CheckoutStateOpen, checkout.State)
+	assert.Equal(suite.T(), int64(25), checkout.Amount)
+	assert.NotEmpty(suite.T(), checkout.Address)
+	// the checkout address is not handed out for other receives of the same amount
+	assert.NotEqual(suite.T(), checkout.Address, suite.createAddress(suite.aliceToken, 25))
+
+	_, err := suite.mtapd.SimulateReceive(checkout.Address, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED)
+	assert.NoError(suite.T(), err)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(suite.T(), int64(25), suite.balance(suite.alice))
+
+	rec = suite.restRequest(http.MethodGet, fmt.Sprintf("/v2/checkouts/%d", checkout.ID), suite.aliceToken, nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	paid := &models.Checkout{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(paid))
+	assert.Equal(suite.T(), models.CheckoutStatePaid, paid.State)
+	assert.False(suite.T(), paid.WebhookDeliveredAt.IsZero())
+
+	// the webhook is signed with the checkout's own secret, which is not shown again
+	webhook := <-webhooks
+	body := <-bodies
+	timestamp, err := strconv.ParseInt(webhook.Header.Get(service.CheckoutTimestampHeader), 10, 64)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), service.CheckoutWebhookSignature(created.WebhookSecret, timestamp, body), webhook.Header.Get(service.CheckoutSignatureHeader))
+	assert.NotContains(suite.T(), string(body), created.WebhookSecret)
+	payload := &service.CheckoutWebhookPayload{}
+	assert.NoError(suite.T(), json.Unmarshal(body, payload))
+	assert.Equal(suite.T(), service.CheckoutPaidEvent, payload.Event)
+	assert.Equal(suite.T(), checkout.ID, payload.Checkout.ID)
+	assert.Equal(suite.T(), "order-1", payload.Checkout.OrderRef)
+
+	// other merchants cannot see the checkout
+	rec = suite.restRequest(http.MethodGet, fmt.Sprintf("/v2/checkouts/%d", checkout.ID), suite.bobToken, nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+
+	// fiat priced checkouts need a price oracle
+	rec = suite.restRequest(http.MethodPost, "/v2/checkouts", suite.bobToken, &v2controllers.CheckoutRequestBody{AssetID: suite.assetId, FiatAmount: "5.00"})
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	oracle, err := service.NewStaticPriceOracle(map[string]string{suite.assetId: "0.30"})
+	assert.NoError(suite.T(), err)
+	suite.service.PriceOracle = oracle
+	suite.service.Config.PriceCurrency = "USD"
+	defer func() { suite.service.PriceOracle = nil }()
+	assert.NoError(suite.T(), suite.service.RefreshPrices(context.Background()))
+
+	// checkouts past their expiry are expired, a late payment still marks them paid
+	suite.service.Config.CheckoutExpiry = 0
+	rec = suite.restRequest(http.MethodPost, "/v2/checkouts", suite.bobToken, &v2controllers.CheckoutRequestBody{AssetID: suite.assetId, FiatAmount: "5.00"})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	priced := &models.Checkout{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(priced))
+	assert.Equal(suite.T(), int64(17), priced.Amount)
+	assert.Equal(suite.T(), "0.30", priced.FiatPrice)
+	assert.Equal(suite.T(), "USD", priced.FiatCurrency)
+	rec = suite.restRequest(http.MethodGet, fmt.Sprintf("/v2/checkouts/%d", priced.ID), suite.bobToken, nil)
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(priced))
+	assert.Equal(suite.T(), models.CheckoutStateExpired, priced.State)
+	_, err = suite.mtapd.SimulateReceive(priced.Address, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED)
+	assert.NoError(suite.T(), err)
+	time.Sleep(200 * time.Millisecond)
+	rec = suite.restRequest(http.MethodGet, fmt.Sprintf("/v2/checkouts/%d", priced.ID), suite.bobToken, nil)
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(priced))
+	assert.Equal(suite.T(), models.CheckoutStatePaid, priced.State)
+	assert.Equal(suite.T(), int64(17), suite.balance(suite.bob))
+}
+
+func (suite *TapdAssetTestSuite) TestLightningAddress() {
+	suite.service.Config.PublicURL = "https://hub.example.com"
+	suite.service.Config.LnurlMinSendable = 1
+	suite.service.Config.MaxReceiveAmount = 0
+	defer func() {
+		suite.service.Config.PublicURL = ""
+		_, _ = suite.service.SetUsername(context.Background(), suite.alice, "")
+		_, _ = suite.service.SetUsername(context.Background(), suite.bob, "")
+	}()
+
+	rec := suite.nostrEvent(suite.aliceKey, "TAHUB_SET_USERNAME:Alice")
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	username := &responses.NostrUsernameResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(username))
+	assert.Equal(suite.T(), "alice", username.Username)
+	assert.Equal(suite.T(), "alice@hub.example.com", username.LightningAddress)
+	// names are unique
+	rec = suite.nostrEvent(suite.bobKey, "TAHUB_SET_USERNAME:alice")
+	errResp := &responses.NostrErrorResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(errResp))
+	assert.Equal(suite.T(), service.UsernameTakenError.Error(), errResp.Message)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/lnurlp/alice", nil)
+	rec = httptest.NewRecorder()
+	suite.echo.ServeHTTP(rec, req)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	params := &service.LnurlPayParams{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(params))
+	assert.Equal(suite.T(), "https://hub.example.com/lnurlp/alice/callback", params.Callback)
+	assert.Equal(suite.T(), int64(1000), params.MinSendable)
+	assert.Equal(suite.T(), int64(service.DefaultLnurlMaxSendable*1000), params.MaxSendable)
+	assert.Equal(suite.T(), service.LnurlMetadata("alice@hub.example.com"), params.Metadata)
+	assert.Equal(suite.T(), service.LnurlPayTag, params.Tag)
+
+	req = httptest.NewRequest(http.MethodGet, "/lnurlp/alice/callback?amount=21000&comment=thanks", nil)
+	rec = httptest.NewRecorder()
+	suite.echo.ServeHTTP(rec, req)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	callback := &v2controllers.LnurlCallbackResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(callback))
+	assert.NotEmpty(suite.T(), callback.PaymentRequest)
+	invoice := models.Invoice{}
+	err := suite.service.DB.NewSelect().Model(&invoice).Where("payment_request = ?", callback.PaymentRequest).Scan(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.alice.ID, invoice.UserID)
+	assert.Equal(suite.T(), int64(21), invoice.Amount)
+	assert.Equal(suite.T(), "thanks", invoice.Memo)
+	descriptionHash := sha256.Sum256([]byte(params.Metadata))
+	assert.Equal(suite.T(), hex.EncodeToString(descriptionHash[:]), invoice.DescriptionHash)
+
+	// amounts must be whole sats within the limits, unknown names are not found
+	for _, path := range []string{"/lnurlp/alice/callback?amount=21500", "/lnurlp/alice/callback?amount=0", "/lnurlp/alice/callback"} {
+		rec = httptest.NewRecorder()
+		suite.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(suite.T(), http.StatusBadRequest, rec.Code, path)
+	}
+	suite.service.Config.MaxReceiveAmount = 10
+	rec = httptest.NewRecorder()
+	suite.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lnurlp/alice/callback?amount=21000", nil))
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	suite.service.Config.MaxReceiveAmount = 0
+	rec = httptest.NewRecorder()
+	suite.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/lnurlp/carol", nil))
+	assert.Equal(suite.T(), http.StatusNotFound, rec.Code)
+}
+
+func (suite *TapdAssetTestSuite) TestNip05() {
+	suite.service.Config.RelayURI = []string{"wss://relay.example.com"}
+	suite.service.Config.ReservedUsernames = []string{"Treasury"}
+	suite.service.Config.UsernameChangeCooldown = 0
+	suite.service.Config.UsernameQuarantine = 3600
+	defer func() {
+		suite.service.Config.ReservedUsernames = nil
+		suite.service.Config.UsernameQuarantine = 0
+		suite.service.Config.UsernameChangeCooldown = 0
+		_, _ = suite.service.SetUsername(context.Background(), suite.alice, "")
+		_, _ = suite.service.SetUsername(context.Background(), suite.bob, "")
+	}()
+	nostrJson := func(name string) *service.Nip05Response {
+		rec := httptest.NewRecorder()
+		suite.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/nostr.json?name="+name, nil))
+		assert.Equal(suite.T(), http.StatusOK, rec.Code)
+		res := &service.Nip05Response{}
+		assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(res))
+		return res
+	}
+	setUsername := func(key string, name string) string {
+		rec := suite.nostrEvent(key, "TAHUB_SET_USERNAME:"+name)
+		errResp := &responses.NostrErrorResponseBody{}
+		assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(errResp))
+		return errResp.Message
+	}
+
+	_, err := suite.service.SetUsername(context.Background(), suite.alice, "alice")
+	assert.NoError(suite.T(), err)
+	res := nostrJson("Alice")
+	assert.Equal(suite.T(), map[string]string{"alice": suite.alice.Pubkey}, res.Names)
+	assert.Equal(suite.T(), map[string][]string{suite.alice.Pubkey: {"wss://relay.example.com"}}, res.Relays)
+	// the root name is the hub, unknown names resolve to nothing
+	assert.Equal(suite.T(), map[string]string{"_": suite.service.Config.TahubPublicKey}, nostrJson("_").Names)
+	assert.Empty(suite.T(), nostrJson("carol").Names)
+
+	// reserved and pubkey like names cannot be claimed
+	for _, name := range []string{"admin", "treasury", "npub1alice", suite.bob.Pubkey} {
+		assert.Equal(suite.T(), service.UsernameReservedError.Error(), setUsername(suite.bobKey, name), name)
+	}
+
+	// a released name is held for its former owner
+	_, err = suite.service.SetUsername(context.Background(), suite.alice, "alice2")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), nostrJson("alice").Names)
+	assert.Equal(suite.T(), service.UsernameTakenError.Error(), setUsername(suite.bobKey, "alice"))
+	_, err = suite.service.SetUsername(context.Background(), suite.alice, "alice")
+	assert.NoError(suite.T(), err)
+
+	// changes are rate limited
+	suite.service.Config.UsernameChangeCooldown = 3600
+	_, err = suite.service.SetUsername(context.Background(), suite.alice, "alice3")
+	assert.ErrorIs(suite.T(), err, service.UsernameChangeTooSoonError)
+	suite.service.Config.UsernameChangeCooldown = 0
+
+	// revoked names are taken away and stay blocked, for their former owner too
+	rec := suite.restRequest(http.MethodPost, "/v2/admin/usernames/revoke", "", map[string]string{
+		"name":     "alice",
+		"reason":   "impersonation",
+		"operator": "ops",
+	})
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	hold := &models.UsernameHold{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(hold))
+	assert.Equal(suite.T(), models.UsernameHoldRevoked, hold.Kind)
+	assert.Equal(suite.T(), suite.alice.ID, hold.UserID)
+	assert.Empty(suite.T(), nostrJson("alice").Names)
+	assert.Equal(suite.T(), service.UsernameTakenError.Error(), setUsername(suite.aliceKey, "alice"))
+	assert.Equal(suite.T(), service.UsernameTakenError.Error(), setUsername(suite.bobKey, "alice"))
+
+	rec = suite.restRequest(http.MethodGet, "/v2/admin/username-holds", "", nil)
+	holds := &v2controllers.UsernameHoldsResponseBody{}
+	assert.NoError(suite.T(), json.NewDecoder(rec.Body).Decode(holds))
+	names := []string{}
+	for _, hold := range holds.Holds {
+		names = append(names, hold.Name)
+	}
+	assert.ElementsMatch(suite.T(), []string{"alice", "alice2"}, names)
+
+	rec = suite.restRequest(http.MethodDelete, "/v2/admin/username-holds/alice", "", nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	rec = suite.restRequest(http.MethodDelete, "/v2/admin/username-holds/alice", "", nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	_, err = suite.service.SetUsername(context.Background(), suite.bob, "alice")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string]string{"alice": suite.bob.Pubkey}, nostrJson("alice").Names)
+}
+
+func TestTapdAssetSuite(t *testing.T) {
+	suite.Run(t, new(TapdAssetTestSuite))
+}
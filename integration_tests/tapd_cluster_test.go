@@ -0,0 +1,77 @@
+package integration_tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/getAlby/lndhub.go/lib"
+	"github.com/getAlby/lndhub.go/tapd"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMockTapdCluster(livenessPeriod int) (*tapd.TapdCluster, *MockTapd, *MockTapd) {
+	mockTapd1 := NewMockTapd()
+	mockTapd2 := NewMockTapd()
+	//tell the nodes apart by their block height
+	mockTapd2.BlockHeight = 2000
+	cluster := &tapd.TapdCluster{
+		Nodes:               []tapd.TapdClientWrapper{mockTapd1, mockTapd2},
+		ActiveNode:          mockTapd1,
+		Logger:              lib.Logger(""),
+		LivenessCheckPeriod: livenessPeriod,
+		RequireSync:         true,
+	}
+	return cluster, mockTapd1, mockTapd2
+}
+
+func TestTapdCluster(t *testing.T) {
+	cluster, mockTapd1, mockTapd2 := newMockTapdCluster(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	go cluster.StartLivenessLoop(ctx)
+	//call getinfo - should be tapd1 that responds
+	resp, err := cluster.GetInfo(ctx, &taprpc.GetInfoRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, mockTapd1.BlockHeight, resp.BlockHeight)
+	//make tapd-1 return the error
+	mockTapd1.GetInfoError = fmt.Errorf("some error")
+	time.Sleep(2 * time.Second)
+	//call getinfo, should be tapd2 that responds
+	resp, err = cluster.GetInfo(ctx, &taprpc.GetInfoRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, mockTapd2.BlockHeight, resp.BlockHeight)
+	//make tapd-1 return no error
+	mockTapd1.GetInfoError = nil
+	time.Sleep(2 * time.Second)
+	//call getinfo, should be tapd1 that responds again
+	resp, err = cluster.GetInfo(ctx, &taprpc.GetInfoRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, mockTapd1.BlockHeight, resp.BlockHeight)
+	cancel()
+}
+
+func TestTapdClusterSubscriptionFailover(t *testing.T) {
+	//no liveness check during the test, the broken stream has to trigger the switch
+	cluster, mockTapd1, mockTapd2 := newMockTapdCluster(3600)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := cluster.SubscribeReceiveAssetEvent(ctx, &taprpc.SubscribeReceiveAssetEventNtfnsRequest{})
+	assert.NoError(t, err)
+	//tapd-1 goes down and breaks the stream
+	mockTapd1.GetInfoError = fmt.Errorf("some error")
+	mockTapd1.FailReceiveSubscription(fmt.Errorf("connection lost"))
+	_, err = stream.Recv()
+	assert.Error(t, err)
+	//the cluster switched right away, the new subscription is on tapd-2
+	resp, err := cluster.GetInfo(ctx, &taprpc.GetInfoRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, mockTapd2.BlockHeight, resp.BlockHeight)
+	sendStream, err := cluster.SubscribeSendAssetEvent(ctx, &taprpc.SubscribeSendAssetEventNtfnsRequest{})
+	assert.NoError(t, err)
+	mockTapd2.CompleteSends()
+	event, err := sendStream.Recv()
+	assert.NoError(t, err)
+	assert.NotNil(t, event.GetExecuteSendStateEvent())
+}
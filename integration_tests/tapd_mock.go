@@ -0,0 +1,582 @@
+package integration_tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/getAlby/lndhub.go/tapd"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/lightninglabs/taproot-assets/taprpc/mintrpc"
+	"github.com/lightninglabs/taproot-assets/taprpc/universerpc"
+	"google.golang.org/grpc"
+)
+
+const mockTapdSendStateComplete = "SendStateComplete"
+
+// MockTapd : in-memory tapd. Assets are minted with MintFakeAsset or the mint RPCs, addresses are
+// issued by NewAddress (hub addresses) or NewExternalAddr (addresses of other wallets). Receives and
+// send progress are pushed to the subscriptions by the test through SimulateReceive, CompleteSends
+// and the Backoff helpers.
+type MockTapd struct {
+	mu            sync.Mutex
+	assets        map[string]*taprpc.Asset     // keyed by hex asset id
+	metas         map[string]*taprpc.AssetMeta // keyed by hex asset id
+	addrs         map[string]*taprpc.Addr      // keyed by encoded address
+	receives      []*taprpc.AddrEvent
+	transfers     []*taprpc.AssetTransfer
+	pendingBatch  *mintrpc.MintingBatch
+	batches       []*mintrpc.MintingBatch
+	receiveEvents chan *taprpc.ReceiveAssetEvent
+	sendEvents    chan *taprpc.SendAssetEvent
+	receiveErrors chan error
+	sendErrors    chan error
+	// chain fees in sats reported for every send
+	ChainFees int64
+	// height reported as confirmation height of simulated receives
+	BlockHeight uint32
+	// returned by the next SendAsset call, reset afterwards
+	SendAssetError error
+	// returned by the next BurnAsset call, reset afterwards
+	BurnAssetError error
+	// returned by ExportProof while set
+	ExportProofError error
+	// returned by GetInfo while set, marks the node as down for a tapd cluster
+	GetInfoError error
+}
+
+func NewMockTapd() *MockTapd {
+	return &MockTapd{
+		assets:        map[string]*taprpc.Asset{},
+		metas:         map[string]*taprpc.AssetMeta{},
+		addrs:         map[string]*taprpc.Addr{},
+		receiveEvents: make(chan *taprpc.ReceiveAssetEvent, 16),
+		sendEvents:    make(chan *taprpc.SendAssetEvent, 16),
+		receiveErrors: make(chan error, 1),
+		sendErrors:    make(chan error, 1),
+		BlockHeight:   1000,
+	}
+}
+
+func mockRandBytes(length int) []byte {
+	b := make([]byte, length)
+	_, _ = rand.Read(b)
+	return b
+}
+
+// mockOutpoint returns a random outpoint in the txid:index format used by tapd
+func mockOutpoint(index int) string {
+	return fmt.Sprintf("%s:%d", hex.EncodeToString(mockRandBytes(32)), index)
+}
+
+// MintFakeAsset adds an asset held by the hub and returns its hex asset id
+func (mt *MockTapd) MintFakeAsset(name string, amount uint64) string {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	asset := mt.newAsset(name, amount, mockOutpoint(0))
+	return hex.EncodeToString(asset.AssetGenesis.AssetId)
+}
+
+func (mt *MockTapd) newAsset(name string, amount uint64, anchorOutpoint string) *taprpc.Asset {
+	asset := &taprpc.Asset{
+		AssetGenesis: &taprpc.GenesisInfo{
+			Name:         name,
+			AssetId:      mockRandBytes(32),
+			GenesisPoint: anchorOutpoint,
+		},
+		Amount:      amount,
+		ScriptKey:   mockRandBytes(33),
+		ChainAnchor: &taprpc.AnchorInfo{AnchorOutpoint: anchorOutpoint, BlockHeight: mt.BlockHeight},
+	}
+	mt.assets[hex.EncodeToString(asset.AssetGenesis.AssetId)] = asset
+	return asset
+}
+
+// SetAssetMeta sets the meta data tapd returns for an asset
+func (mt *MockTapd) SetAssetMeta(assetId string, data []byte) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	mt.metas[assetId] = &taprpc.AssetMeta{Data: data, Type: taprpc.AssetMetaType_META_TYPE_OPAQUE}
+}
+
+// Balance is the amount of an asset the hub holds on tapd
+func (mt *MockTapd) Balance(assetId string) uint64 {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if asset, ok := mt.assets[assetId]; ok {
+		return asset.Amount
+	}
+	return 0
+}
+
+func (mt *MockTapd) newAddr(assetId []byte, amt uint64, prefix string) *taprpc.Addr {
+	addr := &taprpc.Addr{
+		Encoded:     prefix + hex.EncodeToString(mockRandBytes(16)),
+		AssetId:     assetId,
+		Amount:      amt,
+		ScriptKey:   mockRandBytes(33),
+		InternalKey: mockRandBytes(33),
+	}
+	mt.addrs[addr.Encoded] = addr
+	return addr
+}
+
+// NewExternalAddr issues an address that belongs to a wallet outside the hub, it decodes but
+// is not known to the hub database
+func (mt *MockTapd) NewExternalAddr(assetId string, amt uint64) (string, error) {
+	decoded, err := hex.DecodeString(assetId)
+	if err != nil {
+		return "", err
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	return mt.newAddr(decoded, amt, "taprtext1").Encoded, nil
+}
+
+// SimulateReceive books an incoming transfer to an address in the given state. A completed
+// receive is also pushed to the receive subscription.
+func (mt *MockTapd) SimulateReceive(encoded string, status taprpc.AddrEventStatus) (string, error) {
+	mt.mu.Lock()
+	addr, ok := mt.addrs[encoded]
+	if !ok {
+		mt.mu.Unlock()
+		return "", errors.New("unknown address")
+	}
+	event := &taprpc.AddrEvent{
+		CreationTimeUnixSeconds: uint64(time.Now().Unix()),
+		Addr:                    addr,
+		Status:                  status,
+		Outpoint:                mockOutpoint(1),
+	}
+	mt.receives = append(mt.receives, event)
+	mt.mu.Unlock()
+	return event.Outpoint, mt.UpdateReceive(event.Outpoint, status)
+}
+
+// UpdateReceive moves a simulated receive to a new state, completing it credits the hub's tapd
+// balance and notifies the receive subscription
+func (mt *MockTapd) UpdateReceive(outpoint string, status taprpc.AddrEventStatus) error {
+	mt.mu.Lock()
+	var event *taprpc.AddrEvent
+	for _, receive := range mt.receives {
+		if receive.Outpoint == outpoint {
+			event = receive
+		}
+	}
+	if event == nil {
+		mt.mu.Unlock()
+		return errors.New("unknown receive")
+	}
+	event.Status = status
+	if status >= taprpc.AddrEventStatus_ADDR_EVENT_STATUS_TRANSACTION_CONFIRMED && event.ConfirmationHeight == 0 {
+		event.ConfirmationHeight = mt.BlockHeight
+	}
+	if status != taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED {
+		mt.mu.Unlock()
+		return nil
+	}
+	event.HasProof = true
+	assetId := hex.EncodeToString(event.Addr.AssetId)
+	asset, ok := mt.assets[assetId]
+	if !ok {
+		// first units of an asset minted elsewhere
+		asset = &taprpc.Asset{
+			AssetGenesis: &taprpc.GenesisInfo{Name: assetId, AssetId: event.Addr.AssetId},
+			ScriptKey:    event.Addr.ScriptKey,
+			ChainAnchor:  &taprpc.AnchorInfo{AnchorOutpoint: outpoint, BlockHeight: event.ConfirmationHeight},
+		}
+		mt.assets[assetId] = asset
+	}
+	asset.Amount += event.Addr.Amount
+	mt.mu.Unlock()
+	mt.receiveEvents <- &taprpc.ReceiveAssetEvent{
+		Event: &taprpc.ReceiveAssetEvent_AssetReceiveCompleteEvent{
+			AssetReceiveCompleteEvent: &taprpc.AssetReceiveCompleteEvent{
+				Timestamp: time.Now().UnixMicro(),
+				Address:   event.Addr,
+				Outpoint:  outpoint,
+			},
+		},
+	}
+	return nil
+}
+
+// BackoffReceive pushes a proof courier backoff to the receive subscription
+func (mt *MockTapd) BackoffReceive(tries int64) {
+	mt.receiveEvents <- &taprpc.ReceiveAssetEvent{
+		Event: &taprpc.ReceiveAssetEvent_ProofTransferBackoffWaitEvent{
+			ProofTransferBackoffWaitEvent: &taprpc.ProofTransferBackoffWaitEvent{
+				Timestamp:    time.Now().UnixMicro(),
+				Backoff:      int64(time.Second),
+				TriesCounter: tries,
+			},
+		},
+	}
+}
+
+// BackoffSend pushes a proof courier backoff to the send subscription
+func (mt *MockTapd) BackoffSend(tries int64) {
+	mt.sendEvents <- &taprpc.SendAssetEvent{
+		Event: &taprpc.SendAssetEvent_ProofTransferBackoffWaitEvent{
+			ProofTransferBackoffWaitEvent: &taprpc.ProofTransferBackoffWaitEvent{
+				Timestamp:    time.Now().UnixMicro(),
+				Backoff:      int64(time.Second),
+				TriesCounter: tries,
+			},
+		},
+	}
+}
+
+// FailReceiveSubscription breaks the receive subscription stream with err
+func (mt *MockTapd) FailReceiveSubscription(err error) {
+	mt.receiveErrors <- err
+}
+
+// FailSendSubscription breaks the send subscription stream with err
+func (mt *MockTapd) FailSendSubscription(err error) {
+	mt.sendErrors <- err
+}
+
+// PushSendState pushes a send state change to the send subscription
+func (mt *MockTapd) PushSendState(state string) {
+	mt.sendEvents <- &taprpc.SendAssetEvent{
+		Event: &taprpc.SendAssetEvent_ExecuteSendStateEvent{
+			ExecuteSendStateEvent: &taprpc.ExecuteSendStateEvent{
+				Timestamp: time.Now().UnixMicro(),
+				SendState: state,
+			},
+		},
+	}
+}
+
+// CompleteSends reports the pending sends as complete to the send subscription
+func (mt *MockTapd) CompleteSends() {
+	mt.PushSendState(mockTapdSendStateComplete)
+}
+
+func (mt *MockTapd) GetInfo(ctx context.Context, req *taprpc.GetInfoRequest, options ...grpc.CallOption) (*taprpc.GetInfoResponse, error) {
+	if mt.GetInfoError != nil {
+		return nil, mt.GetInfoError
+	}
+	return &taprpc.GetInfoResponse{
+		Version:     "mock",
+		Network:     "regtest",
+		BlockHeight: mt.BlockHeight,
+		SyncToChain: true,
+	}, nil
+}
+
+func (mt *MockTapd) ListAssets(ctx context.Context, req *taprpc.ListAssetRequest, options ...grpc.CallOption) (*taprpc.ListAssetResponse, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	assets := []*taprpc.Asset{}
+	for _, asset := range mt.assets {
+		assets = append(assets, asset)
+	}
+	return &taprpc.ListAssetResponse{Assets: assets}, nil
+}
+
+func (mt *MockTapd) ListBalances(ctx context.Context, req *taprpc.ListBalancesRequest, options ...grpc.CallOption) (*taprpc.ListBalancesResponse, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	balances := map[string]*taprpc.AssetBalance{}
+	for assetId, asset := range mt.assets {
+		balances[assetId] = &taprpc.AssetBalance{
+			AssetGenesis: asset.AssetGenesis,
+			Balance:      asset.Amount,
+		}
+	}
+	return &taprpc.ListBalancesResponse{AssetBalances: balances}, nil
+}
+
+func (mt *MockTapd) NewAddress(ctx context.Context, req *taprpc.NewAddrRequest, options ...grpc.CallOption) (*taprpc.Addr, error) {
+	if len(req.AssetId) == 0 || req.Amt == 0 {
+		return nil, errors.New("asset id and amount are required")
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	return mt.newAddr(req.AssetId, req.Amt, "taprt1"), nil
+}
+
+func (mt *MockTapd) GetUniverseAssets(ctx context.Context, req *universerpc.AssetRootRequest, options ...grpc.CallOption) (*universerpc.AssetRootResponse, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	roots := map[string]*universerpc.UniverseRoot{}
+	for assetId, asset := range mt.assets {
+		roots[assetId] = &universerpc.UniverseRoot{
+			Id:        &universerpc.ID{Id: &universerpc.ID_AssetId{AssetId: asset.AssetGenesis.AssetId}},
+			AssetName: asset.AssetGenesis.Name,
+		}
+	}
+	return &universerpc.AssetRootResponse{UniverseRoots: roots}, nil
+}
+
+func (mt *MockTapd) GetAssetStats(ctx context.Context, req *universerpc.AssetStatsQuery, options ...grpc.CallOption) (*universerpc.UniverseAssetStats, error) {
+	// the mock keeps no universe, the hub falls back to its own mint records
+	return &universerpc.UniverseAssetStats{}, nil
+}
+
+func (mt *MockTapd) GetDecodedAddress(ctx context.Context, req *taprpc.DecodeAddrRequest, options ...grpc.CallOption) (*taprpc.Addr, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	addr, ok := mt.addrs[req.Addr]
+	if !ok {
+		return nil, errors.New("invalid address")
+	}
+	return addr, nil
+}
+
+func (mt *MockTapd) SendAsset(ctx context.Context, req *taprpc.SendAssetRequest, options ...grpc.CallOption) (*taprpc.SendAssetResponse, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.SendAssetError != nil {
+		err := mt.SendAssetError
+		mt.SendAssetError = nil
+		return nil, err
+	}
+	required := map[string]uint64{}
+	addrs := []*taprpc.Addr{}
+	for _, encoded := range req.TapAddrs {
+		addr, ok := mt.addrs[encoded]
+		if !ok {
+			return nil, fmt.Errorf("invalid address %s", encoded)
+		}
+		addrs = append(addrs, addr)
+		required[hex.EncodeToString(addr.AssetId)] += addr.Amount
+	}
+	for assetId, amount := range required {
+		asset, ok := mt.assets[assetId]
+		if !ok || asset.Amount < amount {
+			return nil, fmt.Errorf("insufficient balance of asset %s", assetId)
+		}
+	}
+	txid := hex.EncodeToString(mockRandBytes(32))
+	transfer := &taprpc.AssetTransfer{
+		TransferTimestamp:  time.Now().Unix(),
+		AnchorTxHeightHint: mt.BlockHeight,
+		AnchorTxChainFees:  mt.ChainFees,
+	}
+	for i, addr := range addrs {
+		mt.assets[hex.EncodeToString(addr.AssetId)].Amount -= addr.Amount
+		transfer.Outputs = append(transfer.Outputs, &taprpc.TransferOutput{
+			Anchor:       &taprpc.TransferOutputAnchor{Outpoint: fmt.Sprintf("%s:%d", txid, i)},
+			ScriptKey:    addr.ScriptKey,
+			Amount:       addr.Amount,
+			NewProofBlob: []byte("proof:" + addr.Encoded),
+		})
+	}
+	mt.transfers = append(mt.transfers, transfer)
+	return &taprpc.SendAssetResponse{Transfer: transfer}, nil
+}
+
+func (mt *MockTapd) SubscribeReceiveAssetEvent(ctx context.Context, req *taprpc.SubscribeReceiveAssetEventNtfnsRequest, options ...grpc.CallOption) (tapd.SubscribeReceiveAssetEventWrapper, error) {
+	return &MockReceiveAssetEvents{ctx: ctx, events: mt.receiveEvents, errors: mt.receiveErrors}, nil
+}
+
+func (mt *MockTapd) SubscribeSendAssetEvent(ctx context.Context, req *taprpc.SubscribeSendAssetEventNtfnsRequest, options ...grpc.CallOption) (tapd.SubscribeSendAssetEventWrapper, error) {
+	return &MockSendAssetEvents{ctx: ctx, events: mt.sendEvents, errors: mt.sendErrors}, nil
+}
+
+func (mt *MockTapd) MintAsset(ctx context.Context, req *mintrpc.MintAssetRequest, options ...grpc.CallOption) (*mintrpc.MintAssetResponse, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.pendingBatch == nil {
+		mt.pendingBatch = &mintrpc.MintingBatch{
+			BatchKey: mockRandBytes(33),
+			State:    mintrpc.BatchState_BATCH_STATE_PENDING,
+		}
+	}
+	mt.pendingBatch.Assets = append(mt.pendingBatch.Assets, &mintrpc.PendingAsset{
+		AssetType:       req.Asset.AssetType,
+		Name:            req.Asset.Name,
+		AssetMeta:       req.Asset.AssetMeta,
+		Amount:          req.Asset.Amount,
+		NewGroupedAsset: req.Asset.NewGroupedAsset,
+		GroupKey:        req.Asset.GroupKey,
+	})
+	return &mintrpc.MintAssetResponse{PendingBatch: mt.pendingBatch}, nil
+}
+
+func (mt *MockTapd) FinalizeBatch(ctx context.Context, req *mintrpc.FinalizeBatchRequest, options ...grpc.CallOption) (*mintrpc.FinalizeBatchResponse, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	batch := mt.pendingBatch
+	if batch == nil {
+		return nil, errors.New("no pending batch")
+	}
+	mt.pendingBatch = nil
+	// the mock confirms the minting transaction right away
+	batch.BatchTxid = hex.EncodeToString(mockRandBytes(32))
+	batch.State = mintrpc.BatchState_BATCH_STATE_FINALIZED
+	for i, seedling := range batch.Assets {
+		asset := mt.newAsset(seedling.Name, seedling.Amount, fmt.Sprintf("%s:%d", batch.BatchTxid, i))
+		if seedling.AssetMeta != nil {
+			mt.metas[hex.EncodeToString(asset.AssetGenesis.AssetId)] = seedling.AssetMeta
+		}
+	}
+	mt.batches = append(mt.batches, batch)
+	return &mintrpc.FinalizeBatchResponse{Batch: batch}, nil
+}
+
+func (mt *MockTapd) CancelBatch(ctx context.Context, req *mintrpc.CancelBatchRequest, options ...grpc.CallOption) (*mintrpc.CancelBatchResponse, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	batch := mt.pendingBatch
+	if batch == nil {
+		return nil, errors.New("no pending batch")
+	}
+	mt.pendingBatch = nil
+	batch.State = mintrpc.BatchState_BATCH_STATE_SEEDLING_CANCELLED
+	mt.batches = append(mt.batches, batch)
+	return &mintrpc.CancelBatchResponse{BatchKey: batch.BatchKey}, nil
+}
+
+func (mt *MockTapd) ListBatches(ctx context.Context, req *mintrpc.ListBatchRequest, options ...grpc.CallOption) (*mintrpc.ListBatchResponse, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	batches := append([]*mintrpc.MintingBatch{}, mt.batches...)
+	if mt.pendingBatch != nil {
+		batches = append(batches, mt.pendingBatch)
+	}
+	return &mintrpc.ListBatchResponse{Batches: batches}, nil
+}
+
+func (mt *MockTapd) BurnAsset(ctx context.Context, req *taprpc.BurnAssetRequest, options ...grpc.CallOption) (*taprpc.BurnAssetResponse, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.BurnAssetError != nil {
+		err := mt.BurnAssetError
+		mt.BurnAssetError = nil
+		return nil, err
+	}
+	assetId := req.GetAssetIdStr()
+	if assetId == "" {
+		assetId = hex.EncodeToString(req.GetAssetId())
+	}
+	asset, ok := mt.assets[assetId]
+	if !ok || asset.Amount < req.AmountToBurn {
+		return nil, errors.New("insufficient balance to burn")
+	}
+	asset.Amount -= req.AmountToBurn
+	outpoint := mockOutpoint(0)
+	scriptKey := mockRandBytes(33)
+	transfer := &taprpc.AssetTransfer{
+		TransferTimestamp: time.Now().Unix(),
+		AnchorTxChainFees: mt.ChainFees,
+		Inputs:            []*taprpc.TransferInput{{AssetId: asset.AssetGenesis.AssetId, Amount: req.AmountToBurn}},
+		Outputs: []*taprpc.TransferOutput{{
+			Anchor:       &taprpc.TransferOutputAnchor{Outpoint: outpoint},
+			ScriptKey:    scriptKey,
+			Amount:       req.AmountToBurn,
+			NewProofBlob: []byte("burn proof"),
+		}},
+	}
+	mt.transfers = append(mt.transfers, transfer)
+	return &taprpc.BurnAssetResponse{
+		BurnTransfer: transfer,
+		BurnProof: &taprpc.DecodedProof{
+			Asset: &taprpc.Asset{
+				AssetGenesis: asset.AssetGenesis,
+				Amount:       req.AmountToBurn,
+				ScriptKey:    scriptKey,
+				ChainAnchor:  &taprpc.AnchorInfo{AnchorOutpoint: outpoint},
+			},
+		},
+	}, nil
+}
+
+func (mt *MockTapd) ExportProof(ctx context.Context, req *taprpc.ExportProofRequest, options ...grpc.CallOption) (*taprpc.ProofFile, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.ExportProofError != nil {
+		return nil, mt.ExportProofError
+	}
+	for _, transfer := range mt.transfers {
+		for _, output := range transfer.Outputs {
+			if bytes.Equal(output.ScriptKey, req.ScriptKey) {
+				return &taprpc.ProofFile{RawProofFile: output.NewProofBlob}, nil
+			}
+		}
+	}
+	return nil, errors.New("proof not found")
+}
+
+func (mt *MockTapd) AddrReceives(ctx context.Context, req *taprpc.AddrReceivesRequest, options ...grpc.CallOption) (*taprpc.AddrReceivesResponse, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	events := []*taprpc.AddrEvent{}
+	for _, event := range mt.receives {
+		if req.FilterAddr != "" && event.Addr.Encoded != req.FilterAddr {
+			continue
+		}
+		if req.FilterStatus != taprpc.AddrEventStatus_ADDR_EVENT_STATUS_UNKNOWN && event.Status != req.FilterStatus {
+			continue
+		}
+		events = append(events, event)
+	}
+	return &taprpc.AddrReceivesResponse{Events: events}, nil
+}
+
+func (mt *MockTapd) ListTransfers(ctx context.Context, req *taprpc.ListTransfersRequest, options ...grpc.CallOption) (*taprpc.ListTransfersResponse, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	return &taprpc.ListTransfersResponse{Transfers: append([]*taprpc.AssetTransfer{}, mt.transfers...)}, nil
+}
+
+func (mt *MockTapd) FetchAssetMeta(ctx context.Context, req *taprpc.FetchAssetMetaRequest, options ...grpc.CallOption) (*taprpc.AssetMeta, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	meta, ok := mt.metas[hex.EncodeToString(req.GetAssetId())]
+	if !ok {
+		meta, ok = mt.metas[req.GetAssetIdStr()]
+	}
+	if !ok {
+		return nil, errors.New("asset meta not found")
+	}
+	return meta, nil
+}
+
+// MockReceiveAssetEvents : receive subscription stream, ends when its context is cancelled or
+// the test fails it
+type MockReceiveAssetEvents struct {
+	ctx    context.Context
+	events chan *taprpc.ReceiveAssetEvent
+	errors chan error
+}
+
+func (stream *MockReceiveAssetEvents) Recv() (*taprpc.ReceiveAssetEvent, error) {
+	select {
+	case <-stream.ctx.Done():
+		return nil, stream.ctx.Err()
+	case err := <-stream.errors:
+		return nil, err
+	case event := <-stream.events:
+		return event, nil
+	}
+}
+
+// MockSendAssetEvents : send subscription stream, ends when its context is cancelled or the
+// test fails it
+type MockSendAssetEvents struct {
+	ctx    context.Context
+	events chan *taprpc.SendAssetEvent
+	errors chan error
+}
+
+func (stream *MockSendAssetEvents) Recv() (*taprpc.SendAssetEvent, error) {
+	select {
+	case <-stream.ctx.Done():
+		return nil, stream.ctx.Err()
+	case err := <-stream.errors:
+		return nil, err
+	case event := <-stream.events:
+		return event, nil
+	}
+}
+
+var _ tapd.TapdClientWrapper = (*MockTapd)(nil)
@@ -0,0 +1,143 @@
+package merklesum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+var InvalidLeafIndexError = errors.New("leaf index out of range")
+
+// domain separation between leaves and inner nodes
+const (
+	leafPrefix byte = 0x00
+	nodePrefix byte = 0x01
+)
+
+// Node : a hash committing to everything below it together with the sum of all leaf values below it
+type Node struct {
+	Hash [32]byte
+	Sum  uint64
+}
+
+// EmptyNode pads levels with an odd number of nodes, it does not add to the sum
+var EmptyNode = Node{}
+
+// LeafNode commits to a key (e.g. a user pubkey) and its value. The salt keeps the
+// key from being brute forced out of a sibling hash that is handed to another user.
+func LeafNode(key string, salt []byte, sum uint64) Node {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(salt)
+	h.Write([]byte(key))
+	h.Write(uint64Bytes(sum))
+	node := Node{Sum: sum}
+	copy(node.Hash[:], h.Sum(nil))
+	return node
+}
+
+// ParentNode commits to both children and their sums, so a parent sum can not be
+// smaller than the sums of its children without changing the hash
+func ParentNode(left, right Node) Node {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left.Hash[:])
+	h.Write(uint64Bytes(left.Sum))
+	h.Write(right.Hash[:])
+	h.Write(uint64Bytes(right.Sum))
+	node := Node{Sum: left.Sum + right.Sum}
+	copy(node.Hash[:], h.Sum(nil))
+	return node
+}
+
+func (node Node) HashHex() string {
+	return hex.EncodeToString(node.Hash[:])
+}
+
+// Tree : merkle sum tree, levels[0] are the leaves and the last level is the root
+type Tree struct {
+	levels [][]Node
+}
+
+func New(leaves []Node) *Tree {
+	tree := &Tree{levels: [][]Node{leaves}}
+	current := leaves
+	for len(current) > 1 {
+		next := make([]Node, 0, (len(current)+1)/2)
+		for i := 0; i < len(current); i += 2 {
+			right := EmptyNode
+			if i+1 < len(current) {
+				right = current[i+1]
+			}
+			next = append(next, ParentNode(current[i], right))
+		}
+		tree.levels = append(tree.levels, next)
+		current = next
+	}
+	return tree
+}
+
+func (tree *Tree) Root() Node {
+	top := tree.levels[len(tree.levels)-1]
+	if len(top) == 0 {
+		return EmptyNode
+	}
+	return top[0]
+}
+
+// ProofStep : sibling on the path from a leaf to the root
+type ProofStep struct {
+	Hash string `json:"hash"`
+	Sum  uint64 `json:"sum"`
+	// true if the sibling is the left child
+	Left bool `json:"left"`
+}
+
+// Proof returns the inclusion path for the leaf at index, ordered from the leaf up to the root
+func (tree *Tree) Proof(index int) ([]ProofStep, error) {
+	if index < 0 || index >= len(tree.levels[0]) {
+		return nil, InvalidLeafIndexError
+	}
+	path := []ProofStep{}
+	for _, level := range tree.levels[:len(tree.levels)-1] {
+		siblingIndex := index ^ 1
+		sibling := EmptyNode
+		if siblingIndex < len(level) {
+			sibling = level[siblingIndex]
+		}
+		path = append(path, ProofStep{
+			Hash: sibling.HashHex(),
+			Sum:  sibling.Sum,
+			Left: siblingIndex < index,
+		})
+		index /= 2
+	}
+	return path, nil
+}
+
+// Verify recomputes the root from a leaf and its path
+func Verify(leaf Node, path []ProofStep, root Node) bool {
+	current := leaf
+	for _, step := range path {
+		decoded, err := hex.DecodeString(step.Hash)
+		if err != nil || len(decoded) != 32 {
+			return false
+		}
+		sibling := Node{Sum: step.Sum}
+		copy(sibling.Hash[:], decoded)
+		if step.Left {
+			current = ParentNode(sibling, current)
+		} else {
+			current = ParentNode(current, sibling)
+		}
+	}
+	return bytes.Equal(current.Hash[:], root.Hash[:]) && current.Sum == root.Sum
+}
+
+func uint64Bytes(value uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, value)
+	return b
+}
@@ -0,0 +1,49 @@
+package merklesum
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreeProofs(t *testing.T) {
+	leaves := []Node{}
+	total := uint64(0)
+	for i := 0; i < 5; i++ {
+		leaves = append(leaves, LeafNode(fmt.Sprintf("pubkey%d", i), []byte{byte(i)}, uint64(i*100)))
+		total += uint64(i * 100)
+	}
+	tree := New(leaves)
+	root := tree.Root()
+	assert.Equal(t, total, root.Sum)
+
+	for i, leaf := range leaves {
+		path, err := tree.Proof(i)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(path))
+		assert.True(t, Verify(leaf, path, root))
+	}
+
+	// a different balance for the same user does not verify
+	path, _ := tree.Proof(2)
+	assert.False(t, Verify(LeafNode("pubkey2", []byte{2}, 100), path, root))
+	// neither does a sibling with a lowered sum
+	path[0].Sum = 0
+	assert.False(t, Verify(leaves[2], path, root))
+
+	_, err := tree.Proof(5)
+	assert.Equal(t, InvalidLeafIndexError, err)
+}
+
+func TestSingleAndEmptyTree(t *testing.T) {
+	leaf := LeafNode("pubkey", []byte("salt"), 42)
+	tree := New([]Node{leaf})
+	assert.Equal(t, leaf, tree.Root())
+	path, err := tree.Proof(0)
+	assert.NoError(t, err)
+	assert.Empty(t, path)
+	assert.True(t, Verify(leaf, path, tree.Root()))
+
+	assert.Equal(t, EmptyNode, New([]Node{}).Root())
+}
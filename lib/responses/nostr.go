@@ -23,6 +23,10 @@ type NostrServerPubkeyResponseBody struct {
 
 type NostrBalanceResponseBody struct {
 	Balances map[string]int64 `json:"balances"`
+	// balances as decimal strings in the asset's decimal display
+	Amounts map[string]string `json:"amounts"`
+	// fiat value of the balances, only with a price oracle configured
+	Valuation interface{} `json:"valuation,omitempty"`
 }
 /// universe response
 type NostrUniAssetResponseBody struct {
@@ -36,6 +40,57 @@ type NostrTransferResponseBody struct {
 type NostrAddressResponseBody struct {
 	Address string `json:"address"`
 }
+/// liability proof response
+type NostrLiabilityProofResponseBody struct {
+	Proof interface{} `json:"proof"`
+}
+/// receives response
+type NostrReceivesResponseBody struct {
+	Receives interface{} `json:"receives"`
+}
+/// asset invoice response
+type NostrAssetInvoiceResponseBody struct {
+	PaymentRequest string `json:"payment_request"`
+	RHash          string `json:"r_hash"`
+}
+/// asset payment response
+type NostrAssetPaymentResponseBody struct {
+	Preimage string `json:"preimage"`
+	Amount   int64  `json:"amount"`
+}
+/// swap quote response, also returned once the quote is executed
+type NostrSwapQuoteResponseBody struct {
+	Quote interface{} `json:"quote"`
+}
+/// single order response
+type NostrOrderResponseBody struct {
+	Order interface{} `json:"order"`
+}
+/// orders response
+type NostrOrdersResponseBody struct {
+	Orders interface{} `json:"orders"`
+}
+/// trades response
+type NostrTradesResponseBody struct {
+	Trades interface{} `json:"trades"`
+}
+/// internal transfer response
+type NostrInternalTransferResponseBody struct {
+	Transfer interface{} `json:"transfer"`
+}
+/// single payment request response
+type NostrPaymentRequestResponseBody struct {
+	PaymentRequest interface{} `json:"payment_request"`
+}
+/// payment requests response
+type NostrPaymentRequestsResponseBody struct {
+	PaymentRequests interface{} `json:"payment_requests"`
+}
+/// username response
+type NostrUsernameResponseBody struct {
+	Username         string `json:"username"`
+	LightningAddress string `json:"lightning_address,omitempty"`
+}
 /// auth response
 type AuthResponseBody struct {
 	Pubkey       string `json:"pubkey"`
@@ -95,9 +150,11 @@ func (responder *RelayResponder) GetAddressJson(c echo.Context, address string)
 	return c.JSON(http.StatusOK, &res)
 }
 
-func (responder *RelayResponder) GetBalancesJson(c echo.Context, balances map[string]int64) error {
+func (responder *RelayResponder) GetBalancesJson(c echo.Context, balances map[string]int64, amounts map[string]string, valuation interface{}) error {
 	var res NostrBalanceResponseBody
 	res.Balances = balances
+	res.Amounts = amounts
+	res.Valuation = valuation
 
 	return c.JSON(http.StatusOK, &res)
 }
@@ -109,6 +166,105 @@ func (responder *RelayResponder) TransferAssetsJson(c echo.Context, msg string)
 	return c.JSON(http.StatusOK, &res)
 }
 
+func (responder *RelayResponder) TransactionHistoryJson(c echo.Context, page interface{}) error {
+	return c.JSON(http.StatusOK, page)
+}
+
+func (responder *RelayResponder) LiabilityProofJson(c echo.Context, proof interface{}) error {
+	var res NostrLiabilityProofResponseBody
+	res.Proof = proof
+
+	return c.JSON(http.StatusOK, &res)
+}
+
+func (responder *RelayResponder) ReceivesJson(c echo.Context, receives interface{}) error {
+	var res NostrReceivesResponseBody
+	res.Receives = receives
+
+	return c.JSON(http.StatusOK, &res)
+}
+
+func (responder *RelayResponder) AssetInvoiceJson(c echo.Context, paymentRequest string, rHash string) error {
+	var res NostrAssetInvoiceResponseBody
+	res.PaymentRequest = paymentRequest
+	res.RHash = rHash
+
+	return c.JSON(http.StatusOK, &res)
+}
+
+func (responder *RelayResponder) AssetPaymentJson(c echo.Context, preimage string, amount int64) error {
+	var res NostrAssetPaymentResponseBody
+	res.Preimage = preimage
+	res.Amount = amount
+
+	return c.JSON(http.StatusOK, &res)
+}
+
+func (responder *RelayResponder) SwapQuoteJson(c echo.Context, quote interface{}) error {
+	var res NostrSwapQuoteResponseBody
+	res.Quote = quote
+
+	return c.JSON(http.StatusOK, &res)
+}
+
+func (responder *RelayResponder) OrderPlacementJson(c echo.Context, placement interface{}) error {
+	return c.JSON(http.StatusOK, placement)
+}
+
+func (responder *RelayResponder) OrderJson(c echo.Context, order interface{}) error {
+	var res NostrOrderResponseBody
+	res.Order = order
+
+	return c.JSON(http.StatusOK, &res)
+}
+
+func (responder *RelayResponder) OrderBookJson(c echo.Context, book interface{}) error {
+	return c.JSON(http.StatusOK, book)
+}
+
+func (responder *RelayResponder) OrdersJson(c echo.Context, orders interface{}) error {
+	var res NostrOrdersResponseBody
+	res.Orders = orders
+
+	return c.JSON(http.StatusOK, &res)
+}
+
+func (responder *RelayResponder) TradesJson(c echo.Context, trades interface{}) error {
+	var res NostrTradesResponseBody
+	res.Trades = trades
+
+	return c.JSON(http.StatusOK, &res)
+}
+
+func (responder *RelayResponder) InternalTransferJson(c echo.Context, transfer interface{}) error {
+	var res NostrInternalTransferResponseBody
+	res.Transfer = transfer
+
+	return c.JSON(http.StatusOK, &res)
+}
+
+func (responder *RelayResponder) PaymentRequestJson(c echo.Context, request interface{}) error {
+	var res NostrPaymentRequestResponseBody
+	res.PaymentRequest = request
+
+	return c.JSON(http.StatusOK, &res)
+}
+
+func (responder *RelayResponder) PaymentRequestsJson(c echo.Context, requests interface{}) error {
+	var res NostrPaymentRequestsResponseBody
+	res.PaymentRequests = requests
+
+	return c.JSON(http.StatusOK, &res)
+}
+
+func (responder *RelayResponder) UsernameJson(c echo.Context, username string, lightningAddress string) error {
+	var res NostrUsernameResponseBody
+	res.Username = username
+	res.LightningAddress = lightningAddress
+
+	return c.JSON(http.StatusOK, &res)
+}
+
 func (responder *RelayResponder) AuthJson(c echo.Context, pubkey string, accessToken string, refreshToken string) error {
 	var res AuthResponseBody
 	res.Pubkey = pubkey
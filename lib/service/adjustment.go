@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/uptrace/bun"
+)
+
+var (
+	InvalidAdjustmentAmountError   = errors.New("adjustment amount must be non-zero")
+	MissingAdjustmentReasonError   = errors.New("adjustment requires a reason and a ticket reference")
+	MissingAdjustmentOperatorError = errors.New("adjustment requires an operator")
+)
+
+type LedgerAdjustmentRequest struct {
+	UserID    int64
+	TaAssetID string
+	// positive amounts credit the user's current account, negative amounts debit it
+	Amount    int64
+	Reason    string
+	TicketRef string
+	Operator  string
+}
+
+func (req *LedgerAdjustmentRequest) Validate() error {
+	if req.Amount == 0 {
+		return InvalidAdjustmentAmountError
+	}
+	if req.Reason == "" || req.TicketRef == "" {
+		return MissingAdjustmentReasonError
+	}
+	if req.Operator == "" {
+		return MissingAdjustmentOperatorError
+	}
+	return nil
+}
+
+// PostLedgerAdjustment moves funds between a user's current account and the system adjustments account
+// for the asset. The entry and its audit row are written in one DB transaction, so debits that would
+// take the user below zero are rejected by the check_balance trigger and nothing is recorded.
+func (svc *LndhubService) PostLedgerAdjustment(ctx context.Context, req LedgerAdjustmentRequest) (*models.LedgerAdjustment, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.TaAssetID == "" {
+		req.TaAssetID = common.BTC_TA_ASSET_ID
+	}
+	adjustment := &models.LedgerAdjustment{
+		UserID:    req.UserID,
+		TaAssetID: req.TaAssetID,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+		TicketRef: req.TicketRef,
+		Operator:  req.Operator,
+	}
+	err := svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		userAccount, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, req.TaAssetID, req.UserID)
+		if err != nil {
+			return fmt.Errorf("could not find current account for user_id:%d asset:%s: %w", req.UserID, req.TaAssetID, err)
+		}
+		adjustmentAccount, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeAdjustments, req.TaAssetID)
+		if err != nil {
+			return err
+		}
+		entry := models.TransactionEntry{
+			UserID:    req.UserID,
+			TaAssetID: req.TaAssetID,
+			EntryType: models.EntryTypeAdjustment,
+		}
+		if req.Amount > 0 {
+			entry.DebitAccountID = adjustmentAccount.ID
+			entry.CreditAccountID = userAccount.ID
+			entry.Amount = req.Amount
+		} else {
+			entry.DebitAccountID = userAccount.ID
+			entry.CreditAccountID = adjustmentAccount.ID
+			entry.Amount = -req.Amount
+		}
+		if _, err := tx.NewInsert().Model(&entry).Exec(ctx); err != nil {
+			return err
+		}
+		adjustment.TransactionEntryID = entry.ID
+		_, err = tx.NewInsert().Model(adjustment).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("Could not post ledger adjustment user_id:%v asset:%s amount:%d ticket:%s error %v", req.UserID, req.TaAssetID, req.Amount, req.TicketRef, err)
+		return nil, err
+	}
+	svc.Logger.Infof("Posted ledger adjustment id:%d user_id:%v asset:%s amount:%d ticket:%s operator:%s", adjustment.ID, req.UserID, req.TaAssetID, req.Amount, req.TicketRef, req.Operator)
+	return adjustment, nil
+}
+
+func (svc *LndhubService) LedgerAdjustmentsFor(ctx context.Context, userId int64) ([]models.LedgerAdjustment, error) {
+	adjustments := []models.LedgerAdjustment{}
+	query := svc.DB.NewSelect().Model(&adjustments)
+	if userId > 0 {
+		query.Where("user_id = ?", userId)
+	}
+	err := query.OrderExpr("id DESC").Limit(100).Scan(ctx)
+	return adjustments, err
+}
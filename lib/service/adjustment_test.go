@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedgerAdjustmentRequestValidate(t *testing.T) {
+	req := LedgerAdjustmentRequest{UserID: 1, TaAssetID: "btc", Amount: -500, Reason: "double credit", TicketRef: "OPS-12", Operator: "alice"}
+	assert.NoError(t, req.Validate())
+
+	zero := req
+	zero.Amount = 0
+	assert.Equal(t, InvalidAdjustmentAmountError, zero.Validate())
+
+	noTicket := req
+	noTicket.TicketRef = ""
+	assert.Equal(t, MissingAdjustmentReasonError, noTicket.Validate())
+
+	noReason := req
+	noReason.Reason = ""
+	assert.Equal(t, MissingAdjustmentReasonError, noReason.Validate())
+
+	noOperator := req
+	noOperator.Operator = ""
+	assert.Equal(t, MissingAdjustmentOperatorError, noOperator.Validate())
+}
@@ -18,6 +18,15 @@ func (svc *LndhubService) CreateAsset(ctx context.Context, name string, tapdAsse
 	if err != nil {
 		return nil, err
 	}
+	// every asset gets its own set of hub owned accounts
+	err = svc.EnsureSystemAccounts(ctx, asset.TaAssetID)
+	if err != nil {
+		return nil, err
+	}
+	// amounts are shown in raw units until the decimal display can be read
+	if err := svc.RefreshAssetDecimalDisplay(ctx, asset); err != nil {
+		svc.Logger.Errorf("failed to read decimal display of asset %s: %v", asset.TaAssetID, err)
+	}
 	return asset, nil
 }
 
@@ -41,7 +50,7 @@ func (svc *LndhubService) FindAsset(ctx context.Context, assetId int64) (*models
 func (svc *LndhubService) FindAssetByName(ctx context.Context, assetName string) (*models.Asset, error) {
 	var asset models.Asset
 
-	err := svc.DB.NewSelect().Model(&asset).Where("name = ?", assetName).Limit(1).Scan(ctx)
+	err := svc.DB.NewSelect().Model(&asset).Where("asset_name = ?", assetName).Limit(1).Scan(ctx)
 	if err != nil {
 		return &asset, err
 	}
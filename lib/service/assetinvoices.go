@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/tapd"
+	"github.com/getsentry/sentry-go"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/uptrace/bun"
+)
+
+var (
+	InvalidAssetInvoiceAmountError = errors.New("asset invoice amount must be greater than zero")
+	AssetInvoiceBtcError           = errors.New("use a regular invoice for btc")
+)
+
+// EnsureUserAssetAccounts creates the incoming, current and outgoing accounts of a user for an asset if they do not exist yet
+func (svc *LndhubService) EnsureUserAssetAccounts(ctx context.Context, userId int64, assetId string) error {
+	return svc.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		count, err := tx.NewSelect().Model((*models.Account)(nil)).Where("user_id = ? AND ta_asset_id = ?", userId, assetId).Count(ctx)
+		if err != nil || count > 0 {
+			return err
+		}
+		for _, accountType := range []string{common.AccountTypeIncoming, common.AccountTypeCurrent, common.AccountTypeOutgoing} {
+			account := models.Account{UserID: userId, Type: accountType, TaAssetID: assetId}
+			if _, err := tx.NewInsert().Model(&account).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// IsAssetInvoiceUserError tells the errors of an asset invoice the user can act on apart from internal errors,
+// whose messages are not shown to the user
+func IsAssetInvoiceUserError(err error) bool {
+	for _, userErr := range []error{
+		InvalidAssetInvoiceAmountError,
+		AssetInvoiceBtcError,
+		tapd.AssetChannelsUnsupportedError,
+	} {
+		if errors.Is(err, userErr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (svc *LndhubService) assetChannelClient() (tapd.AssetChannelClient, error) {
+	if svc.AssetChannelClient == nil {
+		return nil, tapd.AssetChannelsUnsupportedError
+	}
+	return svc.AssetChannelClient, nil
+}
+
+// AddAssetInvoice creates a BOLT11 invoice that is paid in the given asset through an asset channel.
+// The invoice amount is the asset amount, once the invoice settles the user is credited that amount.
+func (svc *LndhubService) AddAssetInvoice(ctx context.Context, userId int64, assetId string, amount int64, memo string) (*models.Invoice, error) {
+	if assetId == "" || assetId == common.BTC_TA_ASSET_ID {
+		return nil, AssetInvoiceBtcError
+	}
+	if amount <= 0 {
+		return nil, InvalidAssetInvoiceAmountError
+	}
+	client, err := svc.assetChannelClient()
+	if err != nil {
+		return nil, err
+	}
+	err = svc.EnsureUserAssetAccounts(ctx, userId, assetId)
+	if err != nil {
+		return nil, err
+	}
+	expiry := time.Hour * 24 // invoice expires in 24h
+	invoice := models.Invoice{
+		Type:      common.InvoiceTypeIncoming,
+		UserID:    userId,
+		TaAssetID: assetId,
+		Amount:    amount,
+		Memo:      memo,
+		State:     common.InvoiceStateInitialized,
+		ExpiresAt: bun.NullTime{Time: time.Now().Add(expiry)},
+	}
+	// Save invoice - we save the invoice early to have a record in case the tapd call fails
+	_, err = svc.DB.NewInsert().Model(&invoice).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.AddInvoice(ctx, &tapd.AssetInvoiceRequest{
+		AssetID:     assetId,
+		AssetAmount: uint64(amount),
+		PeerPubkey:  svc.Config.AssetChannelPeerPubkey,
+		Memo:        memo,
+		Expiry:      int64(expiry.Seconds()),
+	})
+	if err != nil {
+		svc.Logger.Errorf("Error creating asset invoice: user_id:%v asset:%s error: %v", userId, assetId, err)
+		return nil, err
+	}
+	invoice.PaymentRequest = result.InvoiceResult.PaymentRequest
+	invoice.RHash = hex.EncodeToString(result.InvoiceResult.RHash)
+	invoice.AddIndex = result.InvoiceResult.AddIndex
+	invoice.DestinationPubkeyHex = svc.LndClient.GetMainPubkey() // Our node pubkey for incoming invoices
+	invoice.State = common.InvoiceStateOpen
+	_, err = svc.DB.NewUpdate().Model(&invoice).WherePK().Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// PayAssetInvoice pays a BOLT11 invoice with the user's asset balance. The quoted asset amount is
+// moved to the user's outgoing account before the payment and reverted if the payment fails.
+func (svc *LndhubService) PayAssetInvoice(ctx context.Context, userId int64, assetId string, paymentRequest string) (*SendPaymentResponse, error) {
+	if assetId == "" || assetId == common.BTC_TA_ASSET_ID {
+		return nil, AssetInvoiceBtcError
+	}
+	client, err := svc.assetChannelClient()
+	if err != nil {
+		return nil, err
+	}
+	payReq, err := svc.DecodePaymentRequest(ctx, paymentRequest)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := client.QuotePayment(ctx, &tapd.AssetPaymentQuoteRequest{
+		AssetID:        assetId,
+		PaymentRequest: paymentRequest,
+		PeerPubkey:     svc.Config.AssetChannelPeerPubkey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	debitAccount, err := svc.AccountFor(ctx, common.AccountTypeCurrent, assetId, userId)
+	if err != nil {
+		return nil, fmt.Errorf("could not find current account user_id:%d asset:%s: %w", userId, assetId, err)
+	}
+	creditAccount, err := svc.AccountFor(ctx, common.AccountTypeOutgoing, assetId, userId)
+	if err != nil {
+		return nil, fmt.Errorf("could not find outgoing account user_id:%d asset:%s: %w", userId, assetId, err)
+	}
+	invoice := &models.Invoice{
+		Type:                 common.InvoiceTypeOutgoing,
+		UserID:               userId,
+		TaAssetID:            assetId,
+		PaymentRequest:       paymentRequest,
+		RHash:                payReq.PaymentHash,
+		Amount:               int64(quote.AssetAmount),
+		State:                common.InvoiceStateInitialized,
+		DestinationPubkeyHex: payReq.Destination,
+		DescriptionHash:      payReq.DescriptionHash,
+		Memo:                 payReq.Description,
+		ExpiresAt:            bun.NullTime{Time: time.Unix(payReq.Timestamp, 0).Add(time.Duration(payReq.Expiry) * time.Second)},
+	}
+	entry := models.TransactionEntry{
+		UserID:          userId,
+		CreditAccountID: creditAccount.ID,
+		DebitAccountID:  debitAccount.ID,
+		Amount:          invoice.Amount,
+		TaAssetID:       assetId,
+		EntryType:       models.EntryTypeOutgoing,
+	}
+	// The DB constraints make sure the user actually has enough balance for the transaction
+	err = svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(invoice).Exec(ctx); err != nil {
+			return err
+		}
+		entry.InvoiceID = invoice.ID
+		_, err := tx.NewInsert().Model(&entry).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		svc.Logger.Errorf("Could not insert asset payment user_id:%v asset:%s: %v", userId, assetId, err)
+		return nil, err
+	}
+
+	// Here we start using context.Background because we want to complete these calls
+	// regardless of if the request's context is canceled or not.
+	result, err := client.SendPayment(context.Background(), &tapd.AssetPaymentRequest{
+		AssetID:        assetId,
+		PaymentRequest: paymentRequest,
+		PeerPubkey:     quote.PeerPubkey,
+		RfqID:          quote.ID,
+		FeeLimitSat:    svc.Config.MaxFeeAmount,
+	})
+	if err == nil && result.Payment.Status != lnrpc.Payment_SUCCEEDED {
+		err = fmt.Errorf("asset payment failed: %s", result.Payment.FailureReason.String())
+	}
+	if err != nil {
+		svc.HandleFailedPayment(context.Background(), invoice, entry, err)
+		return nil, err
+	}
+
+	// refund the part of the quote that was not spent
+	if result.AssetAmount < quote.AssetAmount {
+		err = svc.refundUnspentQuote(context.Background(), invoice, entry, int64(quote.AssetAmount-result.AssetAmount))
+		if err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("Could not refund unspent asset quote user_id:%v invoice_id:%v: %v", userId, invoice.ID, err)
+		} else {
+			invoice.Amount = int64(result.AssetAmount)
+		}
+	}
+	invoice.Preimage = result.Payment.PaymentPreimage
+	err = svc.HandleSuccessfulPayment(context.Background(), invoice, entry)
+	return &SendPaymentResponse{
+		PaymentPreimageStr: result.Payment.PaymentPreimage,
+		PaymentHashStr:     result.Payment.PaymentHash,
+		PaymentRoute:       &Route{TotalAmt: invoice.Amount},
+		TransactionEntry:   &entry,
+		Invoice:            invoice,
+	}, err
+}
+
+func (svc *LndhubService) refundUnspentQuote(ctx context.Context, invoice *models.Invoice, entry models.TransactionEntry, amount int64) error {
+	refund := models.TransactionEntry{
+		UserID:          invoice.UserID,
+		InvoiceID:       invoice.ID,
+		CreditAccountID: entry.DebitAccountID,
+		DebitAccountID:  entry.CreditAccountID,
+		Amount:          amount,
+		TaAssetID:       invoice.TaAssetID,
+		ParentID:        entry.ID,
+		EntryType:       models.EntryTypeOutgoingReversal,
+	}
+	_, err := svc.DB.NewInsert().Model(&refund).Exec(ctx)
+	return err
+}
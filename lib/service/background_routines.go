@@ -7,9 +7,13 @@ import (
 
 	//"time"
 	//"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
 	"github.com/nbd-wtf/go-nostr"
 	//"github.com/nbd-wtf/go-nostr/nip19"
 )
+
+// wait before reconnecting a failed tapd receive or send subscription
+const tapdSubscriptionRetryDelay = 10 * time.Second
 func (svc *LndhubService) StartRelayRoutine(ctx context.Context, uri string, lastSeen int64) (err error) {
 	// TODO what is the proper way to not have a timeout on the context?
 	bgCtx, cancel := context.WithTimeout(context.Background(), 180*time.Second)
@@ -69,13 +73,20 @@ func (svc *LndhubService) StartReceiveSubscription(ctx context.Context) (err err
 		// TODO populate - apply sentry and rabbit mq
 		return errors.New("RabbitMQ not implemented")
 	} else {
-		err = svc.TapdReceiveSubscription(ctx)
-		if err != nil && err != context.Canceled {
-			// in case of an error in this routine, we want to restart
-			return err
+		for {
+			err = svc.TapdReceiveSubscription(ctx)
+			if err == nil || err == context.Canceled {
+				return nil
+			}
+			// reconnect, receives completed in the meantime are caught up on connect
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("tapd receive subscription failed, reconnecting in %v: %v", tapdSubscriptionRetryDelay, err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(tapdSubscriptionRetryDelay):
+			}
 		}
-
-		return nil
 	}
 }
 
@@ -116,12 +127,19 @@ func (svc *LndhubService) StartSendSubscription(ctx context.Context) (err error)
 		// TODO populate - apply sentry and rabbit mq
 		return errors.New("RabbitMQ not implemented")
 	} else {
-		err = svc.TapdSendSubscription(ctx)
-		if err != nil && err != context.Canceled {
-			// in case of an error in this routine, we want to restart
-			return err
+		for {
+			err = svc.TapdSendSubscription(ctx)
+			if err == nil || err == context.Canceled {
+				return nil
+			}
+			// reconnect, with a tapd cluster this lands on the new active node
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("tapd send subscription failed, reconnecting in %v: %v", tapdSubscriptionRetryDelay, err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(tapdSubscriptionRetryDelay):
+			}
 		}
-
-		return nil
 	}
 }
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/lightninglabs/taproot-assets/taprpc/universerpc"
+	"github.com/uptrace/bun"
+)
+
+// tapd refuses to burn without this exact confirmation text
+const burnConfirmationText = "assets will be destroyed"
+
+var (
+	InvalidBurnAmountError = errors.New("burn amount must be greater than zero")
+	BurnBtcError           = errors.New("btc can not be burned")
+)
+
+type BurnAssetRequest struct {
+	// the user whose current balance is burned, 0 burns hub owned units from the treasury
+	UserID    int64
+	TaAssetID string
+	Amount    int64
+	Note      string
+}
+
+func (req *BurnAssetRequest) Validate() error {
+	if req.TaAssetID == common.BTC_TA_ASSET_ID {
+		return BurnBtcError
+	}
+	if req.Amount <= 0 {
+		return InvalidBurnAmountError
+	}
+	return nil
+}
+
+// AssetSupply : issued units come from the universe, burned units from the recorded burns
+type AssetSupply struct {
+	TaAssetID   string `json:"asset_id"`
+	AssetName   string `json:"asset_name"`
+	Issued      int64  `json:"issued"`
+	Burned      int64  `json:"burned"`
+	Circulating int64  `json:"circulating"`
+}
+
+// burnOutput extracts the anchor outpoint, anchor txid and hex encoded proof of the burn output
+func burnOutput(resp *taprpc.BurnAssetResponse) (outpoint string, txid string, proof string) {
+	if resp.BurnProof == nil || resp.BurnProof.Asset == nil {
+		return "", "", ""
+	}
+	burned := resp.BurnProof.Asset
+	if burned.ChainAnchor != nil {
+		outpoint = burned.ChainAnchor.AnchorOutpoint
+		txid, _, _ = strings.Cut(outpoint, ":")
+	}
+	if resp.BurnTransfer == nil {
+		return outpoint, txid, ""
+	}
+	for _, output := range resp.BurnTransfer.Outputs {
+		if hex.EncodeToString(output.ScriptKey) == hex.EncodeToString(burned.ScriptKey) {
+			return outpoint, txid, hex.EncodeToString(output.NewProofBlob)
+		}
+	}
+	return outpoint, txid, ""
+}
+
+const (
+	// burns still pending after this delay lost their outcome, they are reconciled with tapd
+	pendingBurnReconcileDelay = 10 * time.Minute
+	// a pending burn without a tapd transfer after this delay never reached tapd and is refunded
+	pendingBurnRefundDelay = time.Hour
+)
+
+// BurnAsset destroys units on tapd and books them from the user's current account (or the treasury)
+// to the burned system account. The debit is committed with a pending burn before tapd is called, so
+// a user can not burn more than their balance. A failed burn is refunded, a burn whose outcome can
+// not be recorded stays pending until ReconcilePendingBurns finds its transfer.
+func (svc *LndhubService) BurnAsset(ctx context.Context, req BurnAssetRequest) (*models.AssetBurn, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	count, err := svc.DB.NewSelect().Model((*models.Asset)(nil)).Where("ta_asset_id = ?", req.TaAssetID).Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("asset %s not found: %w", req.TaAssetID, sql.ErrNoRows)
+	}
+	burn, err := svc.claimBurn(ctx, req)
+	if err != nil {
+		svc.Logger.Errorf("Could not burn asset user_id:%v asset:%s amount:%d error %v", req.UserID, req.TaAssetID, req.Amount, err)
+		return nil, err
+	}
+	// Here we start using context.Background because we want to record the outcome
+	// regardless of if the request's context is canceled or not.
+	resp, burnErr := svc.TapdClient.BurnAsset(context.Background(), &taprpc.BurnAssetRequest{
+		Asset:            &taprpc.BurnAssetRequest_AssetIdStr{AssetIdStr: req.TaAssetID},
+		AmountToBurn:     uint64(req.Amount),
+		ConfirmationText: burnConfirmationText,
+	})
+	outpoint, txid, proof := "", "", ""
+	if burnErr != nil {
+		burnErr = fmt.Errorf("tapd burn failed: %w", burnErr)
+	} else {
+		outpoint, txid, proof = burnOutput(resp)
+	}
+	burn, err = svc.recordBurn(context.Background(), burn.ID, outpoint, txid, proof, burnErr)
+	if err != nil {
+		return nil, err
+	}
+	if burn.State == models.AssetBurnStateFailed {
+		return nil, burnErr
+	}
+	return burn, nil
+}
+
+// claimBurn debits the burned amount and inserts the burn in the pending state
+func (svc *LndhubService) claimBurn(ctx context.Context, req BurnAssetRequest) (*models.AssetBurn, error) {
+	burn := &models.AssetBurn{
+		TaAssetID: req.TaAssetID,
+		UserID:    req.UserID,
+		Amount:    req.Amount,
+		Note:      req.Note,
+		State:     models.AssetBurnStatePending,
+	}
+	err := svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		var debitAccount models.Account
+		var err error
+		if req.UserID > 0 {
+			debitAccount, err = svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, req.TaAssetID, req.UserID)
+			if err != nil {
+				return fmt.Errorf("could not find current account for user_id:%d asset:%s: %w", req.UserID, req.TaAssetID, err)
+			}
+		} else {
+			debitAccount, err = svc.SystemAccountForInTx(ctx, tx, common.AccountTypeTreasury, req.TaAssetID)
+			if err != nil {
+				return err
+			}
+		}
+		burnedAccount, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeBurned, req.TaAssetID)
+		if err != nil {
+			return err
+		}
+		entry := models.TransactionEntry{
+			UserID:          req.UserID,
+			TaAssetID:       req.TaAssetID,
+			DebitAccountID:  debitAccount.ID,
+			CreditAccountID: burnedAccount.ID,
+			Amount:          req.Amount,
+			EntryType:       models.EntryTypeBurn,
+			CreatedAt:       time.Now(),
+		}
+		// the check_balance trigger rejects burns above the user's balance before tapd is called
+		if _, err := tx.NewInsert().Model(&entry).Exec(ctx); err != nil {
+			return err
+		}
+		burn.TransactionEntryID = entry.ID
+		_, err = tx.NewInsert().Model(burn).Exec(ctx)
+		return err
+	})
+	return burn, err
+}
+
+// recordBurn books the outcome of a pending burn, the burn output made by tapd or the error of the
+// BurnAsset call. A burn that was recorded in the meantime is returned as it is.
+func (svc *LndhubService) recordBurn(ctx context.Context, burnId int64, outpoint, txid, proof string, burnErr error) (*models.AssetBurn, error) {
+	burn := &models.AssetBurn{}
+	err := svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewSelect().Model(burn).Where("id = ?", burnId).For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+		if burn.State != models.AssetBurnStatePending {
+			return nil
+		}
+		entry := models.TransactionEntry{}
+		if err := tx.NewSelect().Model(&entry).Where("id = ?", burn.TransactionEntryID).Scan(ctx); err != nil {
+			return err
+		}
+		if burnErr != nil {
+			burn.State = models.AssetBurnStateFailed
+			burn.Error = burnErr.Error()
+			reversal := models.TransactionEntry{
+				UserID:          entry.UserID,
+				ParentID:        entry.ID,
+				TaAssetID:       entry.TaAssetID,
+				DebitAccountID:  entry.CreditAccountID,
+				CreditAccountID: entry.DebitAccountID,
+				Amount:          entry.Amount,
+				EntryType:       models.EntryTypeOutgoingReversal,
+				CreatedAt:       time.Now(),
+			}
+			if _, err := tx.NewInsert().Model(&reversal).Exec(ctx); err != nil {
+				return err
+			}
+		} else {
+			burn.State = models.AssetBurnStateBurned
+			burn.Outpoint, burn.AnchorTxid, burn.BurnProof = outpoint, txid, proof
+			entry.Outpoint = outpoint
+			if _, err := tx.NewUpdate().Model(&entry).Column("outpoint").WherePK().Exec(ctx); err != nil {
+				return err
+			}
+		}
+		_, err := tx.NewUpdate().Model(burn).Column("state", "error", "outpoint", "anchor_txid", "burn_proof").WherePK().Exec(ctx)
+		return err
+	})
+	if err != nil {
+		// units burned on tapd stay booked to the burned account, ReconcilePendingBurns records the burn
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("Could not record burn id:%d outpoint:%s burn error:%v error %v", burnId, outpoint, burnErr, err)
+		return nil, err
+	}
+	if burnErr != nil {
+		svc.Logger.Errorf("Burn id:%d user_id:%v asset:%s amount:%d failed and was refunded: %v", burn.ID, burn.UserID, burn.TaAssetID, burn.Amount, burnErr)
+		return burn, nil
+	}
+	svc.Logger.Infof("Burned asset id:%d user_id:%v asset:%s amount:%d outpoint:%s", burn.ID, burn.UserID, burn.TaAssetID, burn.Amount, burn.Outpoint)
+	return burn, nil
+}
+
+// burnTransfer finds the tapd transfer of a burn, a transfer spending the burned asset with an output
+// of the burned amount that is not the anchor of another burn or send batch
+func burnTransfer(burn *models.AssetBurn, transfers []*taprpc.AssetTransfer, recordedTxids map[string]bool) *taprpc.TransferOutput {
+	for _, transfer := range transfers {
+		txid := transferAnchorTxid(transfer)
+		if txid == "" || recordedTxids[txid] || transfer.TransferTimestamp < burn.CreatedAt.Add(-time.Minute).Unix() {
+			continue
+		}
+		spendsAsset := false
+		for _, input := range transfer.Inputs {
+			if hex.EncodeToString(input.AssetId) == burn.TaAssetID {
+				spendsAsset = true
+				break
+			}
+		}
+		if !spendsAsset {
+			continue
+		}
+		for _, output := range transfer.Outputs {
+			if int64(output.Amount) == burn.Amount && output.Anchor != nil {
+				return output
+			}
+		}
+	}
+	return nil
+}
+
+// ReconcilePendingBurns books the burns whose outcome was lost between the BurnAsset call and its
+// recording. A burn with a matching transfer on tapd is booked as burned, a burn tapd has no transfer
+// for after pendingBurnRefundDelay is refunded. Burns are never sent to tapd again.
+func (svc *LndhubService) ReconcilePendingBurns(ctx context.Context) error {
+	burns := []models.AssetBurn{}
+	err := svc.DB.NewSelect().Model(&burns).
+		Where("state = ? AND created_at < ?", models.AssetBurnStatePending, time.Now().Add(-pendingBurnReconcileDelay)).
+		OrderExpr("id ASC").
+		Scan(ctx)
+	if err != nil || len(burns) == 0 {
+		return err
+	}
+	resp, err := svc.TapdClient.ListTransfers(ctx, &taprpc.ListTransfersRequest{})
+	if err != nil {
+		return err
+	}
+	txids := []string{}
+	err = svc.DB.NewSelect().Model((*models.AssetBurn)(nil)).Column("anchor_txid").Where("anchor_txid IS NOT NULL").Scan(ctx, &txids)
+	if err != nil {
+		return err
+	}
+	batchTxids := []string{}
+	err = svc.DB.NewSelect().Model((*models.SendBatch)(nil)).Column("anchor_txid").Where("anchor_txid IS NOT NULL").Scan(ctx, &batchTxids)
+	if err != nil {
+		return err
+	}
+	recordedTxids := map[string]bool{}
+	for _, txid := range append(txids, batchTxids...) {
+		recordedTxids[txid] = true
+	}
+	for i := range burns {
+		burn := &burns[i]
+		output := burnTransfer(burn, resp.Transfers, recordedTxids)
+		outpoint, txid, proof := "", "", ""
+		var burnErr error
+		switch {
+		case output != nil:
+			outpoint = output.Anchor.Outpoint
+			txid, _, _ = strings.Cut(outpoint, ":")
+			proof = hex.EncodeToString(output.NewProofBlob)
+			recordedTxids[txid] = true
+		case time.Since(burn.CreatedAt) > pendingBurnRefundDelay:
+			burnErr = fmt.Errorf("no tapd transfer found for burn %d", burn.ID)
+		default:
+			continue
+		}
+		svc.Logger.Infof("Reconciling burn id:%d asset:%s outpoint:%s", burn.ID, burn.TaAssetID, outpoint)
+		if _, err := svc.recordBurn(ctx, burn.ID, outpoint, txid, proof, burnErr); err != nil {
+			svc.Logger.Errorf("Could not reconcile burn id:%d: %v", burn.ID, err)
+		}
+	}
+	return nil
+}
+
+// StartBurnReconcileRoutine reconciles the pending burns every pendingBurnReconcileDelay
+func (svc *LndhubService) StartBurnReconcileRoutine(ctx context.Context) {
+	ticker := time.NewTicker(pendingBurnReconcileDelay)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := svc.ReconcilePendingBurns(ctx); err != nil {
+				sentry.CaptureException(err)
+				svc.Logger.Errorf("Failed to reconcile pending burns: %v", err)
+			}
+		}
+	}
+}
+
+// AssetSupplyFor reports issued, burned and circulating units of an asset. The issued supply is
+// taken from the universe stats, falling back to the units minted through the hub.
+func (svc *LndhubService) AssetSupplyFor(ctx context.Context, assetId string) (*AssetSupply, error) {
+	asset := models.Asset{}
+	err := svc.DB.NewSelect().Model(&asset).Where("ta_asset_id = ?", assetId).Limit(1).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	supply := &AssetSupply{TaAssetID: asset.TaAssetID, AssetName: asset.AssetName}
+	supply.Issued, err = svc.issuedSupplyFor(ctx, assetId)
+	if err != nil {
+		return nil, err
+	}
+	err = svc.DB.NewSelect().Model((*models.AssetBurn)(nil)).ColumnExpr("coalesce(sum(amount), 0)").
+		Where("ta_asset_id = ? AND state = ?", assetId, models.AssetBurnStateBurned).
+		Scan(ctx, &supply.Burned)
+	if err != nil {
+		return nil, err
+	}
+	supply.Circulating = supply.Issued - supply.Burned
+	return supply, nil
+}
+
+func (svc *LndhubService) issuedSupplyFor(ctx context.Context, assetId string) (int64, error) {
+	assetIdBytes, err := hex.DecodeString(assetId)
+	if err == nil {
+		stats, err := svc.TapdClient.GetAssetStats(ctx, &universerpc.AssetStatsQuery{AssetIdFilter: assetIdBytes})
+		if err == nil && len(stats.AssetStats) > 0 && stats.AssetStats[0].Asset != nil {
+			return stats.AssetStats[0].Asset.TotalSupply, nil
+		}
+		if err != nil {
+			svc.Logger.Errorf("Failed to fetch universe stats for asset %s: %v", assetId, err)
+		}
+	}
+	var issued int64
+	err = svc.DB.NewSelect().Model((*models.MintBatchAsset)(nil)).ColumnExpr("coalesce(sum(amount), 0)").Where("ta_asset_id = ?", assetId).Scan(ctx, &issued)
+	return issued, err
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBurnAssetRequestValidate(t *testing.T) {
+	valid := BurnAssetRequest{TaAssetID: "abcd", Amount: 10}
+	assert.NoError(t, valid.Validate())
+
+	btc := BurnAssetRequest{TaAssetID: common.BTC_TA_ASSET_ID, Amount: 10}
+	assert.ErrorIs(t, btc.Validate(), BurnBtcError)
+
+	zero := BurnAssetRequest{TaAssetID: "abcd"}
+	assert.ErrorIs(t, zero.Validate(), InvalidBurnAmountError)
+}
+
+func TestBurnOutput(t *testing.T) {
+	resp := &taprpc.BurnAssetResponse{
+		BurnTransfer: &taprpc.AssetTransfer{
+			Outputs: []*taprpc.TransferOutput{
+				{ScriptKey: []byte{1}, NewProofBlob: []byte{0xaa}},
+				{ScriptKey: []byte{2}, NewProofBlob: []byte{0xbb}},
+			},
+		},
+		BurnProof: &taprpc.DecodedProof{
+			Asset: &taprpc.Asset{
+				ScriptKey:   []byte{2},
+				ChainAnchor: &taprpc.AnchorInfo{AnchorOutpoint: "burntx:1"},
+			},
+		},
+	}
+	outpoint, txid, proof := burnOutput(resp)
+	assert.Equal(t, "burntx:1", outpoint)
+	assert.Equal(t, "burntx", txid)
+	assert.Equal(t, "bb", proof)
+
+	outpoint, txid, proof = burnOutput(&taprpc.BurnAssetResponse{})
+	assert.Empty(t, outpoint+txid+proof)
+}
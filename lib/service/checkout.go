@@ -0,0 +1,611 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/uptrace/bun"
+)
+
+const (
+	// CheckoutPaidEvent is the event of the webhook sent when a checkout is paid
+	CheckoutPaidEvent = "checkout.paid"
+	// CheckoutTimestampHeader and CheckoutSignatureHeader carry the signature of a checkout webhook,
+	// the hex HMAC-SHA256 of "<timestamp>.<body>" with the checkout's secret or CHECKOUT_WEBHOOK_SECRET
+	CheckoutTimestampHeader = "X-Tahub-Timestamp"
+	CheckoutSignatureHeader = "X-Tahub-Signature"
+	// MaxCheckoutWebhookAttempts is how often a webhook is sent before it is given up on
+	MaxCheckoutWebhookAttempts = 10
+	// checkoutWebhookTimeout bounds the first delivery after a payment, ProcessCheckouts only retries
+	// webhooks of checkouts paid before that
+	checkoutWebhookTimeout = 30 * time.Second
+)
+
+var (
+	CheckoutNotFoundError          = errors.New("checkout not found")
+	CheckoutAmountError            = errors.New("a checkout needs either an amount or a fiat amount")
+	InvalidFiatAmountError         = errors.New("fiat amount must be a positive decimal number")
+	FiatCheckoutsUnavailableError  = errors.New("fiat priced checkouts require a price oracle")
+	UnknownCheckoutAssetError      = errors.New("unknown asset")
+	InvalidWebhookUrlError         = errors.New("webhook url must be an absolute http(s) url")
+	PrivateWebhookHostError        = errors.New("webhook host must resolve to public addresses")
+	CheckoutWebhooksDisabledError  = errors.New("checkout webhooks are not enabled on this hub")
+	CheckoutOrderRefTooLongError   = errors.New("order reference is too long")
+	InvalidCheckoutStateQueryError = errors.New("state must be open, paid or expired")
+)
+
+// MaxCheckoutOrderRefLength is the longest order reference a merchant can attach to a checkout
+const MaxCheckoutOrderRefLength = 128
+
+var checkoutWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// merchantWebhookClient only connects to public addresses, so merchant webhooks cannot reach the
+// hub's own network even when their host resolves differently than at creation
+var merchantWebhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 10 * time.Second, Control: publicDialControl}).DialContext,
+	},
+}
+
+// publicIP tells addresses on the internet from loopback, private, link-local and shared ones
+func publicIP(ip net.IP) bool {
+	cgnat := net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || cgnat.Contains(ip))
+}
+
+func publicDialControl(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%w: %s", PrivateWebhookHostError, host)
+	}
+	return nil
+}
+
+type CheckoutRequest struct {
+	UserID  int64
+	AssetID string
+	// decimal amount of the asset, or empty when the checkout is priced in fiat
+	Amount string
+	// decimal amount in PRICE_CURRENCY, converted at the latest price of the asset
+	FiatAmount string
+	Memo       string
+	// the merchant's own reference of the order
+	OrderRef string
+	// overrides CHECKOUT_WEBHOOK_URL
+	WebhookUrl string
+}
+
+// CheckoutWebhookPayload : body of the webhook sent when a checkout is paid
+type CheckoutWebhookPayload struct {
+	Event    string           `json:"event"`
+	Checkout *models.Checkout `json:"checkout"`
+}
+
+// FiatUnits converts a fiat amount to units of an asset at the price of one unit in its decimal
+// display, rounded up so the merchant receives at least the fiat amount
+func FiatUnits(fiatAmount string, decimalDisplay uint32, price string) (int64, error) {
+	fiat, err := parsePrice(fiatAmount)
+	if err != nil || fiat.Sign() == 0 {
+		return 0, InvalidFiatAmountError
+	}
+	rat, err := parsePrice(price)
+	if err != nil {
+		return 0, err
+	}
+	if rat.Sign() == 0 {
+		return 0, SwapPriceUnavailableError
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimalDisplay)), nil)
+	amount := new(big.Rat).Quo(fiat, rat)
+	amount.Mul(amount, new(big.Rat).SetInt(scale))
+	units, rest := new(big.Int).QuoRem(amount.Num(), amount.Denom(), new(big.Int))
+	if rest.Sign() > 0 {
+		units.Add(units, big.NewInt(1))
+	}
+	if !units.IsInt64() {
+		return 0, UnrepresentableAssetAmountError
+	}
+	return units.Int64(), nil
+}
+
+// CheckoutWebhookSignature signs the body of a checkout webhook sent at timestamp (unix seconds)
+func CheckoutWebhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateCheckout opens a checkout for a merchant. Asset checkouts get a tap address of their own
+// so the receive identifies the checkout, btc checkouts an LN invoice.
+func (svc *LndhubService) CreateCheckout(ctx context.Context, req CheckoutRequest) (*models.Checkout, error) {
+	if len([]rune(req.Memo)) > MaxTransferMemoLength {
+		return nil, TransferMemoTooLongError
+	}
+	if len(req.OrderRef) > MaxCheckoutOrderRefLength {
+		return nil, CheckoutOrderRefTooLongError
+	}
+	exists, err := svc.DB.NewSelect().Model((*models.Asset)(nil)).Where("ta_asset_id = ?", req.AssetID).Exists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, UnknownCheckoutAssetError
+	}
+	webhookUrl, err := svc.checkoutWebhookUrl(ctx, req.WebhookUrl)
+	if err != nil {
+		return nil, err
+	}
+	checkout := &models.Checkout{
+		UserID:     req.UserID,
+		TaAssetID:  req.AssetID,
+		Memo:       req.Memo,
+		OrderRef:   req.OrderRef,
+		State:      models.CheckoutStateOpen,
+		WebhookUrl: webhookUrl,
+		ExpiresAt:  time.Now().Add(time.Duration(svc.Config.CheckoutExpiry) * time.Second),
+	}
+	if req.WebhookUrl != "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		checkout.WebhookSecret = hex.EncodeToString(secret)
+	}
+	switch {
+	case req.Amount != "" && req.FiatAmount != "":
+		return nil, CheckoutAmountError
+	case req.Amount != "":
+		units, err := svc.ParseAssetAmountFor(ctx, req.AssetID, req.Amount)
+		if err != nil {
+			return nil, err
+		}
+		if units > math.MaxInt64 {
+			return nil, UnrepresentableAssetAmountError
+		}
+		checkout.Amount = int64(units)
+	case req.FiatAmount != "":
+		if err := svc.priceCheckout(ctx, checkout, req.FiatAmount); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, CheckoutAmountError
+	}
+
+	if checkout.TaAssetID == common.BTC_TA_ASSET_ID {
+		invoice, errResp := svc.AddIncomingInvoice(ctx, checkout.UserID, checkout.Amount, checkout.Memo, "")
+		if errResp != nil {
+			return nil, fmt.Errorf("could not create checkout invoice for user_id:%d: %s", checkout.UserID, errResp.Message)
+		}
+		checkout.InvoiceID = invoice.ID
+		checkout.PaymentRequest = invoice.PaymentRequest
+	} else {
+		addr, err := svc.newCheckoutAddress(ctx, checkout)
+		if err != nil {
+			return nil, err
+		}
+		checkout.Address = addr
+	}
+	if _, err := svc.DB.NewInsert().Model(checkout).Exec(ctx); err != nil {
+		return nil, err
+	}
+	svc.Logger.Infof("Opened checkout id:%d user_id:%d amount:%d asset:%s", checkout.ID, checkout.UserID, checkout.Amount, checkout.TaAssetID)
+	return checkout, nil
+}
+
+// checkoutWebhookUrl is the webhook a new checkout reports to, the merchant's own or the hub default
+func (svc *LndhubService) checkoutWebhookUrl(ctx context.Context, requested string) (string, error) {
+	if requested == "" {
+		if svc.Config.CheckoutWebhookSecret == "" {
+			return "", nil
+		}
+		return svc.Config.CheckoutWebhookUrl, nil
+	}
+	if svc.Config.CheckoutWebhookSecret == "" {
+		return "", CheckoutWebhooksDisabledError
+	}
+	parsed, err := url.ParseRequestURI(requested)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return "", InvalidWebhookUrlError
+	}
+	if svc.webhookHostAllowed(parsed.Hostname()) {
+		return requested, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, parsed.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return "", InvalidWebhookUrlError
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return "", PrivateWebhookHostError
+		}
+	}
+	return requested, nil
+}
+
+// webhookHostAllowed tells the merchant webhook hosts that may resolve to private addresses
+func (svc *LndhubService) webhookHostAllowed(host string) bool {
+	for _, allowed := range svc.Config.CheckoutWebhookAllowedHosts {
+		if strings.EqualFold(strings.TrimSpace(allowed), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// priceCheckout sets the amount of a fiat priced checkout at the latest price of its asset
+func (svc *LndhubService) priceCheckout(ctx context.Context, checkout *models.Checkout, fiatAmount string) error {
+	if svc.PriceOracle == nil {
+		return FiatCheckoutsUnavailableError
+	}
+	prices, err := svc.LatestPrices(ctx)
+	if err != nil {
+		return err
+	}
+	price, err := svc.swapPrice(prices, checkout.TaAssetID)
+	if err != nil {
+		return err
+	}
+	decimalDisplay, err := svc.AssetDecimalDisplay(ctx, checkout.TaAssetID)
+	if err != nil {
+		return err
+	}
+	units, err := FiatUnits(fiatAmount, decimalDisplay, price.Price)
+	if err != nil {
+		return err
+	}
+	if units <= 0 {
+		return InvalidFiatAmountError
+	}
+	checkout.Amount = units
+	checkout.FiatAmount = fiatAmount
+	checkout.FiatCurrency = svc.Config.PriceCurrency
+	checkout.FiatPrice = price.Price
+	return nil
+}
+
+// newCheckoutAddress creates a tap address of the merchant for the exact amount of the checkout.
+// The address is never handed out by FetchOrCreateAssetAddr, so a receive to it pays the checkout.
+func (svc *LndhubService) newCheckoutAddress(ctx context.Context, checkout *models.Checkout) (string, error) {
+	decoded, err := hex.DecodeString(checkout.TaAssetID)
+	if err != nil {
+		return "", UnknownCheckoutAssetError
+	}
+	if err := svc.EnsureUserAssetAccounts(ctx, checkout.UserID, checkout.TaAssetID); err != nil {
+		return "", err
+	}
+	newAddr, err := svc.TapdClient.NewAddress(ctx, &taprpc.NewAddrRequest{
+		AssetId: decoded,
+		Amt:     uint64(checkout.Amount),
+	})
+	if err != nil {
+		return "", fmt.Errorf("tapd could not create checkout address for user_id:%d: %w", checkout.UserID, err)
+	}
+	if _, err := svc.CreateAddress(ctx, newAddr.Encoded, uint64(checkout.UserID), checkout.TaAssetID, uint64(checkout.Amount), false); err != nil {
+		return "", err
+	}
+	return newAddr.Encoded, nil
+}
+
+// isCheckoutAddress tells whether an address was created for a checkout
+func (svc *LndhubService) isCheckoutAddress(ctx context.Context, addr string) (bool, error) {
+	return svc.DB.NewSelect().Model((*models.Checkout)(nil)).Where("address = ?", addr).Exists(ctx)
+}
+
+// CheckoutFor returns a checkout of the merchant
+func (svc *LndhubService) CheckoutFor(ctx context.Context, userId int64, checkoutId int64) (*models.Checkout, error) {
+	if err := svc.ExpireCheckouts(ctx, userId); err != nil {
+		return nil, err
+	}
+	checkout := &models.Checkout{}
+	err := svc.DB.NewSelect().Model(checkout).Where("id = ? AND user_id = ?", checkoutId, userId).Limit(1).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, CheckoutNotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+	return checkout, nil
+}
+
+// CheckoutsFor returns the latest checkouts of the merchant, all of them when state is empty
+func (svc *LndhubService) CheckoutsFor(ctx context.Context, userId int64, state string) ([]models.Checkout, error) {
+	switch state {
+	case "", models.CheckoutStateOpen, models.CheckoutStatePaid, models.CheckoutStateExpired:
+	default:
+		return nil, InvalidCheckoutStateQueryError
+	}
+	if err := svc.ExpireCheckouts(ctx, userId); err != nil {
+		return nil, err
+	}
+	checkouts := []models.Checkout{}
+	query := svc.DB.NewSelect().Model(&checkouts).Where("user_id = ?", userId)
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+	err := query.OrderExpr("id DESC").Limit(100).Scan(ctx)
+	return checkouts, err
+}
+
+// ExpireCheckouts marks the open checkouts past their expiry as expired, those of one merchant or
+// all of them when userId is 0. A payment that still arrives marks an expired checkout paid.
+func (svc *LndhubService) ExpireCheckouts(ctx context.Context, userId int64) error {
+	query := svc.DB.NewUpdate().Model((*models.Checkout)(nil)).
+		Set("state = ?", models.CheckoutStateExpired).
+		Set("updated_at = ?", time.Now()).
+		Where("state = ? AND expires_at <= ?", models.CheckoutStateOpen, time.Now())
+	if userId != 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+	if _, err := query.Exec(ctx); err != nil {
+		svc.Logger.Errorf("Could not expire checkouts: %v", err)
+		return err
+	}
+	return nil
+}
+
+// CompleteCheckoutForAddress marks the checkout of an address paid once its receive was credited
+// with entryId. It returns nil when the address does not belong to an unpaid checkout.
+func (svc *LndhubService) CompleteCheckoutForAddress(ctx context.Context, addr string, entryId int64) (*models.Checkout, error) {
+	return svc.completeCheckout(ctx, "address", addr, entryId)
+}
+
+// CompleteCheckoutForInvoice marks the checkout of a settled LN invoice paid. It returns nil when
+// the invoice does not belong to an unpaid checkout.
+func (svc *LndhubService) CompleteCheckoutForInvoice(ctx context.Context, invoiceId int64) (*models.Checkout, error) {
+	entry := models.TransactionEntry{}
+	err := svc.DB.NewSelect().Model(&entry).
+		Where("invoice_id = ? AND entry_type = ?", invoiceId, models.EntryTypeIncoming).
+		Limit(1).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return svc.completeCheckout(ctx, "invoice_id", invoiceId, entry.ID)
+}
+
+// completeCheckout marks the open or expired checkout whose column has value paid by entryId and lets
+// the merchant know. The webhook is sent in the background, so a slow webhook does not hold up the
+// receive or invoice subscription that paid the checkout.
+func (svc *LndhubService) completeCheckout(ctx context.Context, column string, value interface{}, entryId int64) (*models.Checkout, error) {
+	checkout := &models.Checkout{}
+	now := time.Now()
+	err := svc.DB.NewUpdate().Model(checkout).
+		Set("state = ?", models.CheckoutStatePaid).
+		Set("transaction_entry_id = ?", entryId).
+		Set("paid_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("? = ?", bun.Ident(column), value).
+		Where("state IN (?)", bun.In([]string{models.CheckoutStateOpen, models.CheckoutStateExpired})).
+		Returning("*").
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	svc.Logger.Infof("Checkout id:%d user_id:%d paid by transaction entry id:%d", checkout.ID, checkout.UserID, entryId)
+	if merchant, err := svc.FindUser(ctx, checkout.UserID); err == nil {
+		if amountText, err := svc.checkoutAmountText(ctx, checkout); err != nil {
+			svc.Logger.Errorf("Could not notify about paid checkout id:%d: %v", checkout.ID, err)
+		} else {
+			message := fmt.Sprintf("checkout %d paid: %s", checkout.ID, amountText)
+			if checkout.OrderRef != "" {
+				message += " order: " + checkout.OrderRef
+			}
+			_ = svc.SendNip4Notification(ctx, message, merchant.Pubkey)
+		}
+	}
+	delivery := *checkout
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), checkoutWebhookTimeout)
+		defer cancel()
+		svc.deliverCheckoutWebhook(ctx, &delivery)
+	}()
+	return checkout, nil
+}
+
+// deliverCheckoutWebhook posts the signed paid event of a checkout to its webhook and records the
+// attempt, undelivered webhooks are retried by ProcessCheckouts
+func (svc *LndhubService) deliverCheckoutWebhook(ctx context.Context, checkout *models.Checkout) {
+	if checkout.WebhookUrl == "" || svc.Config.CheckoutWebhookSecret == "" {
+		return
+	}
+	body, err := json.Marshal(&CheckoutWebhookPayload{Event: CheckoutPaidEvent, Checkout: checkout})
+	if err != nil {
+		svc.Logger.Error(err)
+		return
+	}
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, checkout.WebhookUrl, bytes.NewReader(body))
+	if err != nil {
+		svc.Logger.Errorf("Invalid webhook url of checkout id:%d: %v", checkout.ID, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(CheckoutTimestampHeader, strconv.FormatInt(timestamp, 10))
+	secret := checkout.WebhookSecret
+	if secret == "" {
+		secret = svc.Config.CheckoutWebhookSecret
+	}
+	req.Header.Set(CheckoutSignatureHeader, CheckoutWebhookSignature(secret, timestamp, body))
+	// the hub's own webhook may live in its network, merchant webhooks may not
+	client := merchantWebhookClient
+	if checkout.WebhookUrl == svc.Config.CheckoutWebhookUrl || svc.webhookHostAllowed(req.URL.Hostname()) {
+		client = checkoutWebhookClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		svc.Logger.Errorf("Checkout id:%d webhook failed: %v", checkout.ID, err)
+	} else {
+		defer resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			checkout.WebhookDeliveredAt = bun.NullTime{Time: time.Now()}
+		} else {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			svc.Logger.Errorf("Checkout id:%d webhook status code was %d, body: %s", checkout.ID, resp.StatusCode, msg)
+		}
+	}
+	checkout.WebhookAttempts++
+	_, err = svc.DB.NewUpdate().Model(checkout).Column("webhook_attempts", "webhook_delivered_at").WherePK().Exec(ctx)
+	if err != nil {
+		svc.Logger.Errorf("Could not record webhook attempt of checkout id:%d: %v", checkout.ID, err)
+	}
+}
+
+// ProcessCheckouts marks the checkouts paid whose payment was credited while no notification
+// reached them, expires the open checkouts past their expiry and retries undelivered webhooks
+func (svc *LndhubService) ProcessCheckouts(ctx context.Context) error {
+	receives := []models.AssetReceive{}
+	err := svc.DB.NewSelect().Model(&receives).
+		Where("state = ? AND transaction_entry_id IS NOT NULL", models.AssetReceiveStateCredited).
+		Where("address IN (SELECT address FROM checkouts WHERE state <> ? AND address IS NOT NULL)", models.CheckoutStatePaid).
+		Scan(ctx)
+	if err != nil {
+		svc.Logger.Errorf("Could not load checkout receives: %v", err)
+		return err
+	}
+	for _, receive := range receives {
+		if _, err := svc.CompleteCheckoutForAddress(ctx, receive.Address, receive.TransactionEntryID); err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("Could not complete checkout of address %s: %v", receive.Address, err)
+		}
+	}
+	entries := []models.TransactionEntry{}
+	err = svc.DB.NewSelect().Model(&entries).
+		Where("entry_type = ?", models.EntryTypeIncoming).
+		Where("invoice_id IN (SELECT invoice_id FROM checkouts WHERE state <> ? AND invoice_id IS NOT NULL)", models.CheckoutStatePaid).
+		Scan(ctx)
+	if err != nil {
+		svc.Logger.Errorf("Could not load checkout invoice entries: %v", err)
+		return err
+	}
+	for _, entry := range entries {
+		if _, err := svc.completeCheckout(ctx, "invoice_id", entry.InvoiceID, entry.ID); err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("Could not complete checkout of invoice id:%d: %v", entry.InvoiceID, err)
+		}
+	}
+	if err := svc.ExpireCheckouts(ctx, 0); err != nil {
+		return err
+	}
+	if svc.Config.CheckoutWebhookSecret == "" {
+		return nil
+	}
+	undelivered := []models.Checkout{}
+	err = svc.DB.NewSelect().Model(&undelivered).
+		Where("state = ? AND webhook_url IS NOT NULL AND webhook_delivered_at IS NULL", models.CheckoutStatePaid).
+		Where("webhook_attempts < ? AND paid_at < ?", MaxCheckoutWebhookAttempts, time.Now().Add(-checkoutWebhookTimeout)).
+		OrderExpr("id ASC").
+		Scan(ctx)
+	if err != nil {
+		svc.Logger.Errorf("Could not load undelivered checkout webhooks: %v", err)
+		return err
+	}
+	for i := range undelivered {
+		svc.deliverCheckoutWebhook(ctx, &undelivered[i])
+	}
+	return nil
+}
+
+func (svc *LndhubService) checkoutAmountText(ctx context.Context, checkout *models.Checkout) (string, error) {
+	assetName := checkout.TaAssetID
+	asset := models.Asset{}
+	if err := svc.DB.NewSelect().Model(&asset).Where("ta_asset_id = ?", checkout.TaAssetID).Limit(1).Scan(ctx); err == nil && asset.AssetName != "" {
+		assetName = asset.AssetName
+	}
+	decimalDisplay, err := svc.AssetDecimalDisplay(ctx, checkout.TaAssetID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s", FormatAssetAmount(checkout.Amount, decimalDisplay), assetName), nil
+}
+
+// StartCheckoutInvoiceSubscription marks btc checkouts paid as their invoices settle
+func (svc *LndhubService) StartCheckoutInvoiceSubscription(ctx context.Context) {
+	invoices, subId, err := svc.InvoicePubSub.Subscribe(common.InvoiceTypeIncoming)
+	if err != nil {
+		svc.Logger.Error(err)
+		return
+	}
+	defer svc.InvoicePubSub.Unsubscribe(subId, common.InvoiceTypeIncoming)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case invoice := <-invoices:
+			if _, err := svc.CompleteCheckoutForInvoice(ctx, invoice.ID); err != nil {
+				sentry.CaptureException(err)
+				svc.Logger.Errorf("Could not complete checkout of invoice id:%d: %v", invoice.ID, err)
+			}
+		}
+	}
+}
+
+func (svc *LndhubService) StartCheckoutRoutine(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(svc.Config.CheckoutInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = svc.ProcessCheckouts(ctx)
+		}
+	}
+}
+
+// IsCheckoutUserError tells the errors of checkouts the merchant can act on from failures of the hub
+func IsCheckoutUserError(err error) bool {
+	for _, userErr := range []error{
+		CheckoutNotFoundError,
+		CheckoutAmountError,
+		InvalidFiatAmountError,
+		FiatCheckoutsUnavailableError,
+		UnknownCheckoutAssetError,
+		InvalidWebhookUrlError,
+		PrivateWebhookHostError,
+		CheckoutWebhooksDisabledError,
+		CheckoutOrderRefTooLongError,
+		InvalidCheckoutStateQueryError,
+		SwapPriceUnavailableError,
+		TransferMemoTooLongError,
+		InvalidAssetAmountError,
+		UnrepresentableAssetAmountError,
+	} {
+		if errors.Is(err, userErr) {
+			return true
+		}
+	}
+	return false
+}
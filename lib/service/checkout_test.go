@@ -0,0 +1,58 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFiatUnits(t *testing.T) {
+	// 3.00 of a 6 decimal asset at 2.00
+	units, err := FiatUnits("3.00", 6, "2.00")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500000), units)
+
+	// 65.00 at 0.00065 per sat
+	units, err = FiatUnits("65", 0, "0.00065")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100000), units)
+
+	// rounded up so the merchant gets at least the fiat amount
+	units, err = FiatUnits("1.00", 0, "0.3")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), units)
+
+	for _, invalid := range []string{"", "0", "-1", "1/3", "1e3", "abc"} {
+		_, err = FiatUnits(invalid, 0, "1")
+		assert.ErrorIs(t, err, InvalidFiatAmountError, invalid)
+	}
+	_, err = FiatUnits("1", 0, "0")
+	assert.ErrorIs(t, err, SwapPriceUnavailableError)
+	_, err = FiatUnits("1", 18, "0.000000000000000001")
+	assert.ErrorIs(t, err, UnrepresentableAssetAmountError)
+}
+
+func TestCheckoutWebhookSignature(t *testing.T) {
+	body := []byte(`{"event":"checkout.paid"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`1700000000.{"event":"checkout.paid"}`))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), CheckoutWebhookSignature("secret", 1700000000, body))
+	// timestamp and key are part of the signature
+	assert.NotEqual(t, CheckoutWebhookSignature("secret", 1700000000, body), CheckoutWebhookSignature("secret", 1700000001, body))
+	assert.NotEqual(t, CheckoutWebhookSignature("secret", 1700000000, body), CheckoutWebhookSignature("other", 1700000000, body))
+}
+
+func TestPublicIP(t *testing.T) {
+	for _, public := range []string{"1.1.1.1", "93.184.216.34", "2606:4700:4700::1111"} {
+		assert.True(t, publicIP(net.ParseIP(public)), public)
+	}
+	for _, private := range []string{"127.0.0.1", "10.0.0.1", "172.16.5.4", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fd00::1", "fe80::1"} {
+		assert.False(t, publicIP(net.ParseIP(private)), private)
+	}
+	assert.ErrorIs(t, publicDialControl("tcp", "127.0.0.1:80", nil), PrivateWebhookHostError)
+	assert.NoError(t, publicDialControl("tcp", "1.1.1.1:443", nil))
+}
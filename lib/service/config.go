@@ -55,6 +55,35 @@ type Config struct {
 	TahubPublicKey                   string   `envconfig:"TAHUB_PUBLIC_KEY_HEX" required:"true"`
 	TahubPrivateKey                  string   `envconfig:"TAHUB_PRIVATE_KEY_HEX" required:"true"`
 	RelayURI                         []string `envconfig:"RELAY_URI" required:"true"`
+	SolvencyCheckInterval            int      `envconfig:"SOLVENCY_CHECK_INTERVAL" default:"0"` // in seconds, 0 disables the periodic check
+	AssetChannelPeerPubkey           string   `envconfig:"ASSET_CHANNEL_PEER_PUBKEY"` // edge node used for rfq quotes on asset invoices and payments
+	LiabilitySnapshotInterval        int      `envconfig:"LIABILITY_SNAPSHOT_INTERVAL" default:"0"` // in seconds, 0 disables the periodic snapshot
+	MintBatchSyncInterval            int      `envconfig:"MINT_BATCH_SYNC_INTERVAL" default:"0"` // in seconds, 0 disables the periodic mint batch sync
+	SendBatchInterval                int      `envconfig:"SEND_BATCH_INTERVAL" default:"0"` // in seconds, 0 sends every external transfer right away
+	SendBatchMaxSize                 int      `envconfig:"SEND_BATCH_MAX_SIZE" default:"20"` // a full batch is sent without waiting for the interval
+	ReceiveConfirmationDepth         uint32   `envconfig:"RECEIVE_CONFIRMATION_DEPTH" default:"1"` // confirmations of the anchor transaction before a receive is credited
+	ReceiveTrackingInterval          int      `envconfig:"RECEIVE_TRACKING_INTERVAL" default:"0"` // in seconds, 0 disables the periodic receive tracking
+	PriceOracle                      string   `envconfig:"PRICE_ORACLE"` // static or feed, empty disables fiat valuations
+	PriceCurrency                    string   `envconfig:"PRICE_CURRENCY" default:"USD"`
+	PriceOracleStaticPrices          map[string]string `envconfig:"PRICE_ORACLE_STATIC_PRICES"` // asset_id:price pairs for the static oracle
+	PriceOracleFeedURL               string   `envconfig:"PRICE_ORACLE_FEED_URL"` // http(s):// or file:// URL of the JSON price feed
+	PriceOracleInterval              int      `envconfig:"PRICE_ORACLE_INTERVAL" default:"300"` // in seconds, 0 only reads the prices on startup
+	SwapSpreadBps                    int64    `envconfig:"SWAP_SPREAD_BPS" default:"50"` // spread the hub keeps on swaps, in basis points
+	SwapQuoteTTL                     int      `envconfig:"SWAP_QUOTE_TTL" default:"30"` // in seconds a swap quote can be executed
+	SwapMaxPriceAge                  int      `envconfig:"SWAP_MAX_PRICE_AGE" default:"600"` // in seconds, older prices are not quoted, 0 disables the check
+	ClaimableTransferExpiry          int      `envconfig:"CLAIMABLE_TRANSFER_EXPIRY" default:"604800"` // in seconds a transfer to an unregistered pubkey can be claimed, 0 disables them
+	ClaimableTransferExpiryInterval  int      `envconfig:"CLAIMABLE_TRANSFER_EXPIRY_INTERVAL" default:"300"` // in seconds, 0 disables the periodic reversal of expired transfers
+	PaymentRequestExpiry             int      `envconfig:"PAYMENT_REQUEST_EXPIRY" default:"86400"` // in seconds a payment request can be paid
+	CheckoutExpiry                   int      `envconfig:"CHECKOUT_EXPIRY" default:"900"` // in seconds a checkout can be paid at its amount
+	CheckoutInterval                 int      `envconfig:"CHECKOUT_INTERVAL" default:"60"` // in seconds, 0 disables the periodic checkout expiry, payment reconciliation and webhook retries
+	CheckoutWebhookUrl               string   `envconfig:"CHECKOUT_WEBHOOK_URL"` // webhook of the checkouts that do not set their own
+	CheckoutWebhookSecret            string   `envconfig:"CHECKOUT_WEBHOOK_SECRET"` // HMAC-SHA256 key of the CHECKOUT_WEBHOOK_URL signatures, empty disables checkout webhooks
+	CheckoutWebhookAllowedHosts      []string `envconfig:"CHECKOUT_WEBHOOK_ALLOWED_HOSTS"` // merchant webhook hosts that may resolve to private or loopback addresses
+	PublicURL                        string   `envconfig:"PUBLIC_URL"` // URL the hub is publicly reachable at, its host is the domain of lightning addresses and NIP-05 names, empty disables lightning addresses
+	LnurlMinSendable                 int64    `envconfig:"LNURL_MIN_SENDABLE" default:"1"` // in sats, the most is MAX_RECEIVE_AMOUNT
+	ReservedUsernames                []string `envconfig:"RESERVED_USERNAMES"` // names users cannot claim on top of the built in ones
+	UsernameChangeCooldown           int      `envconfig:"USERNAME_CHANGE_COOLDOWN" default:"86400"` // in seconds between two username changes of a user, 0 disables the check
+	UsernameQuarantine               int      `envconfig:"USERNAME_QUARANTINE" default:"2592000"` // in seconds a released username is held for its former owner, 0 frees it right away
 	Branding                         BrandingConfig
 }
 
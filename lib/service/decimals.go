@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+)
+
+// MaxDecimalDisplay is the most decimal places an amount can be given with, 10^19 does not fit
+// into the uint64 amounts tapd works with
+const MaxDecimalDisplay = 18
+
+var InvalidAssetAmountError = errors.New("amount must be a positive decimal number")
+var UnrepresentableAssetAmountError = errors.New("amount has more decimal places than the asset or does not fit its units")
+
+// ParseAssetAmount converts a decimal string like "1.5" to integer asset units, an asset with 6
+// decimals gets 1500000. Amounts with more non-zero decimal places than the asset has are rejected
+// instead of rounded.
+func ParseAssetAmount(amount string, decimalDisplay uint32) (uint64, error) {
+	whole, fraction, hasPoint := strings.Cut(amount, ".")
+	if whole == "" || (hasPoint && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return 0, InvalidAssetAmountError
+	}
+	trimmed := strings.TrimRight(fraction, "0")
+	if len(trimmed) > int(decimalDisplay) || decimalDisplay > MaxDecimalDisplay {
+		return 0, UnrepresentableAssetAmountError
+	}
+	units, err := strconv.ParseUint(whole+trimmed+strings.Repeat("0", int(decimalDisplay)-len(trimmed)), 10, 64)
+	if err != nil {
+		return 0, UnrepresentableAssetAmountError
+	}
+	if units == 0 {
+		return 0, InvalidAssetAmountError
+	}
+	return units, nil
+}
+
+// FormatAssetAmount renders integer asset units as a decimal string with all decimal places of
+// the asset, 1500000 units of an asset with 6 decimals are "1.500000"
+func FormatAssetAmount(units int64, decimalDisplay uint32) string {
+	sign := ""
+	abs := uint64(units)
+	if units < 0 {
+		sign = "-"
+		abs = uint64(-units)
+	}
+	digits := strconv.FormatUint(abs, 10)
+	if decimalDisplay == 0 {
+		return sign + digits
+	}
+	if len(digits) <= int(decimalDisplay) {
+		digits = strings.Repeat("0", int(decimalDisplay)-len(digits)+1) + digits
+	}
+	point := len(digits) - int(decimalDisplay)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// decimalDisplayFromMeta reads the decimal display from JSON asset meta data, 0 for other meta data
+func decimalDisplayFromMeta(data []byte) uint32 {
+	meta := struct {
+		DecimalDisplay uint32 `json:"decimal_display"`
+	}{}
+	if err := json.Unmarshal(data, &meta); err != nil || meta.DecimalDisplay > MaxDecimalDisplay {
+		return 0
+	}
+	return meta.DecimalDisplay
+}
+
+// AssetDecimalDisplay is the decimal display of a known asset, 0 for unknown assets and btc
+func (svc *LndhubService) AssetDecimalDisplay(ctx context.Context, taAssetId string) (uint32, error) {
+	asset := models.Asset{}
+	err := svc.DB.NewSelect().Model(&asset).Column("decimal_display").Where("ta_asset_id = ?", taAssetId).Limit(1).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return asset.DecimalDisplay, nil
+}
+
+// AssetDecimalDisplays maps the ta_asset_id of all known assets to their decimal display
+func (svc *LndhubService) AssetDecimalDisplays(ctx context.Context) (map[string]uint32, error) {
+	assets, err := svc.GetAssets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	decimals := make(map[string]uint32, len(assets))
+	for _, asset := range assets {
+		decimals[asset.TaAssetID] = asset.DecimalDisplay
+	}
+	return decimals, nil
+}
+
+// ParseAssetAmountFor converts a decimal string amount to units of the given asset
+func (svc *LndhubService) ParseAssetAmountFor(ctx context.Context, taAssetId string, amount string) (uint64, error) {
+	decimalDisplay, err := svc.AssetDecimalDisplay(ctx, taAssetId)
+	if err != nil {
+		return 0, err
+	}
+	return ParseAssetAmount(amount, decimalDisplay)
+}
+
+// RefreshAssetDecimalDisplay reads the decimal display of an asset from its meta data on tapd
+func (svc *LndhubService) RefreshAssetDecimalDisplay(ctx context.Context, asset *models.Asset) error {
+	assetId, err := hex.DecodeString(asset.TaAssetID)
+	if err != nil || len(assetId) != 32 {
+		// btc and other assets that do not live on tapd
+		return nil
+	}
+	meta, err := svc.TapdClient.FetchAssetMeta(ctx, &taprpc.FetchAssetMetaRequest{
+		Asset: &taprpc.FetchAssetMetaRequest_AssetId{AssetId: assetId},
+	})
+	if err != nil {
+		return err
+	}
+	decimalDisplay := decimalDisplayFromMeta(meta.Data)
+	if decimalDisplay == asset.DecimalDisplay {
+		return nil
+	}
+	asset.DecimalDisplay = decimalDisplay
+	_, err = svc.DB.NewUpdate().Model(asset).Column("decimal_display").WherePK().Exec(ctx)
+	return err
+}
+
+// RefreshAssetDecimalDisplays reads the decimal display of all known assets from tapd, assets
+// tapd has no meta data for keep their decimal display
+func (svc *LndhubService) RefreshAssetDecimalDisplays(ctx context.Context) error {
+	assets, err := svc.GetAssets(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range assets {
+		if err := svc.RefreshAssetDecimalDisplay(ctx, &assets[i]); err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("failed to read decimal display of asset %s: %v", assets[i].TaAssetID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAssetAmount(t *testing.T) {
+	units, err := ParseAssetAmount("1", 6)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1000000), units)
+
+	units, err = ParseAssetAmount("1.5", 6)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1500000), units)
+
+	units, err = ParseAssetAmount("0.000001", 6)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), units)
+
+	// trailing zeros beyond the decimal display do not change the amount
+	units, err = ParseAssetAmount("2.500000000", 6)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2500000), units)
+
+	units, err = ParseAssetAmount("50", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(50), units)
+
+	units, err = ParseAssetAmount("18446744073709551615", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(18446744073709551615), units)
+
+	_, err = ParseAssetAmount("0.0000001", 6)
+	assert.ErrorIs(t, err, UnrepresentableAssetAmountError)
+	_, err = ParseAssetAmount("1.5", 0)
+	assert.ErrorIs(t, err, UnrepresentableAssetAmountError)
+	_, err = ParseAssetAmount("18446744073709551616", 0)
+	assert.ErrorIs(t, err, UnrepresentableAssetAmountError)
+	_, err = ParseAssetAmount("18446744073709.551616", 6)
+	assert.ErrorIs(t, err, UnrepresentableAssetAmountError)
+
+	for _, invalid := range []string{"", "0", "0.000", "-1", "+1", "1.", ".5", "1e6", "1,5", "1.2.3", " 1"} {
+		_, err = ParseAssetAmount(invalid, 6)
+		assert.ErrorIs(t, err, InvalidAssetAmountError, invalid)
+	}
+}
+
+func TestFormatAssetAmount(t *testing.T) {
+	assert.Equal(t, "1.000000", FormatAssetAmount(1000000, 6))
+	assert.Equal(t, "1.500000", FormatAssetAmount(1500000, 6))
+	assert.Equal(t, "0.000001", FormatAssetAmount(1, 6))
+	assert.Equal(t, "0.000000", FormatAssetAmount(0, 6))
+	assert.Equal(t, "-0.25", FormatAssetAmount(-25, 2))
+	assert.Equal(t, "50", FormatAssetAmount(50, 0))
+
+	// formatting and parsing round trip exactly
+	units, err := ParseAssetAmount(FormatAssetAmount(123456789, 8), 8)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(123456789), units)
+}
+
+func TestDecimalDisplayFromMeta(t *testing.T) {
+	assert.Equal(t, uint32(6), decimalDisplayFromMeta([]byte(`{"ticker":"USDT","decimal_display":6}`)))
+	assert.Equal(t, uint32(0), decimalDisplayFromMeta([]byte(`{"ticker":"USDT"}`)))
+	assert.Equal(t, uint32(0), decimalDisplayFromMeta([]byte("plain text")))
+	assert.Equal(t, uint32(0), decimalDisplayFromMeta([]byte(`{"decimal_display":40}`)))
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -80,6 +81,10 @@ func (svc *LndhubService) EventHandler(ctx context.Context, payload nostr.Event,
 			svc.Logger.Errorf("Failed to create user via Nostr event: %v", err)
 			return svc.RespondToNip4(ctx, "error: failed to create user", true, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
 		}
+		// credit what was sent to the pubkey before it registered
+		if _, err := svc.ClaimTransfersFor(ctx, user); err != nil {
+			svc.Logger.Errorf("Failed to claim transfers for user_id:%d: %v", user.ID, err)
+		}
 		// create user success response
 		msg := fmt.Sprintf("userid: %d", user.ID)
 		return svc.RespondToNip4(ctx, msg, false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
@@ -113,10 +118,10 @@ func (svc *LndhubService) EventHandler(ctx context.Context, payload nostr.Event,
 		// given an asset_id and amt, return the address
 		// these values are prevalidated by CheckEvent
 		assetId := data[1]
-		amt, err := strconv.ParseUint(data[2], 10, 64)
+		amt, err := svc.ParseAssetAmountFor(ctx, assetId, data[2])
 		if err != nil {
 			svc.Logger.Errorf("Failed to parse amt field in content: %v", err)
-			return svc.RespondToNip4(ctx, "error: failed to parse amt", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+			return svc.RespondToNip4(ctx, fmt.Sprintf("error: %s", err.Error()), true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
 		}
 		// find or create address for user, by asset_id and amount
 		msgContent, err := svc.FetchOrCreateAssetAddr(ctx, uint64(existingUser.ID), assetId, amt)
@@ -142,6 +147,13 @@ func (svc *LndhubService) EventHandler(ctx context.Context, payload nostr.Event,
 			svc.Logger.Errorf("Failed to calculate balances: %s", err)
 			return svc.RespondToNip4(ctx, "error: failed to get balances", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
 		} 
+		// append the fiat value if prices are available
+		valuation, err := svc.ValueBalances(ctx, existingUser.ID)
+		if err != nil {
+			svc.Logger.Errorf("Failed to value balances: %s", err)
+		} else if valuation != nil {
+			msg = msg + fmt.Sprintf(" value: %s %s", valuation.Total, valuation.Currency)
+		}
 		// create string from balances 
 		return svc.RespondToNip4(ctx,msg, false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
 	} else if data[0] == "TAHUB_SEND_ASSET" {
@@ -152,7 +164,8 @@ func (svc *LndhubService) EventHandler(ctx context.Context, payload nostr.Event,
 			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
 		}
 		// check balance and send
-		msg, success := svc.TransferAssets(ctx, uint64(existingUser.ID), data[1])
+		// TAHUB_SEND_ASSET:<addr>:withdraw exports the proof file once the send completes
+		msg, success := svc.TransferAssets(ctx, uint64(existingUser.ID), data[1], len(data) == 3)
 		if !success {
 			svc.Logger.Errorf("Failed to transfer assets: %s", msg)
 			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
@@ -160,6 +173,404 @@ func (svc *LndhubService) EventHandler(ctx context.Context, payload nostr.Event,
 			// success subscription will handle the rest
 			return svc.RespondToNip4(ctx, msg, false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
 		}		
+	} else if data[0] == "TAHUB_GET_HISTORY" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for get history.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		// params were validated in CheckEvent
+		params, _ := ParseHistoryCommandParams(data[1:])
+		filter, _ := ParseTransactionHistoryFilter(params)
+		page, err := svc.TransactionHistoryFor(ctx, existingUser.ID, filter)
+		if err != nil {
+			svc.Logger.Errorf("Failed to get transaction history: %v", err)
+			return svc.RespondToNip4(ctx, "error: failed to get history", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(page)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to get history", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_GET_LIABILITY_PROOF" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for get liability proof.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		proof, err := svc.GetLiabilityProof(ctx, existingUser, data[1])
+		if err != nil {
+			svc.Logger.Errorf("Failed to get liability proof: %v", err)
+			return svc.RespondToNip4(ctx, "error: failed to get liability proof", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(proof)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to get liability proof", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_GET_RECEIVES" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for get receives.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		receives, err := svc.ReceivesFor(ctx, existingUser.ID)
+		if err != nil {
+			svc.Logger.Errorf("Failed to get receives: %v", err)
+			return svc.RespondToNip4(ctx, "error: failed to get receives", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(receives)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to get receives", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_CREATE_ASSET_INVOICE" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for create asset invoice.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		amt, err := svc.ParseAssetAmountFor(ctx, data[1], data[2])
+		if err != nil {
+			svc.Logger.Errorf("Failed to parse amt field in content: %v", err)
+			return svc.RespondToNip4(ctx, fmt.Sprintf("error: %s", err.Error()), true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		// the memo may contain colons
+		memo := strings.Join(data[3:], ":")
+		invoice, err := svc.AddAssetInvoice(ctx, existingUser.ID, data[1], int64(amt), memo)
+		if err != nil {
+			svc.Logger.Errorf("Failed to create asset invoice: %v", err)
+			msg := "error: failed to create asset invoice"
+			if IsAssetInvoiceUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg := fmt.Sprintf("invoice: %s", invoice.PaymentRequest)
+		return svc.RespondToNip4(ctx, msg, false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_PAY_ASSET_INVOICE" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for pay asset invoice.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		result, err := svc.PayAssetInvoice(ctx, existingUser.ID, data[1], data[2])
+		if err != nil {
+			svc.Logger.Errorf("Failed to pay asset invoice: %v", err)
+			msg := "error: failed to pay asset invoice"
+			if IsAssetInvoiceUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg := fmt.Sprintf("preimage: %s", result.PaymentPreimageStr)
+		return svc.RespondToNip4(ctx, msg, false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_SWAP_QUOTE" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for swap quote.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		quote, err := svc.SwapQuoteFor(ctx, existingUser.ID, data[1], data[2], data[3])
+		if err != nil {
+			svc.Logger.Errorf("Failed to quote swap: %v", err)
+			msg := "error: failed to quote swap"
+			if IsSwapUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(quote)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to quote swap", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_SWAP_EXECUTE" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for swap execute.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		// quote_id was validated in CheckEvent
+		quoteId, _ := strconv.ParseInt(data[1], 10, 64)
+		quote, err := svc.ExecuteSwap(ctx, existingUser.ID, quoteId)
+		if err != nil {
+			svc.Logger.Errorf("Failed to execute swap: %v", err)
+			msg := "error: failed to execute swap"
+			if IsSwapUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(quote)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to execute swap", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_PLACE_ORDER" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for place order.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		placement, err := svc.PlaceOrder(ctx, OrderRequest{
+			UserID:       existingUser.ID,
+			Side:         data[1],
+			BaseAssetID:  data[2],
+			QuoteAssetID: data[3],
+			Amount:       data[4],
+			Price:        data[5],
+		})
+		if err != nil {
+			svc.Logger.Errorf("Failed to place order: %v", err)
+			msg := "error: failed to place order"
+			if IsOrderUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(placement)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to place order", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_CANCEL_ORDER" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for cancel order.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		// order_id was validated in CheckEvent
+		orderId, _ := strconv.ParseInt(data[1], 10, 64)
+		order, err := svc.CancelOrder(ctx, existingUser.ID, orderId)
+		if err != nil {
+			svc.Logger.Errorf("Failed to cancel order: %v", err)
+			msg := "error: failed to cancel order"
+			if IsOrderUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(order)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to cancel order", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_GET_ORDERS" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for get orders.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		state := ""
+		if len(data) == 2 {
+			state = data[1]
+		}
+		orders, err := svc.OrdersFor(ctx, existingUser.ID, state)
+		if err != nil {
+			svc.Logger.Errorf("Failed to get orders: %v", err)
+			return svc.RespondToNip4(ctx, "error: failed to get orders", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(orders)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to get orders", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_GET_ORDER_BOOK" {
+		// public, no authentication
+		book, err := svc.OrderBookFor(ctx, data[1], data[2])
+		if err != nil {
+			svc.Logger.Errorf("Failed to get order book: %v", err)
+			return svc.RespondToNip4(ctx, "error: failed to get order book", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(book)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to get order book", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_GET_TRADES" {
+		// public, no authentication
+		trades, err := svc.TradesFor(ctx, data[1], data[2], 0, DefaultTradesLimit)
+		if err != nil {
+			svc.Logger.Errorf("Failed to get trades: %v", err)
+			return svc.RespondToNip4(ctx, "error: failed to get trades", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(trades)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to get trades", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_SEND_TO_PUBKEY" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for send to pubkey.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		// the memo may contain colons itself
+		memo := strings.Join(data[4:], ":")
+		transfer, err := svc.TransferToPubkey(ctx, existingUser.ID, data[1], data[2], data[3], memo)
+		if err != nil {
+			svc.Logger.Errorf("Failed to send to pubkey: %v", err)
+			msg := "error: failed to send to pubkey"
+			if IsTransferUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(transfer)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to send to pubkey", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_REQUEST_PAYMENT" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for request payment.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		// the memo may contain colons itself
+		memo := strings.Join(data[4:], ":")
+		request, err := svc.RequestPayment(ctx, existingUser, data[1], data[2], data[3], memo)
+		if err != nil {
+			svc.Logger.Errorf("Failed to request payment: %v", err)
+			msg := "error: failed to request payment"
+			if IsPaymentRequestUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(request)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to request payment", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_PAY_REQUEST" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for pay request.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		// request_id was validated in CheckEvent
+		requestId, _ := strconv.ParseInt(data[1], 10, 64)
+		request, err := svc.PayPaymentRequest(ctx, existingUser, requestId)
+		if err != nil {
+			svc.Logger.Errorf("Failed to pay request: %v", err)
+			msg := "error: failed to pay request"
+			if IsPaymentRequestUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(request)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to pay request", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_CANCEL_PAYMENT_REQUEST" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for cancel payment request.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		// request_id was validated in CheckEvent
+		requestId, _ := strconv.ParseInt(data[1], 10, 64)
+		request, err := svc.CancelPaymentRequest(ctx, existingUser, requestId)
+		if err != nil {
+			svc.Logger.Errorf("Failed to cancel payment request: %v", err)
+			msg := "error: failed to cancel payment request"
+			if IsPaymentRequestUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(request)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to cancel payment request", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_GET_PAYMENT_REQUEST" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for get payment request.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		// request_id was validated in CheckEvent
+		requestId, _ := strconv.ParseInt(data[1], 10, 64)
+		request, err := svc.PaymentRequestFor(ctx, existingUser, requestId)
+		if err != nil {
+			svc.Logger.Errorf("Failed to get payment request: %v", err)
+			msg := "error: failed to get payment request"
+			if IsPaymentRequestUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(request)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to get payment request", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_GET_PAYMENT_REQUESTS" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for get payment requests.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		state := ""
+		if len(data) == 2 {
+			state = data[1]
+		}
+		requests, err := svc.PaymentRequestsFor(ctx, existingUser, state)
+		if err != nil {
+			svc.Logger.Errorf("Failed to get payment requests: %v", err)
+			return svc.RespondToNip4(ctx, "error: failed to get payment requests", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		msg, err := json.Marshal(requests)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to get payment requests", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
+	} else if data[0] == "TAHUB_SET_USERNAME" {
+		// authentication required
+		existingUser, isAuthenticated := svc.GetUserIfExists(ctx, decoded)
+		if existingUser == nil || !isAuthenticated {
+			svc.Logger.Errorf("Failed to authenticate user for set username.")
+			return svc.RespondToNip4(ctx, "error: failed to authenticate", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		user, err := svc.SetUsername(ctx, existingUser, data[1])
+		if err != nil {
+			svc.Logger.Errorf("Failed to set username: %v", err)
+			msg := "error: failed to set username"
+			if IsUsernameUserError(err) {
+				msg = fmt.Sprintf("error: %s", err.Error())
+			}
+			return svc.RespondToNip4(ctx, msg, true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		res := responses.NostrUsernameResponseBody{Username: user.Username}
+		if user.Username != "" {
+			res.LightningAddress, _ = svc.LightningAddress(user.Username)
+		}
+		msg, err := json.Marshal(res)
+		if err != nil {
+			return svc.RespondToNip4(ctx, "error: failed to set username", true, decoded.PubKey, decoded.ID, relayUri, lastSeen)
+		}
+		return svc.RespondToNip4(ctx, string(msg), false, decoded.PubKey, decoded.ID, relayUri, decoded.CreatedAt.Time().Unix())
 	} else {
 		// catch all - unimplemented
 		svc.Logger.Errorf("Unimplemented event content: %s", decoded.Content)
@@ -345,6 +756,11 @@ func (svc *LndhubService) SendNip4Notification(ctx context.Context, rawContent s
 	resp.Tags = nostr.Tags{pTag}
 	// sign event
 	resp.Sign(svc.Config.TahubPrivateKey)
+	return svc.PublishToRelays(ctx, resp)
+}
+
+// PublishToRelays broadcasts a signed event to the first relay that accepts it
+func (svc *LndhubService) PublishToRelays(ctx context.Context, resp nostr.Event) error {
 	// get relays
 	relays, err := svc.GetRelays(ctx)
 	if err != nil {
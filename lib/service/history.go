@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getAlby/lndhub.go/db/models"
+)
+
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 100
+
+	CounterpartyInternal = "internal"
+	CounterpartyExternal = "external"
+	CounterpartyHub      = "hub"
+)
+
+var InvalidHistoryFilterError = errors.New("invalid history filter")
+
+// TransactionHistoryFilter : cursor is the id of the last entry of the previous page, entries are returned newest first
+type TransactionHistoryFilter struct {
+	AssetID        string
+	EntryType      string
+	BroadcastState string
+	From           time.Time
+	To             time.Time
+	Cursor         int64
+	Limit          int
+}
+
+// ParseTransactionHistoryFilter reads the filter from key/value pairs, shared by the REST query
+// params and the TAHUB_GET_HISTORY command. from and to are unix timestamps in seconds.
+func ParseTransactionHistoryFilter(params map[string]string) (filter TransactionHistoryFilter, err error) {
+	filter.Limit = DefaultHistoryLimit
+	for key, value := range params {
+		if value == "" {
+			continue
+		}
+		switch key {
+		case "asset_id":
+			filter.AssetID = value
+		case "entry_type":
+			filter.EntryType = value
+		case "broadcast_state":
+			filter.BroadcastState = value
+		case "from", "to":
+			ts, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || ts < 0 {
+				return filter, InvalidHistoryFilterError
+			}
+			if key == "from" {
+				filter.From = time.Unix(ts, 0)
+			} else {
+				filter.To = time.Unix(ts, 0)
+			}
+		case "cursor":
+			filter.Cursor, err = strconv.ParseInt(value, 10, 64)
+			if err != nil || filter.Cursor < 0 {
+				return filter, InvalidHistoryFilterError
+			}
+		case "limit":
+			filter.Limit, err = strconv.Atoi(value)
+			if err != nil || filter.Limit <= 0 {
+				return filter, InvalidHistoryFilterError
+			}
+			if filter.Limit > MaxHistoryLimit {
+				filter.Limit = MaxHistoryLimit
+			}
+		default:
+			return filter, InvalidHistoryFilterError
+		}
+	}
+	return filter, nil
+}
+
+// ParseHistoryCommandParams splits the key=value parts of a TAHUB_GET_HISTORY command
+func ParseHistoryCommandParams(parts []string) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		key, value, found := strings.Cut(part, "=")
+		if !found || key == "" {
+			return nil, InvalidHistoryFilterError
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
+type TransactionHistoryEntry struct {
+	ID             int64  `json:"id"`
+	AssetID        string `json:"asset_id"`
+	AssetName      string `json:"asset_name"`
+	EntryType      string `json:"entry_type"`
+	Amount         int64  `json:"amount"`
+	BroadcastState string `json:"broadcast_state"`
+	Outpoint       string `json:"outpoint"`
+	Address        string `json:"address,omitempty"`
+	Memo           string `json:"memo,omitempty"`
+	Counterparty   string `json:"counterparty"`
+	// pubkey of the other hub user for internal transfers
+	CounterpartyPubkey string    `json:"counterparty_pubkey,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+	DecimalDisplay     uint32    `json:"-"`
+	// fiat value at the latest price before the entry, only with a price oracle configured
+	FiatPrice    string `json:"fiat_price,omitempty"`
+	FiatValue    string `json:"fiat_value,omitempty"`
+	FiatCurrency string `json:"fiat_currency,omitempty"`
+}
+
+type TransactionHistoryPage struct {
+	Entries []TransactionHistoryEntry `json:"entries"`
+	// empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func (svc *LndhubService) TransactionHistoryFor(ctx context.Context, userId int64, filter TransactionHistoryFilter) (*TransactionHistoryPage, error) {
+	if filter.Limit <= 0 || filter.Limit > MaxHistoryLimit {
+		filter.Limit = DefaultHistoryLimit
+	}
+	entries := []TransactionHistoryEntry{}
+	query := svc.DB.NewSelect().
+		TableExpr("transaction_entries AS te").
+		Join("LEFT JOIN assets ON assets.ta_asset_id = te.ta_asset_id").
+		// internal receives point to the sender's entry, internal sends are pointed to by the receiver's entry
+		Join("LEFT JOIN transaction_entries AS sender ON sender.id = te.parent_id AND te.entry_type = ?", models.EntryTypeIncoming).
+		Join("LEFT JOIN users AS sender_user ON sender_user.id = sender.user_id").
+		Join("LEFT JOIN transaction_entries AS receiver ON receiver.parent_id = te.id AND receiver.entry_type = ? AND te.entry_type = ?", models.EntryTypeIncoming, models.EntryTypeOutgoing).
+		Join("LEFT JOIN users AS receiver_user ON receiver_user.id = receiver.user_id").
+		// transfers to a pubkey are recorded against the sender's entry
+		Join("LEFT JOIN internal_transfers AS it ON it.transaction_entry_id = coalesce(sender.id, te.id)").
+		ColumnExpr("te.id, coalesce(te.ta_asset_id, '') AS asset_id, coalesce(assets.asset_name, '') AS asset_name, coalesce(te.entry_type, '') AS entry_type, te.amount").
+		ColumnExpr("coalesce(te.broadcast_state, '') AS broadcast_state, coalesce(te.outpoint, '') AS outpoint, coalesce(te.address, '') AS address, te.created_at").
+		ColumnExpr("coalesce(sender_user.pubkey, receiver_user.pubkey, it.recipient_pubkey, '') AS counterparty_pubkey").
+		ColumnExpr("coalesce(it.memo, '') AS memo").
+		ColumnExpr("coalesce(assets.decimal_display, 0) AS decimal_display").
+		Where("te.user_id = ?", userId)
+	if svc.PriceOracle != nil {
+		// value entries at the price that was current when they were booked
+		query.Join("LEFT JOIN LATERAL (SELECT ap.price FROM asset_prices AS ap WHERE ap.ta_asset_id = te.ta_asset_id AND ap.currency = ? AND ap.created_at <= te.created_at ORDER BY ap.created_at DESC, ap.id DESC LIMIT 1) AS price ON true", svc.Config.PriceCurrency).
+			ColumnExpr("coalesce(price.price::text, '') AS fiat_price")
+	}
+	if filter.AssetID != "" {
+		query.Where("te.ta_asset_id = ?", filter.AssetID)
+	}
+	if filter.EntryType != "" {
+		query.Where("te.entry_type = ?", filter.EntryType)
+	}
+	if filter.BroadcastState != "" {
+		query.Where("te.broadcast_state = ?", filter.BroadcastState)
+	}
+	if !filter.From.IsZero() {
+		query.Where("te.created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query.Where("te.created_at < ?", filter.To)
+	}
+	if filter.Cursor > 0 {
+		query.Where("te.id < ?", filter.Cursor)
+	}
+	err := query.OrderExpr("te.id DESC").Limit(filter.Limit).Scan(ctx, &entries)
+	if err != nil {
+		return nil, err
+	}
+	page := &TransactionHistoryPage{Entries: entries}
+	for i := range page.Entries {
+		entry := &page.Entries[i]
+		entry.Counterparty = counterpartyFor(*entry)
+		if entry.FiatPrice != "" {
+			entry.FiatValue, err = FiatValue(entry.Amount, entry.DecimalDisplay, entry.FiatPrice)
+			if err != nil {
+				return nil, err
+			}
+			entry.FiatCurrency = svc.Config.PriceCurrency
+		}
+	}
+	if len(entries) == filter.Limit {
+		page.NextCursor = strconv.FormatInt(entries[len(entries)-1].ID, 10)
+	}
+	return page, nil
+}
+
+func counterpartyFor(entry TransactionHistoryEntry) string {
+	if entry.EntryType == models.EntryTypeAdjustment {
+		return CounterpartyHub
+	}
+	if entry.CounterpartyPubkey != "" || entry.BroadcastState == models.TahubInternalComplete || entry.Outpoint == models.TahubInternalOutpoint {
+		return CounterpartyInternal
+	}
+	return CounterpartyExternal
+}
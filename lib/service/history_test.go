@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTransactionHistoryFilter(t *testing.T) {
+	params, err := ParseHistoryCommandParams([]string{"asset_id=btc", "limit=500", "cursor=120", "from=1700000000"})
+	assert.NoError(t, err)
+	filter, err := ParseTransactionHistoryFilter(params)
+	assert.NoError(t, err)
+	assert.Equal(t, "btc", filter.AssetID)
+	assert.Equal(t, MaxHistoryLimit, filter.Limit)
+	assert.Equal(t, int64(120), filter.Cursor)
+	assert.Equal(t, time.Unix(1700000000, 0), filter.From)
+	assert.True(t, filter.To.IsZero())
+
+	filter, err = ParseTransactionHistoryFilter(map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultHistoryLimit, filter.Limit)
+
+	_, err = ParseTransactionHistoryFilter(map[string]string{"limit": "-1"})
+	assert.Equal(t, InvalidHistoryFilterError, err)
+	_, err = ParseTransactionHistoryFilter(map[string]string{"unknown": "1"})
+	assert.Equal(t, InvalidHistoryFilterError, err)
+	_, err = ParseHistoryCommandParams([]string{"asset_id"})
+	assert.Equal(t, InvalidHistoryFilterError, err)
+}
+
+func TestCounterpartyFor(t *testing.T) {
+	assert.Equal(t, CounterpartyInternal, counterpartyFor(TransactionHistoryEntry{EntryType: models.EntryTypeIncoming, CounterpartyPubkey: "abc"}))
+	assert.Equal(t, CounterpartyInternal, counterpartyFor(TransactionHistoryEntry{EntryType: models.EntryTypeOutgoing, BroadcastState: models.TahubInternalComplete}))
+	assert.Equal(t, CounterpartyExternal, counterpartyFor(TransactionHistoryEntry{EntryType: models.EntryTypeOutgoing, BroadcastState: models.BroadcastStateBroadcast}))
+	assert.Equal(t, CounterpartyHub, counterpartyFor(TransactionHistoryEntry{EntryType: models.EntryTypeAdjustment}))
+}
@@ -0,0 +1,442 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/uptrace/bun"
+)
+
+// MaxTransferMemoLength is the longest memo an internal transfer can carry
+const MaxTransferMemoLength = 280
+
+var (
+	InvalidRecipientPubkeyError = errors.New("recipient must be an npub or a hex pubkey")
+	RecipientNotFoundError      = errors.New("recipient is not a hub user")
+	SelfTransferError           = errors.New("cannot transfer to yourself")
+	TransferMemoTooLongError    = fmt.Errorf("memo is longer than %d characters", MaxTransferMemoLength)
+)
+
+// ParseRecipientPubkey accepts an npub or a hex pubkey and returns the hex pubkey
+func ParseRecipientPubkey(recipient string) (string, error) {
+	if strings.HasPrefix(recipient, "npub") {
+		prefix, value, err := nip19.Decode(recipient)
+		if err != nil || prefix != "npub" {
+			return "", InvalidRecipientPubkeyError
+		}
+		recipient = value.(string)
+	}
+	if !nostr.IsValidPublicKeyHex(recipient) {
+		return "", InvalidRecipientPubkeyError
+	}
+	return recipient, nil
+}
+
+// TransferToPubkey moves a decimal amount of an asset from the sender's current account to the
+// current account of the hub user with the given pubkey. Nothing is sent on chain, so there is no
+// chain fee. Both sides are notified by DM. Pubkeys that are not registered yet get the amount held
+// in escrow until they register or the transfer expires, see escrowTransfer.
+func (svc *LndhubService) TransferToPubkey(ctx context.Context, senderId int64, recipient string, assetId string, amount string, memo string) (*models.InternalTransfer, error) {
+	if len([]rune(memo)) > MaxTransferMemoLength {
+		return nil, TransferMemoTooLongError
+	}
+	pubkey, err := ParseRecipientPubkey(recipient)
+	if err != nil {
+		return nil, err
+	}
+	units, err := svc.ParseAssetAmountFor(ctx, assetId, amount)
+	if err != nil {
+		return nil, err
+	}
+	if units > math.MaxInt64 {
+		return nil, UnrepresentableAssetAmountError
+	}
+	return svc.transferToPubkey(ctx, &models.InternalTransfer{
+		SenderID:        senderId,
+		RecipientPubkey: pubkey,
+		TaAssetID:       assetId,
+		Amount:          int64(units),
+		Memo:            memo,
+	}, nil)
+}
+
+// transferToPubkey books a transfer of integer units to transfer.RecipientPubkey. inTx, when set,
+// runs in the DB transaction of the transfer after it is inserted, its error rolls the transfer back.
+func (svc *LndhubService) transferToPubkey(ctx context.Context, transfer *models.InternalTransfer, inTx func(ctx context.Context, tx bun.Tx) error) (*models.InternalTransfer, error) {
+	senderId, assetId := transfer.SenderID, transfer.TaAssetID
+	recipientUser, err := svc.FindUserByPubkey(ctx, transfer.RecipientPubkey)
+	if errors.Is(err, sql.ErrNoRows) {
+		if svc.Config.ClaimableTransferExpiry <= 0 {
+			return nil, RecipientNotFoundError
+		}
+		return svc.escrowTransfer(ctx, transfer, inTx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if recipientUser.ID == senderId {
+		return nil, SelfTransferError
+	}
+	// the recipient may not have received the asset before
+	if err := svc.EnsureUserAssetAccounts(ctx, recipientUser.ID, assetId); err != nil {
+		return nil, err
+	}
+	transfer.RecipientID = recipientUser.ID
+	transfer.State = models.InternalTransferStateCompleted
+	assetName := assetId
+	var inTxErr error
+	err = svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		senderCurrent, err := svc.senderCurrentAccountInTx(ctx, tx, transfer)
+		if err != nil {
+			return err
+		}
+		senderOutgoing, err := svc.AccountForInTx(ctx, tx, common.AccountTypeOutgoing, assetId, senderId)
+		if err != nil {
+			return fmt.Errorf("could not find outgoing account for user_id:%d asset:%s: %w", senderId, assetId, err)
+		}
+		recipientIncoming, err := svc.AccountForInTx(ctx, tx, common.AccountTypeIncoming, assetId, recipientUser.ID)
+		if err != nil {
+			return fmt.Errorf("could not find incoming account for user_id:%d asset:%s: %w", recipientUser.ID, assetId, err)
+		}
+		recipientCurrent, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, assetId, recipientUser.ID)
+		if err != nil {
+			return fmt.Errorf("could not find current account for user_id:%d asset:%s: %w", recipientUser.ID, assetId, err)
+		}
+		if senderCurrent.Asset != nil && senderCurrent.Asset.AssetName != "" {
+			assetName = senderCurrent.Asset.AssetName
+		}
+		now := time.Now()
+		sendEntry := models.TransactionEntry{
+			UserID:          senderId,
+			TaAssetID:       assetId,
+			DebitAccountID:  senderCurrent.ID,
+			CreditAccountID: senderOutgoing.ID,
+			Amount:          transfer.Amount,
+			EntryType:       models.EntryTypeOutgoing,
+			Outpoint:        models.TahubInternalOutpoint,
+			BroadcastState:  models.TahubInternalComplete,
+			CreatedAt:       now,
+		}
+		if _, err := tx.NewInsert().Model(&sendEntry).Exec(ctx); err != nil {
+			return err
+		}
+		receiveEntry := models.TransactionEntry{
+			UserID:          recipientUser.ID,
+			ParentID:        sendEntry.ID,
+			TaAssetID:       assetId,
+			DebitAccountID:  recipientIncoming.ID,
+			CreditAccountID: recipientCurrent.ID,
+			Amount:          transfer.Amount,
+			EntryType:       models.EntryTypeIncoming,
+			Outpoint:        models.TahubInternalOutpoint,
+			BroadcastState:  models.TahubInternalComplete,
+			CreatedAt:       now,
+		}
+		if _, err := tx.NewInsert().Model(&receiveEntry).Exec(ctx); err != nil {
+			return err
+		}
+		transfer.TransactionEntryID = sendEntry.ID
+		if _, err := tx.NewInsert().Model(transfer).Exec(ctx); err != nil {
+			return err
+		}
+		if inTx != nil {
+			inTxErr = inTx(ctx, tx)
+		}
+		return inTxErr
+	})
+	if err != nil {
+		if !errors.Is(err, InsufficientBalanceError) && err != inTxErr {
+			sentry.CaptureException(err)
+		}
+		svc.Logger.Errorf("Could not transfer user_id:%v to user_id:%v asset:%s amount:%d error %v", senderId, recipientUser.ID, assetId, transfer.Amount, err)
+		return nil, err
+	}
+	svc.Logger.Infof("Internal transfer id:%d user_id:%v to user_id:%v asset:%s amount:%d", transfer.ID, senderId, recipientUser.ID, assetId, transfer.Amount)
+	sender, recipientNpub, senderNpub, amountText, ok := svc.transferNotificationParts(ctx, transfer, assetName)
+	if ok {
+		_ = svc.SendNip4Notification(ctx, fmt.Sprintf("sent: %s to %s%s", amountText, recipientNpub, memoSuffix(transfer.Memo)), sender.Pubkey)
+		_ = svc.SendNip4Notification(ctx, fmt.Sprintf("received: %s from %s%s", amountText, senderNpub, memoSuffix(transfer.Memo)), transfer.RecipientPubkey)
+	}
+	return transfer, nil
+}
+
+// senderCurrentAccountInTx returns the sender's current account of the transfer's asset once it is
+// known to cover the amount
+func (svc *LndhubService) senderCurrentAccountInTx(ctx context.Context, tx bun.Tx, transfer *models.InternalTransfer) (models.Account, error) {
+	senderCurrent, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, transfer.TaAssetID, transfer.SenderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// never held the asset
+		return senderCurrent, InsufficientBalanceError
+	}
+	if err != nil {
+		return senderCurrent, fmt.Errorf("could not find current account for user_id:%d asset:%s: %w", transfer.SenderID, transfer.TaAssetID, err)
+	}
+	balance, err := accountBalanceInTx(ctx, tx, senderCurrent.ID)
+	if err != nil {
+		return senderCurrent, err
+	}
+	if balance < transfer.Amount {
+		return senderCurrent, InsufficientBalanceError
+	}
+	return senderCurrent, nil
+}
+
+// escrowTransfer moves the amount of a transfer to a pubkey that is not registered yet from the
+// sender's current account to the escrow account of the asset. The recipient is invited by DM and
+// the transfer is credited when they register (ClaimTransfersFor) or returned to the sender once
+// CLAIMABLE_TRANSFER_EXPIRY has passed (RevertExpiredTransfers).
+func (svc *LndhubService) escrowTransfer(ctx context.Context, transfer *models.InternalTransfer, inTx func(ctx context.Context, tx bun.Tx) error) (*models.InternalTransfer, error) {
+	transfer.State = models.InternalTransferStatePending
+	transfer.ExpiresAt = time.Now().Add(time.Duration(svc.Config.ClaimableTransferExpiry) * time.Second)
+	assetName := transfer.TaAssetID
+	var inTxErr error
+	err := svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		senderCurrent, err := svc.senderCurrentAccountInTx(ctx, tx, transfer)
+		if err != nil {
+			return err
+		}
+		escrow, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeEscrow, transfer.TaAssetID)
+		if err != nil {
+			return fmt.Errorf("could not find escrow account for asset:%s: %w", transfer.TaAssetID, err)
+		}
+		if senderCurrent.Asset != nil && senderCurrent.Asset.AssetName != "" {
+			assetName = senderCurrent.Asset.AssetName
+		}
+		entry := models.TransactionEntry{
+			UserID:          transfer.SenderID,
+			TaAssetID:       transfer.TaAssetID,
+			DebitAccountID:  senderCurrent.ID,
+			CreditAccountID: escrow.ID,
+			Amount:          transfer.Amount,
+			EntryType:       models.EntryTypeOutgoing,
+			Outpoint:        models.TahubInternalOutpoint,
+			BroadcastState:  models.TahubInternalPending,
+		}
+		if _, err := tx.NewInsert().Model(&entry).Exec(ctx); err != nil {
+			return err
+		}
+		transfer.TransactionEntryID = entry.ID
+		if _, err := tx.NewInsert().Model(transfer).Exec(ctx); err != nil {
+			return err
+		}
+		if inTx != nil {
+			inTxErr = inTx(ctx, tx)
+		}
+		return inTxErr
+	})
+	if err != nil {
+		if !errors.Is(err, InsufficientBalanceError) && err != inTxErr {
+			sentry.CaptureException(err)
+		}
+		svc.Logger.Errorf("Could not escrow transfer user_id:%v to pubkey:%s asset:%s amount:%d error %v", transfer.SenderID, transfer.RecipientPubkey, transfer.TaAssetID, transfer.Amount, err)
+		return nil, err
+	}
+	svc.Logger.Infof("Escrowed transfer id:%d user_id:%v to pubkey:%s asset:%s amount:%d", transfer.ID, transfer.SenderID, transfer.RecipientPubkey, transfer.TaAssetID, transfer.Amount)
+	sender, recipientNpub, senderNpub, amountText, ok := svc.transferNotificationParts(ctx, transfer, assetName)
+	if ok {
+		expiry := transfer.ExpiresAt.UTC().Format(time.RFC3339)
+		_ = svc.SendNip4Notification(ctx, fmt.Sprintf("sent: %s to %s, it is held until they register and returned to you if not claimed by %s%s", amountText, recipientNpub, expiry, memoSuffix(transfer.Memo)), sender.Pubkey)
+		_ = svc.SendNip4Notification(ctx, fmt.Sprintf("%s sent you %s on this hub. register with TAHUB_CREATE_USER before %s to claim it%s", senderNpub, amountText, expiry, memoSuffix(transfer.Memo)), transfer.RecipientPubkey)
+	}
+	return transfer, nil
+}
+
+// ClaimTransfersFor credits the pending transfers to a newly registered user's pubkey, expired
+// transfers are left to RevertExpiredTransfers. Failures are logged and leave the transfer pending.
+func (svc *LndhubService) ClaimTransfersFor(ctx context.Context, user *models.User) ([]models.InternalTransfer, error) {
+	pending := []models.InternalTransfer{}
+	err := svc.DB.NewSelect().Model(&pending).
+		Where("recipient_pubkey = ? AND state = ? AND expires_at > ?", user.Pubkey, models.InternalTransferStatePending, time.Now()).
+		OrderExpr("id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claimed := []models.InternalTransfer{}
+	for _, transfer := range pending {
+		if err := svc.EnsureUserAssetAccounts(ctx, user.ID, transfer.TaAssetID); err != nil {
+			svc.Logger.Errorf("Could not create accounts to claim transfer id:%d user_id:%v: %v", transfer.ID, user.ID, err)
+			continue
+		}
+		settled, err := svc.settleEscrowedTransfer(ctx, transfer.ID, user.ID, models.InternalTransferStateClaimed)
+		if err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("Could not claim transfer id:%d user_id:%v: %v", transfer.ID, user.ID, err)
+			continue
+		}
+		if settled != nil {
+			claimed = append(claimed, *settled)
+		}
+	}
+	return claimed, nil
+}
+
+// RevertExpiredTransfers returns every pending transfer that was not claimed in time to its sender
+func (svc *LndhubService) RevertExpiredTransfers(ctx context.Context) error {
+	expired := []models.InternalTransfer{}
+	err := svc.DB.NewSelect().Model(&expired).
+		Where("state = ? AND expires_at <= ?", models.InternalTransferStatePending, time.Now()).
+		OrderExpr("id ASC").
+		Scan(ctx)
+	if err != nil {
+		svc.Logger.Errorf("Could not load expired transfers: %v", err)
+		return err
+	}
+	for _, transfer := range expired {
+		if _, err := svc.settleEscrowedTransfer(ctx, transfer.ID, transfer.SenderID, models.InternalTransferStateReverted); err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("Could not revert expired transfer id:%d: %v", transfer.ID, err)
+		}
+	}
+	return nil
+}
+
+// settleEscrowedTransfer moves a pending transfer out of escrow to the current account of userId,
+// the recipient for claims and the sender for reversals. It returns nil when the transfer was
+// settled concurrently.
+func (svc *LndhubService) settleEscrowedTransfer(ctx context.Context, transferId int64, userId int64, state string) (*models.InternalTransfer, error) {
+	transfer := &models.InternalTransfer{}
+	assetName := ""
+	settled := false
+	err := svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewSelect().Model(transfer).
+			Where("id = ? AND state = ?", transferId, models.InternalTransferStatePending).
+			For("UPDATE").
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		escrow, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeEscrow, transfer.TaAssetID)
+		if err != nil {
+			return fmt.Errorf("could not find escrow account for asset:%s: %w", transfer.TaAssetID, err)
+		}
+		current, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, transfer.TaAssetID, userId)
+		if err != nil {
+			return fmt.Errorf("could not find current account for user_id:%d asset:%s: %w", userId, transfer.TaAssetID, err)
+		}
+		if escrow.Asset != nil {
+			assetName = escrow.Asset.AssetName
+		}
+		entry := models.TransactionEntry{
+			UserID:          userId,
+			ParentID:        transfer.TransactionEntryID,
+			TaAssetID:       transfer.TaAssetID,
+			DebitAccountID:  escrow.ID,
+			CreditAccountID: current.ID,
+			Amount:          transfer.Amount,
+			EntryType:       models.EntryTypeIncoming,
+			Outpoint:        models.TahubInternalOutpoint,
+			BroadcastState:  models.TahubInternalComplete,
+		}
+		sendState := models.TahubInternalComplete
+		if state == models.InternalTransferStateReverted {
+			entry.EntryType = models.EntryTypeOutgoingReversal
+			sendState = models.BroadcastStateFailed
+		} else {
+			transfer.RecipientID = userId
+		}
+		if _, err := tx.NewInsert().Model(&entry).Exec(ctx); err != nil {
+			return err
+		}
+		_, err = tx.NewUpdate().Model(&models.TransactionEntry{ID: transfer.TransactionEntryID, BroadcastState: sendState}).
+			Column("broadcast_state").WherePK().Exec(ctx)
+		if err != nil {
+			return err
+		}
+		transfer.State = state
+		transfer.SettlementEntryID = entry.ID
+		transfer.SettledAt = time.Now()
+		_, err = tx.NewUpdate().Model(transfer).Column("state", "recipient_id", "settlement_entry_id", "settled_at").WherePK().Exec(ctx)
+		settled = err == nil
+		return err
+	})
+	if err != nil || !settled {
+		return nil, err
+	}
+	if assetName == "" {
+		assetName = transfer.TaAssetID
+	}
+	svc.Logger.Infof("Settled escrowed transfer id:%d state:%s to user_id:%v", transfer.ID, state, userId)
+	sender, recipientNpub, senderNpub, amountText, ok := svc.transferNotificationParts(ctx, transfer, assetName)
+	if !ok {
+		return transfer, nil
+	}
+	if state == models.InternalTransferStateReverted {
+		_ = svc.SendNip4Notification(ctx, fmt.Sprintf("returned: %s sent to %s was not claimed in time%s", amountText, recipientNpub, memoSuffix(transfer.Memo)), sender.Pubkey)
+	} else {
+		_ = svc.SendNip4Notification(ctx, fmt.Sprintf("received: %s from %s%s", amountText, senderNpub, memoSuffix(transfer.Memo)), transfer.RecipientPubkey)
+		_ = svc.SendNip4Notification(ctx, fmt.Sprintf("claimed: %s sent to %s%s", amountText, recipientNpub, memoSuffix(transfer.Memo)), sender.Pubkey)
+	}
+	return transfer, nil
+}
+
+// memoSuffix ends the DMs about transfers and payment requests with their memo
+func memoSuffix(memo string) string {
+	if memo == "" {
+		return ""
+	}
+	return " memo: " + memo
+}
+
+// transferNotificationParts looks up what the DMs about a transfer mention, ok is false when the
+// sender cannot be found and the DMs are skipped
+func (svc *LndhubService) transferNotificationParts(ctx context.Context, transfer *models.InternalTransfer, assetName string) (sender *models.User, recipientNpub string, senderNpub string, amountText string, ok bool) {
+	sender, err := svc.FindUser(ctx, transfer.SenderID)
+	if err != nil {
+		svc.Logger.Errorf("Could not find sender of internal transfer id:%d: %v", transfer.ID, err)
+		return nil, "", "", "", false
+	}
+	decimalDisplay, err := svc.AssetDecimalDisplay(ctx, transfer.TaAssetID)
+	if err != nil {
+		svc.Logger.Errorf("Could not find decimal display of internal transfer id:%d: %v", transfer.ID, err)
+		return nil, "", "", "", false
+	}
+	amountText = fmt.Sprintf("%s %s", FormatAssetAmount(transfer.Amount, decimalDisplay), assetName)
+	senderNpub, _ = nip19.EncodePublicKey(sender.Pubkey)
+	recipientNpub, _ = nip19.EncodePublicKey(transfer.RecipientPubkey)
+	return sender, recipientNpub, senderNpub, amountText, true
+}
+
+func (svc *LndhubService) StartClaimableTransferExpiryRoutine(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(svc.Config.ClaimableTransferExpiryInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = svc.RevertExpiredTransfers(ctx)
+		}
+	}
+}
+
+// IsTransferUserError tells the errors of a transfer the sender can act on from failures of the hub
+func IsTransferUserError(err error) bool {
+	for _, userErr := range []error{
+		InvalidRecipientPubkeyError,
+		RecipientNotFoundError,
+		SelfTransferError,
+		TransferMemoTooLongError,
+		InsufficientBalanceError,
+		InvalidAssetAmountError,
+		UnrepresentableAssetAmountError,
+	} {
+		if errors.Is(err, userErr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecipientPubkey(t *testing.T) {
+	hex := strings.Repeat("ab", 32)
+	npub, err := nip19.EncodePublicKey(hex)
+	assert.NoError(t, err)
+
+	pubkey, err := ParseRecipientPubkey(npub)
+	assert.NoError(t, err)
+	assert.Equal(t, hex, pubkey)
+	pubkey, err = ParseRecipientPubkey(hex)
+	assert.NoError(t, err)
+	assert.Equal(t, hex, pubkey)
+
+	nsec, err := nip19.EncodePrivateKey(hex)
+	assert.NoError(t, err)
+	for _, recipient := range []string{"", "npub1xyz", nsec, strings.Repeat("zz", 32), hex[2:]} {
+		_, err = ParseRecipientPubkey(recipient)
+		assert.ErrorIs(t, err, InvalidRecipientPubkeyError, recipient)
+	}
+}
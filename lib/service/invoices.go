@@ -274,6 +274,7 @@ func (svc *LndhubService) HandleFailedPayment(ctx context.Context, invoice *mode
 		CreditAccountID: entryToRevert.DebitAccountID,
 		DebitAccountID:  entryToRevert.CreditAccountID,
 		Amount:          invoice.Amount,
+		TaAssetID:       entryToRevert.TaAssetID,
 		EntryType:       models.EntryTypeOutgoingReversal,
 	}
 	_, err = tx.NewInsert().Model(&entry).Exec(ctx)
@@ -334,7 +335,7 @@ func (svc *LndhubService) InsertTapdTransactionEntry(ctx context.Context, userId
 }
 /// * NOTE the difference between this function and InsertTapdTransactionEntry is that the transaction has already started in
 ///		   this function.
-func (svc *LndhubService) InsertTapdTransactionEntryInTx(ctx context.Context, tx bun.Tx, userId int64, creditAccount models.Account, debitAccount models.Account, amt uint64) (entry models.TransactionEntry, err error) {
+func (svc *LndhubService) InsertTapdTransactionEntryInTx(ctx context.Context, tx bun.Tx, userId int64, creditAccount models.Account, debitAccount models.Account, amt uint64, addr string) (entry models.TransactionEntry, err error) {
 	entry = models.TransactionEntry{
 		UserID:          userId,
 		CreditAccountID: creditAccount.ID,
@@ -343,6 +344,7 @@ func (svc *LndhubService) InsertTapdTransactionEntryInTx(ctx context.Context, tx
 		BroadcastState:  models.BroadcastStatePending,
 		TaAssetID: 		 creditAccount.TaAssetID,
 		EntryType:       models.EntryTypeOutgoing,
+		Address:         addr,
 	}
 
 	// The DB constraints make sure the user actually has enough balance for the transaction
@@ -116,6 +116,13 @@ func (svc *LndhubService) ProcessInvoiceUpdate(ctx context.Context, rawInvoice *
 	}
 	// Get the user's incoming account for the transaction entry
 	debitAccount, err := svc.AccountFor(ctx, common.AccountTypeIncoming, invoice.TaAssetID, invoice.UserID)
+	// asset invoices are paid through an asset channel: the user is credited the quoted
+	// asset amount and the counterparty is the asset treasury
+	amountPaid := rawInvoice.AmtPaidSat
+	if invoice.IsAssetInvoice() {
+		debitAccount, err = svc.SystemAccountFor(ctx, common.AccountTypeTreasury, invoice.TaAssetID)
+		amountPaid = invoice.Amount
+	}
 	if err != nil {
 		svc.Logger.Errorf("Could not find incoming account user_id:%v invoice_id:%v", invoice.UserID, invoice.ID)
 		return err
@@ -128,7 +135,7 @@ func (svc *LndhubService) ProcessInvoiceUpdate(ctx context.Context, rawInvoice *
 		return err
 	}
 
-	if rawInvoice.AmtPaidSat != invoice.Amount {
+	if !invoice.IsAssetInvoice() && rawInvoice.AmtPaidSat != invoice.Amount {
 		svc.Logger.Infof("Incoming invoice amount mismatch. user_id:%v invoice_id:%v, amt:%d, amt_paid:%d.", invoice.UserID, invoice.ID, invoice.Amount, rawInvoice.AmtPaidSat)
 	}
 
@@ -141,7 +148,7 @@ func (svc *LndhubService) ProcessInvoiceUpdate(ctx context.Context, rawInvoice *
 		// if the invoice is settled we update the state and create an transaction entry to the current account
 		invoice.SettledAt = bun.NullTime{Time: time.Unix(rawInvoice.SettleDate, 0)}
 		invoice.State = common.InvoiceStateSettled
-		invoice.Amount = rawInvoice.AmtPaidSat
+		invoice.Amount = amountPaid
 		_, err = tx.NewUpdate().Model(&invoice).WherePK().Exec(ctx)
 		if err != nil {
 			tx.Rollback()
@@ -155,8 +162,9 @@ func (svc *LndhubService) ProcessInvoiceUpdate(ctx context.Context, rawInvoice *
 			InvoiceID:       invoice.ID,
 			CreditAccountID: creditAccount.ID,
 			DebitAccountID:  debitAccount.ID,
-			Amount:          rawInvoice.AmtPaidSat,
+			Amount:          amountPaid,
 			EntryType:       models.EntryTypeIncoming,
+			TaAssetID:       invoice.TaAssetID,
 		}
 		// Save the transaction entry
 		_, err = tx.NewInsert().Model(&entry).Exec(ctx)
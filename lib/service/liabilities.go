@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getAlby/lndhub.go/lib/merklesum"
+	"github.com/getsentry/sentry-go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/uptrace/bun"
+)
+
+// tag on the published root events so clients can filter for them
+const LiabilityRootNostrTag = "tahub-liabilities"
+
+// LiabilityProof : everything a user needs to verify that their balance is part of a published root
+type LiabilityProof struct {
+	SnapshotID   int64                 `json:"snapshot_id"`
+	TaAssetID    string                `json:"asset_id"`
+	CreatedAt    time.Time             `json:"created_at"`
+	RootHash     string                `json:"root_hash"`
+	RootSum      int64                 `json:"root_sum"`
+	NostrEventID string                `json:"nostr_event_id"`
+	Pubkey       string                `json:"pubkey"`
+	Salt         string                `json:"salt"`
+	Balance      int64                 `json:"balance"`
+	LeafIndex    int64                 `json:"leaf_index"`
+	Path         []merklesum.ProofStep `json:"path"`
+}
+
+type userBalance struct {
+	UserID  int64
+	Pubkey  string
+	Balance int64
+}
+
+// addEscrowed adds the pending escrowed transfers to the leaves of their senders, the amounts are
+// owed to the recipient once they register and return to the sender when the transfer expires.
+// The result is ordered by user id like the balances.
+func addEscrowed(balances []userBalance, escrowed []userBalance) []userBalance {
+	index := make(map[int64]int, len(balances))
+	for i, balance := range balances {
+		index[balance.UserID] = i
+	}
+	for _, pending := range escrowed {
+		if i, ok := index[pending.UserID]; ok {
+			balances[i].Balance += pending.Balance
+			continue
+		}
+		index[pending.UserID] = len(balances)
+		balances = append(balances, pending)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].UserID < balances[j].UserID })
+	return balances
+}
+
+// SnapshotLiabilities builds a merkle sum tree over the current and reserved balances of all users
+// holding an account for the asset plus what they hold in escrow, so the root sum matches the
+// liabilities of the solvency report. Stores the salted leaves and publishes the root as a signed Nostr event
+func (svc *LndhubService) SnapshotLiabilities(ctx context.Context, assetId string) (*models.LiabilitySnapshot, error) {
+	snapshot := &models.LiabilitySnapshot{TaAssetID: assetId}
+	// repeatable read so all balances are taken at the same point in time
+	err := svc.DB.RunInTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead}, func(ctx context.Context, tx bun.Tx) error {
+		balances := []userBalance{}
+		err := tx.NewSelect().
+			TableExpr("accounts").
+			Join("JOIN users ON users.id = accounts.user_id").
+			Join("LEFT JOIN account_ledgers ON account_ledgers.account_id = accounts.id").
+			ColumnExpr("accounts.user_id, users.pubkey, coalesce(sum(account_ledgers.amount), 0) AS balance").
+			Where("accounts.type IN (?) AND accounts.ta_asset_id = ?", bun.In([]string{common.AccountTypeCurrent, common.AccountTypeReserved}), assetId).
+			GroupExpr("accounts.user_id, users.pubkey").
+			OrderExpr("accounts.user_id").
+			Scan(ctx, &balances)
+		if err != nil {
+			return err
+		}
+		escrowed := []userBalance{}
+		err = tx.NewSelect().
+			TableExpr("internal_transfers").
+			Join("JOIN users ON users.id = internal_transfers.sender_id").
+			ColumnExpr("internal_transfers.sender_id AS user_id, users.pubkey, sum(internal_transfers.amount) AS balance").
+			Where("internal_transfers.state = ? AND internal_transfers.ta_asset_id = ?", models.InternalTransferStatePending, assetId).
+			GroupExpr("internal_transfers.sender_id, users.pubkey").
+			Scan(ctx, &escrowed)
+		if err != nil {
+			return err
+		}
+		balances = addEscrowed(balances, escrowed)
+		leaves := make([]models.LiabilityLeaf, 0, len(balances))
+		nodes := make([]merklesum.Node, 0, len(balances))
+		for i, balance := range balances {
+			if balance.Balance < 0 {
+				return fmt.Errorf("negative balance for user_id:%d asset:%s", balance.UserID, assetId)
+			}
+			salt := make([]byte, 16)
+			if _, err := rand.Read(salt); err != nil {
+				return err
+			}
+			leaves = append(leaves, models.LiabilityLeaf{
+				UserID:    balance.UserID,
+				LeafIndex: int64(i),
+				Salt:      hex.EncodeToString(salt),
+				Balance:   balance.Balance,
+			})
+			nodes = append(nodes, merklesum.LeafNode(balance.Pubkey, salt, uint64(balance.Balance)))
+		}
+		root := merklesum.New(nodes).Root()
+		snapshot.RootHash = root.HashHex()
+		snapshot.RootSum = int64(root.Sum)
+		snapshot.LeafCount = int64(len(leaves))
+		if _, err := tx.NewInsert().Model(snapshot).Exec(ctx); err != nil {
+			return err
+		}
+		if len(leaves) == 0 {
+			return nil
+		}
+		for i := range leaves {
+			leaves[i].SnapshotID = snapshot.ID
+		}
+		_, err = tx.NewInsert().Model(&leaves).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	svc.Logger.Infof("Liability snapshot id:%d asset:%s root:%s sum:%d leaves:%d", snapshot.ID, assetId, snapshot.RootHash, snapshot.RootSum, snapshot.LeafCount)
+	err = svc.PublishLiabilityRoot(ctx, snapshot)
+	if err != nil {
+		// the snapshot is valid without the event, the root can still be fetched with a proof
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("Failed to publish liability root for snapshot id:%d: %v", snapshot.ID, err)
+	}
+	return snapshot, nil
+}
+
+// PublishLiabilityRoot publishes the snapshot root as a text note signed by the hub key
+func (svc *LndhubService) PublishLiabilityRoot(ctx context.Context, snapshot *models.LiabilitySnapshot) error {
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	event := nostr.Event{
+		CreatedAt: nostr.Timestamp(snapshot.CreatedAt.Unix()),
+		PubKey:    svc.Config.TahubPublicKey,
+		Kind:      nostr.KindTextNote,
+		Content:   string(content),
+		Tags: nostr.Tags{
+			{"t", LiabilityRootNostrTag},
+			{"asset_id", snapshot.TaAssetID},
+			{"root", snapshot.RootHash},
+		},
+	}
+	if snapshot.CreatedAt.IsZero() {
+		event.CreatedAt = nostr.Now()
+	}
+	if err = event.Sign(svc.Config.TahubPrivateKey); err != nil {
+		return err
+	}
+	if err = svc.PublishToRelays(ctx, event); err != nil {
+		return err
+	}
+	snapshot.NostrEventID = event.ID
+	_, err = svc.DB.NewUpdate().Model(snapshot).Column("nostr_event_id").WherePK().Exec(ctx)
+	return err
+}
+
+// SnapshotAllLiabilities takes a snapshot for every registered asset
+func (svc *LndhubService) SnapshotAllLiabilities(ctx context.Context) error {
+	assets, err := svc.GetAssets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, asset := range assets {
+		if _, err := svc.SnapshotLiabilities(ctx, asset.TaAssetID); err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("Failed to snapshot liabilities for asset %s: %v", asset.TaAssetID, err)
+		}
+	}
+	return nil
+}
+
+func (svc *LndhubService) StartLiabilitySnapshotRoutine(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(svc.Config.LiabilitySnapshotInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = svc.SnapshotAllLiabilities(ctx)
+		}
+	}
+}
+
+// GetLiabilityProof returns the user's inclusion path in the latest snapshot of the asset
+func (svc *LndhubService) GetLiabilityProof(ctx context.Context, user *models.User, assetId string) (*LiabilityProof, error) {
+	snapshot := models.LiabilitySnapshot{}
+	err := svc.DB.NewSelect().Model(&snapshot).Where("ta_asset_id = ?", assetId).OrderExpr("id DESC").Limit(1).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	leaves := []models.LiabilityLeaf{}
+	err = svc.DB.NewSelect().Model(&leaves).Where("snapshot_id = ?", snapshot.ID).Relation("User").OrderExpr("leaf_index ASC").Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]merklesum.Node, 0, len(leaves))
+	userLeaf := -1
+	for i, leaf := range leaves {
+		salt, err := hex.DecodeString(leaf.Salt)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, merklesum.LeafNode(leaf.User.Pubkey, salt, uint64(leaf.Balance)))
+		if leaf.UserID == user.ID {
+			userLeaf = i
+		}
+	}
+	if userLeaf < 0 {
+		return nil, fmt.Errorf("user_id:%d is not part of snapshot id:%d", user.ID, snapshot.ID)
+	}
+	tree := merklesum.New(nodes)
+	if tree.Root().HashHex() != snapshot.RootHash {
+		return nil, fmt.Errorf("stored leaves do not match root of snapshot id:%d", snapshot.ID)
+	}
+	path, err := tree.Proof(userLeaf)
+	if err != nil {
+		return nil, err
+	}
+	return &LiabilityProof{
+		SnapshotID:   snapshot.ID,
+		TaAssetID:    snapshot.TaAssetID,
+		CreatedAt:    snapshot.CreatedAt,
+		RootHash:     snapshot.RootHash,
+		RootSum:      snapshot.RootSum,
+		NostrEventID: snapshot.NostrEventID,
+		Pubkey:       user.Pubkey,
+		Salt:         leaves[userLeaf].Salt,
+		Balance:      leaves[userLeaf].Balance,
+		LeafIndex:    leaves[userLeaf].LeafIndex,
+		Path:         path,
+	}, nil
+}
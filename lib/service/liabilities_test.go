@@ -0,0 +1,18 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddEscrowed(t *testing.T) {
+	balances := []userBalance{{UserID: 1, Pubkey: "a", Balance: 100}, {UserID: 3, Pubkey: "c", Balance: 0}}
+	escrowed := []userBalance{{UserID: 3, Pubkey: "c", Balance: 25}, {UserID: 2, Pubkey: "b", Balance: 10}}
+	assert.Equal(t, []userBalance{
+		{UserID: 1, Pubkey: "a", Balance: 100},
+		{UserID: 2, Pubkey: "b", Balance: 10},
+		{UserID: 3, Pubkey: "c", Balance: 25},
+	}, addEscrowed(balances, escrowed))
+	assert.Equal(t, 0, len(addEscrowed(nil, nil)))
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/getAlby/lndhub.go/db/models"
+)
+
+const (
+	LnurlPayTag = "payRequest"
+	// LnurlCommentAllowed is the longest comment a payer can attach to a payment (LUD-12)
+	LnurlCommentAllowed = 255
+	// DefaultLnurlMaxSendable caps LNURL payments in sats when MAX_RECEIVE_AMOUNT is unlimited
+	DefaultLnurlMaxSendable = 100000000
+)
+
+var (
+	LightningAddressesDisabledError = errors.New("lightning addresses are not enabled on this hub")
+	LnurlAmountError                = errors.New("amount must be whole sats within the sendable range")
+	LnurlCommentTooLongError        = errors.New("comment is too long")
+)
+
+// LnurlPayParams : the first LNURL-pay response (LUD-06), amounts are in millisatoshis
+type LnurlPayParams struct {
+	Callback       string `json:"callback"`
+	MinSendable    int64  `json:"minSendable"`
+	MaxSendable    int64  `json:"maxSendable"`
+	Metadata       string `json:"metadata"`
+	CommentAllowed int    `json:"commentAllowed"`
+	Tag            string `json:"tag"`
+}
+
+// lightningAddressBase is the public URL of the hub, its host is the domain of lightning addresses
+func (svc *LndhubService) lightningAddressBase() (*url.URL, error) {
+	if svc.Config.PublicURL == "" {
+		return nil, LightningAddressesDisabledError
+	}
+	base, err := url.Parse(strings.TrimRight(svc.Config.PublicURL, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid PUBLIC_URL %q: %w", svc.Config.PublicURL, LightningAddressesDisabledError)
+	}
+	return base, nil
+}
+
+// LightningAddress is the name@domain address of a username
+func (svc *LndhubService) LightningAddress(username string) (string, error) {
+	base, err := svc.lightningAddressBase()
+	if err != nil {
+		return "", err
+	}
+	return username + "@" + base.Host, nil
+}
+
+// LnurlMetadata is the metadata of a lightning address, invoices commit to it with their description hash
+func LnurlMetadata(address string) string {
+	metadata, _ := json.Marshal([][]string{
+		{"text/plain", "Payment to " + address},
+		{"text/identifier", address},
+	})
+	return string(metadata)
+}
+
+// lnurlSendable is the range of LNURL payments in sats
+func (svc *LndhubService) lnurlSendable() (min int64, max int64) {
+	min = svc.Config.LnurlMinSendable
+	if min < 1 {
+		min = 1
+	}
+	max = svc.Config.MaxReceiveAmount
+	if max <= 0 {
+		max = DefaultLnurlMaxSendable
+	}
+	return min, max
+}
+
+// LnurlPayParamsFor describes how to pay the lightning address of a username
+func (svc *LndhubService) LnurlPayParamsFor(ctx context.Context, username string) (*LnurlPayParams, error) {
+	base, err := svc.lightningAddressBase()
+	if err != nil {
+		return nil, err
+	}
+	user, err := svc.FindUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	address := user.Username + "@" + base.Host
+	min, max := svc.lnurlSendable()
+	return &LnurlPayParams{
+		Callback:       base.String() + "/lnurlp/" + user.Username + "/callback",
+		MinSendable:    min * 1000,
+		MaxSendable:    max * 1000,
+		Metadata:       LnurlMetadata(address),
+		CommentAllowed: LnurlCommentAllowed,
+		Tag:            LnurlPayTag,
+	}, nil
+}
+
+// LnurlPayInvoice creates the btc invoice of an LNURL payment to a user. The invoice commits to the
+// lightning address metadata with its description hash, so the payer's comment is only kept as the
+// memo of the invoice in the hub.
+func (svc *LndhubService) LnurlPayInvoice(ctx context.Context, user *models.User, amountMsat int64, comment string) (*models.Invoice, error) {
+	address, err := svc.LightningAddress(user.Username)
+	if err != nil {
+		return nil, err
+	}
+	min, max := svc.lnurlSendable()
+	if amountMsat%1000 != 0 || amountMsat < min*1000 || amountMsat > max*1000 {
+		return nil, LnurlAmountError
+	}
+	if len([]rune(comment)) > LnurlCommentAllowed {
+		return nil, LnurlCommentTooLongError
+	}
+	descriptionHash := sha256.Sum256([]byte(LnurlMetadata(address)))
+	invoice, errResp := svc.AddIncomingInvoice(ctx, user.ID, amountMsat/1000, "", hex.EncodeToString(descriptionHash[:]))
+	if errResp != nil {
+		return nil, fmt.Errorf("could not create lnurl invoice for user_id:%d: %s", user.ID, errResp.Message)
+	}
+	if comment != "" {
+		invoice.Memo = comment
+		if _, err := svc.DB.NewUpdate().Model(invoice).Column("memo").WherePK().Exec(ctx); err != nil {
+			svc.Logger.Errorf("Could not store lnurl comment of invoice id:%d: %v", invoice.ID, err)
+		}
+	}
+	return invoice, nil
+}
+
+// IsLnurlUserError tells the errors of LNURL requests the payer can act on from failures of the hub
+func IsLnurlUserError(err error) bool {
+	for _, userErr := range []error{
+		LightningAddressesDisabledError,
+		UsernameNotFoundError,
+		LnurlAmountError,
+		LnurlCommentTooLongError,
+	} {
+		if errors.Is(err, userErr) {
+			return true
+		}
+	}
+	return false
+}
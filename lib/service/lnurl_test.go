@@ -0,0 +1,28 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	name, err := NormalizeUsername(" Alice.Smith_1 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice.smith_1", name)
+
+	for _, invalid := range []string{"", "alice smith", "alice@hub", "ålice", "a:b", string(make([]byte, 65))} {
+		_, err = NormalizeUsername(invalid)
+		assert.ErrorIs(t, err, InvalidUsernameError, invalid)
+	}
+}
+
+func TestLnurlMetadata(t *testing.T) {
+	metadata := [][]string{}
+	assert.NoError(t, json.Unmarshal([]byte(LnurlMetadata("alice@hub.example.com")), &metadata))
+	assert.Equal(t, [][]string{
+		{"text/plain", "Payment to alice@hub.example.com"},
+		{"text/identifier", "alice@hub.example.com"},
+	}, metadata)
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/lightninglabs/taproot-assets/taprpc/mintrpc"
+	"github.com/uptrace/bun"
+)
+
+const (
+	MintAssetTypeNormal      = "normal"
+	MintAssetTypeCollectible = "collectible"
+)
+
+var InvalidMintRequestError = errors.New("invalid mint request")
+
+// MintAssetRequest : a seedling added to tapd's pending minting batch
+type MintAssetRequest struct {
+	Name      string
+	AssetType string
+	Amount    int64
+	// opaque metadata, if DecimalDisplay is set it is stored as a JSON object carrying decimal_display
+	Metadata       string
+	DecimalDisplay uint32
+	// hex encoded group key of an existing group to reissue into
+	GroupKey        string
+	NewGroupedAsset bool
+	// optional user credited with the minted supply once the batch is finalized
+	IssuerUserID int64
+}
+
+func (req MintAssetRequest) validate() error {
+	if req.Name == "" || req.Amount <= 0 {
+		return InvalidMintRequestError
+	}
+	if req.AssetType != MintAssetTypeNormal && req.AssetType != MintAssetTypeCollectible {
+		return InvalidMintRequestError
+	}
+	if req.AssetType == MintAssetTypeCollectible && (req.Amount != 1 || req.DecimalDisplay > 0) {
+		return InvalidMintRequestError
+	}
+	if req.GroupKey != "" && req.NewGroupedAsset {
+		return InvalidMintRequestError
+	}
+	if req.GroupKey != "" {
+		if _, err := hex.DecodeString(req.GroupKey); err != nil {
+			return InvalidMintRequestError
+		}
+	}
+	return nil
+}
+
+// mintAssetMeta builds the asset meta, the decimal display is added to a JSON metadata object or
+// the metadata is wrapped as description
+func mintAssetMeta(metadata string, decimalDisplay uint32) ([]byte, error) {
+	if decimalDisplay == 0 {
+		return []byte(metadata), nil
+	}
+	meta := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(metadata), &meta); err != nil || metadata == "" {
+		meta = map[string]interface{}{}
+		if metadata != "" {
+			meta["description"] = metadata
+		}
+	}
+	meta["decimal_display"] = decimalDisplay
+	return json.Marshal(meta)
+}
+
+// mintBatchState maps the tapd batch state to the state stored in mint_batches
+func mintBatchState(state mintrpc.BatchState) string {
+	return strings.ToLower(strings.TrimPrefix(state.String(), "BATCH_STATE_"))
+}
+
+// MintAsset adds a seedling to the pending batch on tapd and tracks it
+func (svc *LndhubService) MintAsset(ctx context.Context, req MintAssetRequest) (*models.MintBatchAsset, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	if req.IssuerUserID > 0 {
+		if _, err := svc.FindUser(ctx, req.IssuerUserID); err != nil {
+			return nil, fmt.Errorf("issuer user_id:%d not found: %w", req.IssuerUserID, err)
+		}
+	}
+	meta, err := mintAssetMeta(req.Metadata, req.DecimalDisplay)
+	if err != nil {
+		return nil, err
+	}
+	assetType := taprpc.AssetType_NORMAL
+	if req.AssetType == MintAssetTypeCollectible {
+		assetType = taprpc.AssetType_COLLECTIBLE
+	}
+	mintAsset := &mintrpc.MintAsset{
+		AssetType:       assetType,
+		Name:            req.Name,
+		AssetMeta:       &taprpc.AssetMeta{Data: meta, Type: taprpc.AssetMetaType_META_TYPE_OPAQUE},
+		Amount:          uint64(req.Amount),
+		NewGroupedAsset: req.NewGroupedAsset,
+	}
+	if req.GroupKey != "" {
+		mintAsset.GroupedAsset = true
+		mintAsset.GroupKey, _ = hex.DecodeString(req.GroupKey)
+	}
+	resp, err := svc.TapdClient.MintAsset(ctx, &mintrpc.MintAssetRequest{Asset: mintAsset, ShortResponse: true})
+	if err != nil {
+		svc.Logger.Errorf("Failed to mint asset %s: %v", req.Name, err)
+		return nil, err
+	}
+	batch := models.MintBatch{
+		BatchKey: hex.EncodeToString(resp.PendingBatch.BatchKey),
+		State:    mintBatchState(resp.PendingBatch.State),
+	}
+	seedling := models.MintBatchAsset{
+		AssetName:       req.Name,
+		AssetType:       int64(assetType),
+		Amount:          req.Amount,
+		Metadata:        string(meta),
+		DecimalDisplay:  req.DecimalDisplay,
+		GroupKey:        req.GroupKey,
+		NewGroupedAsset: req.NewGroupedAsset,
+		IssuerUserID:    req.IssuerUserID,
+	}
+	err = svc.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewInsert().Model(&batch).
+			On("CONFLICT (batch_key) DO UPDATE").
+			Set("state = EXCLUDED.state").
+			Returning("id").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		seedling.BatchID = batch.ID
+		_, err = tx.NewInsert().Model(&seedling).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &seedling, nil
+}
+
+// FinalizeMintBatch finalizes the pending batch, feeRate is in sat/kw (0 lets tapd estimate)
+func (svc *LndhubService) FinalizeMintBatch(ctx context.Context, feeRate uint32) (*models.MintBatch, error) {
+	resp, err := svc.TapdClient.FinalizeBatch(ctx, &mintrpc.FinalizeBatchRequest{ShortResponse: true, FeeRate: feeRate})
+	if err != nil {
+		svc.Logger.Errorf("Failed to finalize mint batch: %v", err)
+		return nil, err
+	}
+	batch := models.MintBatch{}
+	err = svc.DB.NewSelect().Model(&batch).Where("batch_key = ?", hex.EncodeToString(resp.Batch.BatchKey)).Limit(1).Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("finalized batch %x is not tracked: %w", resp.Batch.BatchKey, err)
+	}
+	batch.BatchTxid = resp.Batch.BatchTxid
+	batch.State = mintBatchState(resp.Batch.State)
+	batch.FeeRate = feeRate
+	_, err = svc.DB.NewUpdate().Model(&batch).WherePK().Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// CancelMintBatch cancels the pending batch
+func (svc *LndhubService) CancelMintBatch(ctx context.Context) (*models.MintBatch, error) {
+	resp, err := svc.TapdClient.CancelBatch(ctx, &mintrpc.CancelBatchRequest{})
+	if err != nil {
+		svc.Logger.Errorf("Failed to cancel mint batch: %v", err)
+		return nil, err
+	}
+	batch := models.MintBatch{}
+	err = svc.DB.NewSelect().Model(&batch).Where("batch_key = ?", hex.EncodeToString(resp.BatchKey)).Limit(1).Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cancelled batch %x is not tracked: %w", resp.BatchKey, err)
+	}
+	batch.State = models.MintBatchStateCancelled
+	_, err = svc.DB.NewUpdate().Model(&batch).WherePK().Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (svc *LndhubService) MintBatches(ctx context.Context) ([]models.MintBatch, error) {
+	batches := []models.MintBatch{}
+	err := svc.DB.NewSelect().Model(&batches).Relation("Assets").OrderExpr("id DESC").Limit(100).Scan(ctx)
+	return batches, err
+}
+
+// SyncMintBatches updates the state of tracked batches from tapd and registers the assets
+// of finalized batches
+func (svc *LndhubService) SyncMintBatches(ctx context.Context) error {
+	batches := []models.MintBatch{}
+	err := svc.DB.NewSelect().Model(&batches).Relation("Assets").
+		Where("state NOT IN (?)", bun.In([]string{models.MintBatchStateFinalized, models.MintBatchStateCancelled, models.MintBatchStateSproutCancelled})).
+		Scan(ctx)
+	if err != nil || len(batches) == 0 {
+		return err
+	}
+	resp, err := svc.TapdClient.ListBatches(ctx, &mintrpc.ListBatchRequest{})
+	if err != nil {
+		svc.Logger.Errorf("Failed to list mint batches: %v", err)
+		return err
+	}
+	tapdBatches := make(map[string]*mintrpc.MintingBatch, len(resp.Batches))
+	for _, tapdBatch := range resp.Batches {
+		tapdBatches[hex.EncodeToString(tapdBatch.BatchKey)] = tapdBatch
+	}
+	for _, batch := range batches {
+		tapdBatch, ok := tapdBatches[batch.BatchKey]
+		if !ok {
+			continue
+		}
+		state := mintBatchState(tapdBatch.State)
+		if state == models.MintBatchStateFinalized {
+			// the batch is only marked finalized once all its assets are registered
+			if err := svc.registerMintedAssets(ctx, &batch, tapdBatch.BatchTxid); err != nil {
+				sentry.CaptureException(err)
+				svc.Logger.Errorf("Failed to register minted assets of batch %s: %v", batch.BatchKey, err)
+				continue
+			}
+		}
+		if state == batch.State && tapdBatch.BatchTxid == batch.BatchTxid {
+			continue
+		}
+		batch.State = state
+		batch.BatchTxid = tapdBatch.BatchTxid
+		if _, err := svc.DB.NewUpdate().Model(&batch).WherePK().Exec(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mintedAssetFor finds the asset anchored in the batch transaction by its genesis name
+func mintedAssetFor(assets []*taprpc.Asset, batchTxid string, name string) *taprpc.Asset {
+	for _, asset := range assets {
+		if asset.AssetGenesis == nil || asset.ChainAnchor == nil {
+			continue
+		}
+		if asset.AssetGenesis.Name == name && strings.HasPrefix(asset.ChainAnchor.AnchorOutpoint, batchTxid+":") {
+			return asset
+		}
+	}
+	return nil
+}
+
+func (svc *LndhubService) registerMintedAssets(ctx context.Context, batch *models.MintBatch, batchTxid string) error {
+	listResp, err := svc.TapdClient.ListAssets(ctx, &taprpc.ListAssetRequest{IncludeSpent: true})
+	if err != nil {
+		return err
+	}
+	for i := range batch.Assets {
+		seedling := &batch.Assets[i]
+		if seedling.TaAssetID != "" && (seedling.IssuerUserID == 0 || seedling.TransactionEntryID != 0) {
+			continue
+		}
+		minted := mintedAssetFor(listResp.Assets, batchTxid, seedling.AssetName)
+		if minted == nil {
+			return fmt.Errorf("minted asset %s not found in batch tx %s", seedling.AssetName, batchTxid)
+		}
+		seedling.TaAssetID = hex.EncodeToString(minted.AssetGenesis.AssetId)
+		if err := svc.registerMintedAsset(ctx, seedling); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerMintedAsset adds the asset to the assets table and credits the supply to the issuer
+func (svc *LndhubService) registerMintedAsset(ctx context.Context, seedling *models.MintBatchAsset) error {
+	count, err := svc.DB.NewSelect().Model((*models.Asset)(nil)).Where("ta_asset_id = ?", seedling.TaAssetID).Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		name := seedling.AssetName
+		// assets reissued into a group share the name of the group's first asset
+		if _, err := svc.FindAssetByName(ctx, name); err == nil {
+			name = fmt.Sprintf("%s-%s", name, seedling.TaAssetID[:8])
+		}
+		if _, err := svc.CreateAsset(ctx, name, seedling.TaAssetID, seedling.AssetType); err != nil {
+			return err
+		}
+	}
+	if seedling.IssuerUserID > 0 {
+		if err := svc.EnsureUserAssetAccounts(ctx, seedling.IssuerUserID, seedling.TaAssetID); err != nil {
+			return err
+		}
+	}
+	return svc.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if seedling.IssuerUserID > 0 {
+			treasury, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeTreasury, seedling.TaAssetID)
+			if err != nil {
+				return err
+			}
+			current, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, seedling.TaAssetID, seedling.IssuerUserID)
+			if err != nil {
+				return err
+			}
+			entry := models.TransactionEntry{
+				UserID:          seedling.IssuerUserID,
+				TaAssetID:       seedling.TaAssetID,
+				DebitAccountID:  treasury.ID,
+				CreditAccountID: current.ID,
+				Amount:          seedling.Amount,
+				EntryType:       models.EntryTypeMint,
+				CreatedAt:       time.Now(),
+			}
+			if _, err := tx.NewInsert().Model(&entry).Exec(ctx); err != nil {
+				return err
+			}
+			seedling.TransactionEntryID = entry.ID
+		}
+		_, err := tx.NewUpdate().Model(seedling).Column("ta_asset_id", "transaction_entry_id").WherePK().Exec(ctx)
+		return err
+	})
+}
+
+func (svc *LndhubService) StartMintBatchSyncRoutine(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(svc.Config.MintBatchSyncInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = svc.SyncMintBatches(ctx)
+		}
+	}
+}
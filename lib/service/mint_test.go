@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/lightninglabs/taproot-assets/taprpc/mintrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMintAssetMeta(t *testing.T) {
+	meta, err := mintAssetMeta("plain text", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "plain text", string(meta))
+
+	meta, err = mintAssetMeta("plain text", 2)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"description":"plain text","decimal_display":2}`, string(meta))
+
+	meta, err = mintAssetMeta(`{"ticker":"USDT"}`, 6)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ticker":"USDT","decimal_display":6}`, string(meta))
+
+	meta, err = mintAssetMeta("", 2)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"decimal_display":2}`, string(meta))
+}
+
+func TestMintBatchState(t *testing.T) {
+	assert.Equal(t, models.MintBatchStatePending, mintBatchState(mintrpc.BatchState_BATCH_STATE_PENDING))
+	assert.Equal(t, models.MintBatchStateFinalized, mintBatchState(mintrpc.BatchState_BATCH_STATE_FINALIZED))
+	assert.Equal(t, models.MintBatchStateCancelled, mintBatchState(mintrpc.BatchState_BATCH_STATE_SEEDLING_CANCELLED))
+	assert.Equal(t, models.MintBatchStateSproutCancelled, mintBatchState(mintrpc.BatchState_BATCH_STATE_SPROUT_CANCELLED))
+}
+
+func TestMintAssetRequestValidate(t *testing.T) {
+	valid := MintAssetRequest{Name: "usdt", AssetType: MintAssetTypeNormal, Amount: 1000}
+	assert.NoError(t, valid.validate())
+
+	collectible := MintAssetRequest{Name: "nft", AssetType: MintAssetTypeCollectible, Amount: 2}
+	assert.ErrorIs(t, collectible.validate(), InvalidMintRequestError)
+
+	badGroup := MintAssetRequest{Name: "usdt", AssetType: MintAssetTypeNormal, Amount: 1, GroupKey: "zz"}
+	assert.ErrorIs(t, badGroup.validate(), InvalidMintRequestError)
+
+	bothGroups := MintAssetRequest{Name: "usdt", AssetType: MintAssetTypeNormal, Amount: 1, GroupKey: "02ab", NewGroupedAsset: true}
+	assert.ErrorIs(t, bothGroups.validate(), InvalidMintRequestError)
+}
+
+func TestMintedAssetFor(t *testing.T) {
+	assets := []*taprpc.Asset{
+		{AssetGenesis: &taprpc.GenesisInfo{Name: "usdt", AssetId: []byte{1}}, ChainAnchor: &taprpc.AnchorInfo{AnchorOutpoint: "othertx:0"}},
+		{AssetGenesis: &taprpc.GenesisInfo{Name: "usdt", AssetId: []byte{2}}, ChainAnchor: &taprpc.AnchorInfo{AnchorOutpoint: "batchtx:1"}},
+		{AssetGenesis: &taprpc.GenesisInfo{Name: "nft", AssetId: []byte{3}}, ChainAnchor: &taprpc.AnchorInfo{AnchorOutpoint: "batchtx:1"}},
+	}
+	minted := mintedAssetFor(assets, "batchtx", "usdt")
+	assert.NotNil(t, minted)
+	assert.Equal(t, []byte{2}, minted.AssetGenesis.AssetId)
+	assert.Nil(t, mintedAssetFor(assets, "batchtx", "eur"))
+}
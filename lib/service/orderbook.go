@@ -0,0 +1,468 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/uptrace/bun"
+)
+
+const (
+	// price levels per side in the order book
+	OrderBookDepth     = 50
+	DefaultTradesLimit = 50
+	MaxTradesLimit     = 100
+)
+
+var (
+	InvalidOrderSideError  = errors.New("order side must be buy or sell")
+	InvalidOrderPriceError = errors.New("order price must be a positive decimal number")
+	SameOrderAssetError    = errors.New("base and quote asset of an order must differ")
+	OrderTooSmallError     = errors.New("order is worth less than one unit of the quote asset")
+	OrderNotFoundError     = errors.New("order not found")
+	OrderNotOpenError      = errors.New("order is not open")
+)
+
+type OrderRequest struct {
+	UserID       int64
+	Side         string
+	BaseAssetID  string
+	QuoteAssetID string
+	// decimal amount of the base asset
+	Amount string
+	// decimal price of one display unit of the base asset in the quote asset
+	Price string
+}
+
+// OrderPlacement : the order after matching and the trades it was filled with right away
+type OrderPlacement struct {
+	Order  *models.Order  `json:"order"`
+	Trades []models.Trade `json:"trades"`
+}
+
+type OrderBookLevel struct {
+	Price string `json:"price"`
+	// open base units at the price
+	Amount int64 `json:"amount"`
+}
+
+type OrderBook struct {
+	BaseAssetID  string `json:"base_asset_id"`
+	QuoteAssetID string `json:"quote_asset_id"`
+	// best price first
+	Bids []OrderBookLevel `json:"bids"`
+	Asks []OrderBookLevel `json:"asks"`
+}
+
+// QuoteAmount is the price of base units in quote units, rounded down
+func QuoteAmount(baseUnits int64, baseDecimals uint32, quoteDecimals uint32, price string) (int64, error) {
+	rat, err := parsePrice(price)
+	if err != nil {
+		return 0, err
+	}
+	baseScale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(baseDecimals)), nil)
+	quoteScale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(quoteDecimals)), nil)
+	amount := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(baseUnits), quoteScale), baseScale)
+	amount.Mul(amount, rat)
+	units := new(big.Int).Quo(amount.Num(), amount.Denom())
+	if !units.IsInt64() {
+		return 0, UnrepresentableAssetAmountError
+	}
+	return units.Int64(), nil
+}
+
+// lockedAssetOf is the asset an order locks, buys pay with the quote asset and sells deliver the base asset
+func lockedAssetOf(order *models.Order) string {
+	if order.Side == models.OrderSideBuy {
+		return order.QuoteAssetID
+	}
+	return order.BaseAssetID
+}
+
+// lockOrderBookInTx serializes placing, matching and cancelling orders of an asset pair
+func lockOrderBookInTx(ctx context.Context, tx bun.Tx, baseAssetId string, quoteAssetId string) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext(?))", baseAssetId+":"+quoteAssetId)
+	return err
+}
+
+// reservedAccountForInTx returns the reserved account of a user for an asset, creating it on first use
+func (svc *LndhubService) reservedAccountForInTx(ctx context.Context, tx bun.Tx, assetId string, userId int64) (models.Account, error) {
+	account, err := svc.AccountForInTx(ctx, tx, common.AccountTypeReserved, assetId, userId)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return account, err
+	}
+	account = models.Account{UserID: userId, Type: common.AccountTypeReserved, TaAssetID: assetId}
+	_, err = tx.NewInsert().Model(&account).Exec(ctx)
+	return account, err
+}
+
+// PlaceOrder locks the funds of a limit order in the user's reserved account and matches it against
+// the resting orders of other users, best price first. Whatever is not filled rests in the book.
+func (svc *LndhubService) PlaceOrder(ctx context.Context, req OrderRequest) (*OrderPlacement, error) {
+	if req.Side != models.OrderSideBuy && req.Side != models.OrderSideSell {
+		return nil, InvalidOrderSideError
+	}
+	if req.BaseAssetID == req.QuoteAssetID {
+		return nil, SameOrderAssetError
+	}
+	price, err := parsePrice(req.Price)
+	if err != nil || price.Sign() == 0 {
+		return nil, InvalidOrderPriceError
+	}
+	amount, err := svc.ParseAssetAmountFor(ctx, req.BaseAssetID, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if amount > math.MaxInt64 {
+		return nil, UnrepresentableAssetAmountError
+	}
+	baseDecimals, err := svc.AssetDecimalDisplay(ctx, req.BaseAssetID)
+	if err != nil {
+		return nil, err
+	}
+	quoteDecimals, err := svc.AssetDecimalDisplay(ctx, req.QuoteAssetID)
+	if err != nil {
+		return nil, err
+	}
+	quoteAmount, err := QuoteAmount(int64(amount), baseDecimals, quoteDecimals, req.Price)
+	if err != nil {
+		return nil, err
+	}
+	if quoteAmount == 0 {
+		return nil, OrderTooSmallError
+	}
+	order := &models.Order{
+		UserID:       req.UserID,
+		Side:         req.Side,
+		BaseAssetID:  req.BaseAssetID,
+		QuoteAssetID: req.QuoteAssetID,
+		Price:        req.Price,
+		Amount:       int64(amount),
+		Locked:       int64(amount),
+		State:        models.OrderStateOpen,
+	}
+	if req.Side == models.OrderSideBuy {
+		// fills at or below the limit price never cost more than this
+		order.Locked = quoteAmount
+	}
+	// fills credit both assets to the user
+	for _, assetId := range []string{req.BaseAssetID, req.QuoteAssetID} {
+		if err := svc.EnsureUserAssetAccounts(ctx, req.UserID, assetId); err != nil {
+			return nil, err
+		}
+	}
+	placement := &OrderPlacement{Order: order, Trades: []models.Trade{}}
+	err = svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if err := lockOrderBookInTx(ctx, tx, order.BaseAssetID, order.QuoteAssetID); err != nil {
+			return err
+		}
+		lockedAsset := lockedAssetOf(order)
+		current, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, lockedAsset, order.UserID)
+		if err != nil {
+			return fmt.Errorf("could not find current account for user_id:%d asset:%s: %w", order.UserID, lockedAsset, err)
+		}
+		reserved, err := svc.reservedAccountForInTx(ctx, tx, lockedAsset, order.UserID)
+		if err != nil {
+			return err
+		}
+		balance, err := accountBalanceInTx(ctx, tx, current.ID)
+		if err != nil {
+			return err
+		}
+		if balance < order.Locked {
+			return InsufficientBalanceError
+		}
+		if _, err := tx.NewInsert().Model(order).Exec(ctx); err != nil {
+			return err
+		}
+		entry := models.TransactionEntry{
+			UserID:          order.UserID,
+			TaAssetID:       lockedAsset,
+			DebitAccountID:  current.ID,
+			CreditAccountID: reserved.ID,
+			Amount:          order.Locked,
+			EntryType:       models.EntryTypeReserve,
+			CreatedAt:       time.Now(),
+		}
+		if _, err := tx.NewInsert().Model(&entry).Exec(ctx); err != nil {
+			return err
+		}
+		placement.Trades, err = svc.matchOrderInTx(ctx, tx, order, baseDecimals, quoteDecimals)
+		return err
+	})
+	if err != nil {
+		svc.Logger.Errorf("Could not place order user_id:%v %s %s %s/%s at %s error %v", req.UserID, req.Side, req.Amount, req.BaseAssetID, req.QuoteAssetID, req.Price, err)
+		return nil, err
+	}
+	svc.Logger.Infof("Placed order id:%d user_id:%v %s %d %s/%s at %s, %d trades", order.ID, order.UserID, order.Side, order.Amount, order.BaseAssetID, order.QuoteAssetID, order.Price, len(placement.Trades))
+	return placement, nil
+}
+
+// matchOrderInTx fills the taker against resting orders of other users at the resting order's price
+func (svc *LndhubService) matchOrderInTx(ctx context.Context, tx bun.Tx, taker *models.Order, baseDecimals uint32, quoteDecimals uint32) ([]models.Trade, error) {
+	makers := []models.Order{}
+	query := tx.NewSelect().Model(&makers).
+		Where("base_asset_id = ? AND quote_asset_id = ? AND state = ? AND user_id <> ?", taker.BaseAssetID, taker.QuoteAssetID, models.OrderStateOpen, taker.UserID)
+	if taker.Side == models.OrderSideBuy {
+		query.Where("side = ? AND price <= ?", models.OrderSideSell, taker.Price).OrderExpr("price ASC, id ASC")
+	} else {
+		query.Where("side = ? AND price >= ?", models.OrderSideBuy, taker.Price).OrderExpr("price DESC, id ASC")
+	}
+	if err := query.For("UPDATE").Scan(ctx); err != nil {
+		return nil, err
+	}
+	trades := []models.Trade{}
+	for i := range makers {
+		if taker.Filled == taker.Amount {
+			break
+		}
+		maker := &makers[i]
+		fill := min(taker.Amount-taker.Filled, maker.Amount-maker.Filled)
+		quoteAmount, err := QuoteAmount(fill, baseDecimals, quoteDecimals, maker.Price)
+		if err != nil {
+			return nil, err
+		}
+		// fills worth less than a unit of the quote asset are not matched
+		if quoteAmount == 0 {
+			continue
+		}
+		buy, sell := taker, maker
+		if taker.Side == models.OrderSideSell {
+			buy, sell = maker, taker
+		}
+		trade, err := svc.settleTradeInTx(ctx, tx, buy, sell, taker.Side, maker.Price, fill, quoteAmount)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, *trade)
+		if err := svc.updateOrderInTx(ctx, tx, maker); err != nil {
+			return nil, err
+		}
+	}
+	return trades, svc.updateOrderInTx(ctx, tx, taker)
+}
+
+// settleTradeInTx moves the base units from the seller's reserved account to the buyer and the quote
+// units from the buyer's reserved account to the seller
+func (svc *LndhubService) settleTradeInTx(ctx context.Context, tx bun.Tx, buy *models.Order, sell *models.Order, takerSide string, price string, fill int64, quoteAmount int64) (*models.Trade, error) {
+	buyerBase, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, buy.BaseAssetID, buy.UserID)
+	if err != nil {
+		return nil, err
+	}
+	buyerReserved, err := svc.reservedAccountForInTx(ctx, tx, buy.QuoteAssetID, buy.UserID)
+	if err != nil {
+		return nil, err
+	}
+	sellerQuote, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, sell.QuoteAssetID, sell.UserID)
+	if err != nil {
+		return nil, err
+	}
+	sellerReserved, err := svc.reservedAccountForInTx(ctx, tx, sell.BaseAssetID, sell.UserID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	baseEntry := models.TransactionEntry{
+		UserID:          sell.UserID,
+		TaAssetID:       sell.BaseAssetID,
+		DebitAccountID:  sellerReserved.ID,
+		CreditAccountID: buyerBase.ID,
+		Amount:          fill,
+		EntryType:       models.EntryTypeTrade,
+		CreatedAt:       now,
+	}
+	if _, err := tx.NewInsert().Model(&baseEntry).Exec(ctx); err != nil {
+		return nil, err
+	}
+	quoteEntry := models.TransactionEntry{
+		UserID:          buy.UserID,
+		ParentID:        baseEntry.ID,
+		TaAssetID:       buy.QuoteAssetID,
+		DebitAccountID:  buyerReserved.ID,
+		CreditAccountID: sellerQuote.ID,
+		Amount:          quoteAmount,
+		EntryType:       models.EntryTypeTrade,
+		CreatedAt:       now,
+	}
+	if _, err := tx.NewInsert().Model(&quoteEntry).Exec(ctx); err != nil {
+		return nil, err
+	}
+	buy.Filled += fill
+	buy.Locked -= quoteAmount
+	sell.Filled += fill
+	sell.Locked -= fill
+	trade := &models.Trade{
+		BaseAssetID:        buy.BaseAssetID,
+		QuoteAssetID:       buy.QuoteAssetID,
+		BuyOrderID:         buy.ID,
+		SellOrderID:        sell.ID,
+		TakerSide:          takerSide,
+		Price:              price,
+		Amount:             fill,
+		QuoteAmount:        quoteAmount,
+		TransactionEntryID: baseEntry.ID,
+		CreatedAt:          now,
+	}
+	_, err = tx.NewInsert().Model(trade).Exec(ctx)
+	return trade, err
+}
+
+// updateOrderInTx stores the fill of an order, filled orders release what they still hold
+func (svc *LndhubService) updateOrderInTx(ctx context.Context, tx bun.Tx, order *models.Order) error {
+	if order.State == models.OrderStateOpen && order.Filled == order.Amount {
+		// buys filled below their limit price keep the difference locked until now
+		if err := svc.releaseOrderInTx(ctx, tx, order); err != nil {
+			return err
+		}
+		order.State = models.OrderStateFilled
+	}
+	order.UpdatedAt = time.Now()
+	_, err := tx.NewUpdate().Model(order).Column("filled", "locked", "state", "updated_at").WherePK().Exec(ctx)
+	return err
+}
+
+// releaseOrderInTx moves what an order still holds from the reserved account back to the current account
+func (svc *LndhubService) releaseOrderInTx(ctx context.Context, tx bun.Tx, order *models.Order) error {
+	if order.Locked == 0 {
+		return nil
+	}
+	lockedAsset := lockedAssetOf(order)
+	current, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, lockedAsset, order.UserID)
+	if err != nil {
+		return err
+	}
+	reserved, err := svc.reservedAccountForInTx(ctx, tx, lockedAsset, order.UserID)
+	if err != nil {
+		return err
+	}
+	entry := models.TransactionEntry{
+		UserID:          order.UserID,
+		TaAssetID:       lockedAsset,
+		DebitAccountID:  reserved.ID,
+		CreditAccountID: current.ID,
+		Amount:          order.Locked,
+		EntryType:       models.EntryTypeRelease,
+		CreatedAt:       time.Now(),
+	}
+	if _, err := tx.NewInsert().Model(&entry).Exec(ctx); err != nil {
+		return err
+	}
+	order.Locked = 0
+	return nil
+}
+
+// CancelOrder takes an open order of the user out of the book and releases its locked funds
+func (svc *LndhubService) CancelOrder(ctx context.Context, userId int64, orderId int64) (*models.Order, error) {
+	order := &models.Order{}
+	err := svc.DB.NewSelect().Model(order).Where("id = ? AND user_id = ?", orderId, userId).Limit(1).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, OrderNotFoundError
+		}
+		return nil, err
+	}
+	err = svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if err := lockOrderBookInTx(ctx, tx, order.BaseAssetID, order.QuoteAssetID); err != nil {
+			return err
+		}
+		if err := tx.NewSelect().Model(order).Where("id = ?", order.ID).For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+		if order.State != models.OrderStateOpen {
+			return OrderNotOpenError
+		}
+		if err := svc.releaseOrderInTx(ctx, tx, order); err != nil {
+			return err
+		}
+		order.State = models.OrderStateCancelled
+		order.UpdatedAt = time.Now()
+		_, err := tx.NewUpdate().Model(order).Column("locked", "state", "updated_at").WherePK().Exec(ctx)
+		return err
+	})
+	if err != nil {
+		svc.Logger.Errorf("Could not cancel order id:%d user_id:%v error %v", orderId, userId, err)
+		return nil, err
+	}
+	svc.Logger.Infof("Cancelled order id:%d user_id:%v", order.ID, userId)
+	return order, nil
+}
+
+// OrdersFor returns the latest orders of a user, optionally only those in the given state
+func (svc *LndhubService) OrdersFor(ctx context.Context, userId int64, state string) ([]models.Order, error) {
+	orders := []models.Order{}
+	query := svc.DB.NewSelect().Model(&orders).Where("user_id = ?", userId)
+	if state != "" {
+		query.Where("state = ?", state)
+	}
+	err := query.OrderExpr("id DESC").Limit(100).Scan(ctx)
+	return orders, err
+}
+
+// OrderBookFor aggregates the open orders of an asset pair by price
+func (svc *LndhubService) OrderBookFor(ctx context.Context, baseAssetId string, quoteAssetId string) (*OrderBook, error) {
+	book := &OrderBook{BaseAssetID: baseAssetId, QuoteAssetID: quoteAssetId}
+	for _, side := range []struct {
+		side   string
+		order  string
+		levels *[]OrderBookLevel
+	}{
+		{models.OrderSideBuy, "price DESC", &book.Bids},
+		{models.OrderSideSell, "price ASC", &book.Asks},
+	} {
+		*side.levels = []OrderBookLevel{}
+		err := svc.DB.NewSelect().
+			TableExpr("orders").
+			ColumnExpr("price, sum(amount - filled) AS amount").
+			Where("base_asset_id = ? AND quote_asset_id = ? AND side = ? AND state = ?", baseAssetId, quoteAssetId, side.side, models.OrderStateOpen).
+			GroupExpr("price").
+			OrderExpr(side.order).
+			Limit(OrderBookDepth).
+			Scan(ctx, side.levels)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return book, nil
+}
+
+// TradesFor returns the trades of an asset pair newest first, the cursor is the id of the last
+// trade of the previous page
+func (svc *LndhubService) TradesFor(ctx context.Context, baseAssetId string, quoteAssetId string, cursor int64, limit int) ([]models.Trade, error) {
+	if limit <= 0 || limit > MaxTradesLimit {
+		limit = DefaultTradesLimit
+	}
+	trades := []models.Trade{}
+	query := svc.DB.NewSelect().Model(&trades).Where("base_asset_id = ? AND quote_asset_id = ?", baseAssetId, quoteAssetId)
+	if cursor > 0 {
+		query.Where("id < ?", cursor)
+	}
+	err := query.OrderExpr("id DESC").Limit(limit).Scan(ctx)
+	return trades, err
+}
+
+// IsOrderUserError tells the errors of a failed order the user can act on apart from internal errors
+func IsOrderUserError(err error) bool {
+	for _, userErr := range []error{
+		InvalidOrderSideError,
+		InvalidOrderPriceError,
+		SameOrderAssetError,
+		OrderTooSmallError,
+		OrderNotFoundError,
+		OrderNotOpenError,
+		InsufficientBalanceError,
+		InvalidAssetAmountError,
+		UnrepresentableAssetAmountError,
+	} {
+		if errors.Is(err, userErr) {
+			return true
+		}
+	}
+	return false
+}
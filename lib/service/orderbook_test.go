@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteAmount(t *testing.T) {
+	units, err := QuoteAmount(20, 0, 0, "100")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2000), units)
+
+	// 1.5 of a base asset with 6 decimals at 2.25 of a quote asset with 2 decimals
+	units, err = QuoteAmount(1500000, 6, 2, "2.25")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(337), units)
+
+	// rounded down, partial fills never cost more than the whole order
+	whole, err := QuoteAmount(3, 0, 0, "0.5")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), whole)
+	part, err := QuoteAmount(1, 0, 0, "0.5")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), part)
+
+	_, err = QuoteAmount(1, 0, 0, "-1")
+	assert.ErrorIs(t, err, InvalidPriceError)
+}
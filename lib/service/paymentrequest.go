@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/uptrace/bun"
+)
+
+var (
+	PaymentRequestNotFoundError = errors.New("payment request not found")
+	PaymentRequestNotOpenError  = errors.New("payment request is not open")
+	PaymentRequestExpiredError  = errors.New("payment request has expired")
+	SelfPaymentRequestError     = errors.New("cannot request a payment from yourself")
+)
+
+// RequestPayment stores a request for a decimal amount of an asset from the owner of the payer
+// pubkey and sends it to them by DM. The payer does not need to be registered yet.
+func (svc *LndhubService) RequestPayment(ctx context.Context, requester *models.User, payer string, assetId string, amount string, memo string) (*models.PaymentRequest, error) {
+	if len([]rune(memo)) > MaxTransferMemoLength {
+		return nil, TransferMemoTooLongError
+	}
+	payerPubkey, err := ParseRecipientPubkey(payer)
+	if err != nil {
+		return nil, err
+	}
+	if payerPubkey == requester.Pubkey {
+		return nil, SelfPaymentRequestError
+	}
+	units, err := svc.ParseAssetAmountFor(ctx, assetId, amount)
+	if err != nil {
+		return nil, err
+	}
+	if units > math.MaxInt64 {
+		return nil, UnrepresentableAssetAmountError
+	}
+	request := &models.PaymentRequest{
+		RequesterID:     requester.ID,
+		RequesterPubkey: requester.Pubkey,
+		PayerPubkey:     payerPubkey,
+		TaAssetID:       assetId,
+		Amount:          int64(units),
+		Memo:            memo,
+		State:           models.PaymentRequestStateOpen,
+		ExpiresAt:       time.Now().Add(time.Duration(svc.Config.PaymentRequestExpiry) * time.Second),
+	}
+	amountText, err := svc.paymentRequestAmountText(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := svc.DB.NewInsert().Model(request).Exec(ctx); err != nil {
+		return nil, err
+	}
+	requesterNpub, _ := nip19.EncodePublicKey(request.RequesterPubkey)
+	_ = svc.SendNip4Notification(ctx, fmt.Sprintf("%s requests %s%s. pay with TAHUB_PAY_REQUEST:%d before %s",
+		requesterNpub, amountText, memoSuffix(request.Memo), request.ID, request.ExpiresAt.UTC().Format(time.RFC3339)), request.PayerPubkey)
+	return request, nil
+}
+
+// PayPaymentRequest pays an open request addressed to the user with an internal transfer to the
+// requester. The request is marked paid in the DB transaction of the transfer, so it is paid once.
+func (svc *LndhubService) PayPaymentRequest(ctx context.Context, payer *models.User, requestId int64) (*models.PaymentRequest, error) {
+	request, err := svc.PaymentRequestFor(ctx, payer, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if request.PayerPubkey != payer.Pubkey {
+		return nil, PaymentRequestNotFoundError
+	}
+	if err := checkPaymentRequestOpen(request); err != nil {
+		return nil, err
+	}
+	transfer := &models.InternalTransfer{
+		SenderID:        payer.ID,
+		RecipientPubkey: request.RequesterPubkey,
+		TaAssetID:       request.TaAssetID,
+		Amount:          request.Amount,
+		Memo:            request.Memo,
+	}
+	_, err = svc.transferToPubkey(ctx, transfer, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewSelect().Model(request).WherePK().For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+		if request.State == models.PaymentRequestStateOpen && !time.Now().Before(request.ExpiresAt) {
+			return PaymentRequestExpiredError
+		}
+		if err := checkPaymentRequestOpen(request); err != nil {
+			return err
+		}
+		request.State = models.PaymentRequestStatePaid
+		request.InternalTransferID = transfer.ID
+		request.UpdatedAt = time.Now()
+		_, err := tx.NewUpdate().Model(request).Column("state", "internal_transfer_id", "updated_at").WherePK().Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	svc.Logger.Infof("Paid payment request id:%d user_id:%v transfer id:%d", request.ID, payer.ID, transfer.ID)
+	return request, nil
+}
+
+// CancelPaymentRequest closes an open request, the requester withdraws it or the payer declines it.
+// The other side is notified by DM.
+func (svc *LndhubService) CancelPaymentRequest(ctx context.Context, user *models.User, requestId int64) (*models.PaymentRequest, error) {
+	request, err := svc.PaymentRequestFor(ctx, user, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPaymentRequestOpen(request); err != nil {
+		return nil, err
+	}
+	request.State = models.PaymentRequestStateCancelled
+	request.UpdatedAt = time.Now()
+	res, err := svc.DB.NewUpdate().Model(request).
+		Column("state", "updated_at").
+		WherePK().
+		Where("state = ?", models.PaymentRequestStateOpen).
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rows, err := res.RowsAffected(); err != nil || rows == 0 {
+		// paid or cancelled concurrently
+		return nil, PaymentRequestNotOpenError
+	}
+	other, verb := request.PayerPubkey, "withdrawn"
+	if user.ID != request.RequesterID {
+		other, verb = request.RequesterPubkey, "declined"
+	}
+	amountText, err := svc.paymentRequestAmountText(ctx, request)
+	if err != nil {
+		svc.Logger.Errorf("Could not notify about payment request id:%d: %v", request.ID, err)
+		return request, nil
+	}
+	_ = svc.SendNip4Notification(ctx, fmt.Sprintf("payment request %d for %s was %s", request.ID, amountText, verb), other)
+	return request, nil
+}
+
+// PaymentRequestFor returns a request the user made or that is addressed to them
+func (svc *LndhubService) PaymentRequestFor(ctx context.Context, user *models.User, requestId int64) (*models.PaymentRequest, error) {
+	request := &models.PaymentRequest{}
+	err := svc.DB.NewSelect().Model(request).
+		Where("id = ?", requestId).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("requester_id = ?", user.ID).WhereOr("payer_pubkey = ?", user.Pubkey)
+		}).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, PaymentRequestNotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+	svc.expirePaymentRequest(ctx, request)
+	return request, nil
+}
+
+// PaymentRequestsFor lists the latest requests the user made or that are addressed to them,
+// optionally filtered by state
+func (svc *LndhubService) PaymentRequestsFor(ctx context.Context, user *models.User, state string) ([]models.PaymentRequest, error) {
+	// stale open requests are expired on read
+	_, err := svc.DB.NewUpdate().Model((*models.PaymentRequest)(nil)).
+		Set("state = ?, updated_at = ?", models.PaymentRequestStateExpired, time.Now()).
+		Where("state = ? AND expires_at <= ?", models.PaymentRequestStateOpen, time.Now()).
+		WhereGroup(" AND ", func(q *bun.UpdateQuery) *bun.UpdateQuery {
+			return q.Where("requester_id = ?", user.ID).WhereOr("payer_pubkey = ?", user.Pubkey)
+		}).
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	requests := []models.PaymentRequest{}
+	query := svc.DB.NewSelect().Model(&requests).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("requester_id = ?", user.ID).WhereOr("payer_pubkey = ?", user.Pubkey)
+		})
+	if state != "" {
+		query.Where("state = ?", state)
+	}
+	err = query.OrderExpr("id DESC").Limit(100).Scan(ctx)
+	return requests, err
+}
+
+// checkPaymentRequestOpen tells why a request can no longer be paid or cancelled
+func checkPaymentRequestOpen(request *models.PaymentRequest) error {
+	if request.State == models.PaymentRequestStateExpired {
+		return PaymentRequestExpiredError
+	}
+	if request.State != models.PaymentRequestStateOpen {
+		return PaymentRequestNotOpenError
+	}
+	return nil
+}
+
+// expirePaymentRequest marks an open request that is past its expiry as expired
+func (svc *LndhubService) expirePaymentRequest(ctx context.Context, request *models.PaymentRequest) {
+	if request.State != models.PaymentRequestStateOpen || time.Now().Before(request.ExpiresAt) {
+		return
+	}
+	request.State = models.PaymentRequestStateExpired
+	request.UpdatedAt = time.Now()
+	_, err := svc.DB.NewUpdate().Model(request).
+		Column("state", "updated_at").
+		WherePK().
+		Where("state = ?", models.PaymentRequestStateOpen).
+		Exec(ctx)
+	if err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("Could not expire payment request id:%d: %v", request.ID, err)
+	}
+}
+
+func (svc *LndhubService) paymentRequestAmountText(ctx context.Context, request *models.PaymentRequest) (string, error) {
+	assetName := request.TaAssetID
+	asset := models.Asset{}
+	if err := svc.DB.NewSelect().Model(&asset).Where("ta_asset_id = ?", request.TaAssetID).Limit(1).Scan(ctx); err == nil && asset.AssetName != "" {
+		assetName = asset.AssetName
+	}
+	decimalDisplay, err := svc.AssetDecimalDisplay(ctx, request.TaAssetID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s", FormatAssetAmount(request.Amount, decimalDisplay), assetName), nil
+}
+
+// IsPaymentRequestUserError tells the errors of payment requests the user can act on from failures of the hub
+func IsPaymentRequestUserError(err error) bool {
+	for _, userErr := range []error{
+		PaymentRequestNotFoundError,
+		PaymentRequestNotOpenError,
+		PaymentRequestExpiredError,
+		SelfPaymentRequestError,
+	} {
+		if errors.Is(err, userErr) {
+			return true
+		}
+	}
+	return IsTransferUserError(err)
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+)
+
+const (
+	PriceOracleStatic = "static"
+	PriceOracleFeed   = "feed"
+
+	// fiat values are rounded to cents
+	FiatValueDecimals = 2
+)
+
+var InvalidPriceError = errors.New("price must be a non-negative decimal number")
+
+// PriceOracle : source of fiat prices. Prices are decimal strings for one unit of an asset in its
+// decimal display (a sat for btc), keyed by ta_asset_id.
+type PriceOracle interface {
+	// Source names the oracle in the price history
+	Source() string
+	Prices(ctx context.Context) (map[string]string, error)
+}
+
+// StaticPriceOracle : fixed prices from the config
+type StaticPriceOracle struct {
+	prices map[string]string
+}
+
+func NewStaticPriceOracle(prices map[string]string) (*StaticPriceOracle, error) {
+	for assetId, price := range prices {
+		if _, err := parsePrice(price); err != nil {
+			return nil, fmt.Errorf("static price of asset %s: %w", assetId, err)
+		}
+	}
+	return &StaticPriceOracle{prices: prices}, nil
+}
+
+func (oracle *StaticPriceOracle) Source() string {
+	return PriceOracleStatic
+}
+
+func (oracle *StaticPriceOracle) Prices(ctx context.Context) (map[string]string, error) {
+	return oracle.prices, nil
+}
+
+// FeedPriceOracle : prices read from a JSON feed {"prices": {"<asset_id>": "1.00"}}, fetched over
+// HTTP or read from a file:// URL for local setups
+type FeedPriceOracle struct {
+	URL    string
+	Client *http.Client
+}
+
+func (oracle *FeedPriceOracle) Source() string {
+	return PriceOracleFeed
+}
+
+type priceFeed struct {
+	Prices map[string]json.Number `json:"prices"`
+}
+
+func (oracle *FeedPriceOracle) Prices(ctx context.Context) (map[string]string, error) {
+	body, err := oracle.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	feed := priceFeed{}
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to decode price feed: %w", err)
+	}
+	prices := make(map[string]string, len(feed.Prices))
+	for assetId, price := range feed.Prices {
+		if _, err := parsePrice(price.String()); err != nil {
+			return nil, fmt.Errorf("feed price of asset %s: %w", assetId, err)
+		}
+		prices[assetId] = price.String()
+	}
+	return prices, nil
+}
+
+func (oracle *FeedPriceOracle) read(ctx context.Context) (io.ReadCloser, error) {
+	if path, isFile := strings.CutPrefix(oracle.URL, "file://"); isFile {
+		return os.Open(path)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, oracle.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := oracle.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("price feed returned status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// NewPriceOracle builds the oracle selected by PRICE_ORACLE, nil if none is configured
+func NewPriceOracle(c *Config) (PriceOracle, error) {
+	switch c.PriceOracle {
+	case "":
+		return nil, nil
+	case PriceOracleStatic:
+		return NewStaticPriceOracle(c.PriceOracleStaticPrices)
+	case PriceOracleFeed:
+		if c.PriceOracleFeedURL == "" {
+			return nil, errors.New("PRICE_ORACLE_FEED_URL is required for the feed price oracle")
+		}
+		return &FeedPriceOracle{URL: c.PriceOracleFeedURL}, nil
+	default:
+		return nil, fmt.Errorf("Did not recognize price oracle %s", c.PriceOracle)
+	}
+}
+
+func parsePrice(price string) (*big.Rat, error) {
+	rat, ok := new(big.Rat).SetString(price)
+	if !ok || rat.Sign() < 0 || strings.ContainsAny(price, "/eE") {
+		return nil, InvalidPriceError
+	}
+	return rat, nil
+}
+
+// FiatValue values integer asset units at the price of one unit in the asset's decimal display,
+// rounded to cents
+func FiatValue(units int64, decimalDisplay uint32, price string) (string, error) {
+	rat, err := parsePrice(price)
+	if err != nil {
+		return "", err
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimalDisplay)), nil)
+	value := new(big.Rat).SetFrac(big.NewInt(units), scale)
+	return value.Mul(value, rat).FloatString(FiatValueDecimals), nil
+}
+
+// RefreshPrices adds the current prices of the oracle to the price history
+func (svc *LndhubService) RefreshPrices(ctx context.Context) error {
+	if svc.PriceOracle == nil {
+		return nil
+	}
+	prices, err := svc.PriceOracle.Prices(ctx)
+	if err != nil {
+		return err
+	}
+	if len(prices) == 0 {
+		return nil
+	}
+	rows := make([]models.AssetPrice, 0, len(prices))
+	now := time.Now()
+	for assetId, price := range prices {
+		rows = append(rows, models.AssetPrice{
+			TaAssetID: assetId,
+			Currency:  svc.Config.PriceCurrency,
+			Price:     price,
+			Source:    svc.PriceOracle.Source(),
+			CreatedAt: now,
+		})
+	}
+	_, err = svc.DB.NewInsert().Model(&rows).Exec(ctx)
+	return err
+}
+
+// LatestPrices returns the latest price of every asset in the configured currency keyed by ta_asset_id
+func (svc *LndhubService) LatestPrices(ctx context.Context) (map[string]models.AssetPrice, error) {
+	rows := []models.AssetPrice{}
+	err := svc.DB.NewSelect().Model(&rows).
+		DistinctOn("ta_asset_id").
+		Where("currency = ?", svc.Config.PriceCurrency).
+		OrderExpr("ta_asset_id, created_at DESC, id DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prices := make(map[string]models.AssetPrice, len(rows))
+	for _, row := range rows {
+		prices[row.TaAssetID] = row
+	}
+	return prices, nil
+}
+
+// PriceHistory returns the latest prices of an asset, newest first
+func (svc *LndhubService) PriceHistory(ctx context.Context, taAssetId string, limit int) ([]models.AssetPrice, error) {
+	rows := []models.AssetPrice{}
+	err := svc.DB.NewSelect().Model(&rows).
+		Where("ta_asset_id = ? AND currency = ?", taAssetId, svc.Config.PriceCurrency).
+		OrderExpr("created_at DESC, id DESC").
+		Limit(limit).
+		Scan(ctx)
+	return rows, err
+}
+
+type AssetValuation struct {
+	Price   string    `json:"price"`
+	Value   string    `json:"value"`
+	PriceAt time.Time `json:"price_at"`
+}
+
+// BalanceValuation : fiat value of a user's balances at the latest prices, assets without a
+// price are left out of the total
+type BalanceValuation struct {
+	Currency string                    `json:"currency"`
+	Assets   map[string]AssetValuation `json:"assets"`
+	Total    string                    `json:"total"`
+}
+
+// ValueBalances values the current balances of a user, nil if no price oracle is configured
+func (svc *LndhubService) ValueBalances(ctx context.Context, userId int64) (*BalanceValuation, error) {
+	if svc.PriceOracle == nil {
+		return nil, nil
+	}
+	balances, err := svc.CurrentUserBalanceByAsset(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	decimals, err := svc.AssetDecimalDisplays(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := svc.LatestPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	valuation := &BalanceValuation{
+		Currency: svc.Config.PriceCurrency,
+		Assets:   map[string]AssetValuation{},
+	}
+	total := new(big.Rat)
+	for assetId, balance := range balances {
+		price, ok := prices[assetId]
+		if !ok {
+			continue
+		}
+		value, err := FiatValue(balance, decimals[assetId], price.Price)
+		if err != nil {
+			return nil, err
+		}
+		valuation.Assets[assetId] = AssetValuation{Price: price.Price, Value: value, PriceAt: price.CreatedAt}
+		rat, _ := new(big.Rat).SetString(value)
+		total.Add(total, rat)
+	}
+	valuation.Total = total.FloatString(FiatValueDecimals)
+	return valuation, nil
+}
+
+func (svc *LndhubService) StartPriceOracleRoutine(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(svc.Config.PriceOracleInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := svc.RefreshPrices(ctx); err != nil {
+				sentry.CaptureException(err)
+				svc.Logger.Errorf("failed to refresh prices from the %s price oracle: %v", svc.PriceOracle.Source(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFiatValue(t *testing.T) {
+	// 1.5 units of a 6 decimal asset at 2.00
+	value, err := FiatValue(1500000, 6, "2.00")
+	assert.NoError(t, err)
+	assert.Equal(t, "3.00", value)
+
+	// 100000 sats at 0.00065 per sat
+	value, err = FiatValue(100000, 0, "0.00065")
+	assert.NoError(t, err)
+	assert.Equal(t, "65.00", value)
+
+	// rounded to cents, halves away from zero
+	value, err = FiatValue(1, 0, "0.005")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.01", value)
+
+	value, err = FiatValue(-250, 2, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "-2.50", value)
+
+	for _, invalid := range []string{"", "-1", "1/3", "1e3", "abc"} {
+		_, err = FiatValue(1, 0, invalid)
+		assert.ErrorIs(t, err, InvalidPriceError, invalid)
+	}
+}
+
+func TestNewPriceOracle(t *testing.T) {
+	oracle, err := NewPriceOracle(&Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, oracle)
+
+	oracle, err = NewPriceOracle(&Config{PriceOracle: PriceOracleStatic, PriceOracleStaticPrices: map[string]string{"btc": "0.00065"}})
+	assert.NoError(t, err)
+	prices, err := oracle.Prices(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"btc": "0.00065"}, prices)
+
+	_, err = NewPriceOracle(&Config{PriceOracle: PriceOracleStatic, PriceOracleStaticPrices: map[string]string{"btc": "cheap"}})
+	assert.Error(t, err)
+	_, err = NewPriceOracle(&Config{PriceOracle: PriceOracleFeed})
+	assert.Error(t, err)
+	_, err = NewPriceOracle(&Config{PriceOracle: "magic"})
+	assert.Error(t, err)
+}
+
+func TestFeedPriceOracle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"prices": {"btc": 0.00065, "abcd": "1.0001"}}`), 0600))
+	oracle := &FeedPriceOracle{URL: "file://" + path}
+	prices, err := oracle.Prices(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"btc": "0.00065", "abcd": "1.0001"}, prices)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.Write([]byte(`{"prices": {"btc": "-1"}}`))
+			return
+		}
+		if r.URL.Path != "/prices" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"prices": {"btc": "0.0007"}}`))
+	}))
+	defer server.Close()
+	oracle = &FeedPriceOracle{URL: server.URL + "/prices"}
+	prices, err = oracle.Prices(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"btc": "0.0007"}, prices)
+
+	_, err = (&FeedPriceOracle{URL: server.URL + "/missing"}).Prices(context.Background())
+	assert.Error(t, err)
+	_, err = (&FeedPriceOracle{URL: server.URL + "/bad"}).Prices(context.Background())
+	assert.Error(t, err)
+}
@@ -2,13 +2,17 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
 
 	"github.com/getAlby/lndhub.go/common"
 	"github.com/getAlby/lndhub.go/db/models"
 	"github.com/getAlby/lndhub.go/tapd"
+	"github.com/getsentry/sentry-go"
 	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/uptrace/bun"
 )
 
 var AlreadyProcessedTapdReceiveEventError = errors.New("already processed tapd event")
@@ -25,6 +29,11 @@ func (svc *LndhubService) TapdReceiveSubscription(ctx context.Context) (err erro
 		// TODO apply sentry
 		return err
 	}
+	// receives completed while the subscription was down (startup or reconnect) are not replayed by tapd
+	if err := svc.CatchUpTapdReceives(ctx); err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("tapd receive catch-up failed: %v", err)
+	}
 	for {
 		select {
 		case <-ctx.Done():
@@ -38,6 +47,11 @@ func (svc *LndhubService) TapdReceiveSubscription(ctx context.Context) (err erro
 			}
 			// handle event
 			err = svc.HandleTapdReceiveEvent(ctx, rcvEvent)
+			if errors.Is(err, AlreadyProcessedTapdReceiveEventError) {
+				// replayed event, the receive is already credited
+				svc.Logger.Info(err.Error())
+				continue
+			}
 			if err != nil {
 				// TODO apply sentry
 				return err
@@ -51,87 +65,234 @@ func (svc *LndhubService) HandleTapdReceiveEvent(ctx context.Context, rcvEvent *
 	backoffEvent := rcvEvent.GetProofTransferBackoffWaitEvent()
 	if backoffEvent != nil {
 		// handle backoff event
-		svc.Logger.Error("backoff event received")
-		// wait for completed 
+		svc.Logger.Errorf("backoff event received, proof transfer attempt %d", backoffEvent.TriesCounter)
+		if err := svc.MarkReceivesBackingOff(ctx, backoffEvent); err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("error marking receives as backing off: %v", err)
+		}
+		// wait for completed
 		return nil
 	}
 	// check complete event
 	completeEvent := rcvEvent.GetAssetReceiveCompleteEvent()
 	if completeEvent != nil {
-		// handle backoff event
-		// get user by address
-		addressObj, err := svc.LookupUserByAddr(ctx, completeEvent.Address.Encoded)
-		if err != nil {
-			svc.Logger.Error("error finding user by address")
+		if completeEvent.Timestamp == 0 || completeEvent.Address == nil {
+			// TODO what is this condition?
 			// TODO apply sentry
+			svc.Logger.Error("event not completed, execution path needs exploration")
 			return nil
 		}
-		// check if user is found
-		if addressObj.User == nil {
-			svc.Logger.Error("user not found by address")
-			// TODO apply sentry
-			return nil
+		// the receive is credited by the lifecycle tracking once it reached the confirmation depth
+		_, err := svc.trackAddressReceives(ctx, completeEvent.Address.Encoded)
+		return err
+	}
+
+	return nil
+}
+
+// CreditTapdReceive books a completed receive to the owner of the address, or to suspense if the
+// address can not be matched to a user. A receive is only ever credited once per outpoint and
+// address, repeated calls return AlreadyProcessedTapdReceiveEventError.
+func (svc *LndhubService) CreditTapdReceive(ctx context.Context, addr *taprpc.Addr, outpoint string, notify bool) (*models.TransactionEntry, error) {
+	// get user by address
+	addressObj, err := svc.LookupUserByAddr(ctx, addr.Encoded)
+	if err != nil || addressObj.User == nil {
+		svc.Logger.Errorf("user not found by address %s", addr.Encoded)
+		// TODO apply sentry
+		// the hub received the asset anyway, park it in suspense until it is resolved
+		return svc.creditSuspense(ctx, addr, outpoint)
+	}
+	tahubUser := addressObj.User
+	assetId := addressObj.TaAssetID
+	assetName := addressObj.Asset.AssetName
+	svc.Logger.Infof("tahub user found: %s", tahubUser.Pubkey)
+
+	svc.Logger.Infof("asset id decoded: %s", assetId)
+	// get the asset treasury account (it will go negative, it mirrors what the hub holds on tapd)
+	// - this will be the debit_account
+	debitAccount, err := svc.SystemAccountFor(ctx, common.AccountTypeTreasury, assetId)
+	if err != nil {
+		svc.Logger.Error("error getting treasury account")
+		// TODO apply sentry
+		return nil, nil
+	}
+	// get user current account
+	// - this will be the credit_account
+	creditAccount, err := svc.AccountFor(ctx, common.AccountTypeCurrent, assetId, tahubUser.ID)
+	if err != nil {
+		svc.Logger.Error("error getting user current account")
+		// TODO apply sentry
+		return nil, nil
+	}
+	// 	transaction entry
+	entry := models.TransactionEntry{
+		UserID:          tahubUser.ID,
+		DebitAccountID:  debitAccount.ID,
+		CreditAccountID: creditAccount.ID,
+		Amount:          int64(addr.Amount),
+		EntryType:       models.EntryTypeIncoming,
+		Outpoint:        outpoint,
+		Address:         addr.Encoded,
+		TaAssetID:       assetId,
+		BroadcastState:  models.BroadcastStateBroadcast,
+	}
+	err = svc.insertReceiveEntry(ctx, svc.DB, &entry)
+	if err != nil {
+		if !errors.Is(err, AlreadyProcessedTapdReceiveEventError) {
+			svc.Logger.Errorf("error inserting transaction entry: %v", err)
+			sentry.CaptureException(err)
 		}
-		tahubUser := addressObj.User
-		assetId := addressObj.TaAssetID
-		assetName := addressObj.Asset.AssetName
-		svc.Logger.Infof("tahub user found: %s", tahubUser.Pubkey)
+		return nil, err
+	}
+	if notify {
+		// create message
+		message := "received: " + fmt.Sprint(addr.Amount) + " " + assetName
+		// broadcast the notice to the user
+		// TODO consider how to avoid this call if user did not register through the relay
+		_ = svc.SendNip4Notification(ctx, message, tahubUser.Pubkey)
+	}
+	return &entry, nil
+}
 
-		svc.Logger.Infof("asset id decoded: %s", assetId)
-		// get user incoming account (it will go negative which is acceptable per notes in db migration)
-		// - this will be the debit_account
-		debitAccount, err := svc.AccountFor(ctx, common.AccountTypeIncoming, assetId, tahubUser.ID)
+// insertReceiveEntry inserts an incoming entry unless one exists for the same outpoint and address.
+// Receives credited before the address was recorded have no address and escape the unique index,
+// until BackfillReceiveAddresses filled it in their outpoint alone marks them as processed.
+func (svc *LndhubService) insertReceiveEntry(ctx context.Context, db bun.IDB, entry *models.TransactionEntry) error {
+	legacy, err := db.NewSelect().Model((*models.TransactionEntry)(nil)).
+		Where("entry_type = ? AND outpoint = ? AND address IS NULL", models.EntryTypeIncoming, entry.Outpoint).
+		Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if legacy {
+		return fmt.Errorf("%w: outpoint %s credited without address", AlreadyProcessedTapdReceiveEventError, entry.Outpoint)
+	}
+	result, err := db.NewInsert().Model(entry).
+		On("CONFLICT (outpoint, address) WHERE " + models.IncomingReceiveUniquePredicate + " DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%w: outpoint %s address %s", AlreadyProcessedTapdReceiveEventError, entry.Outpoint, entry.Address)
+	}
+	return nil
+}
+
+// creditSuspense books a receive that can not be matched to a user against the asset suspense account
+func (svc *LndhubService) creditSuspense(ctx context.Context, addr *taprpc.Addr, outpoint string) (*models.TransactionEntry, error) {
+	assetId := hex.EncodeToString(addr.AssetId)
+	entry := models.TransactionEntry{
+		Amount:         int64(addr.Amount),
+		EntryType:      models.EntryTypeIncoming,
+		Outpoint:       outpoint,
+		Address:        addr.Encoded,
+		TaAssetID:      assetId,
+		BroadcastState: models.BroadcastStateBroadcast,
+	}
+	err := svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		debitAccount, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeTreasury, assetId)
 		if err != nil {
-			svc.Logger.Error("error getting user incoming account")
-			// TODO apply sentry
-			return nil
+			return err
 		}
-		// get user current account
-		// - this will be the credit_account
-		creditAccount, err := svc.AccountFor(ctx, common.AccountTypeCurrent, assetId, tahubUser.ID)
+		creditAccount, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeSuspense, assetId)
 		if err != nil {
-			svc.Logger.Error("error getting user current account")
-			// TODO apply sentry
-			return nil
+			return err
 		}
-		// 	transaction entry
-		entry := models.TransactionEntry{
-			UserID: tahubUser.ID,
-			DebitAccountID: debitAccount.ID,
-			CreditAccountID: creditAccount.ID,
-			Amount: int64(completeEvent.Address.Amount),
-			EntryType: models.EntryTypeIncoming,
-			Outpoint: completeEvent.Outpoint,
-			TaAssetID: assetId,
-			BroadcastState: models.BroadcastStateBroadcast,
-		}
-
-		if completeEvent.Timestamp > 0 {
-			// TODO ensure that completed Event is always populated even if backoff is too
-			// TODO confirm this is the best indication the event has been processed
+		entry.DebitAccountID = debitAccount.ID
+		entry.CreditAccountID = creditAccount.ID
+		return svc.insertReceiveEntry(ctx, tx, &entry)
+	})
+	if errors.Is(err, AlreadyProcessedTapdReceiveEventError) {
+		return nil, err
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("error crediting suspense for unknown address %s: %v", addr.Encoded, err)
+		return nil, nil
+	}
+	svc.Logger.Infof("credited %d of asset %s to suspense for unknown address %s", addr.Amount, assetId, addr.Encoded)
+	return &entry, nil
+}
 
-			// insert the tx entry
-			_, err = svc.DB.NewInsert().Model(&entry).Exec(ctx)
-			// check error on insertion
-			if err != nil {
-				svc.Logger.Error("error inserting transaction entry")
-				// TODO apply sentry
-				return nil
-			}
-			// create message
-			message := "received: " + fmt.Sprint(completeEvent.Address.Amount) + " " + assetName
-			// broadcast the notice to the user
-			
-			// TODO consider how to avoid this call if user did not register through the relay
-			_ = svc.SendNip4Notification(ctx, message, tahubUser.Pubkey)
-			return nil
-		} else {
-			// TODO what is this condition?
-			// TODO apply sentry
-			svc.Logger.Error("event not completed, execution path needs exploration")
-			return nil
+// CatchUpTapdReceives tracks the receives of the hub's addresses that were missed while the
+// subscription was not running, completed receives are credited and the users notified, receives
+// already booked are skipped. tapd is asked once for all receives, they are matched locally.
+func (svc *LndhubService) CatchUpTapdReceives(ctx context.Context) error {
+	resp, err := svc.TapdClient.AddrReceives(ctx, &taprpc.AddrReceivesRequest{})
+	if err != nil {
+		return err
+	}
+	addrs := []string{}
+	err = svc.DB.NewSelect().Model((*models.Address)(nil)).Column("addr").Scan(ctx, &addrs)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		known[addr] = true
+	}
+	events := []*taprpc.AddrEvent{}
+	for _, event := range resp.Events {
+		if event.Addr != nil && known[event.Addr.Encoded] {
+			events = append(events, event)
 		}
 	}
+	// failing receives are logged and skipped, the next catch-up or tracking run retries them
+	credited, err := svc.trackReceiveEvents(ctx, events)
+	if err != nil {
+		return err
+	}
+	svc.Logger.Infof("tapd receive catch-up checked %d receives, credited %d missed receives", len(events), credited)
+	return nil
+}
 
+// BackfillReceiveAddresses fills in the address of the incoming entries credited before the address
+// was recorded, from the address tapd received on at their outpoint. Entries whose outpoint tapd does
+// not know or whose receive is already booked with its address are left for review.
+func (svc *LndhubService) BackfillReceiveAddresses(ctx context.Context) error {
+	entries := []models.TransactionEntry{}
+	err := svc.DB.NewSelect().Model(&entries).
+		Where("entry_type = ? AND address IS NULL AND outpoint <> '' AND outpoint <> ?", models.EntryTypeIncoming, models.TahubInternalOutpoint).
+		OrderExpr("id ASC").
+		Scan(ctx)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	resp, err := svc.TapdClient.AddrReceives(ctx, &taprpc.AddrReceivesRequest{})
+	if err != nil {
+		return err
+	}
+	addresses := map[string]string{}
+	for _, event := range resp.Events {
+		if event.Addr != nil {
+			addresses[event.Outpoint] = event.Addr.Encoded
+		}
+	}
+	filled := 0
+	for _, entry := range entries {
+		address, ok := addresses[entry.Outpoint]
+		if !ok {
+			svc.Logger.Errorf("no tapd receive found for incoming entry %d outpoint %s", entry.ID, entry.Outpoint)
+			continue
+		}
+		// a receive credited twice keeps the address on its first entry only
+		result, err := svc.DB.NewUpdate().Model((*models.TransactionEntry)(nil)).
+			Set("address = ?", address).
+			Where("id = ?", entry.ID).
+			Where("NOT EXISTS (SELECT 1 FROM transaction_entries e WHERE e.entry_type = ? AND e.outpoint = ? AND e.address = ?)", models.EntryTypeIncoming, entry.Outpoint, address).
+			Exec(ctx)
+		if err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("error backfilling address of incoming entry %d: %v", entry.ID, err)
+			continue
+		}
+		if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+			svc.Logger.Errorf("incoming entry %d outpoint %s is already booked with address %s, review the entry", entry.ID, entry.Outpoint, address)
+			continue
+		}
+		filled++
+	}
+	svc.Logger.Infof("backfilled the address of %d of %d incoming entries", filled, len(entries))
 	return nil
 }
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+)
+
+// receiveConfirmations is the number of confirmations of an anchor transaction mined at confHeight,
+// 0 while it is unconfirmed
+func receiveConfirmations(confHeight uint32, blockHeight uint32) uint32 {
+	if confHeight == 0 {
+		return 0
+	}
+	if blockHeight < confHeight {
+		// our lnd is behind the tapd chain view
+		return 1
+	}
+	return blockHeight - confHeight + 1
+}
+
+// nextReceiveState maps a tapd address event status to the receive lifecycle state. Credited
+// receives stay credited and a backing off proof transfer stays so until tapd completes the receive.
+func nextReceiveState(current string, status taprpc.AddrEventStatus, confirmations uint32, depth uint32) string {
+	if current == models.AssetReceiveStateCredited {
+		return current
+	}
+	switch status {
+	case taprpc.AddrEventStatus_ADDR_EVENT_STATUS_TRANSACTION_DETECTED:
+		return models.AssetReceiveStateDetected
+	case taprpc.AddrEventStatus_ADDR_EVENT_STATUS_TRANSACTION_CONFIRMED,
+		taprpc.AddrEventStatus_ADDR_EVENT_STATUS_PROOF_RECEIVED:
+		if confirmations < depth {
+			return models.AssetReceiveStateConfirming
+		}
+		if current == models.AssetReceiveStateBackoffRetrying {
+			return current
+		}
+		return models.AssetReceiveStateProofPending
+	case taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED:
+		if confirmations < depth {
+			return models.AssetReceiveStateConfirming
+		}
+		return models.AssetReceiveStateCredited
+	}
+	if current == "" {
+		return models.AssetReceiveStateDetected
+	}
+	return current
+}
+
+// receiveStateMessage is the notice sent to the user when a receive changes state
+func receiveStateMessage(receive *models.AssetReceive, assetName string, depth uint32) string {
+	message := fmt.Sprintf("receive of %d %s: %s", receive.Amount, assetName, receive.State)
+	switch receive.State {
+	case models.AssetReceiveStateConfirming:
+		message += fmt.Sprintf(" (%d/%d confirmations)", receive.Confirmations, depth)
+	case models.AssetReceiveStateBackoffRetrying:
+		message += fmt.Sprintf(" (proof transfer attempt %d)", receive.BackoffTries)
+	}
+	return message
+}
+
+func (svc *LndhubService) receiveConfirmationDepth() uint32 {
+	if svc.Config.ReceiveConfirmationDepth == 0 {
+		return 1
+	}
+	return svc.Config.ReceiveConfirmationDepth
+}
+
+// TrackTapdReceive moves the receive of an address event to its next state, credits it once tapd
+// completed it and the anchor transaction reached the confirmation depth and notifies the user
+// of every other transition. credited reports whether this call booked the receive.
+func (svc *LndhubService) TrackTapdReceive(ctx context.Context, event *taprpc.AddrEvent, blockHeight uint32) (receive *models.AssetReceive, credited bool, err error) {
+	if event.Addr == nil {
+		return nil, false, nil
+	}
+	depth := svc.receiveConfirmationDepth()
+	receive = &models.AssetReceive{}
+	err = svc.DB.NewSelect().Model(receive).
+		Where("outpoint = ? AND address = ?", event.Outpoint, event.Addr.Encoded).
+		Limit(1).Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+	previousState := receive.State
+	assetName := hex.EncodeToString(event.Addr.AssetId)
+	receive.TaAssetID = assetName
+	var user *models.User
+	addressObj, err := svc.LookupUserByAddr(ctx, event.Addr.Encoded)
+	if err == nil && addressObj.User != nil {
+		user = addressObj.User
+		receive.UserID = user.ID
+		receive.TaAssetID = addressObj.TaAssetID
+		if addressObj.Asset != nil {
+			assetName = addressObj.Asset.AssetName
+		}
+	}
+	receive.Address = event.Addr.Encoded
+	receive.Outpoint = event.Outpoint
+	receive.Amount = int64(event.Addr.Amount)
+	if event.ConfirmationHeight > 0 {
+		receive.ConfirmationHeight = event.ConfirmationHeight
+	}
+	receive.Confirmations = receiveConfirmations(receive.ConfirmationHeight, blockHeight)
+	receive.State = nextReceiveState(previousState, event.Status, receive.Confirmations, depth)
+
+	if receive.State == models.AssetReceiveStateCredited && previousState != models.AssetReceiveStateCredited {
+		// the user is notified of the credit by CreditTapdReceive
+		entry, err := svc.CreditTapdReceive(ctx, event.Addr, event.Outpoint, true)
+		switch {
+		case errors.Is(err, AlreadyProcessedTapdReceiveEventError):
+			existing := models.TransactionEntry{}
+			err = svc.DB.NewSelect().Model(&existing).
+				Where("outpoint = ? AND (address = ? OR address IS NULL) AND entry_type = ?", event.Outpoint, event.Addr.Encoded, models.EntryTypeIncoming).
+				OrderExpr("address NULLS LAST").
+				Limit(1).Scan(ctx)
+			if err != nil {
+				return nil, false, err
+			}
+			receive.TransactionEntryID = existing.ID
+		case err != nil:
+			return nil, false, err
+		case entry == nil:
+			// crediting failed and was logged, try again with the next event
+			return nil, false, fmt.Errorf("receive outpoint %s address %s could not be credited", event.Outpoint, event.Addr.Encoded)
+		default:
+			receive.TransactionEntryID = entry.ID
+			credited = true
+		}
+	}
+
+	_, err = svc.DB.NewInsert().Model(receive).
+		On("CONFLICT (outpoint, address) DO UPDATE").
+		Set("user_id = EXCLUDED.user_id").
+		Set("state = EXCLUDED.state").
+		Set("confirmation_height = EXCLUDED.confirmation_height").
+		Set("confirmations = EXCLUDED.confirmations").
+		Set("transaction_entry_id = COALESCE(EXCLUDED.transaction_entry_id, asset_receives.transaction_entry_id)").
+		Set("updated_at = ?", time.Now()).
+		Returning("id").
+		Exec(ctx)
+	if err != nil {
+		return nil, credited, err
+	}
+	if receive.State == models.AssetReceiveStateCredited && previousState != models.AssetReceiveStateCredited {
+		// a receive to a checkout address pays the checkout, ProcessCheckouts retries failures
+		if _, err := svc.CompleteCheckoutForAddress(ctx, receive.Address, receive.TransactionEntryID); err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("Could not complete checkout of address %s: %v", receive.Address, err)
+		}
+	}
+	if receive.State != previousState && receive.State != models.AssetReceiveStateCredited && user != nil {
+		svc.Logger.Infof("receive outpoint %s address %s is %s", receive.Outpoint, receive.Address, receive.State)
+		_ = svc.SendNip4Notification(ctx, receiveStateMessage(receive, assetName, depth), user.Pubkey)
+	}
+	return receive, credited, nil
+}
+
+// trackReceiveEvents tracks a list of address events against the current block height and
+// returns how many receives were credited
+func (svc *LndhubService) trackReceiveEvents(ctx context.Context, events []*taprpc.AddrEvent) (int, error) {
+	info, err := svc.GetInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	credited := 0
+	for _, event := range events {
+		if event.Addr == nil {
+			continue
+		}
+		_, ok, err := svc.TrackTapdReceive(ctx, event, info.BlockHeight)
+		if err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("error tracking receive outpoint %s address %s: %v", event.Outpoint, event.Addr.Encoded, err)
+			continue
+		}
+		if ok {
+			credited++
+		}
+	}
+	return credited, nil
+}
+
+// trackAddressReceives tracks all receives of a single address
+func (svc *LndhubService) trackAddressReceives(ctx context.Context, addr string) (int, error) {
+	resp, err := svc.TapdClient.AddrReceives(ctx, &taprpc.AddrReceivesRequest{
+		FilterAddr: addr,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return svc.trackReceiveEvents(ctx, resp.Events)
+}
+
+// TrackTapdReceives refreshes the state of all receives tapd knows of, this moves receives
+// through the confirmation depth as blocks are mined
+func (svc *LndhubService) TrackTapdReceives(ctx context.Context) error {
+	resp, err := svc.TapdClient.AddrReceives(ctx, &taprpc.AddrReceivesRequest{})
+	if err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("error listing tapd receives: %v", err)
+		return err
+	}
+	credited, err := svc.trackReceiveEvents(ctx, resp.Events)
+	if err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("error tracking tapd receives: %v", err)
+		return err
+	}
+	if credited > 0 {
+		svc.Logger.Infof("receive tracking credited %d receives", credited)
+	}
+	return nil
+}
+
+// MarkReceivesBackingOff moves the receives waiting for their proof to backoff_retrying. The
+// backoff event does not name the receive so all receives waiting for a proof are marked.
+func (svc *LndhubService) MarkReceivesBackingOff(ctx context.Context, backoffEvent *taprpc.ProofTransferBackoffWaitEvent) error {
+	receives := []models.AssetReceive{}
+	err := svc.DB.NewUpdate().Model(&receives).
+		Set("state = ?", models.AssetReceiveStateBackoffRetrying).
+		Set("backoff_tries = ?", backoffEvent.TriesCounter).
+		Set("updated_at = ?", time.Now()).
+		Where("state IN (?, ?)", models.AssetReceiveStateProofPending, models.AssetReceiveStateBackoffRetrying).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range receives {
+		receive := &receives[i]
+		if receive.UserID == 0 {
+			continue
+		}
+		user, err := svc.FindUser(ctx, receive.UserID)
+		if err != nil {
+			continue
+		}
+		assetName := receive.TaAssetID
+		asset := models.Asset{}
+		if err := svc.DB.NewSelect().Model(&asset).Where("ta_asset_id = ?", receive.TaAssetID).Limit(1).Scan(ctx); err == nil {
+			assetName = asset.AssetName
+		}
+		_ = svc.SendNip4Notification(ctx, receiveStateMessage(receive, assetName, svc.receiveConfirmationDepth()), user.Pubkey)
+	}
+	return nil
+}
+
+// ReceivesFor returns the latest receives to the addresses of a user
+func (svc *LndhubService) ReceivesFor(ctx context.Context, userId int64) ([]models.AssetReceive, error) {
+	receives := []models.AssetReceive{}
+	err := svc.DB.NewSelect().Model(&receives).
+		Where("user_id = ?", userId).
+		OrderExpr("id DESC").
+		Limit(100).
+		Scan(ctx)
+	return receives, err
+}
+
+func (svc *LndhubService) StartReceiveTrackingRoutine(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(svc.Config.ReceiveTrackingInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = svc.TrackTapdReceives(ctx)
+		}
+	}
+}
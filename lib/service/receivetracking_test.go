@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiveConfirmations(t *testing.T) {
+	assert.Equal(t, uint32(0), receiveConfirmations(0, 800))
+	assert.Equal(t, uint32(1), receiveConfirmations(800, 800))
+	assert.Equal(t, uint32(3), receiveConfirmations(798, 800))
+	assert.Equal(t, uint32(1), receiveConfirmations(801, 800))
+}
+
+func TestNextReceiveState(t *testing.T) {
+	assert.Equal(t, models.AssetReceiveStateDetected,
+		nextReceiveState("", taprpc.AddrEventStatus_ADDR_EVENT_STATUS_TRANSACTION_DETECTED, 0, 1))
+	assert.Equal(t, models.AssetReceiveStateConfirming,
+		nextReceiveState(models.AssetReceiveStateDetected, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_TRANSACTION_CONFIRMED, 1, 3))
+	assert.Equal(t, models.AssetReceiveStateProofPending,
+		nextReceiveState(models.AssetReceiveStateConfirming, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_TRANSACTION_CONFIRMED, 3, 3))
+	assert.Equal(t, models.AssetReceiveStateBackoffRetrying,
+		nextReceiveState(models.AssetReceiveStateBackoffRetrying, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_PROOF_RECEIVED, 1, 1))
+	// completed by tapd but held back until the confirmation depth
+	assert.Equal(t, models.AssetReceiveStateConfirming,
+		nextReceiveState(models.AssetReceiveStateProofPending, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED, 1, 6))
+	assert.Equal(t, models.AssetReceiveStateCredited,
+		nextReceiveState(models.AssetReceiveStateBackoffRetrying, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_COMPLETED, 6, 6))
+	// never regress a credited receive
+	assert.Equal(t, models.AssetReceiveStateCredited,
+		nextReceiveState(models.AssetReceiveStateCredited, taprpc.AddrEventStatus_ADDR_EVENT_STATUS_TRANSACTION_DETECTED, 0, 1))
+}
+
+func TestReceiveStateMessage(t *testing.T) {
+	receive := &models.AssetReceive{Amount: 50, State: models.AssetReceiveStateConfirming, Confirmations: 2}
+	assert.Equal(t, "receive of 50 usdt: confirming (2/6 confirmations)", receiveStateMessage(receive, "usdt", 6))
+	receive.State = models.AssetReceiveStateBackoffRetrying
+	receive.BackoffTries = 3
+	assert.Equal(t, "receive of 50 usdt: backoff_retrying (proof transfer attempt 3)", receiveStateMessage(receive, "usdt", 6))
+}
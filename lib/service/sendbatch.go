@@ -0,0 +1,557 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/uptrace/bun"
+)
+
+// splitChainFee splits the chain fee of a batch pro-rata to the sent amounts, the sats lost to
+// rounding are charged one by one starting with the first item
+func splitChainFee(total int64, amounts []int64) []int64 {
+	shares := make([]int64, len(amounts))
+	if total <= 0 || len(amounts) == 0 {
+		return shares
+	}
+	sum := big.NewInt(0)
+	for _, amount := range amounts {
+		sum.Add(sum, big.NewInt(amount))
+	}
+	if sum.Sign() == 0 {
+		return shares
+	}
+	remainder := total
+	for i, amount := range amounts {
+		share := new(big.Int).Mul(big.NewInt(total), big.NewInt(amount))
+		shares[i] = share.Quo(share, sum).Int64()
+		remainder -= shares[i]
+	}
+	for i := 0; remainder > 0; i = (i + 1) % len(shares) {
+		shares[i]++
+		remainder--
+	}
+	return shares
+}
+
+// transferAnchorTxid is the txid of the anchor transaction of a transfer
+func transferAnchorTxid(transfer *taprpc.AssetTransfer) string {
+	if transfer == nil {
+		return ""
+	}
+	for _, output := range transfer.Outputs {
+		if output.Anchor != nil && output.Anchor.Outpoint != "" {
+			txid, _, _ := strings.Cut(output.Anchor.Outpoint, ":")
+			return txid
+		}
+	}
+	return ""
+}
+
+// batchItems picks the items that go into one SendAsset call, tapd expects every address only
+// once so repeated addresses wait for the next batch
+func batchItems(queued []models.SendBatchItem) []models.SendBatchItem {
+	items := []models.SendBatchItem{}
+	seen := map[string]bool{}
+	for _, item := range queued {
+		if seen[item.Address] {
+			continue
+		}
+		seen[item.Address] = true
+		items = append(items, item)
+	}
+	return items
+}
+
+// queueSendInTx queues an external send, the user's balance is already moved to the outgoing account
+func (svc *LndhubService) queueSendInTx(ctx context.Context, tx bun.Tx, entry *models.TransactionEntry, scriptKey []byte, withdraw bool) (*models.SendBatchItem, error) {
+	entry.BroadcastState = models.BroadcastStateQueued
+	if _, err := tx.NewUpdate().Model(entry).Column("broadcast_state").WherePK().Exec(ctx); err != nil {
+		return nil, err
+	}
+	item := &models.SendBatchItem{
+		UserID:             entry.UserID,
+		TaAssetID:          entry.TaAssetID,
+		TransactionEntryID: entry.ID,
+		Address:            entry.Address,
+		ScriptKey:          hex.EncodeToString(scriptKey),
+		Amount:             entry.Amount,
+		Withdraw:           withdraw,
+		State:              models.SendBatchItemStateQueued,
+	}
+	_, err := tx.NewInsert().Model(item).Exec(ctx)
+	return item, err
+}
+
+// sendQueued sends the queued item right away if batching is disabled, otherwise the item goes out
+// with the next batch of its asset, or now if the batch is full
+func (svc *LndhubService) sendQueued(ctx context.Context, item *models.SendBatchItem) (string, bool) {
+	if svc.Config.SendBatchInterval > 0 {
+		count, err := svc.DB.NewSelect().Model((*models.SendBatchItem)(nil)).
+			Where("state = ? AND ta_asset_id = ?", models.SendBatchItemStateQueued, item.TaAssetID).
+			Count(ctx)
+		if err == nil && count >= svc.sendBatchMaxSize() {
+			_ = svc.SendQueuedBatch(ctx, item.TaAssetID, nil)
+		}
+		return fmt.Sprintf("success: queued %s, send %d goes out with the next batch", item.TaAssetID, item.ID), true
+	}
+	// only the caller's own send goes out here, sends left queued are picked up by StartSendBatchRoutine
+	_, err := svc.SendBatch(ctx, item.TaAssetID, func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("id = ?", item.ID)
+	})
+	if err != nil {
+		svc.Logger.Errorf("Could not send queued send %d: %v", item.ID, err)
+	}
+	return svc.sendQueuedState(ctx, item)
+}
+
+// sendQueuedState reports the state the send is actually in, a send that may still go out is never
+// reported as failed so it is not retried
+func (svc *LndhubService) sendQueuedState(ctx context.Context, item *models.SendBatchItem) (string, bool) {
+	current := &models.SendBatchItem{}
+	if err := svc.DB.NewSelect().Model(current).Where("id = ?", item.ID).Scan(ctx); err != nil {
+		svc.Logger.Errorf("Could not load queued send %d: %v", item.ID, err)
+		return fmt.Sprintf("error: could not load the state of send %d, check /v2/sends before sending again.", item.ID), false
+	}
+	switch current.State {
+	case models.SendBatchItemStateSent:
+		msg := fmt.Sprintf("success: sent %s", item.TaAssetID)
+		if item.Withdraw {
+			msg += ", the proof file is sent once the transfer completes"
+		}
+		return msg, true
+	case models.SendBatchItemStateFailed:
+		return "error: failed to send asset, the amount was refunded.", false
+	case models.SendBatchItemStateSending:
+		return fmt.Sprintf("success: send %d of %s is being sent, see /v2/sends for its outcome", item.ID, item.TaAssetID), true
+	default:
+		return fmt.Sprintf("success: queued %s, send %d goes out shortly", item.TaAssetID, item.ID), true
+	}
+}
+
+func (svc *LndhubService) sendBatchMaxSize() int {
+	if svc.Config.SendBatchMaxSize <= 0 {
+		return 1
+	}
+	return svc.Config.SendBatchMaxSize
+}
+
+const (
+	// batches still sending after this delay lost their outcome, they are reconciled with tapd
+	sendingBatchReconcileDelay = 10 * time.Minute
+	// a sending batch without a tapd transfer after this delay never reached tapd and is refunded
+	sendingBatchRefundDelay = time.Hour
+	// without batching a send still queued after this delay was left behind by its sender
+	leftoverSendDelay = time.Minute
+)
+
+// SendBatch sends the queued sends of an asset with one SendAsset call. Failed sends are refunded,
+// on success every user is charged their pro-rata share of the chain fee from their btc balance.
+// The items are moved to sending before tapd is called, so they can never go out twice: if the
+// outcome can not be recorded they stay sending until ReconcileSendingBatches finds the transfer.
+// Only queued sends matching filter are batched, a nil filter takes any. Returns nil if nothing is queued.
+func (svc *LndhubService) SendBatch(ctx context.Context, assetId string, filter func(*bun.SelectQuery) *bun.SelectQuery) (*models.SendBatch, error) {
+	batch, err := svc.claimSendBatch(ctx, assetId, filter)
+	if err != nil || batch == nil {
+		return nil, err
+	}
+	addrs := make([]string, len(batch.Items))
+	for i, item := range batch.Items {
+		addrs[i] = item.Address
+	}
+	sendResp, sendErr := svc.TapdClient.SendAsset(ctx, &taprpc.SendAssetRequest{TapAddrs: addrs})
+	var transfer *taprpc.AssetTransfer
+	if sendErr == nil {
+		transfer = sendResp.Transfer
+	}
+	return svc.recordSendBatch(ctx, batch.ID, transfer, sendErr)
+}
+
+// claimSendBatch moves the next queued sends of an asset matching filter to a new batch in the sending state
+func (svc *LndhubService) claimSendBatch(ctx context.Context, assetId string, filter func(*bun.SelectQuery) *bun.SelectQuery) (*models.SendBatch, error) {
+	if filter == nil {
+		filter = func(q *bun.SelectQuery) *bun.SelectQuery { return q }
+	}
+	batch := &models.SendBatch{TaAssetID: assetId, State: models.SendBatchStateSending}
+	err := svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		queued := []models.SendBatchItem{}
+		// concurrent batches skip the items that are being claimed
+		err := tx.NewSelect().Model(&queued).
+			Where("state = ? AND ta_asset_id = ?", models.SendBatchItemStateQueued, assetId).
+			Apply(filter).
+			OrderExpr("id ASC").
+			Limit(svc.sendBatchMaxSize()).
+			For("UPDATE SKIP LOCKED").
+			Scan(ctx)
+		if err != nil {
+			return err
+		}
+		items := batchItems(queued)
+		if len(items) == 0 {
+			return nil
+		}
+		if _, err := tx.NewInsert().Model(batch).Exec(ctx); err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].BatchID = batch.ID
+			items[i].State = models.SendBatchItemStateSending
+			if _, err := tx.NewUpdate().Model(&items[i]).Column("batch_id", "state", "updated_at").WherePK().Exec(ctx); err != nil {
+				return err
+			}
+		}
+		batch.Items = items
+		return nil
+	})
+	if err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("Could not claim send batch asset:%s error %v", assetId, err)
+		return nil, err
+	}
+	if batch.ID == 0 {
+		return nil, nil
+	}
+	return batch, nil
+}
+
+// recordSendBatch books the outcome of a sending batch, the transfer made by tapd or the error of
+// the SendAsset call. A batch that was recorded in the meantime is returned as it is.
+func (svc *LndhubService) recordSendBatch(ctx context.Context, batchId int64, transfer *taprpc.AssetTransfer, sendErr error) (*models.SendBatch, error) {
+	batch := &models.SendBatch{}
+	recorded := false
+	err := svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewSelect().Model(batch).Where("id = ?", batchId).For("UPDATE").Scan(ctx)
+		if err != nil {
+			return err
+		}
+		if batch.State != models.SendBatchStateSending {
+			return tx.NewSelect().Model(&batch.Items).Where("batch_id = ?", batch.ID).OrderExpr("id ASC").Scan(ctx)
+		}
+		items := []models.SendBatchItem{}
+		err = tx.NewSelect().Model(&items).
+			Where("batch_id = ? AND state = ?", batch.ID, models.SendBatchItemStateSending).
+			OrderExpr("id ASC").
+			Scan(ctx)
+		if err != nil {
+			return err
+		}
+		if sendErr != nil {
+			batch.State = models.SendBatchStateFailed
+			batch.Error = sendErr.Error()
+		} else {
+			batch.State = models.SendBatchStateSent
+			batch.AnchorTxid = transferAnchorTxid(transfer)
+			if transfer != nil {
+				batch.ChainFees = transfer.AnchorTxChainFees
+			}
+		}
+		if _, err := tx.NewUpdate().Model(batch).Column("state", "anchor_txid", "chain_fees", "error").WherePK().Exec(ctx); err != nil {
+			return err
+		}
+		amounts := make([]int64, len(items))
+		for i, item := range items {
+			amounts[i] = item.Amount
+		}
+		shares := splitChainFee(batch.ChainFees, amounts)
+		for i := range items {
+			item := &items[i]
+			if sendErr != nil {
+				err = svc.refundBatchItemInTx(ctx, tx, item)
+			} else {
+				item.ChainFee = shares[i]
+				err = svc.chargeBatchItemInTx(ctx, tx, item)
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := tx.NewUpdate().Model(item).Column("state", "chain_fee", "fee_entry_id", "updated_at").WherePK().Exec(ctx); err != nil {
+				return err
+			}
+		}
+		batch.Items = items
+		recorded = true
+		return nil
+	})
+	if err != nil {
+		// the items stay sending, ReconcileSendingBatches books the batch from tapd's transfers
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("Could not record send batch id:%d anchor_txid:%s send error:%v error %v", batchId, transferAnchorTxid(transfer), sendErr, err)
+		return nil, err
+	}
+	if !recorded {
+		return batch, nil
+	}
+	if sendErr != nil {
+		svc.Logger.Errorf("Send batch id:%d asset:%s with %d sends failed: %v", batch.ID, batch.TaAssetID, len(batch.Items), sendErr)
+		return batch, nil
+	}
+	svc.Logger.Infof("Sent batch id:%d asset:%s sends:%d anchor_txid:%s chain_fees:%d", batch.ID, batch.TaAssetID, len(batch.Items), batch.AnchorTxid, batch.ChainFees)
+	for i := range batch.Items {
+		item := &batch.Items[i]
+		if !item.Withdraw {
+			continue
+		}
+		entry := &models.TransactionEntry{ID: item.TransactionEntryID, UserID: item.UserID, TaAssetID: item.TaAssetID, Address: item.Address, Amount: item.Amount}
+		scriptKey, _ := hex.DecodeString(item.ScriptKey)
+		if _, err := svc.insertWithdrawal(ctx, entry, scriptKey, transfer); err != nil {
+			sentry.CaptureException(err)
+			svc.Logger.Errorf("Could not record withdrawal of entry %d: %v", item.TransactionEntryID, err)
+		}
+	}
+	return batch, nil
+}
+
+// batchTransfer finds the tapd transfer of a batch, the transfer that pays every item's script key
+// its amount and is not the anchor of another batch
+func batchTransfer(batch *models.SendBatch, transfers []*taprpc.AssetTransfer, recordedTxids map[string]bool) *taprpc.AssetTransfer {
+	for _, transfer := range transfers {
+		txid := transferAnchorTxid(transfer)
+		if txid == "" || recordedTxids[txid] || transfer.TransferTimestamp < batch.CreatedAt.Add(-time.Minute).Unix() {
+			continue
+		}
+		matched := len(batch.Items) > 0
+		for _, item := range batch.Items {
+			found := false
+			for _, output := range transfer.Outputs {
+				if item.ScriptKey != "" && hex.EncodeToString(output.ScriptKey) == item.ScriptKey && int64(output.Amount) == item.Amount {
+					found = true
+					break
+				}
+			}
+			if !found {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return transfer
+		}
+	}
+	return nil
+}
+
+// ReconcileSendingBatches books the batches whose outcome was lost between the SendAsset call and
+// its recording. A batch with a matching transfer on tapd is booked as sent, a batch tapd has no
+// transfer for after sendingBatchRefundDelay is refunded. Batches are never sent again.
+func (svc *LndhubService) ReconcileSendingBatches(ctx context.Context) error {
+	batches := []models.SendBatch{}
+	err := svc.DB.NewSelect().Model(&batches).
+		Relation("Items").
+		Where("state = ? AND created_at < ?", models.SendBatchStateSending, time.Now().Add(-sendingBatchReconcileDelay)).
+		OrderExpr("id ASC").
+		Scan(ctx)
+	if err != nil || len(batches) == 0 {
+		return err
+	}
+	resp, err := svc.TapdClient.ListTransfers(ctx, &taprpc.ListTransfersRequest{})
+	if err != nil {
+		return err
+	}
+	recordedTxids := map[string]bool{}
+	txids := []string{}
+	err = svc.DB.NewSelect().Model((*models.SendBatch)(nil)).Column("anchor_txid").Where("anchor_txid IS NOT NULL").Scan(ctx, &txids)
+	if err != nil {
+		return err
+	}
+	for _, txid := range txids {
+		recordedTxids[txid] = true
+	}
+	for i := range batches {
+		batch := &batches[i]
+		transfer := batchTransfer(batch, resp.Transfers, recordedTxids)
+		var sendErr error
+		switch {
+		case transfer != nil:
+			recordedTxids[transferAnchorTxid(transfer)] = true
+		case time.Since(batch.CreatedAt) > sendingBatchRefundDelay:
+			sendErr = fmt.Errorf("no tapd transfer found for send batch %d", batch.ID)
+		default:
+			continue
+		}
+		svc.Logger.Infof("Reconciling send batch id:%d asset:%s anchor_txid:%s", batch.ID, batch.TaAssetID, transferAnchorTxid(transfer))
+		if _, err := svc.recordSendBatch(ctx, batch.ID, transfer, sendErr); err != nil {
+			svc.Logger.Errorf("Could not reconcile send batch id:%d: %v", batch.ID, err)
+		}
+	}
+	return nil
+}
+
+// refundBatchItemInTx moves the amount of a failed send back to the user's current account
+func (svc *LndhubService) refundBatchItemInTx(ctx context.Context, tx bun.Tx, item *models.SendBatchItem) error {
+	item.State = models.SendBatchItemStateFailed
+	entryToRevert := models.TransactionEntry{}
+	if err := tx.NewSelect().Model(&entryToRevert).Where("id = ?", item.TransactionEntryID).Limit(1).Scan(ctx); err != nil {
+		return err
+	}
+	entry := models.TransactionEntry{
+		UserID:          item.UserID,
+		ParentID:        entryToRevert.ID,
+		CreditAccountID: entryToRevert.DebitAccountID,
+		DebitAccountID:  entryToRevert.CreditAccountID,
+		Amount:          entryToRevert.Amount,
+		TaAssetID:       entryToRevert.TaAssetID,
+		EntryType:       models.EntryTypeOutgoingReversal,
+		Address:         entryToRevert.Address,
+	}
+	if _, err := tx.NewInsert().Model(&entry).Exec(ctx); err != nil {
+		return err
+	}
+	_, err := tx.NewUpdate().Model(&models.TransactionEntry{ID: entryToRevert.ID, BroadcastState: models.BroadcastStateFailed}).
+		Column("broadcast_state").WherePK().Exec(ctx)
+	return err
+}
+
+// chargeBatchItemInTx marks the send as broadcast and charges the user's chain fee share to the btc
+// chain_fees account. Users without enough btc are not charged, the hub covers their share and the
+// item's ChainFee is reset to 0 so it only ever shows what was booked.
+func (svc *LndhubService) chargeBatchItemInTx(ctx context.Context, tx bun.Tx, item *models.SendBatchItem) error {
+	item.State = models.SendBatchItemStateSent
+	_, err := tx.NewUpdate().Model(&models.TransactionEntry{ID: item.TransactionEntryID, BroadcastState: models.BroadcastStatePending}).
+		Column("broadcast_state").WherePK().Exec(ctx)
+	if err != nil || item.ChainFee == 0 {
+		return err
+	}
+	current, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, common.BTC_TA_ASSET_ID, item.UserID)
+	if err != nil {
+		svc.Logger.Errorf("No btc account to charge chain fee share of send %d user_id:%d: %v", item.ID, item.UserID, err)
+		item.ChainFee = 0
+		return nil
+	}
+	var balance int64
+	err = tx.NewSelect().Table("account_ledgers").ColumnExpr("coalesce(sum(account_ledgers.amount), 0)").Where("account_ledgers.account_id = ?", current.ID).Scan(ctx, &balance)
+	if err != nil {
+		return err
+	}
+	if balance < item.ChainFee {
+		svc.Logger.Infof("Not enough btc to charge chain fee share %d of send %d user_id:%d", item.ChainFee, item.ID, item.UserID)
+		item.ChainFee = 0
+		return nil
+	}
+	chainFees, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeChainFees, common.BTC_TA_ASSET_ID)
+	if err != nil {
+		return err
+	}
+	entry := models.TransactionEntry{
+		UserID:          item.UserID,
+		ParentID:        item.TransactionEntryID,
+		DebitAccountID:  current.ID,
+		CreditAccountID: chainFees.ID,
+		Amount:          item.ChainFee,
+		TaAssetID:       common.BTC_TA_ASSET_ID,
+		EntryType:       models.EntryTypeFee,
+		CreatedAt:       time.Now(),
+	}
+	if _, err := tx.NewInsert().Model(&entry).Exec(ctx); err != nil {
+		return err
+	}
+	item.FeeEntryID = entry.ID
+	return nil
+}
+
+// UpdateSendBatchEntries sets the broadcast state of all entries batched with the given entry,
+// returns false if the entry was not sent in a batch
+func (svc *LndhubService) UpdateSendBatchEntries(ctx context.Context, entryId int64, broadcastState string) bool {
+	entryIds := []int64{}
+	err := svc.DB.NewSelect().Model((*models.SendBatchItem)(nil)).
+		Column("transaction_entry_id").
+		Where("state = ?", models.SendBatchItemStateSent).
+		Where("batch_id = (SELECT batch_id FROM send_batch_items WHERE transaction_entry_id = ?)", entryId).
+		Scan(ctx, &entryIds)
+	if err != nil || len(entryIds) == 0 {
+		return false
+	}
+	_, err = svc.DB.NewUpdate().Model((*models.TransactionEntry)(nil)).
+		Set("broadcast_state = ?", broadcastState).
+		Where("id IN (?)", bun.In(entryIds)).
+		Exec(ctx)
+	return err == nil
+}
+
+// SendQueuedBatch sends the queued sends of an asset matching filter and reports the outcome to every user
+func (svc *LndhubService) SendQueuedBatch(ctx context.Context, assetId string, filter func(*bun.SelectQuery) *bun.SelectQuery) error {
+	batch, err := svc.SendBatch(ctx, assetId, filter)
+	if err != nil || batch == nil {
+		return err
+	}
+	for _, item := range batch.Items {
+		user, err := svc.FindUser(ctx, item.UserID)
+		if err != nil {
+			continue
+		}
+		message := fmt.Sprintf("sent: %d %s in batch %d, chain fee share: %d sats", item.Amount, item.TaAssetID, batch.ID, item.ChainFee)
+		if item.State == models.SendBatchItemStateFailed {
+			message = fmt.Sprintf("error: send of %d %s failed, the amount was refunded", item.Amount, item.TaAssetID)
+		}
+		_ = svc.SendNip4Notification(ctx, message, user.Pubkey)
+	}
+	return nil
+}
+
+// SendQueuedBatches sends one batch for every asset with sends queued before queuedBefore
+func (svc *LndhubService) SendQueuedBatches(ctx context.Context, queuedBefore time.Time) error {
+	filter := func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("created_at < ?", queuedBefore)
+	}
+	assetIds := []string{}
+	err := svc.DB.NewSelect().Model((*models.SendBatchItem)(nil)).
+		Distinct().Column("ta_asset_id").
+		Where("state = ?", models.SendBatchItemStateQueued).
+		Apply(filter).
+		Scan(ctx, &assetIds)
+	if err != nil {
+		return err
+	}
+	for _, assetId := range assetIds {
+		if err := svc.SendQueuedBatch(ctx, assetId, filter); err != nil {
+			svc.Logger.Errorf("Failed to send batch of asset %s: %v", assetId, err)
+		}
+	}
+	return nil
+}
+
+// SendBatchItemsFor lists the user's latest queued and batched sends
+func (svc *LndhubService) SendBatchItemsFor(ctx context.Context, userId int64) ([]models.SendBatchItem, error) {
+	items := []models.SendBatchItem{}
+	err := svc.DB.NewSelect().Model(&items).Where("user_id = ?", userId).OrderExpr("id DESC").Limit(100).Scan(ctx)
+	return items, err
+}
+
+// StartSendBatchRoutine sends the queued batches every SendBatchInterval and reconciles the
+// interrupted batches. Without batching it runs once a minute and only sends the sends left queued
+// for longer than leftoverSendDelay, e.g. by a crash between queueing and sending.
+func (svc *LndhubService) StartSendBatchRoutine(ctx context.Context) {
+	interval := time.Duration(svc.Config.SendBatchInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := svc.ReconcileSendingBatches(ctx); err != nil {
+				sentry.CaptureException(err)
+				svc.Logger.Errorf("Failed to reconcile sending batches: %v", err)
+			}
+			queuedBefore := time.Now()
+			if svc.Config.SendBatchInterval <= 0 {
+				queuedBefore = queuedBefore.Add(-leftoverSendDelay)
+			}
+			if err := svc.SendQueuedBatches(ctx, queuedBefore); err != nil {
+				sentry.CaptureException(err)
+				svc.Logger.Errorf("Failed to send queued batches: %v", err)
+			}
+		}
+	}
+}
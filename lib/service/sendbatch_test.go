@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitChainFee(t *testing.T) {
+	assert.Equal(t, []int64{250, 750}, splitChainFee(1000, []int64{100, 300}))
+	// rounding remainder is charged starting with the first send
+	assert.Equal(t, []int64{34, 33, 33}, splitChainFee(100, []int64{1, 1, 1}))
+	assert.Equal(t, []int64{0, 0}, splitChainFee(0, []int64{1, 1}))
+	assert.Equal(t, []int64{}, splitChainFee(100, []int64{}))
+	// large asset amounts do not overflow
+	shares := splitChainFee(1001, []int64{1 << 62, 1 << 62})
+	assert.Equal(t, int64(1001), shares[0]+shares[1])
+}
+
+func TestTransferAnchorTxid(t *testing.T) {
+	transfer := &taprpc.AssetTransfer{
+		Outputs: []*taprpc.TransferOutput{
+			{Anchor: &taprpc.TransferOutputAnchor{Outpoint: "anchortx:0"}},
+			{Anchor: &taprpc.TransferOutputAnchor{Outpoint: "anchortx:1"}},
+		},
+	}
+	assert.Equal(t, "anchortx", transferAnchorTxid(transfer))
+	assert.Equal(t, "", transferAnchorTxid(nil))
+}
+
+func TestBatchItems(t *testing.T) {
+	queued := []models.SendBatchItem{
+		{ID: 1, Address: "addr1"},
+		{ID: 2, Address: "addr2"},
+		{ID: 3, Address: "addr1"},
+	}
+	items := batchItems(queued)
+	assert.Len(t, items, 2)
+	assert.Equal(t, int64(1), items[0].ID)
+	assert.Equal(t, int64(2), items[1].ID)
+}
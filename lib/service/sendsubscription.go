@@ -74,14 +74,17 @@ func (svc *LndhubService) HandleTapdSendEvent(ctx context.Context, sendEvent *ta
 		svc.Logger.Info("send asset event received")
 		// TODO match pending to incoming by something found in the broadcast state string
 		tx := pending[0]
-		// update transaction entry
-		success := svc.UpdateTapdTransactionEntry(
-			ctx,
-			tx.ID,
-			tx.TaAssetID,
-			tx.UserID,
-			event.SendState,
-		)
+		// update transaction entry, sends batched with it share the same anchor transaction
+		success := svc.UpdateSendBatchEntries(ctx, tx.ID, event.SendState)
+		if !success {
+			success = svc.UpdateTapdTransactionEntry(
+				ctx,
+				tx.ID,
+				tx.TaAssetID,
+				tx.UserID,
+				event.SendState,
+			)
+		}
 		// check success updating transaction
 		if !success {
 			// TODO apply sentry
@@ -89,6 +92,12 @@ func (svc *LndhubService) HandleTapdSendEvent(ctx context.Context, sendEvent *ta
 			return nil
 		}
 		// TODO attempt to provide informative updates to sender about transaction
+		if event.SendState == tapdSendStateComplete {
+			// proofs of completed withdrawals are available on tapd now
+			if err := svc.ExportWithdrawalProofs(ctx); err != nil {
+				svc.Logger.Errorf("error exporting withdrawal proofs: %v", err)
+			}
+		}
 		return nil
 	}
 
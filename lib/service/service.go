@@ -28,11 +28,15 @@ type LndhubService struct {
 	Config         *Config
 	DB             *bun.DB
 	TapdClient     tapd.TapdClientWrapper
+	// taproot assets over lightning
+	AssetChannelClient tapd.AssetChannelClient
 	LndClient      lnd.LightningClientWrapper
 	RabbitMQClient rabbitmq.Client
 	Logger         *lecho.Logger
 	InvoicePubSub  *Pubsub
 	TaprootAssetPubSub *TapdPubsub
+	// fiat prices, nil if no price oracle is configured
+	PriceOracle PriceOracle
 }
 
 func (svc *LndhubService) ParseInt(value interface{}) (int64, error) {
@@ -99,17 +103,16 @@ func (svc *LndhubService) CheckEvent(payload nostr.Event) (bool, nostr.Event, er
 		if data[1] == "" {
 			return false, payload, errors.New("Field 'Asset ID' must have a value")
 		}
-		// validate amt
-		amt, err := strconv.ParseUint(data[2], 10, 64)
-		if err != nil || amt == 0 {
-			return false, payload, errors.New("Field 'amt' must be a valid number and non-zero")
+		// validate amt, a decimal string that is converted with the asset's decimal display
+		if _, err := ParseAssetAmount(data[2], MaxDecimalDisplay); err != nil {
+			return false, payload, errors.New("Field 'amt' must be a valid decimal number and non-zero")
 		}
 
 		return true, payload, nil
 
 	case "TAHUB_SEND_ASSET":
-		// this action must have three parts to the content
-		if len(data) != 2 {
+		// this action must have two parts to the content, optionally followed by the withdraw flag
+		if len(data) != 2 && !(len(data) == 3 && data[2] == "withdraw") {
 			return false, payload, errors.New("Invalid 'Content' for TAHUB_SEND_ASSET.")
 		}
 		// Validate specific fields for TAHUB_SEND_ASSET event
@@ -120,6 +123,140 @@ func (svc *LndhubService) CheckEvent(payload nostr.Event) (bool, nostr.Event, er
 
 		return true, payload, nil
 
+	case "TAHUB_GET_HISTORY":
+		// TAHUB_GET_HISTORY[:key=value...] e.g. TAHUB_GET_HISTORY:asset_id=btc:limit=20:cursor=120
+		params, err := ParseHistoryCommandParams(data[1:])
+		if err != nil {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_GET_HISTORY.")
+		}
+		if _, err = ParseTransactionHistoryFilter(params); err != nil {
+			return false, payload, errors.New("Invalid filter for TAHUB_GET_HISTORY.")
+		}
+		return true, payload, nil
+	case "TAHUB_GET_LIABILITY_PROOF":
+		// TAHUB_GET_LIABILITY_PROOF:<asset_id>
+		if len(data) != 2 || data[1] == "" {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_GET_LIABILITY_PROOF.")
+		}
+		return true, payload, nil
+	case "TAHUB_GET_RECEIVES":
+		return true, payload, nil
+	case "TAHUB_CREATE_ASSET_INVOICE":
+		// TAHUB_CREATE_ASSET_INVOICE:<asset_id>:<amt>[:memo]
+		if len(data) < 3 || data[1] == "" {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_CREATE_ASSET_INVOICE.")
+		}
+		// validate amt, a decimal string that is converted with the asset's decimal display
+		if _, err := ParseAssetAmount(data[2], MaxDecimalDisplay); err != nil {
+			return false, payload, errors.New("Field 'amt' must be a valid decimal number and non-zero")
+		}
+		return true, payload, nil
+	case "TAHUB_PAY_ASSET_INVOICE":
+		// TAHUB_PAY_ASSET_INVOICE:<asset_id>:<bolt11>
+		if len(data) != 3 || data[1] == "" || data[2] == "" {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_PAY_ASSET_INVOICE.")
+		}
+		return true, payload, nil
+	case "TAHUB_SWAP_QUOTE":
+		// TAHUB_SWAP_QUOTE:<from_asset_id>:<to_asset_id>:<amt>
+		if len(data) != 4 || data[1] == "" || data[2] == "" {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_SWAP_QUOTE.")
+		}
+		if _, err := ParseAssetAmount(data[3], MaxDecimalDisplay); err != nil {
+			return false, payload, errors.New("Field 'amt' must be a valid decimal number and non-zero")
+		}
+		return true, payload, nil
+	case "TAHUB_SWAP_EXECUTE":
+		// TAHUB_SWAP_EXECUTE:<quote_id>
+		if len(data) != 2 {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_SWAP_EXECUTE.")
+		}
+		if quoteId, err := strconv.ParseInt(data[1], 10, 64); err != nil || quoteId <= 0 {
+			return false, payload, errors.New("Field 'quote_id' must be a valid number")
+		}
+		return true, payload, nil
+	case "TAHUB_PLACE_ORDER":
+		// TAHUB_PLACE_ORDER:<buy|sell>:<base_asset_id>:<quote_asset_id>:<amt>:<price>
+		if len(data) != 6 || data[2] == "" || data[3] == "" {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_PLACE_ORDER.")
+		}
+		if data[1] != models.OrderSideBuy && data[1] != models.OrderSideSell {
+			return false, payload, errors.New("Field 'side' must be buy or sell")
+		}
+		if _, err := ParseAssetAmount(data[4], MaxDecimalDisplay); err != nil {
+			return false, payload, errors.New("Field 'amt' must be a valid decimal number and non-zero")
+		}
+		if price, err := parsePrice(data[5]); err != nil || price.Sign() == 0 {
+			return false, payload, errors.New("Field 'price' must be a valid decimal number and non-zero")
+		}
+		return true, payload, nil
+	case "TAHUB_CANCEL_ORDER":
+		// TAHUB_CANCEL_ORDER:<order_id>
+		if len(data) != 2 {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_CANCEL_ORDER.")
+		}
+		if orderId, err := strconv.ParseInt(data[1], 10, 64); err != nil || orderId <= 0 {
+			return false, payload, errors.New("Field 'order_id' must be a valid number")
+		}
+		return true, payload, nil
+	case "TAHUB_GET_ORDERS":
+		// TAHUB_GET_ORDERS[:<state>]
+		if len(data) > 2 {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_GET_ORDERS.")
+		}
+		return true, payload, nil
+	case "TAHUB_SEND_TO_PUBKEY":
+		// TAHUB_SEND_TO_PUBKEY:<npub>:<asset_id>:<amt>[:<memo>]
+		if len(data) < 4 || data[1] == "" || data[2] == "" {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_SEND_TO_PUBKEY.")
+		}
+		if _, err := ParseRecipientPubkey(data[1]); err != nil {
+			return false, payload, errors.New("Field 'npub' must be a valid npub or hex pubkey")
+		}
+		if _, err := ParseAssetAmount(data[3], MaxDecimalDisplay); err != nil {
+			return false, payload, errors.New("Field 'amt' must be a valid decimal number and non-zero")
+		}
+		return true, payload, nil
+	case "TAHUB_REQUEST_PAYMENT":
+		// TAHUB_REQUEST_PAYMENT:<npub>:<asset_id>:<amt>[:<memo>]
+		if len(data) < 4 || data[1] == "" || data[2] == "" {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_REQUEST_PAYMENT.")
+		}
+		if _, err := ParseRecipientPubkey(data[1]); err != nil {
+			return false, payload, errors.New("Field 'npub' must be a valid npub or hex pubkey")
+		}
+		if _, err := ParseAssetAmount(data[3], MaxDecimalDisplay); err != nil {
+			return false, payload, errors.New("Field 'amt' must be a valid decimal number and non-zero")
+		}
+		return true, payload, nil
+	case "TAHUB_PAY_REQUEST", "TAHUB_CANCEL_PAYMENT_REQUEST", "TAHUB_GET_PAYMENT_REQUEST":
+		// TAHUB_PAY_REQUEST:<request_id>, same for cancelling and fetching a request
+		if len(data) != 2 {
+			return false, payload, fmt.Errorf("Invalid 'Content' for %s.", data[0])
+		}
+		if requestId, err := strconv.ParseInt(data[1], 10, 64); err != nil || requestId <= 0 {
+			return false, payload, errors.New("Field 'request_id' must be a valid number")
+		}
+		return true, payload, nil
+	case "TAHUB_GET_PAYMENT_REQUESTS":
+		// TAHUB_GET_PAYMENT_REQUESTS[:<state>]
+		if len(data) > 2 {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_GET_PAYMENT_REQUESTS.")
+		}
+		return true, payload, nil
+	case "TAHUB_SET_USERNAME":
+		// TAHUB_SET_USERNAME:<name>, an empty name releases the current one
+		if len(data) != 2 {
+			return false, payload, errors.New("Invalid 'Content' for TAHUB_SET_USERNAME.")
+		}
+		return true, payload, nil
+	case "TAHUB_GET_ORDER_BOOK", "TAHUB_GET_TRADES":
+		// TAHUB_GET_ORDER_BOOK:<base_asset_id>:<quote_asset_id>, same for TAHUB_GET_TRADES
+		if len(data) != 3 || data[1] == "" || data[2] == "" {
+			return false, payload, fmt.Errorf("Invalid 'Content' for %s.", data[0])
+		}
+		return true, payload, nil
+
 	default:
 		return false, payload, errors.New("Undefined 'Content' Name")
 	}
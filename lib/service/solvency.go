@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getsentry/sentry-go"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	solvencyLiabilitiesGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tahub_solvency_liabilities",
+		Help: "Sum of all users' current balances per asset",
+	}, []string{"asset_id", "asset_name"})
+	solvencyHoldingsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tahub_solvency_holdings",
+		Help: "Balance held on tapd (assets) or lnd wallet and channels (btc) per asset",
+	}, []string{"asset_id", "asset_name"})
+	solvencySurplusGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tahub_solvency_surplus",
+		Help: "Holdings minus liabilities per asset, negative values mean the hub is insolvent for the asset",
+	}, []string{"asset_id", "asset_name"})
+	solvencySolventGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "tahub_solvency_solvent",
+		Help: "1 if holdings cover liabilities for every asset, 0 otherwise",
+	})
+)
+
+// AssetSolvency compares what the hub owes its users for one asset with what it actually holds
+type AssetSolvency struct {
+	TaAssetID   string `json:"asset_id"`
+	AssetName   string `json:"asset_name"`
+	Liabilities int64  `json:"liabilities"`
+	// tapd on-chain balance, zero for btc
+	TapdBalance int64 `json:"tapd_balance"`
+	// lnd on-chain and local channel balance, only set for btc
+	LndWalletBalance  int64 `json:"lnd_wallet_balance"`
+	LndChannelBalance int64 `json:"lnd_channel_balance"`
+	Holdings          int64 `json:"holdings"`
+	Surplus           int64 `json:"surplus"`
+	Solvent           bool  `json:"solvent"`
+}
+
+type SolvencyReport struct {
+	Assets    []AssetSolvency `json:"assets"`
+	Solvent   bool            `json:"solvent"`
+	CheckedAt time.Time       `json:"checked_at"`
+}
+
+// GetSolvencyReport sums the users' current balances per asset and compares them against
+// tapd ListBalances for taproot assets and the lnd wallet and channel balances for btc
+func (svc *LndhubService) GetSolvencyReport(ctx context.Context) (*SolvencyReport, error) {
+	sheets, err := svc.GetBalanceSheet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tapdBalances, err := svc.tapdBalancesByAsset(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tapd balances: %w", err)
+	}
+	walletBalance, err := svc.LndClient.WalletBalance(ctx, &lnrpc.WalletBalanceRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch lnd wallet balance: %w", err)
+	}
+	channelBalance, err := svc.LndClient.ChannelBalance(ctx, &lnrpc.ChannelBalanceRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch lnd channel balance: %w", err)
+	}
+	report := &SolvencyReport{Solvent: true, CheckedAt: time.Now()}
+	for _, sheet := range sheets {
+		// escrowed transfers are still owed to users
+		assetSolvency := AssetSolvency{
+			TaAssetID:   sheet.TaAssetID,
+			AssetName:   sheet.AssetName,
+			Liabilities: sheet.UserLiabilities + sheet.Escrow,
+		}
+		if sheet.TaAssetID == common.BTC_TA_ASSET_ID {
+			assetSolvency.LndWalletBalance = walletBalance.TotalBalance
+			assetSolvency.LndChannelBalance = int64(channelBalance.GetLocalBalance().GetSat())
+		} else {
+			assetSolvency.TapdBalance = tapdBalances[sheet.TaAssetID]
+		}
+		assetSolvency.computeSurplus()
+		if !assetSolvency.Solvent {
+			report.Solvent = false
+		}
+		report.Assets = append(report.Assets, assetSolvency)
+	}
+	return report, nil
+}
+
+func (assetSolvency *AssetSolvency) computeSurplus() {
+	assetSolvency.Holdings = assetSolvency.TapdBalance + assetSolvency.LndWalletBalance + assetSolvency.LndChannelBalance
+	assetSolvency.Surplus = assetSolvency.Holdings - assetSolvency.Liabilities
+	assetSolvency.Solvent = assetSolvency.Surplus >= 0
+}
+
+// tapdBalancesByAsset returns the tapd balances keyed by hex encoded asset id
+func (svc *LndhubService) tapdBalancesByAsset(ctx context.Context) (map[string]int64, error) {
+	filter := taprpc.ListBalancesRequest_AssetId{AssetId: true}
+	balances, err := svc.TapdClient.ListBalances(ctx, &taprpc.ListBalancesRequest{GroupBy: &filter})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]int64)
+	for _, balance := range balances.AssetBalances {
+		if balance.AssetGenesis == nil {
+			continue
+		}
+		result[hex.EncodeToString(balance.AssetGenesis.AssetId)] += int64(balance.Balance)
+	}
+	return result, nil
+}
+
+// CheckSolvency runs the solvency report, updates the prometheus gauges and alerts when
+// the liabilities for any asset exceed the holdings
+func (svc *LndhubService) CheckSolvency(ctx context.Context) (*SolvencyReport, error) {
+	report, err := svc.GetSolvencyReport(ctx)
+	if err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("Solvency check failed: %v", err)
+		return nil, err
+	}
+	for _, asset := range report.Assets {
+		solvencyLiabilitiesGauge.WithLabelValues(asset.TaAssetID, asset.AssetName).Set(float64(asset.Liabilities))
+		solvencyHoldingsGauge.WithLabelValues(asset.TaAssetID, asset.AssetName).Set(float64(asset.Holdings))
+		solvencySurplusGauge.WithLabelValues(asset.TaAssetID, asset.AssetName).Set(float64(asset.Surplus))
+		if !asset.Solvent {
+			msg := fmt.Sprintf("Liabilities exceed holdings for asset %s (%s): liabilities %d holdings %d", asset.TaAssetID, asset.AssetName, asset.Liabilities, asset.Holdings)
+			sentry.CaptureMessage(msg)
+			svc.Logger.Error(msg)
+		}
+	}
+	if report.Solvent {
+		solvencySolventGauge.Set(1)
+	} else {
+		solvencySolventGauge.Set(0)
+	}
+	return report, nil
+}
+
+func (svc *LndhubService) StartSolvencyRoutine(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(svc.Config.SolvencyCheckInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			_, _ = svc.CheckSolvency(checkCtx)
+			cancel()
+		}
+	}
+}
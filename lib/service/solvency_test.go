@@ -0,0 +1,20 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetSolvencyComputeSurplus(t *testing.T) {
+	btc := AssetSolvency{TaAssetID: "btc", Liabilities: 1500, LndWalletBalance: 1000, LndChannelBalance: 600}
+	btc.computeSurplus()
+	assert.Equal(t, int64(1600), btc.Holdings)
+	assert.Equal(t, int64(100), btc.Surplus)
+	assert.True(t, btc.Solvent)
+
+	asset := AssetSolvency{TaAssetID: "abcd", Liabilities: 1500, TapdBalance: 1000}
+	asset.computeSurplus()
+	assert.Equal(t, int64(-500), asset.Surplus)
+	assert.False(t, asset.Solvent)
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/uptrace/bun"
+)
+
+var (
+	SwapsUnavailableError       = errors.New("swaps require a price oracle")
+	SameSwapAssetError          = errors.New("cannot swap an asset for itself")
+	SwapPriceUnavailableError   = errors.New("no recent price for the asset")
+	SwapAmountTooSmallError     = errors.New("swap amount is too small for a single unit of the target asset")
+	SwapQuoteExpiredError       = errors.New("swap quote has expired")
+	SwapQuoteExecutedError      = errors.New("swap quote has already been executed")
+	SwapQuoteNotFoundError      = errors.New("swap quote not found")
+	InsufficientBalanceError    = errors.New("insufficient balance")
+	InsufficientLiquidityError  = errors.New("insufficient hub liquidity for the swap")
+	InvalidLiquidityAmountError = errors.New("liquidity amount must be non-zero")
+)
+
+// SwapAmount converts units of one asset to units of another at their fiat prices per display
+// unit, minus the spread in basis points. The result is rounded down in the hub's favour.
+func SwapAmount(fromUnits int64, fromDecimals uint32, fromPrice string, toDecimals uint32, toPrice string, spreadBps int64) (int64, error) {
+	from, err := parsePrice(fromPrice)
+	if err != nil {
+		return 0, err
+	}
+	to, err := parsePrice(toPrice)
+	if err != nil {
+		return 0, err
+	}
+	if from.Sign() == 0 || to.Sign() == 0 {
+		return 0, SwapPriceUnavailableError
+	}
+	fromScale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(fromDecimals)), nil)
+	toScale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(toDecimals)), nil)
+	// fiat value of the from amount, then converted into units of the target asset
+	amount := new(big.Rat).SetFrac(big.NewInt(fromUnits), fromScale)
+	amount.Mul(amount, from)
+	amount.Quo(amount, to)
+	amount.Mul(amount, new(big.Rat).SetInt(toScale))
+	amount.Mul(amount, big.NewRat(10000-spreadBps, 10000))
+	units := new(big.Int).Quo(amount.Num(), amount.Denom())
+	if !units.IsInt64() {
+		return 0, UnrepresentableAssetAmountError
+	}
+	if units.Sign() <= 0 {
+		return 0, SwapAmountTooSmallError
+	}
+	return units.Int64(), nil
+}
+
+// swapPrice is the latest price of an asset, as long as it is not older than SWAP_MAX_PRICE_AGE
+func (svc *LndhubService) swapPrice(prices map[string]models.AssetPrice, assetId string) (models.AssetPrice, error) {
+	price, ok := prices[assetId]
+	if !ok {
+		return price, fmt.Errorf("%w: %s", SwapPriceUnavailableError, assetId)
+	}
+	maxAge := time.Duration(svc.Config.SwapMaxPriceAge) * time.Second
+	if maxAge > 0 && time.Since(price.CreatedAt) > maxAge {
+		return price, fmt.Errorf("%w: %s", SwapPriceUnavailableError, assetId)
+	}
+	return price, nil
+}
+
+// SwapQuoteFor quotes a swap of a decimal amount of one asset into another at the latest oracle
+// prices and the configured spread. The quote can be executed until it expires.
+func (svc *LndhubService) SwapQuoteFor(ctx context.Context, userId int64, fromAssetId string, toAssetId string, amount string) (*models.SwapQuote, error) {
+	if svc.PriceOracle == nil {
+		return nil, SwapsUnavailableError
+	}
+	if fromAssetId == toAssetId {
+		return nil, SameSwapAssetError
+	}
+	fromUnits, err := svc.ParseAssetAmountFor(ctx, fromAssetId, amount)
+	if err != nil {
+		return nil, err
+	}
+	if fromUnits > math.MaxInt64 {
+		return nil, UnrepresentableAssetAmountError
+	}
+	prices, err := svc.LatestPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fromPrice, err := svc.swapPrice(prices, fromAssetId)
+	if err != nil {
+		return nil, err
+	}
+	toPrice, err := svc.swapPrice(prices, toAssetId)
+	if err != nil {
+		return nil, err
+	}
+	fromDecimals, err := svc.AssetDecimalDisplay(ctx, fromAssetId)
+	if err != nil {
+		return nil, err
+	}
+	toDecimals, err := svc.AssetDecimalDisplay(ctx, toAssetId)
+	if err != nil {
+		return nil, err
+	}
+	toUnits, err := SwapAmount(int64(fromUnits), fromDecimals, fromPrice.Price, toDecimals, toPrice.Price, svc.Config.SwapSpreadBps)
+	if err != nil {
+		return nil, err
+	}
+	quote := &models.SwapQuote{
+		UserID:      userId,
+		FromAssetID: fromAssetId,
+		ToAssetID:   toAssetId,
+		FromAmount:  int64(fromUnits),
+		ToAmount:    toUnits,
+		FromPrice:   fromPrice.Price,
+		ToPrice:     toPrice.Price,
+		Currency:    svc.Config.PriceCurrency,
+		SpreadBps:   svc.Config.SwapSpreadBps,
+		State:       models.SwapQuoteStateQuoted,
+		ExpiresAt:   time.Now().Add(time.Duration(svc.Config.SwapQuoteTTL) * time.Second),
+	}
+	if _, err := svc.DB.NewInsert().Model(quote).Exec(ctx); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// ExecuteSwap settles a quote of the user. The user's from amount is moved to the hub liquidity
+// account and the to amount from the liquidity account to the user in one DB transaction, so the
+// check_balance trigger rejects the whole swap if either side cannot cover it.
+func (svc *LndhubService) ExecuteSwap(ctx context.Context, userId int64, quoteId int64) (*models.SwapQuote, error) {
+	quote := &models.SwapQuote{}
+	err := svc.DB.NewSelect().Model(quote).Where("id = ? AND user_id = ?", quoteId, userId).Limit(1).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, SwapQuoteNotFoundError
+		}
+		return nil, err
+	}
+	// the user may not have received the target asset before
+	if err := svc.EnsureUserAssetAccounts(ctx, userId, quote.ToAssetID); err != nil {
+		return nil, err
+	}
+	err = svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewSelect().Model(quote).Where("id = ?", quote.ID).For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+		if quote.State != models.SwapQuoteStateQuoted {
+			return SwapQuoteExecutedError
+		}
+		if time.Now().After(quote.ExpiresAt) {
+			return SwapQuoteExpiredError
+		}
+		userFrom, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, quote.FromAssetID, userId)
+		if errors.Is(err, sql.ErrNoRows) {
+			// never held the asset
+			return InsufficientBalanceError
+		}
+		if err != nil {
+			return fmt.Errorf("could not find current account for user_id:%d asset:%s: %w", userId, quote.FromAssetID, err)
+		}
+		userTo, err := svc.AccountForInTx(ctx, tx, common.AccountTypeCurrent, quote.ToAssetID, userId)
+		if err != nil {
+			return fmt.Errorf("could not find current account for user_id:%d asset:%s: %w", userId, quote.ToAssetID, err)
+		}
+		liquidityFrom, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeLiquidity, quote.FromAssetID)
+		if err != nil {
+			return err
+		}
+		liquidityTo, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeLiquidity, quote.ToAssetID)
+		if err != nil {
+			return err
+		}
+		// the trigger would reject these as well, checking first tells the user which side is short
+		userBalance, err := accountBalanceInTx(ctx, tx, userFrom.ID)
+		if err != nil {
+			return err
+		}
+		if userBalance < quote.FromAmount {
+			return InsufficientBalanceError
+		}
+		liquidityBalance, err := accountBalanceInTx(ctx, tx, liquidityTo.ID)
+		if err != nil {
+			return err
+		}
+		if liquidityBalance < quote.ToAmount {
+			return InsufficientLiquidityError
+		}
+		now := time.Now()
+		entry := models.TransactionEntry{
+			UserID:          userId,
+			TaAssetID:       quote.FromAssetID,
+			DebitAccountID:  userFrom.ID,
+			CreditAccountID: liquidityFrom.ID,
+			Amount:          quote.FromAmount,
+			EntryType:       models.EntryTypeSwap,
+			CreatedAt:       now,
+		}
+		if _, err := tx.NewInsert().Model(&entry).Exec(ctx); err != nil {
+			return err
+		}
+		counterEntry := models.TransactionEntry{
+			UserID:          userId,
+			ParentID:        entry.ID,
+			TaAssetID:       quote.ToAssetID,
+			DebitAccountID:  liquidityTo.ID,
+			CreditAccountID: userTo.ID,
+			Amount:          quote.ToAmount,
+			EntryType:       models.EntryTypeSwap,
+			CreatedAt:       now,
+		}
+		if _, err := tx.NewInsert().Model(&counterEntry).Exec(ctx); err != nil {
+			return err
+		}
+		quote.State = models.SwapQuoteStateExecuted
+		quote.TransactionEntryID = entry.ID
+		quote.ExecutedAt = now
+		_, err = tx.NewUpdate().Model(quote).Column("state", "transaction_entry_id", "executed_at").WherePK().Exec(ctx)
+		return err
+	})
+	if err != nil {
+		svc.Logger.Errorf("Could not execute swap quote:%d user_id:%v error %v", quoteId, userId, err)
+		return nil, err
+	}
+	svc.Logger.Infof("Executed swap quote:%d user_id:%v %d %s for %d %s", quote.ID, userId, quote.FromAmount, quote.FromAssetID, quote.ToAmount, quote.ToAssetID)
+	return quote, nil
+}
+
+// FundSwapLiquidity moves hub owned units between the treasury and the liquidity account of an
+// asset, positive amounts add liquidity and negative amounts withdraw it again
+func (svc *LndhubService) FundSwapLiquidity(ctx context.Context, assetId string, amount int64) (*models.TransactionEntry, error) {
+	if amount == 0 {
+		return nil, InvalidLiquidityAmountError
+	}
+	entry := &models.TransactionEntry{
+		TaAssetID: assetId,
+		EntryType: models.EntryTypeLiquidity,
+		CreatedAt: time.Now(),
+	}
+	err := svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		treasury, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeTreasury, assetId)
+		if err != nil {
+			return err
+		}
+		liquidity, err := svc.SystemAccountForInTx(ctx, tx, common.AccountTypeLiquidity, assetId)
+		if err != nil {
+			return err
+		}
+		if amount > 0 {
+			entry.DebitAccountID = treasury.ID
+			entry.CreditAccountID = liquidity.ID
+			entry.Amount = amount
+		} else {
+			entry.DebitAccountID = liquidity.ID
+			entry.CreditAccountID = treasury.ID
+			entry.Amount = -amount
+		}
+		_, err = tx.NewInsert().Model(entry).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		sentry.CaptureException(err)
+		svc.Logger.Errorf("Could not fund swap liquidity asset:%s amount:%d error %v", assetId, amount, err)
+		return nil, err
+	}
+	svc.Logger.Infof("Funded swap liquidity asset:%s amount:%d", assetId, amount)
+	return entry, nil
+}
+
+// SwapLiquidity returns the balance of the liquidity account of every asset keyed by ta_asset_id
+func (svc *LndhubService) SwapLiquidity(ctx context.Context) (map[string]int64, error) {
+	rows := []struct {
+		TaAssetID string
+		Balance   int64
+	}{}
+	err := svc.DB.NewSelect().
+		TableExpr("accounts").
+		ColumnExpr("accounts.ta_asset_id, coalesce(sum(account_ledgers.amount), 0) AS balance").
+		Join("LEFT JOIN account_ledgers ON account_ledgers.account_id = accounts.id").
+		Where("accounts.type = ? AND accounts.user_id IS NULL", common.AccountTypeLiquidity).
+		GroupExpr("accounts.ta_asset_id").
+		Scan(ctx, &rows)
+	if err != nil {
+		return nil, err
+	}
+	liquidity := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		liquidity[row.TaAssetID] = row.Balance
+	}
+	return liquidity, nil
+}
+
+// IsSwapUserError tells the errors of a failed swap the user can act on apart from internal errors,
+// whose messages are not shown to the user
+func IsSwapUserError(err error) bool {
+	for _, userErr := range []error{
+		SwapsUnavailableError,
+		SameSwapAssetError,
+		SwapPriceUnavailableError,
+		SwapAmountTooSmallError,
+		SwapQuoteExpiredError,
+		SwapQuoteExecutedError,
+		SwapQuoteNotFoundError,
+		InsufficientBalanceError,
+		InsufficientLiquidityError,
+		InvalidAssetAmountError,
+		UnrepresentableAssetAmountError,
+	} {
+		if errors.Is(err, userErr) {
+			return true
+		}
+	}
+	return false
+}
+
+func accountBalanceInTx(ctx context.Context, tx bun.Tx, accountId int64) (int64, error) {
+	var balance int64
+	err := tx.NewSelect().Table("account_ledgers").ColumnExpr("coalesce(sum(account_ledgers.amount), 0) as balance").Where("account_ledgers.account_id = ?", accountId).Scan(ctx, &balance)
+	return balance, err
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSwapAmount(t *testing.T) {
+	// 40 units at 0.50 are 20.00, 20000 sats at 0.001 minus a 1% spread
+	units, err := SwapAmount(40, 0, "0.50", 0, "0.001", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(19800), units)
+
+	// decimal displays on both sides, 1.5 of an asset with 6 decimals at 2.00 is 3.00
+	units, err = SwapAmount(1500000, 6, "2.00", 2, "1", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(300), units)
+
+	// rounded down in the hub's favour
+	units, err = SwapAmount(1, 0, "1", 0, "3", 0)
+	assert.ErrorIs(t, err, SwapAmountTooSmallError)
+	assert.Equal(t, int64(0), units)
+	units, err = SwapAmount(10, 0, "1", 0, "3", 50)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), units)
+
+	_, err = SwapAmount(10, 0, "0", 0, "1", 0)
+	assert.ErrorIs(t, err, SwapPriceUnavailableError)
+	_, err = SwapAmount(10, 0, "1", 0, "-1", 0)
+	assert.ErrorIs(t, err, InvalidPriceError)
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/uptrace/bun"
+)
+
+// SystemAccountForInTx returns the hub owned account of the given type for an asset, creating it on first use.
+// System accounts have no user_id and are unique on (ta_asset_id, type).
+func (svc *LndhubService) SystemAccountForInTx(ctx context.Context, tx bun.Tx, accountType string, assetId string) (models.Account, error) {
+	account := models.Account{}
+	_, err := tx.NewInsert().
+		Model(&models.Account{Type: accountType, TaAssetID: assetId}).
+		On("CONFLICT (ta_asset_id, type) WHERE user_id IS NULL DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return account, err
+	}
+	err = tx.NewSelect().Model(&account).Where("user_id IS NULL AND account.ta_asset_id = ? AND type = ?", assetId, accountType).Relation("Asset").Limit(1).Scan(ctx)
+	return account, err
+}
+
+func (svc *LndhubService) SystemAccountFor(ctx context.Context, accountType string, assetId string) (models.Account, error) {
+	account := models.Account{}
+	err := svc.DB.NewSelect().Model(&account).Where("user_id IS NULL AND account.ta_asset_id = ? AND type = ?", assetId, accountType).Relation("Asset").Limit(1).Scan(ctx)
+	return account, err
+}
+
+// EnsureSystemAccounts creates the hub owned treasury, fee revenue, chain fee, suspense, adjustments, burned, liquidity and escrow accounts for an asset.
+func (svc *LndhubService) EnsureSystemAccounts(ctx context.Context, assetId string) error {
+	return svc.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, accountType := range common.SystemAccountTypes {
+			if _, err := svc.SystemAccountForInTx(ctx, tx, accountType, assetId); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AssetBalanceSheet : per asset view on the ledger, all balances are sums over account_ledgers.
+// Credits are positive, so user liabilities are positive and the treasury (the contra account for
+// assets the hub holds on behalf of users) is negative.
+type AssetBalanceSheet struct {
+	TaAssetID string `json:"asset_id"`
+	AssetName string `json:"asset_name"`
+	// sum of all users' current and reserved accounts, what the hub owes its users
+	UserLiabilities int64 `json:"user_liabilities"`
+	// sum of users' incoming, outgoing and fees accounts. legacy entries booked the
+	// counterparty of receives and sends on these per-user accounts instead of the treasury.
+	UserClearing int64 `json:"user_clearing"`
+	Treasury     int64 `json:"treasury"`
+	FeeRevenue   int64 `json:"fee_revenue"`
+	ChainFees    int64 `json:"chain_fees"`
+	Suspense     int64 `json:"suspense"`
+	Adjustments  int64 `json:"adjustments"`
+	// units destroyed with tapd burns, no longer held by the hub
+	Burned int64 `json:"burned"`
+	// hub owned units available to swaps
+	Liquidity int64 `json:"liquidity"`
+	// units sent to pubkeys that have not registered yet, owed to the recipient or back to the sender
+	Escrow int64 `json:"escrow"`
+	// what the hub should be holding on tapd / lnd according to the ledger
+	ExpectedHoldings int64 `json:"expected_holdings"`
+}
+
+type accountTypeBalance struct {
+	TaAssetID string
+	Type      string
+	System    bool
+	Balance   int64
+}
+
+func (svc *LndhubService) GetBalanceSheet(ctx context.Context) ([]AssetBalanceSheet, error) {
+	rows := []accountTypeBalance{}
+	err := svc.DB.NewSelect().
+		TableExpr("account_ledgers").
+		Join("JOIN accounts ON accounts.id = account_ledgers.account_id").
+		ColumnExpr("accounts.ta_asset_id, accounts.type, accounts.user_id IS NULL AS system, sum(account_ledgers.amount) AS balance").
+		GroupExpr("accounts.ta_asset_id, accounts.type, accounts.user_id IS NULL").
+		Scan(ctx, &rows)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := svc.GetAssets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sheets := make(map[string]*AssetBalanceSheet)
+	result := []AssetBalanceSheet{}
+	for _, asset := range assets {
+		sheets[asset.TaAssetID] = &AssetBalanceSheet{TaAssetID: asset.TaAssetID, AssetName: asset.AssetName}
+	}
+	for _, row := range rows {
+		sheet, ok := sheets[row.TaAssetID]
+		if !ok {
+			continue
+		}
+		sheet.addBalance(row)
+	}
+	for _, asset := range assets {
+		sheet := sheets[asset.TaAssetID]
+		sheet.computeExpectedHoldings()
+		result = append(result, *sheet)
+	}
+	return result, nil
+}
+
+// computeExpectedHoldings : the treasury is the contra account of everything received, burned units left the hub
+func (sheet *AssetBalanceSheet) computeExpectedHoldings() {
+	sheet.ExpectedHoldings = -(sheet.Treasury + sheet.UserClearing + sheet.Burned)
+}
+
+func (sheet *AssetBalanceSheet) addBalance(row accountTypeBalance) {
+	if !row.System {
+		if row.Type == common.AccountTypeCurrent || row.Type == common.AccountTypeReserved {
+			sheet.UserLiabilities += row.Balance
+		} else {
+			sheet.UserClearing += row.Balance
+		}
+		return
+	}
+	switch row.Type {
+	case common.AccountTypeTreasury:
+		sheet.Treasury += row.Balance
+	case common.AccountTypeFeeRevenue:
+		sheet.FeeRevenue += row.Balance
+	case common.AccountTypeChainFees:
+		sheet.ChainFees += row.Balance
+	case common.AccountTypeSuspense:
+		sheet.Suspense += row.Balance
+	case common.AccountTypeAdjustments:
+		sheet.Adjustments += row.Balance
+	case common.AccountTypeBurned:
+		sheet.Burned += row.Balance
+	case common.AccountTypeLiquidity:
+		sheet.Liquidity += row.Balance
+	case common.AccountTypeEscrow:
+		sheet.Escrow += row.Balance
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/getAlby/lndhub.go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetBalanceSheetAddBalance(t *testing.T) {
+	sheet := AssetBalanceSheet{TaAssetID: "btc"}
+	// 1000 received on tapd, 100 of it already sent out by a user, 10 parked in suspense
+	sheet.addBalance(accountTypeBalance{TaAssetID: "btc", Type: common.AccountTypeCurrent, Balance: 900})
+	sheet.addBalance(accountTypeBalance{TaAssetID: "btc", Type: common.AccountTypeOutgoing, Balance: 100})
+	sheet.addBalance(accountTypeBalance{TaAssetID: "btc", Type: common.AccountTypeTreasury, System: true, Balance: -1010})
+	sheet.addBalance(accountTypeBalance{TaAssetID: "btc", Type: common.AccountTypeSuspense, System: true, Balance: 10})
+	assert.Equal(t, int64(900), sheet.UserLiabilities)
+	assert.Equal(t, int64(100), sheet.UserClearing)
+	assert.Equal(t, int64(-1010), sheet.Treasury)
+	assert.Equal(t, int64(10), sheet.Suspense)
+}
+
+func TestAssetBalanceSheetBurned(t *testing.T) {
+	sheet := AssetBalanceSheet{TaAssetID: "abcd"}
+	// 1000 received on tapd, the user burned 300 of it
+	sheet.addBalance(accountTypeBalance{TaAssetID: "abcd", Type: common.AccountTypeCurrent, Balance: 700})
+	sheet.addBalance(accountTypeBalance{TaAssetID: "abcd", Type: common.AccountTypeTreasury, System: true, Balance: -1000})
+	sheet.addBalance(accountTypeBalance{TaAssetID: "abcd", Type: common.AccountTypeBurned, System: true, Balance: 300})
+	assert.Equal(t, int64(300), sheet.Burned)
+	sheet.computeExpectedHoldings()
+	assert.Equal(t, int64(700), sheet.ExpectedHoldings)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/getAlby/lndhub.go/common"
 	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
 	"github.com/lightninglabs/taproot-assets/taprpc"
 )
 
@@ -59,62 +60,39 @@ func (svc *LndhubService) GetAllCurrentBalances(ctx context.Context, userId int6
 	if err != nil {
 		return "error: failed to fetch balances.", err
 	}
+	decimals, err := svc.AssetDecimalDisplays(ctx)
+	if err != nil {
+		return "error: failed to fetch balances.", err
+	}
 	// build success msg
 	for asset, balance := range balances {
-		assetMsg := fmt.Sprintf("%s - %d,", asset, balance)
+		assetMsg := fmt.Sprintf("%s - %s,", asset, FormatAssetAmount(balance, decimals[asset]))
 		msg = msg + assetMsg
 	}
 	return msg, nil
 }
 
-func (svc*LndhubService) GetAllCurrentBalancesJson(ctx context.Context, userId int64) (map[string]int64, error) {
+// GetAllCurrentBalancesJson maps asset ids to the user's balance in units and, in amountMap, to
+// the balance as a decimal string in the asset's decimal display
+func (svc*LndhubService) GetAllCurrentBalancesJson(ctx context.Context, userId int64) (balanceMap map[string]int64, amountMap map[string]string, err error) {
 	// balance map
-	balanceMap := make(map[string]int64)
+	balanceMap = make(map[string]int64)
+	amountMap = make(map[string]string)
 	// get balance data
 	balances, err := svc.CurrentUserBalanceByAsset(ctx, userId)
 	if err != nil {
-		return balanceMap, err
+		return balanceMap, amountMap, err
+	}
+	decimals, err := svc.AssetDecimalDisplays(ctx)
+	if err != nil {
+		return balanceMap, amountMap, err
 	}
 	// build success msg
 	for asset, balance := range balances {
 		balanceMap[asset] = balance
+		amountMap[asset] = FormatAssetAmount(balance, decimals[asset])
 	}
-	return balanceMap, nil
-}
-
-func  (svc *LndhubService) BalanceByAsset(ctx context.Context) (okMsg string, success bool) {
-	/// * TODO this is a placeholder for now use account_ledger population on RecieveNotification Subscription
-	filter := taprpc.ListBalancesRequest_AssetId{AssetId: true}
-	req := taprpc.ListBalancesRequest{GroupBy: &filter}
-	balances, err := svc.TapdClient.ListBalances(ctx, &req)
-	if err != nil {
-		// TODO OK Relay-Compatible messages need a central location
-		return "error: failed to fetch balances.", false
-	}
-	aggBalances := make(map[string]uint64)
-	var okSuccessMsg = "balances: "
-	for _, balance := range balances.AssetBalances {
-
-		// seen a group of this asset already
-		bal, ok := aggBalances[balance.AssetGenesis.Name]
-		if ok {
-			aggBalances[balance.AssetGenesis.Name] = bal + balance.Balance
-		} else {
-			// add to map
-			name := balance.AssetGenesis.Name
-			aggBalances[name] = balance.Balance
-		}
-	}
-	// check for no len
-	if len(aggBalances) == 0 {
-		// TODO OK Relay-Compatible messages need a central location
-		return "balance: 0", false
-	}
-	// success message 
-	for asset, balance := range aggBalances {
-		okSuccessMsg = okSuccessMsg + fmt.Sprintf("%s %d,", asset, balance)
-	}
-	return okSuccessMsg, true
+	return balanceMap, amountMap, nil
 }
 
 func (svc *LndhubService) GetAddressByAssetId(ctx context.Context, assetId string, amt uint64) (okMsg string, success bool) {
@@ -136,7 +114,9 @@ func (svc *LndhubService) GetAddressByAssetId(ctx context.Context, assetId strin
 	return fmt.Sprintf("address: %s", newAddr.Encoded), true
 }
 
-func (svc *LndhubService) TransferAssets(ctx context.Context, userId uint64, addr string) (string, bool) {
+// TransferAssets sends to a taproot assets address, internally if the address belongs to a hub user.
+// In withdrawal mode the proof file of an external send is exported once the send completes.
+func (svc *LndhubService) TransferAssets(ctx context.Context, userId uint64, addr string, withdraw bool) (string, bool) {
 	// has funding flag
 	hasFunding := false
 	// decode addr
@@ -184,7 +164,7 @@ func (svc *LndhubService) TransferAssets(ctx context.Context, userId uint64, add
 			// no need to rollback
 			return "error: failed to find credit account for send", false
 		}
-		tx, err := svc.InsertTapdTransactionEntryInTx(ctx, dbTx, int64(userId), creditAccount, debitAccount, sendAmt)
+		tx, err := svc.InsertTapdTransactionEntryInTx(ctx, dbTx, int64(userId), creditAccount, debitAccount, sendAmt, addr)
 		if err != nil {
 			// rollback
 			dbTx.Rollback()
@@ -202,20 +182,21 @@ func (svc *LndhubService) TransferAssets(ctx context.Context, userId uint64, add
 		}
 		if err == nil && rcvAddr == nil {
 			/// * NOTE this is an external transfer
-			// send asset so tx is already in db for status updates
-			sendReq := taprpc.SendAssetRequest{
-				TapAddrs: []string{addr},
-			}
-			_, err = svc.TapdClient.SendAsset(ctx, &sendReq)
+			// queue the send so tx is already in db for status updates
+			item, err := svc.queueSendInTx(ctx, dbTx, &tx, decodedAddr.ScriptKey, withdraw)
 			if err != nil {
 				// rollback since we are returning early on error
 				dbTx.Rollback()
 				// TODO OK Relay-Compatible messages need a central location
-				return "error: failed to send asset.", false
+				return "error: failed to queue send.", false
+			}
+			err = dbTx.Commit()
+			if err != nil {
+				sentry.CaptureException(err)
+				svc.Logger.Errorf("Could not commit external send user_id:%v addr:%s: %v", userId, addr, err)
+				return "error: failed to create transaction entry.", false
 			}
-			// return success message
-			msg := fmt.Sprintf("success: sent %s", sendAssetId)
-			return msg, true
+			return svc.sendQueued(ctx, item)
 		} else {
 			/// * NOTE this is an internal transfer
 			rcvUser := rcvAddr.User
@@ -245,6 +226,9 @@ func (svc *LndhubService) TransferAssets(ctx context.Context, userId uint64, add
 				TaAssetID: sendAssetId,
 				Outpoint: models.TahubInternalOutpoint,
 				BroadcastState: models.TahubInternalComplete,
+				Address: addr,
+				// link to the sender's entry so both sides know their counterparty
+				ParentID: tx.ID,
 			}
 			// insert the tx entry
 			_, err = dbTx.NewInsert().Model(&entry).Exec(ctx)
@@ -307,6 +291,10 @@ func (svc *LndhubService) FetchOrCreateAssetAddr(ctx context.Context, userId uin
 		// attempt to match on amount
 		for _, addr := range addrs {
 			if addr.Amount == amt {
+				// checkout addresses only pay their checkout
+				if isCheckout, err := svc.isCheckoutAddress(ctx, addr.Addr); err != nil || isCheckout {
+					continue
+				}
 				// setting flag to prevent creation of any additional receiver resources. address exists for exact amount.
 				amtMatch = true
 				return addr.Addr, nil
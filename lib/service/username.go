@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/uptrace/bun"
+)
+
+var (
+	InvalidUsernameError       = errors.New("username must be 1 to 64 characters of a-z, 0-9, '-', '_' or '.'")
+	UsernameTakenError         = errors.New("username is already taken")
+	UsernameNotFoundError      = errors.New("username not found")
+	UsernameReservedError      = errors.New("username is reserved")
+	UsernameChangeTooSoonError = errors.New("username was changed too recently")
+	UsernameHoldNotFoundError  = errors.New("username is not held")
+)
+
+// Nip05RootName is the NIP-05 name of the domain itself, _@<domain> identifies the hub
+const Nip05RootName = "_"
+
+// reservedUsernames cannot be claimed by users, they would pass as the hub or its operators
+var reservedUsernames = []string{
+	Nip05RootName, "abuse", "admin", "administrator", "hostmaster", "hub", "help", "info", "mod",
+	"moderator", "no-reply", "noreply", "official", "postmaster", "root", "security", "staff",
+	"support", "system", "tahub", "webmaster",
+}
+
+// usernamePattern are the characters LUD-16 allows in the name part of a lightning address
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_.-]{1,64}$`)
+
+// pubkeyLikePattern matches names that could be mistaken for another user's key
+var pubkeyLikePattern = regexp.MustCompile(`^(npub1|nsec1|nprofile1)|^[0-9a-f]{64}$`)
+
+// Nip05Response : /.well-known/nostr.json of NIP-05
+type Nip05Response struct {
+	Names  map[string]string   `json:"names"`
+	Relays map[string][]string `json:"relays,omitempty"`
+}
+
+// NormalizeUsername lowercases a username and checks it can be used in a lightning address
+func NormalizeUsername(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !usernamePattern.MatchString(name) {
+		return "", InvalidUsernameError
+	}
+	return name, nil
+}
+
+// isReservedUsername tells whether a normalized name is kept from users, the built in names,
+// RESERVED_USERNAMES and names that look like nostr keys
+func (svc *LndhubService) isReservedUsername(name string) bool {
+	if pubkeyLikePattern.MatchString(name) {
+		return true
+	}
+	for _, reserved := range reservedUsernames {
+		if name == reserved {
+			return true
+		}
+	}
+	for _, reserved := range svc.Config.ReservedUsernames {
+		if name == strings.ToLower(strings.TrimSpace(reserved)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetUsername claims a username for the user, replacing the one they had. An empty name releases
+// the current username. A released name is held for the user for USERNAME_QUARANTINE so nobody
+// else can pass as them right away, and users can change their name once per USERNAME_CHANGE_COOLDOWN.
+func (svc *LndhubService) SetUsername(ctx context.Context, user *models.User, name string) (*models.User, error) {
+	if name != "" {
+		normalized, err := NormalizeUsername(name)
+		if err != nil {
+			return nil, err
+		}
+		if svc.isReservedUsername(normalized) {
+			return nil, UsernameReservedError
+		}
+		name = normalized
+	}
+	if name == user.Username {
+		return user, nil
+	}
+	cooldown := time.Duration(svc.Config.UsernameChangeCooldown) * time.Second
+	if cooldown > 0 && !user.UsernameUpdatedAt.IsZero() && time.Since(user.UsernameUpdatedAt) < cooldown {
+		return nil, UsernameChangeTooSoonError
+	}
+	previous := user.Username
+	err := svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if name != "" {
+			taken, err := tx.NewSelect().Model((*models.User)(nil)).
+				Where("username = ? AND id <> ?", name, user.ID).
+				Exists(ctx)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return UsernameTakenError
+			}
+			hold := models.UsernameHold{}
+			err = tx.NewSelect().Model(&hold).Where("name = ?", name).For("UPDATE").Limit(1).Scan(ctx)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if err == nil {
+				expired := !hold.ExpiresAt.IsZero() && time.Now().After(hold.ExpiresAt)
+				if hold.Kind == models.UsernameHoldRevoked || (hold.UserID != user.ID && !expired) {
+					return UsernameTakenError
+				}
+				// the former owner takes the name back or the hold ran out
+				if _, err := tx.NewDelete().Model(&hold).WherePK().Exec(ctx); err != nil {
+					return err
+				}
+			}
+		}
+		if previous != "" && svc.Config.UsernameQuarantine > 0 {
+			hold := &models.UsernameHold{
+				Name:      previous,
+				UserID:    user.ID,
+				Kind:      models.UsernameHoldReleased,
+				ExpiresAt: time.Now().Add(time.Duration(svc.Config.UsernameQuarantine) * time.Second),
+			}
+			if err := upsertUsernameHoldInTx(ctx, tx, hold); err != nil {
+				return err
+			}
+		}
+		user.Username = name
+		user.UsernameUpdatedAt = time.Now()
+		_, err := tx.NewUpdate().Model(user).Column("username", "username_updated_at", "updated_at").WherePK().Exec(ctx)
+		return err
+	})
+	if err != nil {
+		user.Username = previous
+		// lost a race for the name against the unique index
+		if strings.Contains(err.Error(), "index_users_on_username") {
+			return nil, UsernameTakenError
+		}
+		return nil, err
+	}
+	svc.Logger.Infof("User id:%d set username %q", user.ID, name)
+	return user, nil
+}
+
+func upsertUsernameHoldInTx(ctx context.Context, tx bun.Tx, hold *models.UsernameHold) error {
+	_, err := tx.NewInsert().Model(hold).
+		On("CONFLICT (name) DO UPDATE").
+		Set("user_id = EXCLUDED.user_id").
+		Set("kind = EXCLUDED.kind").
+		Set("reason = EXCLUDED.reason").
+		Set("operator = EXCLUDED.operator").
+		Set("expires_at = EXCLUDED.expires_at").
+		Set("created_at = EXCLUDED.created_at").
+		Returning("id").
+		Exec(ctx)
+	return err
+}
+
+// RevokeUsername takes a name away from the user holding it and blocks it until UnblockUsername.
+// Names nobody holds can be blocked the same way.
+func (svc *LndhubService) RevokeUsername(ctx context.Context, name string, reason string, operator string) (*models.UsernameHold, error) {
+	normalized, err := NormalizeUsername(name)
+	if err != nil {
+		return nil, err
+	}
+	hold := &models.UsernameHold{
+		Name:      normalized,
+		Kind:      models.UsernameHoldRevoked,
+		Reason:    reason,
+		Operator:  operator,
+		CreatedAt: time.Now(),
+	}
+	holder := &models.User{}
+	err = svc.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewSelect().Model(holder).Where("username = ?", normalized).For("UPDATE").Limit(1).Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			holder = nil
+		} else if err != nil {
+			return err
+		} else {
+			hold.UserID = holder.ID
+			holder.Username = ""
+			_, err = tx.NewUpdate().Model(holder).Column("username", "updated_at").WherePK().Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		return upsertUsernameHoldInTx(ctx, tx, hold)
+	})
+	if err != nil {
+		return nil, err
+	}
+	svc.Logger.Infof("Operator %s revoked username %q of user_id:%d: %s", operator, normalized, hold.UserID, reason)
+	if holder != nil {
+		_ = svc.SendNip4Notification(ctx, fmt.Sprintf("your username %s was revoked%s", normalized, memoSuffix(reason)), holder.Pubkey)
+	}
+	return hold, nil
+}
+
+// UnblockUsername lifts the hold of a revoked or released name so anyone can claim it
+func (svc *LndhubService) UnblockUsername(ctx context.Context, name string) (*models.UsernameHold, error) {
+	normalized, err := NormalizeUsername(name)
+	if err != nil {
+		return nil, err
+	}
+	hold := &models.UsernameHold{}
+	res, err := svc.DB.NewDelete().Model(hold).Where("name = ?", normalized).Returning("*").Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return nil, UsernameHoldNotFoundError
+	}
+	return hold, nil
+}
+
+// UsernameHolds returns the latest holds that still keep their name from being claimed
+func (svc *LndhubService) UsernameHolds(ctx context.Context) ([]models.UsernameHold, error) {
+	holds := []models.UsernameHold{}
+	err := svc.DB.NewSelect().Model(&holds).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		OrderExpr("id DESC").
+		Limit(100).
+		Scan(ctx)
+	return holds, err
+}
+
+// FindUserByUsername returns the active user with a username
+func (svc *LndhubService) FindUserByUsername(ctx context.Context, name string) (*models.User, error) {
+	normalized, err := NormalizeUsername(name)
+	if err != nil {
+		return nil, UsernameNotFoundError
+	}
+	user := &models.User{}
+	err = svc.DB.NewSelect().Model(user).
+		Where("username = ? AND deactivated = false AND deleted = false", normalized).
+		Limit(1).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, UsernameNotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Nip05For resolves a name to its pubkey and the hub's relays for NIP-05 verification. The root
+// name _ is the hub itself, unknown names resolve to no names.
+func (svc *LndhubService) Nip05For(ctx context.Context, name string) (*Nip05Response, error) {
+	res := &Nip05Response{Names: map[string]string{}}
+	pubkey := ""
+	if name == Nip05RootName {
+		pubkey = svc.Config.TahubPublicKey
+	} else {
+		user, err := svc.FindUserByUsername(ctx, name)
+		if errors.Is(err, UsernameNotFoundError) {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name = user.Username
+		pubkey = user.Pubkey
+	}
+	res.Names[name] = pubkey
+	if len(svc.Config.RelayURI) > 0 {
+		res.Relays = map[string][]string{pubkey: svc.Config.RelayURI}
+	}
+	return res, nil
+}
+
+// IsUsernameUserError tells the errors of claiming a username the user can act on from failures of the hub
+func IsUsernameUserError(err error) bool {
+	for _, userErr := range []error{
+		InvalidUsernameError,
+		UsernameTakenError,
+		UsernameReservedError,
+		UsernameChangeTooSoonError,
+		UsernameHoldNotFoundError,
+	} {
+		if errors.Is(err, userErr) {
+			return true
+		}
+	}
+	return false
+}
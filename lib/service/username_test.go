@@ -0,0 +1,25 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReservedUsername(t *testing.T) {
+	svc := &LndhubService{Config: &Config{ReservedUsernames: []string{" Treasury "}}}
+	for _, reserved := range []string{
+		"_",
+		"admin",
+		"support",
+		"treasury",
+		"npub1qqqq",
+		"nsec1qqqq",
+		"3bf0c63fcb93463407af97a5e5ee64fa883d107ef9e558472c4eb9aaaefa459d",
+	} {
+		assert.True(t, svc.isReservedUsername(reserved), reserved)
+	}
+	for _, free := range []string{"alice", "admin2", "npub", "3bf0c63fcb93463407af97a5e5ee64fa"} {
+		assert.False(t, svc.isReservedUsername(free), free)
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/uptrace/bun"
+)
+
+// send state reported by tapd once the transfer is confirmed and the proofs are stored
+const tapdSendStateComplete = "SendStateComplete"
+
+var WithdrawalProofNotReadyError = errors.New("withdrawal proof is not available yet")
+
+// withdrawalOutput finds the transfer output paying the address' script key, falling back to the
+// first output that is not owned by the hub's tapd
+func withdrawalOutput(transfer *taprpc.AssetTransfer, scriptKey []byte) *taprpc.TransferOutput {
+	if transfer == nil {
+		return nil
+	}
+	var remote *taprpc.TransferOutput
+	for _, output := range transfer.Outputs {
+		if len(scriptKey) > 0 && bytes.Equal(output.ScriptKey, scriptKey) {
+			return output
+		}
+		if remote == nil && !output.ScriptKeyIsLocal {
+			remote = output
+		}
+	}
+	return remote
+}
+
+// withdrawalProofRequest locates the proof of the recipient output on tapd
+func withdrawalProofRequest(withdrawal *models.AssetWithdrawal) (*taprpc.ExportProofRequest, error) {
+	assetId, err := hex.DecodeString(withdrawal.TaAssetID)
+	if err != nil {
+		return nil, err
+	}
+	scriptKey, err := hex.DecodeString(withdrawal.ScriptKey)
+	if err != nil {
+		return nil, err
+	}
+	outpoint, err := wire.NewOutPointFromString(withdrawal.Outpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &taprpc.ExportProofRequest{
+		AssetId:   assetId,
+		ScriptKey: scriptKey,
+		Outpoint:  &taprpc.OutPoint{Txid: outpoint.Hash[:], OutputIndex: outpoint.Index},
+	}, nil
+}
+
+// insertWithdrawal records the recipient output of an external send made in withdrawal mode
+func (svc *LndhubService) insertWithdrawal(ctx context.Context, entry *models.TransactionEntry, scriptKey []byte, transfer *taprpc.AssetTransfer) (*models.AssetWithdrawal, error) {
+	output := withdrawalOutput(transfer, scriptKey)
+	if output == nil || output.Anchor == nil {
+		return nil, fmt.Errorf("recipient output of transfer for entry %d not found", entry.ID)
+	}
+	withdrawal := &models.AssetWithdrawal{
+		UserID:             entry.UserID,
+		TaAssetID:          entry.TaAssetID,
+		TransactionEntryID: entry.ID,
+		Address:            entry.Address,
+		Amount:             entry.Amount,
+		ScriptKey:          hex.EncodeToString(output.ScriptKey),
+		Outpoint:           output.Anchor.Outpoint,
+	}
+	_, err := svc.DB.NewInsert().Model(withdrawal).Exec(ctx)
+	return withdrawal, err
+}
+
+// ExportWithdrawalProof fetches the proof file of the withdrawal from tapd and stores it, tapd only
+// has the proof once the send is complete
+func (svc *LndhubService) ExportWithdrawalProof(ctx context.Context, withdrawal *models.AssetWithdrawal) error {
+	if withdrawal.ProofFile != "" {
+		return nil
+	}
+	req, err := withdrawalProofRequest(withdrawal)
+	if err != nil {
+		return err
+	}
+	proofFile, err := svc.TapdClient.ExportProof(ctx, req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", WithdrawalProofNotReadyError, err)
+	}
+	withdrawal.ProofFile = hex.EncodeToString(proofFile.RawProofFile)
+	withdrawal.ProofExportedAt = bun.NullTime{Time: time.Now()}
+	_, err = svc.DB.NewUpdate().Model(withdrawal).Column("proof_file", "proof_exported_at").WherePK().Exec(ctx)
+	return err
+}
+
+// ExportWithdrawalProofs exports the proofs of all withdrawals that are still missing one and lets
+// the users know where to download them
+func (svc *LndhubService) ExportWithdrawalProofs(ctx context.Context) error {
+	withdrawals := []models.AssetWithdrawal{}
+	err := svc.DB.NewSelect().Model(&withdrawals).Where("proof_exported_at IS NULL").Scan(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range withdrawals {
+		withdrawal := &withdrawals[i]
+		if err := svc.ExportWithdrawalProof(ctx, withdrawal); err != nil {
+			if !errors.Is(err, WithdrawalProofNotReadyError) {
+				sentry.CaptureException(err)
+			}
+			svc.Logger.Errorf("Could not export proof of withdrawal id:%d: %v", withdrawal.ID, err)
+			continue
+		}
+		svc.notifyWithdrawalComplete(ctx, withdrawal)
+	}
+	return nil
+}
+
+func (svc *LndhubService) notifyWithdrawalComplete(ctx context.Context, withdrawal *models.AssetWithdrawal) {
+	user, err := svc.FindUser(ctx, withdrawal.UserID)
+	if err != nil {
+		svc.Logger.Errorf("Could not find user of withdrawal id:%d: %v", withdrawal.ID, err)
+		return
+	}
+	message := fmt.Sprintf("withdrawal complete: %d %s, proof file: /v2/withdrawals/%d/proof", withdrawal.Amount, withdrawal.TaAssetID, withdrawal.ID)
+	_ = svc.SendNip4Notification(ctx, message, user.Pubkey)
+}
+
+func (svc *LndhubService) WithdrawalsFor(ctx context.Context, userId int64) ([]models.AssetWithdrawal, error) {
+	withdrawals := []models.AssetWithdrawal{}
+	err := svc.DB.NewSelect().Model(&withdrawals).Where("user_id = ?", userId).OrderExpr("id DESC").Limit(100).Scan(ctx)
+	return withdrawals, err
+}
+
+// WithdrawalProofFor returns the raw proof file of one of the user's withdrawals, exporting it
+// from tapd if that did not happen yet
+func (svc *LndhubService) WithdrawalProofFor(ctx context.Context, userId int64, withdrawalId int64) (*models.AssetWithdrawal, []byte, error) {
+	withdrawal := &models.AssetWithdrawal{}
+	err := svc.DB.NewSelect().Model(withdrawal).Where("id = ? AND user_id = ?", withdrawalId, userId).Limit(1).Scan(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := svc.ExportWithdrawalProof(ctx, withdrawal); err != nil {
+		return withdrawal, nil, err
+	}
+	proofFile, err := hex.DecodeString(withdrawal.ProofFile)
+	return withdrawal, proofFile, err
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/getAlby/lndhub.go/db/models"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithdrawalOutput(t *testing.T) {
+	transfer := &taprpc.AssetTransfer{
+		Outputs: []*taprpc.TransferOutput{
+			{ScriptKey: []byte{1}, ScriptKeyIsLocal: true},
+			{ScriptKey: []byte{2}},
+			{ScriptKey: []byte{3}},
+		},
+	}
+	assert.Equal(t, []byte{3}, withdrawalOutput(transfer, []byte{3}).ScriptKey)
+	// unknown script key, the first remote output is the recipient
+	assert.Equal(t, []byte{2}, withdrawalOutput(transfer, []byte{9}).ScriptKey)
+	assert.Nil(t, withdrawalOutput(nil, []byte{3}))
+}
+
+func TestWithdrawalProofRequest(t *testing.T) {
+	txid := "8f4c9ae3b5e2a1f3c3d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7"
+	withdrawal := &models.AssetWithdrawal{
+		TaAssetID: "abcd",
+		ScriptKey: "02ff",
+		Outpoint:  txid + ":1",
+	}
+	req, err := withdrawalProofRequest(withdrawal)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xab, 0xcd}, req.AssetId)
+	assert.Equal(t, []byte{0x02, 0xff}, req.ScriptKey)
+	assert.Equal(t, uint32(1), req.Outpoint.OutputIndex)
+	// tapd expects the txid in internal byte order
+	outpoint, _ := wire.NewOutPointFromString(txid + ":1")
+	assert.Equal(t, outpoint.Hash[:], req.Outpoint.Txid)
+	assert.Equal(t, byte(0xb7), req.Outpoint.Txid[0])
+
+	withdrawal.Outpoint = "invalid"
+	_, err = withdrawalProofRequest(withdrawal)
+	assert.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	v2controllers "github.com/getAlby/lndhub.go/controllers_v2"
 	"github.com/getAlby/lndhub.go/lib/service"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 )
 // TODO for the purpose of Tahub it is worth considering trimming/refactoring this
 //		to be for Admin endpoints only.
@@ -12,6 +13,17 @@ func RegisterV2Endpoints(svc *service.LndhubService, e *echo.Echo, secured *echo
 	e.GET("/v2/pubkey", v2controllers.NewNostrController(svc).GetServerPubkey, strictRateLimitMiddleware, logMw)
 	// get universe assets
 	e.GET("/v2/universe-assets", v2controllers.NewUniverseController(svc).UniverseAssets, strictRateLimitMiddleware, logMw)
+	burnCtrl := v2controllers.NewBurnController(svc)
+	swapCtrl := v2controllers.NewSwapController(svc)
+	e.GET("/v2/assets/:asset_id/supply", burnCtrl.Supply, strictRateLimitMiddleware, logMw)
+	orderCtrl := v2controllers.NewOrderController(svc)
+	e.GET("/v2/orderbook", orderCtrl.OrderBook, strictRateLimitMiddleware, logMw)
+	e.GET("/v2/trades", orderCtrl.Trades, strictRateLimitMiddleware, logMw)
+	// lightning addresses of the users, wallets call these from the browser
+	lnurlCtrl := v2controllers.NewLnurlController(svc)
+	e.GET("/.well-known/lnurlp/:name", lnurlCtrl.PayParams, middleware.CORS(), strictRateLimitMiddleware, logMw)
+	e.GET("/lnurlp/:name/callback", lnurlCtrl.Callback, middleware.CORS(), strictRateLimitMiddleware, logMw)
+	e.GET("/.well-known/nostr.json", v2controllers.NewNip05Controller(svc).NostrJson, middleware.CORS(), strictRateLimitMiddleware, logMw)
 	// since tahub users register by pubkey, v2 auth returns tokens if a message
 	// is signed by the pubkey of our user to the server pubkey
 	e.POST("/v2/auth", v2controllers.NewPubkeyAuthController(svc).PubkeyAuth, strictRateLimitMiddleware, adminMw, logMw)
@@ -22,6 +34,23 @@ func RegisterV2Endpoints(svc *service.LndhubService, e *echo.Echo, secured *echo
 	//require admin token for update user endpoint
 	if svc.Config.AdminToken != "" {
 		e.PUT("/v2/admin/users", v2controllers.NewUpdateUserController(svc).UpdateUser, strictRateLimitMiddleware, adminMw)
+		adjustmentCtrl := v2controllers.NewAdjustmentController(svc)
+		e.POST("/v2/admin/adjustments", adjustmentCtrl.PostAdjustment, strictRateLimitMiddleware, adminMw, logMw)
+		e.GET("/v2/admin/adjustments", adjustmentCtrl.ListAdjustments, strictRateLimitMiddleware, adminMw, logMw)
+		e.GET("/v2/admin/solvency", v2controllers.NewSolvencyController(svc).Solvency, strictRateLimitMiddleware, adminMw, logMw)
+		e.GET("/v2/admin/balance-sheet", v2controllers.NewBalanceSheetController(svc).BalanceSheet, strictRateLimitMiddleware, adminMw, logMw)
+		mintCtrl := v2controllers.NewMintController(svc)
+		e.POST("/v2/admin/mint/assets", mintCtrl.MintAsset, strictRateLimitMiddleware, adminMw, logMw)
+		e.POST("/v2/admin/mint/finalize", mintCtrl.FinalizeBatch, strictRateLimitMiddleware, adminMw, logMw)
+		e.POST("/v2/admin/mint/cancel", mintCtrl.CancelBatch, strictRateLimitMiddleware, adminMw, logMw)
+		e.GET("/v2/admin/mint/batches", mintCtrl.ListBatches, strictRateLimitMiddleware, adminMw, logMw)
+		e.POST("/v2/admin/burns", burnCtrl.AdminBurn, strictRateLimitMiddleware, adminMw, logMw)
+		e.POST("/v2/admin/swaps/liquidity", swapCtrl.FundLiquidity, strictRateLimitMiddleware, adminMw, logMw)
+		e.GET("/v2/admin/swaps/liquidity", swapCtrl.Liquidity, strictRateLimitMiddleware, adminMw, logMw)
+		usernameCtrl := v2controllers.NewUsernameController(svc)
+		e.POST("/v2/admin/usernames/revoke", usernameCtrl.RevokeUsername, strictRateLimitMiddleware, adminMw, logMw)
+		e.GET("/v2/admin/username-holds", usernameCtrl.ListHolds, strictRateLimitMiddleware, adminMw, logMw)
+		e.DELETE("/v2/admin/username-holds/:name", usernameCtrl.UnblockUsername, strictRateLimitMiddleware, adminMw, logMw)
 	}
 	// invoiceCtrl := v2controllers.NewInvoiceController(svc)
 	// keysendCtrl := v2controllers.NewKeySendController(svc)
@@ -32,7 +61,37 @@ func RegisterV2Endpoints(svc *service.LndhubService, e *echo.Echo, secured *echo
 	// REST Tahub actions with nostr abstracted
 	secured.GET("/v2/balances/all", v2controllers.NewBalanceController(svc).Balances, strictRateLimitMiddleware, logMw)
 	secured.POST("/v2/create-address", v2controllers.NewAddressController(svc).CreateAddress, strictRateLimitMiddleware, logMw)
-	secured.POST("/v2/transfer", v2controllers.NewTransferController(svc).Transfer, strictRateLimitMiddleware, logMw)
+	transferCtrl := v2controllers.NewTransferController(svc)
+	secured.POST("/v2/transfer", transferCtrl.Transfer, strictRateLimitMiddleware, logMw)
+	secured.POST("/v2/transfer/internal", transferCtrl.InternalTransfer, strictRateLimitMiddleware, logMw)
+	secured.GET("/v2/sends", transferCtrl.Sends, strictRateLimitMiddleware, logMw)
+	secured.GET("/v2/receives", transferCtrl.Receives, strictRateLimitMiddleware, logMw)
+	paymentRequestCtrl := v2controllers.NewPaymentRequestController(svc)
+	secured.POST("/v2/payment-requests", paymentRequestCtrl.Create, strictRateLimitMiddleware, logMw)
+	secured.GET("/v2/payment-requests", paymentRequestCtrl.List, strictRateLimitMiddleware, logMw)
+	secured.GET("/v2/payment-requests/:id", paymentRequestCtrl.Get, strictRateLimitMiddleware, logMw)
+	secured.POST("/v2/payment-requests/:id/pay", paymentRequestCtrl.Pay, strictRateLimitMiddleware, logMw)
+	secured.DELETE("/v2/payment-requests/:id", paymentRequestCtrl.Cancel, strictRateLimitMiddleware, logMw)
+	checkoutCtrl := v2controllers.NewCheckoutController(svc)
+	secured.POST("/v2/checkouts", checkoutCtrl.Create, strictRateLimitMiddleware, logMw)
+	secured.GET("/v2/checkouts", checkoutCtrl.List, strictRateLimitMiddleware, logMw)
+	secured.GET("/v2/checkouts/:id", checkoutCtrl.Get, strictRateLimitMiddleware, logMw)
+	secured.GET("/v2/transactions", v2controllers.NewTransactionsController(svc).Transactions, strictRateLimitMiddleware, logMw)
+	pricesCtrl := v2controllers.NewPricesController(svc)
+	secured.GET("/v2/prices", pricesCtrl.Prices, strictRateLimitMiddleware, logMw)
+	secured.GET("/v2/prices/:asset_id", pricesCtrl.History, strictRateLimitMiddleware, logMw)
+	secured.POST("/v2/swaps/quote", swapCtrl.Quote, strictRateLimitMiddleware, logMw)
+	secured.POST("/v2/swaps/execute", swapCtrl.Execute, strictRateLimitMiddleware, logMw)
+	secured.POST("/v2/orders", orderCtrl.PlaceOrder, strictRateLimitMiddleware, logMw)
+	secured.GET("/v2/orders", orderCtrl.Orders, strictRateLimitMiddleware, logMw)
+	secured.DELETE("/v2/orders/:id", orderCtrl.CancelOrder, strictRateLimitMiddleware, logMw)
+	withdrawalCtrl := v2controllers.NewWithdrawalController(svc)
+	secured.GET("/v2/withdrawals", withdrawalCtrl.Withdrawals, strictRateLimitMiddleware, logMw)
+	secured.GET("/v2/withdrawals/:id/proof", withdrawalCtrl.Proof, strictRateLimitMiddleware, logMw)
+	assetInvoiceCtrl := v2controllers.NewAssetInvoiceController(svc)
+	secured.POST("/v2/invoices/asset", assetInvoiceCtrl.AddAssetInvoice, strictRateLimitMiddleware, logMw)
+	securedWithStrictRateLimit.POST("/v2/payments/asset", assetInvoiceCtrl.PayAssetInvoice, logMw)
+	securedWithStrictRateLimit.POST("/v2/burns", burnCtrl.Burn, logMw)
 
 	// secured.POST("/v2/invoices", invoiceCtrl.AddInvoice)
 	// secured.GET("/v2/invoices/incoming", invoiceCtrl.GetIncomingInvoices)
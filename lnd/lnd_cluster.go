@@ -104,6 +104,41 @@ func (cluster *LNDCluster) DecodeBolt11(ctx context.Context, bolt11 string, opti
 	return cluster.ActiveNode.DecodeBolt11(ctx, bolt11, options...)
 }
 
+// WalletBalance sums the on-chain balances of all nodes, they are all part of the hub's holdings
+func (cluster *LNDCluster) WalletBalance(ctx context.Context, req *lnrpc.WalletBalanceRequest, options ...grpc.CallOption) (*lnrpc.WalletBalanceResponse, error) {
+	result := &lnrpc.WalletBalanceResponse{}
+	for _, node := range cluster.Nodes {
+		balance, err := node.WalletBalance(ctx, req, options...)
+		if err != nil {
+			return nil, err
+		}
+		result.TotalBalance += balance.TotalBalance
+		result.ConfirmedBalance += balance.ConfirmedBalance
+		result.UnconfirmedBalance += balance.UnconfirmedBalance
+		result.LockedBalance += balance.LockedBalance
+	}
+	return result, nil
+}
+
+// ChannelBalance sums the local channel balances of all nodes
+func (cluster *LNDCluster) ChannelBalance(ctx context.Context, req *lnrpc.ChannelBalanceRequest, options ...grpc.CallOption) (*lnrpc.ChannelBalanceResponse, error) {
+	result := &lnrpc.ChannelBalanceResponse{
+		LocalBalance:            &lnrpc.Amount{},
+		PendingOpenLocalBalance: &lnrpc.Amount{},
+	}
+	for _, node := range cluster.Nodes {
+		balance, err := node.ChannelBalance(ctx, req, options...)
+		if err != nil {
+			return nil, err
+		}
+		result.LocalBalance.Sat += balance.GetLocalBalance().GetSat()
+		result.LocalBalance.Msat += balance.GetLocalBalance().GetMsat()
+		result.PendingOpenLocalBalance.Sat += balance.GetPendingOpenLocalBalance().GetSat()
+		result.PendingOpenLocalBalance.Msat += balance.GetPendingOpenLocalBalance().GetMsat()
+	}
+	return result, nil
+}
+
 func (cluster *LNDCluster) IsIdentityPubkey(pubkey string) (isOurPubkey bool) {
 	for _, node := range cluster.Nodes {
 		if node.GetMainPubkey() == pubkey {
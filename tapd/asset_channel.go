@@ -0,0 +1,86 @@
+package tapd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+var AssetChannelsUnsupportedError = errors.New("taproot asset channels are not supported by the connected tapd")
+
+// AssetChannelClient : Taproot Assets over Lightning, mirrors the rfqrpc and tapchannelrpc services.
+// Those services ship with tapd v0.4, the taprpc version this module is built against
+// does not include their generated clients yet.
+type AssetChannelClient interface {
+	// AddInvoice negotiates a buy quote with the asset channel peer and creates
+	// a BOLT11 invoice for the quoted amount of sats
+	AddInvoice(ctx context.Context, req *AssetInvoiceRequest) (*AssetInvoiceResponse, error)
+	// QuotePayment negotiates a sell quote for paying the invoice with the asset
+	QuotePayment(ctx context.Context, req *AssetPaymentQuoteRequest) (*AssetQuote, error)
+	// SendPayment pays the invoice using the asset channel, spending at most the quoted asset amount
+	SendPayment(ctx context.Context, req *AssetPaymentRequest) (*AssetPaymentResponse, error)
+}
+
+type AssetQuote struct {
+	// hex encoded rfq id
+	ID         string
+	PeerPubkey string
+	// asset units and the sats they are exchanged for
+	AssetAmount uint64
+	AmtMsat     uint64
+	Expiry      int64
+}
+
+type AssetInvoiceRequest struct {
+	AssetID     string
+	AssetAmount uint64
+	PeerPubkey  string
+	Memo        string
+	Expiry      int64
+}
+
+type AssetInvoiceResponse struct {
+	AcceptedBuyQuote *AssetQuote
+	InvoiceResult    *lnrpc.AddInvoiceResponse
+}
+
+type AssetPaymentQuoteRequest struct {
+	AssetID        string
+	PaymentRequest string
+	PeerPubkey     string
+}
+
+type AssetPaymentRequest struct {
+	AssetID        string
+	PaymentRequest string
+	PeerPubkey     string
+	RfqID          string
+	FeeLimitSat    int64
+}
+
+type AssetPaymentResponse struct {
+	Payment *lnrpc.Payment
+	// asset units spent including routing fees
+	AssetAmount uint64
+}
+
+// UnsupportedAssetChannelClient is used until the tapd client is built against a taprpc version
+// that provides the asset channel services, every call fails with AssetChannelsUnsupportedError
+type UnsupportedAssetChannelClient struct{}
+
+func (client *UnsupportedAssetChannelClient) AddInvoice(ctx context.Context, req *AssetInvoiceRequest) (*AssetInvoiceResponse, error) {
+	return nil, AssetChannelsUnsupportedError
+}
+
+func (client *UnsupportedAssetChannelClient) QuotePayment(ctx context.Context, req *AssetPaymentQuoteRequest) (*AssetQuote, error) {
+	return nil, AssetChannelsUnsupportedError
+}
+
+func (client *UnsupportedAssetChannelClient) SendPayment(ctx context.Context, req *AssetPaymentRequest) (*AssetPaymentResponse, error) {
+	return nil, AssetChannelsUnsupportedError
+}
+
+func InitAssetChannelClient(c *TapdConfig) AssetChannelClient {
+	return &UnsupportedAssetChannelClient{}
+}
@@ -5,16 +5,20 @@ import (
 )
 
 const (
-	TAPD_CLIENT_TYPE = "tapd"
+	TAPD_CLIENT_TYPE         = "tapd"
+	TAPD_CLUSTER_CLIENT_TYPE = "tapd_cluster"
 )
-/// TODO do we need to import anything from lnd.config ?
+
+// TODO do we need to import anything from lnd.config ?
 type TapdConfig struct {
-	TAPDClientType   string `envconfig:"TAPD_CLIENT_TYPE" default:"tapd"`
-	TAPDAddress      string `envconfig:"TAPD_ADDRESS" required:"true"`
-	TAPDMacaroonFile string `envconfig:"TAPD_MACAROON_FILE"`
-	TAPDCertFile     string `envconfig:"TAPD_CERT_FILE"`
-	TAPDMacaroonHex  string `envconfig:"TAPD_MACAROON_HEX"`
-	TAPDCertHex      string `envconfig:"TAPD_CERT_HEX"`  
+	TAPDClientType            string `envconfig:"TAPD_CLIENT_TYPE" default:"tapd"`
+	TAPDAddress               string `envconfig:"TAPD_ADDRESS" required:"true"`
+	TAPDMacaroonFile          string `envconfig:"TAPD_MACAROON_FILE"`
+	TAPDCertFile              string `envconfig:"TAPD_CERT_FILE"`
+	TAPDMacaroonHex           string `envconfig:"TAPD_MACAROON_HEX"`
+	TAPDCertHex               string `envconfig:"TAPD_CERT_HEX"`
+	TAPDClusterLivenessPeriod int    `envconfig:"TAPD_CLUSTER_LIVENESS_PERIOD" default:"10"`
+	TAPDClusterRequireSync    bool   `envconfig:"TAPD_CLUSTER_REQUIRE_SYNC" default:"true"`
 }
 
 func LoadConfig() (c *TapdConfig, err error) {
@@ -26,4 +30,3 @@ func LoadConfig() (c *TapdConfig, err error) {
 	}
 	return c, nil
 }
-
@@ -146,3 +146,39 @@ func (wrapper *TAPDWrapper) SubscribeReceiveAssetEvent(ctx context.Context, req
 func (wrapper *TAPDWrapper) SubscribeSendAssetEvent(ctx context.Context, req *taprpc.SubscribeSendAssetEventNtfnsRequest, options ...grpc.CallOption) (SubscribeSendAssetEventWrapper, error) {
 	return wrapper.client.SubscribeSendAssetEventNtfns(ctx, req, options...)
 }
+
+func (wrapper *TAPDWrapper) MintAsset(ctx context.Context, req *mintrpc.MintAssetRequest, options ...grpc.CallOption) (*mintrpc.MintAssetResponse, error) {
+	return wrapper.mintClient.MintAsset(ctx, req, options...)
+}
+
+func (wrapper *TAPDWrapper) FinalizeBatch(ctx context.Context, req *mintrpc.FinalizeBatchRequest, options ...grpc.CallOption) (*mintrpc.FinalizeBatchResponse, error) {
+	return wrapper.mintClient.FinalizeBatch(ctx, req, options...)
+}
+
+func (wrapper *TAPDWrapper) CancelBatch(ctx context.Context, req *mintrpc.CancelBatchRequest, options ...grpc.CallOption) (*mintrpc.CancelBatchResponse, error) {
+	return wrapper.mintClient.CancelBatch(ctx, req, options...)
+}
+
+func (wrapper *TAPDWrapper) ListBatches(ctx context.Context, req *mintrpc.ListBatchRequest, options ...grpc.CallOption) (*mintrpc.ListBatchResponse, error) {
+	return wrapper.mintClient.ListBatches(ctx, req, options...)
+}
+
+func (wrapper *TAPDWrapper) BurnAsset(ctx context.Context, req *taprpc.BurnAssetRequest, options ...grpc.CallOption) (*taprpc.BurnAssetResponse, error) {
+	return wrapper.client.BurnAsset(ctx, req, options...)
+}
+
+func (wrapper *TAPDWrapper) ExportProof(ctx context.Context, req *taprpc.ExportProofRequest, options ...grpc.CallOption) (*taprpc.ProofFile, error) {
+	return wrapper.client.ExportProof(ctx, req, options...)
+}
+
+func (wrapper *TAPDWrapper) AddrReceives(ctx context.Context, req *taprpc.AddrReceivesRequest, options ...grpc.CallOption) (*taprpc.AddrReceivesResponse, error) {
+	return wrapper.client.AddrReceives(ctx, req, options...)
+}
+
+func (wrapper *TAPDWrapper) ListTransfers(ctx context.Context, req *taprpc.ListTransfersRequest, options ...grpc.CallOption) (*taprpc.ListTransfersResponse, error) {
+	return wrapper.client.ListTransfers(ctx, req, options...)
+}
+
+func (wrapper *TAPDWrapper) FetchAssetMeta(ctx context.Context, req *taprpc.FetchAssetMetaRequest, options ...grpc.CallOption) (*taprpc.AssetMeta, error) {
+	return wrapper.client.FetchAssetMeta(ctx, req, options...)
+}
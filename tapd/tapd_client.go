@@ -2,13 +2,14 @@ package tapd
 
 import (
 	"context"
-	// "fmt"
-	// "strings"
+	"fmt"
+	"strings"
+
 	"github.com/ziflex/lecho/v3"
 	"google.golang.org/grpc"
 	"github.com/lightninglabs/taproot-assets/taprpc"
 	// "github.com/lightninglabs/taproot-assets/taprpc/assetwalletrpc"
-	// "github.com/lightninglabs/taproot-assets/taprpc/mintrpc"
+	"github.com/lightninglabs/taproot-assets/taprpc/mintrpc"
 	// "github.com/lightninglabs/taproot-assets/taprpc/tapdevrpc"
 	"github.com/lightninglabs/taproot-assets/taprpc/universerpc"	
 )
@@ -24,6 +25,15 @@ type TapdClientWrapper interface {
 	SendAsset(ctx context.Context, req *taprpc.SendAssetRequest, options ...grpc.CallOption) (*taprpc.SendAssetResponse, error)
 	SubscribeReceiveAssetEvent(ctx context.Context, req *taprpc.SubscribeReceiveAssetEventNtfnsRequest, options ...grpc.CallOption) (SubscribeReceiveAssetEventWrapper, error)
 	SubscribeSendAssetEvent(ctx context.Context, req *taprpc.SubscribeSendAssetEventNtfnsRequest, options ...grpc.CallOption) (SubscribeSendAssetEventWrapper, error)
+	MintAsset(ctx context.Context, req *mintrpc.MintAssetRequest, options ...grpc.CallOption) (*mintrpc.MintAssetResponse, error)
+	FinalizeBatch(ctx context.Context, req *mintrpc.FinalizeBatchRequest, options ...grpc.CallOption) (*mintrpc.FinalizeBatchResponse, error)
+	CancelBatch(ctx context.Context, req *mintrpc.CancelBatchRequest, options ...grpc.CallOption) (*mintrpc.CancelBatchResponse, error)
+	ListBatches(ctx context.Context, req *mintrpc.ListBatchRequest, options ...grpc.CallOption) (*mintrpc.ListBatchResponse, error)
+	BurnAsset(ctx context.Context, req *taprpc.BurnAssetRequest, options ...grpc.CallOption) (*taprpc.BurnAssetResponse, error)
+	ExportProof(ctx context.Context, req *taprpc.ExportProofRequest, options ...grpc.CallOption) (*taprpc.ProofFile, error)
+	AddrReceives(ctx context.Context, req *taprpc.AddrReceivesRequest, options ...grpc.CallOption) (*taprpc.AddrReceivesResponse, error)
+	ListTransfers(ctx context.Context, req *taprpc.ListTransfersRequest, options ...grpc.CallOption) (*taprpc.ListTransfersResponse, error)
+	FetchAssetMeta(ctx context.Context, req *taprpc.FetchAssetMetaRequest, options ...grpc.CallOption) (*taprpc.AssetMeta, error)
 }
 
 type SubscribeReceiveAssetEventWrapper interface {
@@ -35,6 +45,17 @@ type SubscribeSendAssetEventWrapper interface {
 }
 
 func InitTAPDClient(c *TapdConfig, logger *lecho.Logger, ctx context.Context) (TapdClientWrapper, error) {
+	switch c.TAPDClientType {
+	case TAPD_CLIENT_TYPE:
+		return InitSingleTAPDClient(c, ctx)
+	case TAPD_CLUSTER_CLIENT_TYPE:
+		return InitTAPDCluster(c, logger, ctx)
+	default:
+		return nil, fmt.Errorf("Did not recognize tapd client type %s", c.TAPDClientType)
+	}
+}
+
+func InitSingleTAPDClient(c *TapdConfig, ctx context.Context) (TapdClientWrapper, error) {
 	client, err := NewTAPDClient(TAPDOptions{
 		Address: c.TAPDAddress,
 		MacaroonFile: c.TAPDAddress,
@@ -54,4 +75,39 @@ func InitTAPDClient(c *TapdConfig, logger *lecho.Logger, ctx context.Context) (T
 	}
 
 	return client, nil
-}
\ No newline at end of file
+}
+
+func InitTAPDCluster(c *TapdConfig, logger *lecho.Logger, ctx context.Context) (TapdClientWrapper, error) {
+	nodes := []TapdClientWrapper{}
+	//interpret tapd address, macaroon file, cert file as comma seperated values
+	addresses := strings.Split(c.TAPDAddress, ",")
+	macaroons := strings.Split(c.TAPDMacaroonFile, ",")
+	certs := strings.Split(c.TAPDCertFile, ",")
+	if len(addresses) != len(macaroons) ||
+		len(addresses) != len(certs) {
+		return nil, fmt.Errorf("Error parsing tapd cluster config: addresses, macaroons or certs array length mismatch")
+	}
+	for i := 0; i < len(addresses); i++ {
+		n, err := NewTAPDClient(TAPDOptions{
+			Address:      addresses[i],
+			MacaroonFile: macaroons[i],
+			CertFile:     certs[i],
+		}, ctx)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	logger.Infof("Initialized tapd cluster with %d nodes", len(nodes))
+	cluster := &TapdCluster{
+		Nodes:               nodes,
+		ActiveNode:          nodes[0],
+		Logger:              logger,
+		LivenessCheckPeriod: c.TAPDClusterLivenessPeriod,
+		RequireSync:         c.TAPDClusterRequireSync,
+	}
+	//pick a healthy node before the subscriptions start, then keep checking
+	cluster.checkClusterStatus(ctx)
+	go cluster.StartLivenessLoop(ctx)
+	return cluster, nil
+}
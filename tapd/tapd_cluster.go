@@ -0,0 +1,213 @@
+package tapd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lightninglabs/taproot-assets/taprpc"
+	"github.com/lightninglabs/taproot-assets/taprpc/mintrpc"
+	"github.com/lightninglabs/taproot-assets/taprpc/universerpc"
+	"github.com/ziflex/lecho/v3"
+	"google.golang.org/grpc"
+)
+
+// TapdCluster is a set of tapd nodes of which one is active, calls are sent to the active node
+// and the liveness loop switches to the first healthy node when the active one goes down
+type TapdCluster struct {
+	Nodes               []TapdClientWrapper
+	ActiveNode          TapdClientWrapper
+	Logger              *lecho.Logger
+	LivenessCheckPeriod int
+	// also treat nodes that are not synced to the chain as unhealthy
+	RequireSync bool
+	// guards ActiveNode, the liveness loop and failing subscriptions both switch nodes
+	mu sync.RWMutex
+}
+
+func (cluster *TapdCluster) active() TapdClientWrapper {
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+	return cluster.ActiveNode
+}
+
+func (cluster *TapdCluster) StartLivenessLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(cluster.LivenessCheckPeriod) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cluster.Logger.Info("Checking tapd cluster status")
+			checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			cluster.checkClusterStatus(checkCtx)
+			cancel()
+		}
+	}
+}
+
+func (cluster *TapdCluster) checkClusterStatus(ctx context.Context) {
+	for i, node := range cluster.Nodes {
+		resp, err := node.GetInfo(ctx, &taprpc.GetInfoRequest{})
+		//if we get an error here, the node is probably offline
+		//so we move to the next node
+		if err != nil {
+			msg := fmt.Sprintf("Error connecting to tapd node %d, error %s", i, err.Error())
+			cluster.Logger.Infof(msg)
+			sentry.CaptureMessage(msg)
+			continue
+		}
+		//if the context has been canceled, return
+		if ctx.Err() == context.Canceled {
+			return
+		}
+		if cluster.RequireSync && !resp.SyncToChain {
+			msg := fmt.Sprintf("Tapd node %d is not synced to the chain yet, block height %d", i, resp.BlockHeight)
+			cluster.Logger.Infof(msg)
+			sentry.CaptureMessage(msg)
+			continue
+		}
+		//node is healthy, set it to active
+		//log & send notification to Sentry in case we're switching
+		cluster.mu.Lock()
+		if cluster.ActiveNode != node {
+			cluster.ActiveNode = node
+			message := fmt.Sprintf("Switched tapd nodes: new node %d, lnd node id %s", i, resp.LndIdentityPubkey)
+			cluster.Logger.Info(message)
+			sentry.CaptureMessage(message)
+		}
+		cluster.mu.Unlock()
+		break
+	}
+}
+
+// failover checks the nodes right away when a subscription on the active node breaks, so the
+// subscription is re-established on the new active node instead of the failed one
+func (cluster *TapdCluster) failover(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if ctx.Err() != nil {
+		return
+	}
+	cluster.checkClusterStatus(checkCtx)
+}
+
+func (cluster *TapdCluster) GetInfo(ctx context.Context, req *taprpc.GetInfoRequest, options ...grpc.CallOption) (*taprpc.GetInfoResponse, error) {
+	return cluster.active().GetInfo(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) ListAssets(ctx context.Context, req *taprpc.ListAssetRequest, options ...grpc.CallOption) (*taprpc.ListAssetResponse, error) {
+	return cluster.active().ListAssets(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) ListBalances(ctx context.Context, req *taprpc.ListBalancesRequest, options ...grpc.CallOption) (*taprpc.ListBalancesResponse, error) {
+	return cluster.active().ListBalances(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) NewAddress(ctx context.Context, req *taprpc.NewAddrRequest, options ...grpc.CallOption) (*taprpc.Addr, error) {
+	return cluster.active().NewAddress(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) GetUniverseAssets(ctx context.Context, req *universerpc.AssetRootRequest, options ...grpc.CallOption) (*universerpc.AssetRootResponse, error) {
+	return cluster.active().GetUniverseAssets(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) GetAssetStats(ctx context.Context, req *universerpc.AssetStatsQuery, options ...grpc.CallOption) (*universerpc.UniverseAssetStats, error) {
+	return cluster.active().GetAssetStats(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) GetDecodedAddress(ctx context.Context, req *taprpc.DecodeAddrRequest, options ...grpc.CallOption) (*taprpc.Addr, error) {
+	return cluster.active().GetDecodedAddress(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) SendAsset(ctx context.Context, req *taprpc.SendAssetRequest, options ...grpc.CallOption) (*taprpc.SendAssetResponse, error) {
+	return cluster.active().SendAsset(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) SubscribeReceiveAssetEvent(ctx context.Context, req *taprpc.SubscribeReceiveAssetEventNtfnsRequest, options ...grpc.CallOption) (SubscribeReceiveAssetEventWrapper, error) {
+	stream, err := cluster.active().SubscribeReceiveAssetEvent(ctx, req, options...)
+	if err != nil {
+		cluster.failover(ctx)
+		return nil, err
+	}
+	return &clusterReceiveStream{ctx: ctx, cluster: cluster, stream: stream}, nil
+}
+
+func (cluster *TapdCluster) SubscribeSendAssetEvent(ctx context.Context, req *taprpc.SubscribeSendAssetEventNtfnsRequest, options ...grpc.CallOption) (SubscribeSendAssetEventWrapper, error) {
+	stream, err := cluster.active().SubscribeSendAssetEvent(ctx, req, options...)
+	if err != nil {
+		cluster.failover(ctx)
+		return nil, err
+	}
+	return &clusterSendStream{ctx: ctx, cluster: cluster, stream: stream}, nil
+}
+
+func (cluster *TapdCluster) MintAsset(ctx context.Context, req *mintrpc.MintAssetRequest, options ...grpc.CallOption) (*mintrpc.MintAssetResponse, error) {
+	return cluster.active().MintAsset(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) FinalizeBatch(ctx context.Context, req *mintrpc.FinalizeBatchRequest, options ...grpc.CallOption) (*mintrpc.FinalizeBatchResponse, error) {
+	return cluster.active().FinalizeBatch(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) CancelBatch(ctx context.Context, req *mintrpc.CancelBatchRequest, options ...grpc.CallOption) (*mintrpc.CancelBatchResponse, error) {
+	return cluster.active().CancelBatch(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) ListBatches(ctx context.Context, req *mintrpc.ListBatchRequest, options ...grpc.CallOption) (*mintrpc.ListBatchResponse, error) {
+	return cluster.active().ListBatches(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) BurnAsset(ctx context.Context, req *taprpc.BurnAssetRequest, options ...grpc.CallOption) (*taprpc.BurnAssetResponse, error) {
+	return cluster.active().BurnAsset(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) ExportProof(ctx context.Context, req *taprpc.ExportProofRequest, options ...grpc.CallOption) (*taprpc.ProofFile, error) {
+	return cluster.active().ExportProof(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) AddrReceives(ctx context.Context, req *taprpc.AddrReceivesRequest, options ...grpc.CallOption) (*taprpc.AddrReceivesResponse, error) {
+	return cluster.active().AddrReceives(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) ListTransfers(ctx context.Context, req *taprpc.ListTransfersRequest, options ...grpc.CallOption) (*taprpc.ListTransfersResponse, error) {
+	return cluster.active().ListTransfers(ctx, req, options...)
+}
+
+func (cluster *TapdCluster) FetchAssetMeta(ctx context.Context, req *taprpc.FetchAssetMetaRequest, options ...grpc.CallOption) (*taprpc.AssetMeta, error) {
+	return cluster.active().FetchAssetMeta(ctx, req, options...)
+}
+
+// clusterReceiveStream fails over the cluster when the receive stream breaks, the subscriber
+// reconnects on the new active node and catches up on the receives it missed
+type clusterReceiveStream struct {
+	ctx     context.Context
+	cluster *TapdCluster
+	stream  SubscribeReceiveAssetEventWrapper
+}
+
+func (s *clusterReceiveStream) Recv() (*taprpc.ReceiveAssetEvent, error) {
+	event, err := s.stream.Recv()
+	if err != nil {
+		s.cluster.failover(s.ctx)
+	}
+	return event, err
+}
+
+// clusterSendStream fails over the cluster when the send stream breaks
+type clusterSendStream struct {
+	ctx     context.Context
+	cluster *TapdCluster
+	stream  SubscribeSendAssetEventWrapper
+}
+
+func (s *clusterSendStream) Recv() (*taprpc.SendAssetEvent, error) {
+	event, err := s.stream.Recv()
+	if err != nil {
+		s.cluster.failover(s.ctx)
+	}
+	return event, err
+}